@@ -0,0 +1,137 @@
+package ODINMarketFeed
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// archiveMagic starts every archive written by ArchiveTo
+const archiveMagic = "ODINARC1"
+
+// archiveRecordHeaderSize is the size of the receive time (int64 Unix nanoseconds) and
+// length (uint32) that precede each archived inner message, both big-endian
+const archiveRecordHeaderSize = 12
+
+// ErrInvalidArchive is returned by NewArchiveReader for data not written by ArchiveTo
+var ErrInvalidArchive = errors.New("not an inner message archive")
+
+// archiveWriter writes inner messages in the archive format
+type archiveWriter struct {
+	w      io.Writer
+	header [archiveRecordHeaderSize]byte
+	mu     sync.Mutex
+}
+
+// ArchiveTo writes every inner message, exactly as produced by the defragmenter, to w with
+// its receive time. The archive starts with a magic string; read it with NewArchiveReader
+// or ReplayArchive. Pass nil to stop archiving. Archiving stops with an OnError report when
+// a write fails. It is safe to call while connected.
+func (tw *ODINMarketFeedClient) ArchiveTo(w io.Writer) error {
+	if w == nil {
+		tw.archive.Store(nil)
+		return nil
+	}
+	if _, err := io.WriteString(w, archiveMagic); err != nil {
+		return err
+	}
+	tw.archive.Store(&archiveWriter{w: w})
+	return nil
+}
+
+// innerMessageReceived passes an inner message to OnInnerMessage and the archive
+func (tw *ODINMarketFeedClient) innerMessageReceived(raw []byte, receivedAt time.Time) {
+	if tw.OnInnerMessage != nil {
+		tw.invokeCallback("OnInnerMessage", func() string { return string(raw) }, func() {
+			tw.deliverBytes("OnInnerMessage", raw, tw.OnInnerMessage)
+		})
+	}
+
+	archive := tw.archive.Load()
+	if archive == nil {
+		return
+	}
+	if err := archive.write(raw, receivedAt); err != nil {
+		tw.archive.CompareAndSwap(archive, nil)
+		tw.reportError(fmt.Sprintf("Archiving stopped: %v", err))
+	}
+}
+
+func (aw *archiveWriter) write(raw []byte, receivedAt time.Time) error {
+	aw.mu.Lock()
+	defer aw.mu.Unlock()
+
+	binary.BigEndian.PutUint64(aw.header[0:8], uint64(receivedAt.UnixNano()))
+	binary.BigEndian.PutUint32(aw.header[8:12], uint32(len(raw)))
+	if _, err := aw.w.Write(aw.header[:]); err != nil {
+		return err
+	}
+	_, err := aw.w.Write(raw)
+	return err
+}
+
+// ArchivedMessage is an inner message read from an archive
+type ArchivedMessage struct {
+	ReceivedAt time.Time
+	Raw        []byte
+}
+
+// ArchiveReader reads the inner messages written by ArchiveTo
+type ArchiveReader struct {
+	r      *bufio.Reader
+	header [archiveRecordHeaderSize]byte
+}
+
+// NewArchiveReader checks the archive magic and returns a reader positioned at the first
+// message
+func NewArchiveReader(r io.Reader) (*ArchiveReader, error) {
+	reader := bufio.NewReader(r)
+
+	magic := make([]byte, len(archiveMagic))
+	if _, err := io.ReadFull(reader, magic); err != nil || string(magic) != archiveMagic {
+		return nil, ErrInvalidArchive
+	}
+	return &ArchiveReader{r: reader}, nil
+}
+
+// Next returns the next archived message, or io.EOF after the last one
+func (ar *ArchiveReader) Next() (ArchivedMessage, error) {
+	if _, err := io.ReadFull(ar.r, ar.header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return ArchivedMessage{}, fmt.Errorf("truncated archive record: %w", err)
+		}
+		return ArchivedMessage{}, err
+	}
+
+	receivedAt := time.Unix(0, int64(binary.BigEndian.Uint64(ar.header[0:8])))
+	raw := make([]byte, binary.BigEndian.Uint32(ar.header[8:12]))
+	if _, err := io.ReadFull(ar.r, raw); err != nil {
+		return ArchivedMessage{}, fmt.Errorf("truncated archive record: %w", err)
+	}
+	return ArchivedMessage{ReceivedAt: receivedAt, Raw: raw}, nil
+}
+
+// ReplayArchive decodes every message of an archive with the decoder and passes it to handle
+// together with its receive time and decode error, in archive order
+func ReplayArchive(r io.Reader, decoder *Decoder, handle func(receivedAt time.Time, msg ParsedMessage, err error)) error {
+	reader, err := NewArchiveReader(r)
+	if err != nil {
+		return err
+	}
+
+	for {
+		archived, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		msg, decodeErr := decoder.Decode(archived.Raw)
+		handle(archived.ReceivedAt, msg, decodeErr)
+	}
+}
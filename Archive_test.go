@@ -0,0 +1,97 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// withoutDelivery strips the fields of a parsed message that differ between a live and a
+// replayed decode
+func withoutDelivery(msg ParsedMessage) ParsedMessage {
+	msg.Seq = 0
+	msg.Raw = nil
+	return msg
+}
+
+func TestArchiveReplayMatchesLiveDecode(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "sessions", "*.session"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no session fixtures: %v", err)
+	}
+
+	for _, path := range paths {
+		t.Run(strings.TrimSuffix(filepath.Base(path), ".session"), func(t *testing.T) {
+			tw := newTestClient()
+			tw.decoder.Epoch = goldenEpoch
+			var live []ParsedMessage
+			tw.OnMessageBatch = func(msgs []ParsedMessage) {
+				for _, msg := range msgs {
+					live = append(live, withoutDelivery(msg))
+				}
+			}
+			var inner [][]byte
+			tw.OnInnerMessage = func(raw []byte) { inner = append(inner, raw) }
+			var archive bytes.Buffer
+			if err := tw.ArchiveTo(&archive); err != nil {
+				t.Fatal(err)
+			}
+
+			start := time.Now()
+			for _, frame := range readSession(t, path) {
+				if frame.messageType == websocket.BinaryMessage {
+					tw.responseReceived(frame.data, 0)
+				}
+			}
+
+			decoder := NewDecoder()
+			decoder.Epoch = goldenEpoch
+			var replayed []ParsedMessage
+			var archived [][]byte
+			err := ReplayArchive(bytes.NewReader(archive.Bytes()), decoder, func(receivedAt time.Time, msg ParsedMessage, err error) {
+				if receivedAt.Before(start) || receivedAt.After(time.Now()) {
+					t.Errorf("receive time %v outside the test", receivedAt)
+				}
+				archived = append(archived, msg.Raw)
+				replayed = append(replayed, withoutDelivery(msg))
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(live) == 0 {
+				t.Fatal("the session decoded to no messages")
+			}
+			if len(inner) != len(live) || !reflect.DeepEqual(archived, inner) {
+				t.Errorf("archived %d inner messages, OnInnerMessage got %d and the live decode %d", len(archived), len(inner), len(live))
+			}
+			if !reflect.DeepEqual(replayed, live) {
+				t.Errorf("replayed decode\n%+v\nwant the live decode\n%+v", replayed, live)
+			}
+		})
+	}
+}
+
+func TestArchiveReaderRejectsOtherData(t *testing.T) {
+	if _, err := NewArchiveReader(strings.NewReader("63=FT3.0|64=206")); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("NewArchiveReader = %v, want ErrInvalidArchive", err)
+	}
+
+	var archive bytes.Buffer
+	tw := newTestClient()
+	tw.ArchiveTo(&archive)
+	tw.responseReceived(frameOf(touchlineMessage(1, 22, 24500)), 0)
+	reader, err := NewArchiveReader(bytes.NewReader(archive.Bytes()[:archive.Len()-1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Next(); err == nil || !strings.Contains(err.Error(), "truncated") {
+		t.Errorf("Next on a truncated record = %v, want a truncated archive error", err)
+	}
+}
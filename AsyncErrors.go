@@ -0,0 +1,105 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/gorilla/websocket"
+)
+
+// Stages of the requests the client sends on its own, as reported by SendFailed and
+// counted in Stats().SendFailures
+const (
+	StageHeartbeat      = "heartbeat"
+	StageHeartbeatReply = "heartbeat reply"
+	StageResubscribe    = "resubscribe"
+	StageQueuedRequest  = "queued request"
+	StageQuoteRelease   = "quote release"
+)
+
+// SendFailed is emitted when a request the client sends on its own, outside any method
+// that returns an error, could not be written. ConnectionLost is set when the error showed
+// the connection to be dead and it was closed to be reported lost.
+type SendFailed struct {
+	EventSource
+	Stage          string
+	Err            error
+	ConnectionLost bool
+}
+
+func (SendFailed) isEvent() {}
+
+// sendFailures counts the SendFailed reports per stage
+type sendFailures struct {
+	counts map[string]uint64
+	mu     sync.Mutex
+}
+
+// reportAsyncError reports a failed background write to OnError and the Events channel and
+// counts it. A write that failed because the connection is dead closes the connection, so
+// that it is reported lost once, and reconnected by a MultiClient, instead of failing
+// again at every interval.
+func (tw *ODINMarketFeedClient) reportAsyncError(stage string, err error) {
+	tw.sendFailures.mu.Lock()
+	if tw.sendFailures.counts == nil {
+		tw.sendFailures.counts = make(map[string]uint64)
+	}
+	tw.sendFailures.counts[stage]++
+	tw.sendFailures.mu.Unlock()
+
+	var conn *websocket.Conn
+	if deadConnection(err) {
+		tw.mu.Lock()
+		conn = tw.conn
+		tw.mu.Unlock()
+	}
+
+	// The failure is reported before the connection loss it causes
+	tw.emit(SendFailed{EventSource: tw.source(), Stage: stage, Err: err, ConnectionLost: conn != nil})
+	tw.reportError(fmt.Sprintf("%s%s failed: %v", strings.ToUpper(stage[:1]), stage[1:], err))
+	if conn != nil {
+		tw.abandonConnection(conn, fmt.Errorf("%s failed: %w", stage, err))
+	}
+}
+
+// abandonConnection closes conn when it is still the current connection, so that its
+// receive loop reports it lost with cause
+func (tw *ODINMarketFeedClient) abandonConnection(conn *websocket.Conn, cause error) {
+	tw.mu.Lock()
+	current := conn != nil && tw.conn == conn
+	if current && tw.sessionErr == nil {
+		tw.sessionErr = cause
+	}
+	tw.mu.Unlock()
+
+	if current {
+		tw.logf("Closing the connection: %v", cause)
+		conn.Close()
+	}
+}
+
+// deadConnection reports whether a write error shows that the connection can no longer be
+// written to
+func deadConnection(err error) bool {
+	return errors.Is(err, net.ErrClosed) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED)
+}
+
+// snapshot returns a copy of the counts, or nil when nothing has failed
+func (sf *sendFailures) snapshot() map[string]uint64 {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	if len(sf.counts) == 0 {
+		return nil
+	}
+	counts := make(map[string]uint64, len(sf.counts))
+	for stage, count := range sf.counts {
+		counts[stage] = count
+	}
+	return counts
+}
@@ -0,0 +1,80 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SubscribeResult describes the outcome of a batch subscribe or unsubscribe call
+type SubscribeResult struct {
+	Requested int          // number of items passed by the caller, including blank ones
+	Sent      []Instrument // instruments included in the request
+	Skipped   []string     // items that could not be parsed, or sent in a partly sent Best Five batch
+	Errors    []error      // one parse or send error per skipped item
+
+	// Accepted and Rejected hold the verdicts of the gateway's ack, set by
+	// SubscribeTouchlineSync
+	Accepted []Instrument
+	Rejected []TokenRejection
+}
+
+// BatchError is returned by the batch subscription methods when some items were skipped.
+// Use errors.As to retrieve it and reconcile which instruments are live.
+type BatchError struct {
+	Result SubscribeResult
+}
+
+// Error returns a summary of the skipped items
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("%d of %d items skipped: %s", len(e.Result.Skipped), e.Result.Requested, strings.Join(e.Result.Skipped, ", "))
+}
+
+// Unwrap returns the parse errors of the skipped items
+func (e *BatchError) Unwrap() []error {
+	return e.Result.Errors
+}
+
+// WithLenientBatchErrors makes the batch subscription methods return nil when at least one
+// instrument was sent, even if other items were skipped. The skipped items are reported via
+// OnError instead.
+func WithLenientBatchErrors() Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.lenientBatchErrors = true
+	}
+}
+
+// batchResult returns the error for a batch request that was sent for instruments. Lenient
+// batches return nil and pass the skipped items to OnError, unless WithLegacyErrorCallbacks
+// has reported them already.
+func (tw *ODINMarketFeedClient) batchResult(requested int, instruments []Instrument, skipped []string, errs []error) error {
+	if len(skipped) == 0 {
+		return nil
+	}
+	batchErr := newBatchError(requested, instruments, skipped, errs)
+	if tw.lenientBatchErrors {
+		if !tw.legacyErrors {
+			tw.reportError(batchErr.Error())
+		}
+		return nil
+	}
+	return batchErr
+}
+
+func newBatchError(requested int, instruments []Instrument, skipped []string, errs []error) *BatchError {
+	return &BatchError{Result: SubscribeResult{
+		Requested: requested,
+		Sent:      instruments,
+		Skipped:   skipped,
+		Errors:    errs,
+	}}
+}
+
+// noValidTokens returns the error for a batch in which no instrument could be sent, wrapping
+// a BatchError when items were skipped as malformed
+func noValidTokens(message string, requested int, skipped []string, errs []error) error {
+	if len(skipped) == 0 {
+		return errors.New(message)
+	}
+	return fmt.Errorf("%s: %w", message, newBatchError(requested, nil, skipped, errs))
+}
@@ -0,0 +1,320 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DepthLevel represents one price level of the order book
+type DepthLevel struct {
+	Price uint32
+	Qty   uint32
+}
+
+// BestFiveData represents a decoded Market Depth (Best Five) response.
+//
+// Depth levels are taken from the repeated buy (2=qty, 3=price) and sell (5=qty, 6=price)
+// tags of the response in wire order, best level first.
+type BestFiveData struct {
+	MktSegID       uint32
+	Token          uint32
+	Symbol         string
+	DecimalLocator uint32
+	Bids           []DepthLevel
+	Asks           []DepthLevel
+	// Stale is set on books of the depth cache received before the last WithDayRollover
+	// reset
+	Stale bool
+}
+
+const bestFiveDepth = 5
+
+// decodeBestFive decodes a textual 64=127 response, returning false when it carries no
+// instrument. pair is the repeating-group delimiter.
+func decodeBestFive(message string, pair byte) (BestFiveData, bool) {
+	var data BestFiveData
+	var hasSegment, hasToken bool
+	var bidQty, bidPrice, askQty, askPrice []uint32
+
+	fields := strings.FieldsFunc(message, func(r rune) bool { return r == FieldDelimiter || r == rune(pair) })
+	for _, field := range fields {
+		tag, value, found := strings.Cut(field, string(TagValueSeparator))
+		if !found {
+			continue
+		}
+		tag = strings.TrimSpace(tag)
+
+		number, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+		if err != nil {
+			continue
+		}
+
+		switch tag {
+		case "1":
+			data.MktSegID = uint32(number)
+			hasSegment = true
+		case "7":
+			data.Token = uint32(number)
+			hasToken = true
+		case "399":
+			data.DecimalLocator = uint32(number)
+		case "2":
+			bidQty = append(bidQty, uint32(number))
+		case "3":
+			bidPrice = append(bidPrice, uint32(number))
+		case "5":
+			askQty = append(askQty, uint32(number))
+		case "6":
+			askPrice = append(askPrice, uint32(number))
+		}
+	}
+
+	data.Bids = buildDepthLevels(bidPrice, bidQty)
+	data.Asks = buildDepthLevels(askPrice, askQty)
+	return data, hasSegment && hasToken
+}
+
+func buildDepthLevels(prices, quantities []uint32) []DepthLevel {
+	count := len(prices)
+	if len(quantities) < count {
+		count = len(quantities)
+	}
+	if count > bestFiveDepth {
+		count = bestFiveDepth
+	}
+
+	levels := make([]DepthLevel, count)
+	for i := 0; i < count; i++ {
+		levels[i] = DepthLevel{Price: prices[i], Qty: quantities[i]}
+	}
+	return levels
+}
+
+// depthCache holds the latest BestFiveData per token
+type depthCache struct {
+	books            map[uint64]BestFiveData
+	lru              *lruKeys
+	clearOnReconnect bool
+	mu               sync.RWMutex
+}
+
+// WithDepthCache keeps the latest Best Five book per token, queryable through GetDepth and
+// GetTopOfBook. Entries are removed on UnsubscribeBestFive, after WithCachePurgeDelay if set;
+// clearOnReconnect also clears the cache whenever a new connection is established.
+func WithDepthCache(clearOnReconnect bool) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.depthCache = &depthCache{
+			books:            make(map[uint64]BestFiveData),
+			lru:              newLRUKeys(),
+			clearOnReconnect: clearOnReconnect,
+		}
+	}
+}
+
+func depthKey(segID, token uint32) uint64 {
+	return uint64(segID)<<32 | uint64(token)
+}
+
+// update stores the book and reports whether another book was evicted to stay within capacity
+func (dc *depthCache) update(data BestFiveData, capacity int) (evicted bool) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	key := depthKey(data.MktSegID, data.Token)
+	dc.books[key] = data
+	if oldest, ok := dc.lru.touch(key, capacity); ok {
+		delete(dc.books, oldest)
+		return true
+	}
+	return false
+}
+
+func (dc *depthCache) remove(segID, token uint32) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	key := depthKey(segID, token)
+	delete(dc.books, key)
+	dc.lru.remove(key)
+}
+
+func (dc *depthCache) clear() {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+	dc.books = make(map[uint64]BestFiveData)
+	dc.lru.clear()
+}
+
+// GetDepth returns a copy of the cached Best Five book for the token
+func (tw *ODINMarketFeedClient) GetDepth(segID, token uint32) (BestFiveData, bool) {
+	if tw.depthCache == nil {
+		return BestFiveData{}, false
+	}
+
+	tw.depthCache.mu.RLock()
+	defer tw.depthCache.mu.RUnlock()
+
+	data, ok := tw.depthCache.books[depthKey(segID, token)]
+	if !ok {
+		return BestFiveData{}, false
+	}
+	data.Bids = append([]DepthLevel(nil), data.Bids...)
+	data.Asks = append([]DepthLevel(nil), data.Asks...)
+	return data, true
+}
+
+// GetTopOfBook returns the best bid and ask from the cached book for the token
+func (tw *ODINMarketFeedClient) GetTopOfBook(segID, token uint32) (bid, ask DepthLevel, ok bool) {
+	if tw.depthCache == nil {
+		return DepthLevel{}, DepthLevel{}, false
+	}
+
+	tw.depthCache.mu.RLock()
+	defer tw.depthCache.mu.RUnlock()
+
+	data, found := tw.depthCache.books[depthKey(segID, token)]
+	if !found {
+		return DepthLevel{}, DepthLevel{}, false
+	}
+	if len(data.Bids) > 0 {
+		bid = data.Bids[0]
+	}
+	if len(data.Asks) > 0 {
+		ask = data.Asks[0]
+	}
+	return bid, ask, true
+}
+
+// bestFiveReceived runs the Best Five consumers for a decoded response
+func (tw *ODINMarketFeedClient) bestFiveReceived(data BestFiveData) {
+	if tw.priceScaler != nil {
+		data.DecimalLocator = tw.priceScaler.Divisor(data.MktSegID, data.DecimalLocator)
+	}
+	if tw.depthCache != nil {
+		if tw.depthCache.update(data, tw.cacheCapacity) {
+			tw.cacheEvicted()
+		}
+	}
+
+	if tw.OnBestFive != nil || tw.OnJSON != nil || tw.publisher.Load() != nil {
+		tw.mu.Lock()
+		resolver := tw.resolver
+		tw.mu.Unlock()
+
+		if reverse, ok := resolver.(ResolverWithReverse); ok {
+			data.Symbol, _ = reverse.Symbol(int(data.MktSegID), int(data.Token))
+		}
+		if tw.OnBestFive != nil {
+			tw.invokeCallback("OnBestFive", func() string { return fmt.Sprintf("%+v", data) }, func() { tw.OnBestFive(data) })
+		}
+		tw.deliverJSON(data.MarshalJSONWithOptions)
+		tw.publish(MessageBestFive, data.MktSegID, data.Token, data)
+	}
+}
+
+// SubscribeBestFiveBatch subscribes to Market Depth (Best Five) for each instrument. A Best
+// Five request carries a single instrument, so one request is sent per instrument. Invalid
+// instruments are skipped and reported in a *BatchError; send failures are joined to it and,
+// when other instruments were sent, listed in it with the skipped ones.
+func (tw *ODINMarketFeedClient) SubscribeBestFiveBatch(instruments []Instrument) error {
+	return tw.bestFiveBatch(instruments, true, true)
+}
+
+// UnsubscribeBestFiveBatch unsubscribes from Market Depth (Best Five) for each instrument
+func (tw *ODINMarketFeedClient) UnsubscribeBestFiveBatch(instruments []Instrument) error {
+	return tw.bestFiveBatch(instruments, false, false)
+}
+
+// bestFiveBatch sends one Best Five request per instrument; direct is passed to
+// trackSubscribe
+func (tw *ODINMarketFeedClient) bestFiveBatch(instruments []Instrument, subscribe, direct bool) error {
+	if len(instruments) == 0 {
+		tw.returnedError("Instrument list cannot be null or empty.")
+		return fmt.Errorf("instrument list cannot be empty")
+	}
+
+	valid := make([]Instrument, 0, len(instruments))
+	var skipped []string
+	var parseErrs []error
+	for _, instrument := range instruments {
+		if err := instrument.validate(); err != nil {
+			tw.returnedError(fmt.Sprintf("Invalid instrument: '%s'.", instrument))
+			skipped = append(skipped, instrument.String())
+			parseErrs = append(parseErrs, err)
+			continue
+		}
+		valid = append(valid, instrument)
+	}
+
+	if len(valid) == 0 {
+		return noValidTokens("no valid instruments found", len(instruments), skipped, parseErrs)
+	}
+	var reservations []*quotaReservation
+	if subscribe {
+		reservation, err := tw.reserveQuota(valid)
+		if err != nil {
+			return err
+		}
+		reservations = reservation.split()
+	}
+
+	action := 1
+	if !subscribe {
+		action = 2
+	}
+
+	sent := make([]Instrument, 0, len(valid))
+	var failed []string
+	var sendErrs []error
+	for i, instrument := range valid {
+		instrument := instrument
+		request := tw.bestFiveRequest(tw.requestHeader(msgCodeBestFive), instrument, action)
+
+		var reservation *quotaReservation
+		if reservations != nil {
+			reservation = reservations[i]
+		}
+		queued, err := tw.sendRequest(request, 1, func() {
+			tw.bestFiveSent(instrument, subscribe, direct)
+		}, reservation)
+		if err != nil {
+			sendErrs = append(sendErrs, fmt.Errorf("%s: %w", instrument, err))
+			failed = append(failed, instrument.String())
+			continue
+		}
+		sent = append(sent, instrument)
+
+		if queued {
+			continue
+		}
+		if subscribe {
+			tw.logf("Subscribed to BestFive tokens: %d, MarketSegmentId: %d", instrument.Token, instrument.MarketSegmentID)
+		} else {
+			tw.logf("Unsubscribed from BestFive tokens: %d, MarketSegmentId: %d", instrument.Token, instrument.MarketSegmentID)
+		}
+	}
+
+	if len(sent) == 0 {
+		return errors.Join(sendErrs...)
+	}
+	if len(failed) > 0 {
+		// Sent in part: the failed instruments are listed with the skipped ones so that the
+		// caller can tell which are live
+		batchErr := newBatchError(len(instruments), sent, append(skipped, failed...), append(parseErrs, sendErrs...))
+		return errors.Join(append(sendErrs, batchErr)...)
+	}
+	return tw.batchResult(len(instruments), sent, skipped, parseErrs)
+}
+
+// bestFiveSent updates the registry and depth cache once a Best Five request has been written
+func (tw *ODINMarketFeedClient) bestFiveSent(instrument Instrument, subscribe, direct bool) {
+	if subscribe {
+		tw.trackSubscribe(SubscriptionBestFive, []Instrument{instrument}, "", false, direct)
+		return
+	}
+
+	tw.trackUnsubscribe(SubscriptionBestFive, []Instrument{instrument})
+}
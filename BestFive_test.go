@@ -0,0 +1,138 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bestFiveMessage returns a Best Five response whose best bid is price-5 and best ask price+5
+func bestFiveMessage(segment, token, price uint32) []byte {
+	return []byte(fmt.Sprintf("63=FT3.0|64=127|1=%d|7=%d|399=100|2=10$3=%d|2=20$3=%d|5=15$6=%d|5=25$6=%d",
+		segment, token, price-5, price-10, price+5, price+10))
+}
+
+func TestBestFiveBatchRequests(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	tw.requestClock.now = func() time.Time { return time.Date(2026, 10, 16, 9, 15, 0, 0, ExchangeLocation) }
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	instruments := []Instrument{
+		{MarketSegmentID: 1, Token: 22},
+		{MarketSegmentID: 2, Token: 35001},
+		{MarketSegmentID: 13, Token: 4},
+	}
+	if err := tw.SubscribeBestFiveBatch(instruments); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"63=FT3.0|64=127|65=84|66=09:15:00|1=1|7=22|230=1",
+		"63=FT3.0|64=127|65=84|66=09:15:00|1=2|7=35001|230=1",
+		"63=FT3.0|64=127|65=84|66=09:15:00|1=13|7=4|230=1",
+	}
+	for _, w := range want {
+		if got := ms.next(t, msgCodeBestFive); got != w {
+			t.Errorf("request = %q, want %q", got, w)
+		}
+	}
+}
+
+func TestBestFiveValidatesLikeTouchline(t *testing.T) {
+	tw := newTestClient()
+	for _, token := range []string{"abc", "0", "-5", "1_2"} {
+		err := tw.SubscribeBestFive(token, 1)
+		var batchErr *BatchError
+		if !errors.As(err, &batchErr) || len(batchErr.Result.Skipped) != 1 {
+			t.Errorf("SubscribeBestFive(%q) = %v, want a *BatchError skipping it", token, err)
+		}
+
+		err = tw.SubscribeTouchlineWithOptions([]string{"1_" + token}, TouchlineOptions{})
+		if !errors.As(err, &batchErr) || len(batchErr.Result.Skipped) != 1 {
+			t.Errorf("SubscribeTouchlineWithOptions(%q) = %v, want a *BatchError skipping it", "1_"+token, err)
+		}
+	}
+}
+
+func TestDepthCacheKeepsTheLastUpdatePerToken(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithDepthCache(true))
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+	for _, token := range []string{"22", "23", "24"} {
+		if err := tw.SubscribeBestFive(token, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Readers query the cache while the updates stream in
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for token := uint32(22); token <= 24; token++ {
+					// GetDepth returns a copy, so writing to it must not reach the cache
+					if book, ok := tw.GetDepth(1, token); ok && len(book.Bids) > 0 {
+						book.Bids[0].Qty = 0
+					}
+					tw.GetTopOfBook(1, token)
+				}
+			}
+		}()
+	}
+
+	const updates = 50
+	for i := uint32(0); i < updates; i++ {
+		ms.latest().send(bestFiveMessage(1, 22, 1000+i), bestFiveMessage(1, 23, 2000+i), bestFiveMessage(1, 24, 3000+i))
+	}
+	last := func(token uint32) uint32 { return (token-21)*1000 + updates - 1 }
+	eventually(t, 5*time.Second, func() bool {
+		bid, _, ok := tw.GetTopOfBook(1, 24)
+		return ok && bid.Price == last(24)-5
+	})
+	close(stop)
+	readers.Wait()
+
+	for token := uint32(22); token <= 24; token++ {
+		book, ok := tw.GetDepth(1, token)
+		if !ok || len(book.Bids) != 2 || len(book.Asks) != 2 {
+			t.Fatalf("GetDepth(1, %d) = %+v, %v, want two levels a side", token, book, ok)
+		}
+		if book.Bids[0] != (DepthLevel{Price: last(token) - 5, Qty: 10}) {
+			t.Errorf("token %d best bid %+v, want the last update", token, book.Bids[0])
+		}
+		bid, ask, _ := tw.GetTopOfBook(1, token)
+		if bid != book.Bids[0] || ask != (DepthLevel{Price: last(token) + 5, Qty: 15}) {
+			t.Errorf("token %d top of book %+v / %+v, want the last update", token, bid, ask)
+		}
+	}
+
+	if err := tw.UnsubscribeBestFive("22", 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tw.GetDepth(1, 22); ok {
+		t.Error("the book of 22 is still cached after its unsubscribe")
+	}
+	if _, ok := tw.GetDepth(1, 23); !ok {
+		t.Error("the book of 23 was removed with the unsubscribe of 22")
+	}
+
+	tw.Disconnect()
+	ms.connect(t, tw)
+	if _, ok := tw.GetDepth(1, 23); ok {
+		t.Error("the book of 23 survived a reconnect with clearOnReconnect")
+	}
+}
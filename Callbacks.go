@@ -0,0 +1,65 @@
+package ODINMarketFeed
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// CallbackPanic is emitted when a user callback panics. The panic is recovered and delivery
+// continues with the next message unless WithFailFastCallbacks is set.
+type CallbackPanic struct {
+	EventSource
+	Callback string // the callback field, e.g. "OnMessage"
+	Value    interface{}
+	Stack    []byte
+	Message  string // the message being delivered
+}
+
+func (CallbackPanic) isEvent() {}
+
+// WithFailFastCallbacks lets panics in callbacks crash the process instead of being
+// recovered and reported
+func WithFailFastCallbacks() Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.failFastCallbacks = true
+	}
+}
+
+// invokeCallback runs fn, recovering and reporting a panic through OnError and the Events
+// channel; a panic in OnError itself is only reported on the Events channel. message
+// describes what was being delivered and is only evaluated on panic.
+func (tw *ODINMarketFeedClient) invokeCallback(callback string, message func() string, fn func()) {
+	if tw.failFastCallbacks {
+		fn()
+		return
+	}
+
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+
+		report := CallbackPanic{EventSource: tw.source(), Callback: callback, Value: r, Stack: debug.Stack(), Message: message()}
+		tw.emit(report)
+		if tw.OnError != nil && callback != "OnError" {
+			tw.OnError(fmt.Sprintf("%s panicked: %v (message: %s)\n%s", callback, r, report.Message, report.Stack))
+		}
+	}()
+	fn()
+}
+
+// reportError passes message to OnError through invokeCallback, so that a panic in OnError
+// is recovered and reported like one in any other callback
+func (tw *ODINMarketFeedClient) reportError(message string) {
+	if tw.OnError != nil {
+		tw.invokeCallback("OnError", func() string { return message }, func() { tw.OnError(message) })
+	}
+}
+
+// batchDescription describes a batch delivery for panic reports
+func batchDescription(count int) func() string {
+	return func() string {
+		return fmt.Sprintf("batch of %d messages", count)
+	}
+}
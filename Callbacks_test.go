@@ -0,0 +1,106 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reentrantOnError sets an OnError that disconnects and subscribes from inside the callback
+// the first time it runs, and returns a channel closed once it has returned
+func reentrantOnError(tw *ODINMarketFeedClient) <-chan struct{} {
+	done := make(chan struct{})
+	var once sync.Once
+	tw.OnError = func(string) {
+		once.Do(func() {
+			tw.Disconnect()
+			tw.SubscribeLTPTouchline([]string{"1_23"})
+			tw.SubscribeTouchlineWithOptions([]string{"1_24"}, TouchlineOptions{})
+			close(done)
+		})
+	}
+	return done
+}
+
+func TestReentrantOnErrorFromMethod(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithLegacyErrorCallbacks(true))
+	done := reentrantOnError(tw)
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	within(t, 2*time.Second, "SubscribeTouchlineWithOptions with a re-entrant OnError", func() {
+		tw.SubscribeTouchlineWithOptions([]string{"1_22", "bad"}, TouchlineOptions{})
+	})
+	select {
+	case <-done:
+	default:
+		t.Fatal("OnError was not invoked for the malformed token")
+	}
+}
+
+func TestReentrantOnErrorFromReceiveLoop(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	done := reentrantOnError(tw)
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	ms.dropAll()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnError calling Disconnect and Subscribe on the receive loop did not return")
+	}
+	if !eventually(t, 2*time.Second, func() bool { return tw.Goroutines() == 0 }) {
+		t.Errorf("%d goroutines left after the connection was lost", tw.Goroutines())
+	}
+}
+
+func TestPanicInCallbackDeliversRestOfFrame(t *testing.T) {
+	tw := newTestClient()
+	var tokens []uint32
+	var errs []string
+	tw.OnTouchline = func(touchline TouchlineData) {
+		tokens = append(tokens, touchline.Token)
+		if len(tokens) == 3 {
+			panic("third message")
+		}
+	}
+	tw.OnError = func(message string) { errs = append(errs, message) }
+
+	tw.responseReceived(frameOf(manyMessages(5)...), 0)
+
+	if len(tokens) != 5 || tokens[3] != 1003 || tokens[4] != 1004 {
+		t.Errorf("delivered tokens %v, want all 5 despite the panic on the third", tokens)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0], "OnTouchline panicked: third message") {
+		t.Errorf("OnError got %q, want the panic of OnTouchline", errs)
+	}
+}
+
+func TestPanicInOnErrorIsRecovered(t *testing.T) {
+	tw := newTestClient()
+	events := tw.Events()
+	var delivered int
+	tw.OnTouchline = func(TouchlineData) { delivered++ }
+	tw.OnError = func(string) { panic("OnError") }
+
+	truncated := []byte("63=FT3.0|64=206|50=short")
+	tw.responseReceived(frameOf(truncated, touchlineMessage(1, 22, 24500)), 0)
+
+	if delivered != 1 {
+		t.Errorf("%d touchlines delivered after OnError panicked, want 1", delivered)
+	}
+	select {
+	case event := <-events:
+		if report, ok := event.(CallbackPanic); !ok || report.Callback != "OnError" {
+			t.Errorf("event %+v, want the CallbackPanic of OnError", event)
+		}
+	case <-time.After(time.Second):
+		t.Error("no CallbackPanic event for OnError")
+	}
+}
@@ -0,0 +1,111 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// defaultCloseTimeout bounds the wait for the server's close frame in Disconnect
+const defaultCloseTimeout = 2 * time.Second
+
+// WithCloseTimeout sets how long Disconnect waits for the server to answer the close frame
+// before closing the connection, and how long Close waits for the goroutines of the client
+// to exit (default 2s)
+func WithCloseTimeout(timeout time.Duration) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.closeTimeout = timeout
+	}
+}
+
+// disconnect sends a close frame and closes the connection once the server has answered it,
+// the close timeout has expired or ctx is done, whichever comes first. When called from a
+// callback, which may run on the receive loop, the loop finishes the handshake itself under
+// a read deadline once the callback has returned, so that it is not blocked on itself.
+func (tw *ODINMarketFeedClient) disconnect(ctx context.Context) error {
+	tw.mu.Lock()
+	if tw.isDisposed {
+		tw.mu.Unlock()
+		return ErrClientDisposed
+	}
+
+	defer releaseQueued(tw.preConnectQueue)
+	tw.preConnectQueue = nil
+
+	conn := tw.conn
+	if conn == nil {
+		tw.mu.Unlock()
+		return nil
+	}
+	tw.conn = nil
+	done := tw.receiveDone
+	endConn := tw.endConn
+	if tw.manual.enabled {
+		// There is no writer goroutine to close the queue when the connection ends
+		tw.sendQueue.close()
+	}
+	tw.setState(StateDisconnected)
+	tw.emit(Disconnected{EventSource: tw.source(), Code: websocket.CloseNormalClosure, Cause: CauseLocalClose})
+	tw.mu.Unlock()
+	if endConn != nil {
+		endConn()
+	}
+	tw.stopResubscribeCheck()
+
+	// The close frame is still sent when ctx is done, with a short grace period
+	deadline := time.Now().Add(closeWriteTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	if minimum := time.Now().Add(closeFrameGrace); deadline.Before(minimum) {
+		deadline = minimum
+	}
+	err := conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), deadline)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+
+	if done != nil && tw.routines.ownsCaller() {
+		// The caller runs on a goroutine of the connection, possibly the receive loop: the
+		// loop reads the server's close frame once the callback has returned, or gives up
+		// at the deadline, and closes conn
+		deadline := time.Now().Add(tw.closeTimeoutOrDefault())
+		if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+			deadline = ctxDeadline
+		}
+		return conn.SetReadDeadline(deadline)
+	}
+	return tw.awaitClose(ctx, conn, done)
+}
+
+// closeTimeoutOrDefault returns the WithCloseTimeout timeout, or defaultCloseTimeout
+func (tw *ODINMarketFeedClient) closeTimeoutOrDefault() time.Duration {
+	if tw.closeTimeout <= 0 {
+		return defaultCloseTimeout
+	}
+	return tw.closeTimeout
+}
+
+// awaitClose waits for the receive loop to read the server's close frame and close conn. It
+// closes conn itself when the close timeout expires or ctx is done first.
+func (tw *ODINMarketFeedClient) awaitClose(ctx context.Context, conn *websocket.Conn, done <-chan struct{}) error {
+	timeout := tw.closeTimeoutOrDefault()
+
+	if done != nil {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+			// The receive loop closes conn when it exits
+			return nil
+		case <-timer.C:
+			tw.logf("Close handshake timed out; closing the connection")
+		case <-ctx.Done():
+		}
+	}
+	return conn.Close()
+}
@@ -0,0 +1,53 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDisconnectFromCallbackDoesNotBlockReceiveLoop(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithCloseTimeout(5 * time.Second))
+	returned := make(chan time.Duration, 1)
+	tw.OnTouchline = func(TouchlineData) {
+		start := time.Now()
+		tw.Disconnect()
+		returned <- time.Since(start)
+	}
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	if !eventually(t, 2*time.Second, func() bool { return ms.latest() != nil }) {
+		t.Fatal("no connection")
+	}
+	ms.latest().send(touchlineMessage(1, 22, 24500))
+
+	select {
+	case elapsed := <-returned:
+		if elapsed > time.Second {
+			t.Errorf("Disconnect from a callback took %v", elapsed)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("Disconnect from a callback blocked")
+	}
+	if !eventually(t, 2*time.Second, func() bool { return tw.Goroutines() == 0 }) {
+		t.Errorf("%d goroutines left after the close handshake", tw.Goroutines())
+	}
+}
+
+func TestDisconnectWaitsForCloseHandshake(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+
+	if err := tw.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if tw.routines.ownsCaller() {
+		t.Error("the test goroutine counted as a goroutine of the connection")
+	}
+	if !eventually(t, time.Second, func() bool { return tw.Goroutines() == 0 }) {
+		t.Errorf("%d goroutines left after Disconnect", tw.Goroutines())
+	}
+}
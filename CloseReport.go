@@ -0,0 +1,159 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// closeUnsubscribeChunk is the number of instruments per unsubscribe request sent by
+// UnsubscribeAll and Close when WithMaxTokensPerRequest is not set
+const closeUnsubscribeChunk = 1000
+
+// closeFrameGrace is the time the close frame may take to write once the Close deadline has
+// passed
+const closeFrameGrace = 100 * time.Millisecond
+
+// CloseReport describes the shutdown performed by CloseWithReport
+type CloseReport struct {
+	UnsubscribesSent    int  // instruments whose unsubscribe request was written
+	UnsubscribesSkipped int  // instruments left subscribed because the deadline passed or the request failed
+	FramesFlushed       int  // queued frames written while the send queue was flushed
+	FramesDropped       int  // frames still queued when the connection was closed
+	DeadlineExceeded    bool // ctx was done before the unsubscribes and the queue were flushed
+}
+
+// unsubscribeBatch is a group of subscriptions unsubscribed together by Close
+type unsubscribeBatch struct {
+	subType         SubscriptionType
+	marketSegmentID int
+	instruments     []Instrument
+}
+
+// CloseWithReport is Close, reporting what was sent before the close frame. With
+// WithUnsubscribeOnClose the tracked subscriptions are unsubscribed in large requests, the
+// largest segment and subscription type first, ahead of the queued subscription traffic.
+// The send queue is then flushed for at most the WithCloseTimeout timeout. When ctx is done
+// the remaining unsubscribes and queued frames are skipped and the close frame is sent at
+// once, so the deadline of ctx bounds the whole shutdown.
+func (tw *ODINMarketFeedClient) CloseWithReport(ctx context.Context) (CloseReport, error) {
+	var report CloseReport
+	if err := tw.checkDisposed(); err != nil {
+		return report, err
+	}
+
+	var errs []error
+	if tw.unsubscribeOnClose {
+		atomic.StoreInt32(&tw.closing, 1)
+		tw.closeSubscriptionFile()
+		if err := tw.unsubscribeAll(ctx, &report); err != nil {
+			if tw.strictErrors {
+				tw.returnedError(fmt.Sprintf("Unsubscribe on close failed: %v", err))
+			}
+			errs = append(errs, err)
+		}
+	}
+	tw.flushOnShutdown(ctx, &report)
+
+	if err := tw.disconnect(ctx); err != nil {
+		errs = append(errs, err)
+	}
+	tw.Dispose()
+	tw.awaitGoroutines()
+	tw.awaitWorkers()
+	return report, errors.Join(errs...)
+}
+
+// unsubscribeBatches groups subscriptions by type and market segment, largest group first
+func unsubscribeBatches(subscriptions map[subscriptionKey]Subscription) []unsubscribeBatch {
+	type batchKey struct {
+		subType         SubscriptionType
+		marketSegmentID int
+	}
+	index := make(map[batchKey]int)
+	var batches []unsubscribeBatch
+	for _, sub := range subscriptions {
+		key := batchKey{subType: sub.Type, marketSegmentID: sub.Instrument.MarketSegmentID}
+		i, ok := index[key]
+		if !ok {
+			i = len(batches)
+			index[key] = i
+			batches = append(batches, unsubscribeBatch{subType: key.subType, marketSegmentID: key.marketSegmentID})
+		}
+		batches[i].instruments = append(batches[i].instruments, sub.Instrument)
+	}
+
+	sort.Slice(batches, func(i, j int) bool {
+		if len(batches[i].instruments) != len(batches[j].instruments) {
+			return len(batches[i].instruments) > len(batches[j].instruments)
+		}
+		if batches[i].subType != batches[j].subType {
+			return batches[i].subType < batches[j].subType
+		}
+		return batches[i].marketSegmentID < batches[j].marketSegmentID
+	})
+	for _, batch := range batches {
+		sortInstruments(batch.instruments)
+	}
+	return batches
+}
+
+// unsubscribeRequest is an unsubscribe message and the number of instruments it carries
+type unsubscribeRequest struct {
+	message string
+	count   int
+}
+
+// unsubscribeRequests builds the unsubscribe messages for instruments of one subscription
+// type. Best Five is unsubscribed one instrument per request.
+func (tw *ODINMarketFeedClient) unsubscribeRequests(subType SubscriptionType, instruments []Instrument) []unsubscribeRequest {
+	switch subType {
+	case SubscriptionTouchline:
+		message := tw.requestHeader(msgCodeTouchline) + fmt.Sprintf("4=|%s230=2", tw.formatTokenGroup(instruments))
+		return []unsubscribeRequest{{message: message, count: len(instruments)}}
+	case SubscriptionLTPTouchline:
+		message := tw.ltpTouchlineRequest(tw.requestHeader(msgCodeLTPTouchline), instruments, 2)
+		return []unsubscribeRequest{{message: message, count: len(instruments)}}
+	case SubscriptionBestFive:
+		requests := make([]unsubscribeRequest, len(instruments))
+		for i, instrument := range instruments {
+			message := tw.bestFiveRequest(tw.requestHeader(msgCodeBestFive), instrument, 2)
+			requests[i] = unsubscribeRequest{message: message, count: 1}
+		}
+		return requests
+	}
+	return nil
+}
+
+// flushOnShutdown waits for the send queue to be written, for at most the close timeout
+// and until ctx is done, and records the frames written and left behind
+func (tw *ODINMarketFeedClient) flushOnShutdown(ctx context.Context, report *CloseReport) {
+	tw.mu.Lock()
+	queue := tw.sendQueue
+	conn := tw.conn
+	tw.mu.Unlock()
+	if queue == nil || conn == nil {
+		return
+	}
+	if tw.manual.enabled {
+		// Without a writer goroutine the queue is written here, except for the requests
+		// held back by a throttle pause
+		written := queue.writtenCount()
+		tw.drainSendQueue(conn, queue)
+		report.FramesFlushed = queue.writtenCount() - written
+		report.FramesDropped = queue.pending()
+		return
+	}
+
+	flushCtx, cancel := context.WithTimeout(ctx, tw.closeTimeoutOrDefault())
+	defer cancel()
+
+	report.FramesFlushed = queue.flush(flushCtx)
+	report.FramesDropped = queue.pending()
+	if report.FramesDropped > 0 && ctx.Err() != nil {
+		report.DeadlineExceeded = true
+	}
+}
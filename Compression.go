@@ -0,0 +1,267 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Decompressor decodes the payload of received outer frames
+type Decompressor interface {
+	Uncompress(data []byte) ([]byte, error)
+	// UncompressLimited returns ErrDecompressedTooLarge when the output exceeds maxSize
+	UncompressLimited(data []byte, maxSize int) ([]byte, error)
+}
+
+// streamDecompressor is a Decompressor whose output can be read incrementally, so that the
+// size of a frame can be limited by the inner headers read so far. The built-in
+// decompressors implement it.
+type streamDecompressor interface {
+	reader(data []byte) (io.ReadCloser, error)
+}
+
+// innerFrameSlack is the number of bytes, such as padding, that may follow each inner frame
+// when the decompressed size of a frame is derived from its inner headers
+const innerFrameSlack = 256
+
+// Compressor encodes the payload of sent outer frames. Outgoing messages are compressed by
+// the Decompressor in use when it also implements Compressor, and with ZLIB otherwise.
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+}
+
+// FlateDecompressor handles raw DEFLATE compression/decompression, without the ZLIB header
+type FlateDecompressor struct{}
+
+// Compress compresses data using raw DEFLATE
+func (f *FlateDecompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	writer, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	return compressWith(writer, &buf, data)
+}
+
+// Uncompress decompresses raw DEFLATE data
+func (f *FlateDecompressor) Uncompress(data []byte) ([]byte, error) {
+	reader := flate.NewReader(bytes.NewReader(data))
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// UncompressLimited decompresses raw DEFLATE data, reading at most maxSize bytes of output
+func (f *FlateDecompressor) UncompressLimited(data []byte, maxSize int) ([]byte, error) {
+	reader := flate.NewReader(bytes.NewReader(data))
+	defer reader.Close()
+	return readLimited(reader, maxSize)
+}
+
+func (f *FlateDecompressor) reader(data []byte) (io.ReadCloser, error) {
+	return flate.NewReader(bytes.NewReader(data)), nil
+}
+
+// GzipDecompressor handles GZIP compression/decompression
+type GzipDecompressor struct{}
+
+// Compress compresses data using GZIP
+func (g *GzipDecompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	return compressWith(gzip.NewWriter(&buf), &buf, data)
+}
+
+// Uncompress decompresses GZIP data
+func (g *GzipDecompressor) Uncompress(data []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// UncompressLimited decompresses GZIP data, reading at most maxSize bytes of output
+func (g *GzipDecompressor) UncompressLimited(data []byte, maxSize int) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return readLimited(reader, maxSize)
+}
+
+func (g *GzipDecompressor) reader(data []byte) (io.ReadCloser, error) {
+	return gzip.NewReader(bytes.NewReader(data))
+}
+
+// compressWith writes data through writer and returns the contents of buf
+func compressWith(writer io.WriteCloser, buf *bytes.Buffer, data []byte) ([]byte, error) {
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// readLimited reads reader to the end, failing with ErrDecompressedTooLarge as soon as the
+// output exceeds maxSize
+func readLimited(reader io.Reader, maxSize int) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(reader, int64(maxSize)+1)); err != nil {
+		return nil, err
+	}
+	if buf.Len() > maxSize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrDecompressedTooLarge, maxSize)
+	}
+	return buf.Bytes(), nil
+}
+
+// readInnerFrames reads reader to the end like readLimited, with a limit that grows with the
+// inner frames read: each complete inner header allows its declared payload, the next
+// header and innerFrameSlack bytes more. Output past an invalid inner header is limited to
+// the slack. maxSize, when positive, caps the output as well.
+func readInnerFrames(reader io.Reader, maxSize int) ([]byte, error) {
+	allowed := FrameHeaderSize + innerFrameSlack
+	next := 0 // the offset of the next inner header
+	valid := true
+	var buf []byte
+
+	for {
+		for valid {
+			if next < len(buf) {
+				next += paddingLength(buf[next:])
+			}
+			if len(buf)-next < FrameHeaderSize {
+				break
+			}
+			_, length, err := parseFrameHeader(buf[next:], true)
+			if err != nil || length == 0 {
+				valid = false
+				break
+			}
+			next += FrameHeaderSize + length
+			allowed = next + FrameHeaderSize + innerFrameSlack
+		}
+
+		limit := allowed
+		if maxSize > 0 && maxSize < limit {
+			limit = maxSize
+		}
+		if len(buf) > limit {
+			return nil, fmt.Errorf("%w: more than %d bytes", ErrDecompressedTooLarge, limit)
+		}
+		if len(buf) == cap(buf) {
+			// Grow to at most one byte past the limit, which is enough to detect an overflow
+			size := 2 * cap(buf)
+			if size < 512 {
+				size = 512
+			}
+			if size > limit+1 {
+				size = limit + 1
+			}
+			grown := make([]byte, len(buf), size)
+			copy(grown, buf)
+			buf = grown
+		}
+
+		n, err := reader.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if err == io.EOF {
+			if len(buf) > limit {
+				return nil, fmt.Errorf("%w: more than %d bytes", ErrDecompressedTooLarge, limit)
+			}
+			return buf, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// WithDecompressor decodes received frames with d instead of ZLIB. Outgoing messages are
+// compressed with d when it implements Compressor.
+func WithDecompressor(d Decompressor) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.fragHandler.primary = d
+		tw.fragHandler.decompressor = d
+	}
+}
+
+// WithDecompressorAutoDetect tries the fallbacks in order when the first frame of a
+// connection cannot be decoded by the configured Decompressor, and keeps the first one that
+// succeeds, logging the switch, for the rest of the connection. Outgoing messages sent
+// after the switch are compressed to match. Each connection starts again with the
+// configured Decompressor, and the fallbacks are tried only once per connection.
+func WithDecompressorAutoDetect(fallbacks ...Decompressor) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.fragHandler.fallbacks = fallbacks
+	}
+}
+
+// uncompress decodes an outer frame payload, detecting the algorithm on the first frame of
+// a connection when fallbacks are configured. fh.mu must be held.
+func (fh *FragmentationHandler) uncompress(data []byte, maxSize int) ([]byte, error) {
+	output, err := uncompressFrame(fh.decompressor, data, maxSize)
+	if fh.detected || len(fh.fallbacks) == 0 {
+		return output, err
+	}
+	fh.detected = true
+	if err == nil || errors.Is(err, ErrDecompressedTooLarge) {
+		return output, err
+	}
+
+	for _, fallback := range fh.fallbacks {
+		if fallbackOutput, fallbackErr := uncompressFrame(fallback, data, maxSize); fallbackErr == nil {
+			fh.switchNote = fmt.Sprintf("Decompression failed (%v); using %T for this connection", err, fallback)
+			fh.decompressor = fallback
+			return fallbackOutput, nil
+		}
+	}
+	return nil, err
+}
+
+// uncompressFrame decodes an outer frame payload with d. A positive maxSize limits the
+// output; otherwise the limit is derived from the inner headers when d is a
+// streamDecompressor, and is DefaultMaxDecompressedSize when it is not.
+func uncompressFrame(d Decompressor, data []byte, maxSize int) ([]byte, error) {
+	stream, ok := d.(streamDecompressor)
+	if !ok {
+		if maxSize <= 0 {
+			maxSize = DefaultMaxDecompressedSize
+		}
+		return d.UncompressLimited(data, maxSize)
+	}
+
+	reader, err := stream.reader(data)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return readInnerFrames(reader, maxSize)
+}
+
+// takeSwitchNote returns and clears the description of a switch made by auto-detection
+func (fh *FragmentationHandler) takeSwitchNote() string {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+
+	note := fh.switchNote
+	fh.switchNote = ""
+	return note
+}
+
+// compressor returns the Compressor matching the Decompressor in use
+func (fh *FragmentationHandler) compressor() Compressor {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+
+	if compressor, ok := fh.decompressor.(Compressor); ok {
+		return compressor
+	}
+	return &ZLIBCompressor{}
+}
@@ -0,0 +1,101 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// compressedFrameOf returns an outer frame holding msgs as inner frames, compressed with c
+func compressedFrameOf(t *testing.T, c Compressor, msgs ...[]byte) []byte {
+	t.Helper()
+
+	var inner []byte
+	for _, msg := range msgs {
+		inner = append(inner, innerFrame(msg)...)
+	}
+	compressed, err := c.Compress(inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frame, err := EncodeFrame(Frame{Flag: FrameCompressed, Payload: compressed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return frame
+}
+
+func TestCompressionRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		codec interface {
+			Compressor
+			Decompressor
+		}
+	}{
+		{"zlib", &ZLIBCompressor{}},
+		{"flate", &FlateDecompressor{}},
+		{"gzip", &GzipDecompressor{}},
+	}
+
+	msg := touchlineMessage(1, 22, 24500)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			compressed, err := test.codec.Compress(msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := test.codec.Uncompress(compressed); err != nil || !bytes.Equal(got, msg) {
+				t.Errorf("Uncompress = %q, %v, want the original message", got, err)
+			}
+			if _, err := test.codec.UncompressLimited(compressed, len(msg)-1); err == nil {
+				t.Error("UncompressLimited below the message size succeeded")
+			}
+
+			fh := NewFragmentationHandler()
+			fh.primary = test.codec
+			fh.decompressor = test.codec
+			messages, err := fh.Defragment(compressedFrameOf(t, test.codec, msg, msg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(messages) != 2 || !bytes.Equal(messages[0], msg) || !bytes.Equal(messages[1], msg) {
+				t.Errorf("Defragment = %q, want the message twice", messages)
+			}
+		})
+	}
+}
+
+func TestDecompressorAutoDetect(t *testing.T) {
+	var logged []string
+	tw := newTestClient(WithDecompressorAutoDetect(&FlateDecompressor{}, &GzipDecompressor{}),
+		WithLogger(func(line string) { logged = append(logged, line) }))
+	defer tw.Close(context.Background())
+	var ltps []uint32
+	tw.OnTouchline = func(touchline TouchlineData) { ltps = append(ltps, touchline.LTP) }
+
+	gzip := &GzipDecompressor{}
+	tw.responseReceived(compressedFrameOf(t, gzip, touchlineMessage(1, 22, 24500)), 0)
+	tw.responseReceived(compressedFrameOf(t, gzip, touchlineMessage(1, 22, 24510)), 0)
+	if len(ltps) != 2 || ltps[0] != 24500 || ltps[1] != 24510 {
+		t.Fatalf("delivered %v, want both gzip frames", ltps)
+	}
+	if _, ok := tw.fragHandler.compressor().(*GzipDecompressor); !ok {
+		t.Errorf("outgoing messages compressed with %T, want gzip", tw.fragHandler.compressor())
+	}
+	switches := 0
+	for _, line := range logged {
+		if strings.Contains(line, "GzipDecompressor") {
+			switches++
+		}
+	}
+	if switches != 1 {
+		t.Errorf("logged %q, want the switch to gzip once", logged)
+	}
+
+	tw.fragHandler.Reset()
+	if _, ok := tw.fragHandler.compressor().(*ZLIBCompressor); !ok {
+		t.Errorf("compressor after Reset is %T, want ZLIB again", tw.fragHandler.compressor())
+	}
+}
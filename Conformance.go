@@ -0,0 +1,399 @@
+package ODINMarketFeed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTranscriptEntries bounds the conformance transcript; later messages are not recorded
+const maxTranscriptEntries = 100000
+
+// ErrNonConforming is wrapped by the *ConformanceError of a request refused in
+// WithConformanceMode
+var ErrNonConforming = errors.New("request does not conform to its schema")
+
+// FieldRule is a field of a RequestSchema
+type FieldRule struct {
+	Tag      int
+	Required bool
+	// Repeated lets the field occur several times in a row
+	Repeated bool
+	// Pattern is a regular expression the whole value must match; empty accepts any value,
+	// including an empty one
+	Pattern string
+	// Entry makes the rule a repeating group, such as the instrument list of a touchline
+	// request: each entry consists of these fields in order, and Tag, Repeated and Pattern
+	// are ignored. A required group needs at least one entry.
+	Entry []FieldRule
+}
+
+// RequestSchema lists the fields of the requests of a 64= code in the order they must
+// appear after the header. The 63=, 64=, 65= and 66= header fields, the WithExtraHeaderTags
+// tags and the WithRequestCorrelation tag are checked for every code and are not listed.
+type RequestSchema struct {
+	Code   int
+	Fields []FieldRule
+	// OpenEnded accepts further fields after the listed ones, in ascending tag order
+	OpenEnded bool
+}
+
+// BuiltinRequestSchemas returns the schemas of the requests the client builds, used by
+// WithConformanceMode unless replaced
+func BuiltinRequestSchemas() []RequestSchema {
+	instruments := FieldRule{Required: true, Entry: []FieldRule{
+		{Tag: 1, Required: true, Pattern: `[0-9]+`},
+		{Tag: 7, Required: true, Pattern: `[0-9]+`},
+	}}
+	action := FieldRule{Tag: 230, Required: true, Pattern: `[12]`}
+
+	return []RequestSchema{
+		{Code: msgCodeHeartbeat, Fields: []FieldRule{
+			{Tag: 67, Required: true, Pattern: `.+`},
+		}},
+		{Code: msgCodeLogin, OpenEnded: true, Fields: []FieldRule{
+			{Tag: 67, Required: true, Pattern: `.+`},
+			{Tag: 68, Required: true},
+			{Tag: 401, Pattern: `[0-9]+`},
+		}},
+		{Code: msgCodePauseResume, Fields: []FieldRule{action}},
+		{Code: msgCodeBestFive, Fields: []FieldRule{
+			{Tag: 1, Required: true, Pattern: `[0-9]+`},
+			{Tag: 7, Required: true, Pattern: `[0-9]+`},
+			action,
+		}},
+		{Code: msgCodeTouchline, Fields: []FieldRule{
+			{Tag: 4, Pattern: ``},
+			{Tag: 49, Pattern: `1`},
+			{Tag: 200, Pattern: `[01]`},
+			instruments,
+			action,
+		}},
+		{Code: msgCodeLTPTouchline, Fields: []FieldRule{instruments, action}},
+	}
+}
+
+// ConformanceError describes a request refused in WithConformanceMode
+type ConformanceError struct {
+	Code       int
+	Message    string // the request, with secrets masked
+	Violations []string
+}
+
+func (e *ConformanceError) Error() string {
+	return fmt.Sprintf("request 64=%d does not conform: %s (%s)", e.Code, strings.Join(e.Violations, "; "), e.Message)
+}
+
+// Unwrap returns ErrNonConforming
+func (e *ConformanceError) Unwrap() error {
+	return ErrNonConforming
+}
+
+// TranscriptEntry is a message of the WithConformanceMode transcript. Pair is the Seq of
+// the message of the other direction it answers, or that answers it, 0 if none.
+type TranscriptEntry struct {
+	Seq       int       `json:"seq"`
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"` // "OUT" or "IN"
+	Code      int       `json:"code"`
+	Message   string    `json:"message"`
+	Pair      int       `json:"pair,omitempty"`
+	// Latency is the time since the paired message, set on the later of the two
+	Latency time.Duration `json:"latency_ns,omitempty"`
+}
+
+// conformance holds the schemas and the transcript of WithConformanceMode
+type conformance struct {
+	schemas map[int]compiledSchema
+
+	mu         sync.Mutex
+	transcript []TranscriptEntry
+	unpaired   map[string][]int // direction and code to the indexes of unanswered entries
+}
+
+// compiledSchema is a RequestSchema with its patterns compiled
+type compiledSchema struct {
+	RequestSchema
+	patterns map[*FieldRule]*regexp.Regexp
+	err      error // first invalid pattern
+}
+
+// WithConformanceMode validates every outgoing request against the schema of its 64= code
+// before it is sent, for certification testing. Requests that do not conform, including
+// requests of codes without a schema, are not sent and their send returns a
+// *ConformanceError listing every violation. The BuiltinRequestSchemas cover the requests
+// the client builds; schemas replaces those of the same code or adds new codes, for
+// example for requests sent with SendRaw.
+//
+// The requests written and the responses and control messages received are also recorded
+// in a transcript (see Transcript and WriteTranscript). Each received message is paired
+// with the oldest unanswered request of its code, and a request with the oldest
+// unanswered heartbeat or other control message received.
+func WithConformanceMode(schemas ...RequestSchema) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.conformance = &conformance{
+			schemas:  make(map[int]compiledSchema),
+			unpaired: make(map[string][]int),
+		}
+		for _, schema := range append(BuiltinRequestSchemas(), schemas...) {
+			tw.conformance.schemas[schema.Code] = compileSchema(schema)
+		}
+	}
+}
+
+// compileSchema compiles the patterns of a copy of a schema
+func compileSchema(schema RequestSchema) compiledSchema {
+	compiled := compiledSchema{RequestSchema: schema, patterns: make(map[*FieldRule]*regexp.Regexp)}
+	var compile func(rules []FieldRule) []FieldRule
+	compile = func(rules []FieldRule) []FieldRule {
+		rules = append([]FieldRule(nil), rules...)
+		for i := range rules {
+			rule := &rules[i]
+			if rule.Entry != nil {
+				rule.Entry = compile(rule.Entry)
+				continue
+			}
+			if rule.Pattern == "" {
+				continue
+			}
+			pattern, err := regexp.Compile(`^(?:` + rule.Pattern + `)$`)
+			if err != nil {
+				if compiled.err == nil {
+					compiled.err = fmt.Errorf("invalid pattern for tag %d: %w", rule.Tag, err)
+				}
+				continue
+			}
+			compiled.patterns[rule] = pattern
+		}
+		return rules
+	}
+	compiled.Fields = compile(schema.Fields)
+	return compiled
+}
+
+// checkConformance returns the *ConformanceError of a request that does not conform to
+// its schema, nil outside WithConformanceMode
+func (tw *ODINMarketFeedClient) checkConformance(message string) error {
+	if tw.conformance == nil {
+		return nil
+	}
+
+	code := messageCode(message)
+	var violations []string
+	if schema, ok := tw.conformance.schemas[code]; !ok {
+		violations = []string{fmt.Sprintf("no schema for code %d", code)}
+	} else if schema.err != nil {
+		violations = []string{schema.err.Error()}
+	} else {
+		fields, malformed := requestFields(message, tw.decoder.pairDelimiter())
+		violations = append(malformed, schema.check(fields, tw.headerExtensionTags())...)
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return &ConformanceError{Code: code, Message: maskSecrets(message), Violations: violations}
+}
+
+// requestFields splits a request into its fields. Unlike parseFields it reports the parts
+// that are not tag=value pairs with a numeric tag instead of skipping them.
+func requestFields(message string, pair byte) (fields []Field, violations []string) {
+	if message == "" {
+		return nil, []string{"request is empty"}
+	}
+
+	text := strings.TrimSuffix(message, string(FieldDelimiter))
+	start := 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && text[i] != FieldDelimiter && text[i] != pair {
+			continue
+		}
+		part := text[start:i]
+		start = i + 1
+
+		tag, value, found := strings.Cut(part, string(TagValueSeparator))
+		number, err := strconv.Atoi(tag)
+		if !found || err != nil || number <= 0 {
+			violations = append(violations, fmt.Sprintf("field %d (%q) is not a tag=value pair", len(fields)+len(violations)+1, part))
+			continue
+		}
+		fields = append(fields, Field{Tag: number, Value: value})
+	}
+	return fields, violations
+}
+
+// headerExtensionTags returns the tags the header builder writes after the 66= field, in
+// the order it writes them
+func (tw *ODINMarketFeedClient) headerExtensionTags() map[int]bool {
+	tags := make(map[int]bool, len(tw.header.extraTags)+1)
+	for tag := range tw.header.extraTags {
+		tags[tag] = true
+	}
+	if tw.correlation != nil {
+		tags[tw.correlation.tag] = true
+	}
+	return tags
+}
+
+// check returns the violations of the fields of a request
+func (s compiledSchema) check(fields []Field, extensions map[int]bool) []string {
+	var violations []string
+	violate := func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Sprintf(format, args...))
+	}
+
+	pos := 0
+	for i, tag := range []int{63, 64, 65, 66} {
+		if pos >= len(fields) || fields[pos].Tag != tag {
+			// The body cannot be located after a malformed header
+			violate("header field %d must be tag %d", i+1, tag)
+			return violations
+		}
+		if tag != 65 && fields[pos].Value == "" {
+			violate("tag %d is empty", tag)
+		}
+		if tag == 64 && fields[pos].Value != strconv.Itoa(s.Code) {
+			violate("tag 64 is %q, expected %d", fields[pos].Value, s.Code)
+		}
+		pos++
+	}
+	for pos < len(fields) && extensions[fields[pos].Tag] {
+		pos++
+	}
+
+	match := func(rule *FieldRule) bool {
+		if pos >= len(fields) || fields[pos].Tag != rule.Tag {
+			return false
+		}
+		if pattern := s.patterns[rule]; pattern != nil && !pattern.MatchString(fields[pos].Value) {
+			violate("field %d: tag %d value %q does not match %s", pos+1, rule.Tag, fields[pos].Value, rule.Pattern)
+		}
+		pos++
+		return true
+	}
+
+	for i := range s.Fields {
+		rule := &s.Fields[i]
+		if rule.Entry == nil {
+			if !match(rule) {
+				if rule.Required {
+					violate("%s: missing required tag %d", s.position(fields, pos), rule.Tag)
+				}
+				continue
+			}
+			for rule.Repeated {
+				if !match(rule) {
+					break
+				}
+			}
+			continue
+		}
+
+		entries := 0
+		for len(rule.Entry) > 0 && pos < len(fields) && fields[pos].Tag == rule.Entry[0].Tag {
+			for j := range rule.Entry {
+				if !match(&rule.Entry[j]) && rule.Entry[j].Required {
+					violate("%s: group entry %d is missing tag %d", s.position(fields, pos), entries+1, rule.Entry[j].Tag)
+				}
+			}
+			entries++
+		}
+		if entries == 0 && rule.Required && len(rule.Entry) > 0 {
+			violate("%s: missing required group starting with tag %d", s.position(fields, pos), rule.Entry[0].Tag)
+		}
+	}
+
+	for last := 0; pos < len(fields); pos++ {
+		if !s.OpenEnded {
+			violate("field %d: unexpected tag %d", pos+1, fields[pos].Tag)
+			continue
+		}
+		if fields[pos].Tag <= last {
+			violate("field %d: tag %d is out of order", pos+1, fields[pos].Tag)
+		}
+		last = fields[pos].Tag
+	}
+	return violations
+}
+
+// position describes the field at pos for a violation
+func (s compiledSchema) position(fields []Field, pos int) string {
+	if pos >= len(fields) {
+		return "end of request"
+	}
+	return fmt.Sprintf("field %d (tag %d)", pos+1, fields[pos].Tag)
+}
+
+// transcribeRequest records a request written to the connection
+func (tw *ODINMarketFeedClient) transcribeRequest(message string, at time.Time) {
+	if tw.conformance == nil {
+		return
+	}
+	tw.conformance.record("OUT", "IN", messageCode(message), message, at, true)
+}
+
+// transcribeResponse records a received message that answers a request or is a control
+// message
+func (tw *ODINMarketFeedClient) transcribeResponse(msg ParsedMessage, at time.Time) {
+	if tw.conformance == nil {
+		return
+	}
+	control := msg.Kind == MessageHeartbeat || msg.Kind == MessageLoginAck || msg.Kind == MessageDuplicateSession
+	tw.conformance.record("IN", "OUT", msg.Code, msg.Text, at, control)
+}
+
+// record appends an entry paired with the oldest unanswered entry of the same code in the
+// other direction. An entry that pairs with nothing is only recorded when always is set.
+func (c *conformance) record(direction, other string, code int, message string, at time.Time, always bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.transcript) >= maxTranscriptEntries {
+		return
+	}
+	entry := TranscriptEntry{Seq: len(c.transcript) + 1, Time: at, Direction: direction, Code: code, Message: message}
+
+	key := other + " " + strconv.Itoa(code)
+	if waiting := c.unpaired[key]; len(waiting) > 0 {
+		request := &c.transcript[waiting[0]]
+		c.unpaired[key] = waiting[1:]
+		request.Pair = entry.Seq
+		entry.Pair = request.Seq
+		entry.Latency = at.Sub(request.Time)
+	} else if !always {
+		return
+	} else {
+		own := direction + " " + strconv.Itoa(code)
+		c.unpaired[own] = append(c.unpaired[own], len(c.transcript))
+	}
+	c.transcript = append(c.transcript, entry)
+}
+
+// Transcript returns the messages recorded in WithConformanceMode, in the order they were
+// written or received
+func (tw *ODINMarketFeedClient) Transcript() []TranscriptEntry {
+	if tw.conformance == nil {
+		return nil
+	}
+
+	tw.conformance.mu.Lock()
+	defer tw.conformance.mu.Unlock()
+	return append([]TranscriptEntry(nil), tw.conformance.transcript...)
+}
+
+// WriteTranscript writes the Transcript to w as JSON lines, one entry per line
+func (tw *ODINMarketFeedClient) WriteTranscript(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range tw.Transcript() {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSendRawRejectsNonConformingRequest(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithConformanceMode())
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	malformed := tw.requestHeader(msgCodeTouchline) + "1=1$7=22|230=3"
+	err := tw.SendRaw(malformed)
+	var conformanceErr *ConformanceError
+	if !errors.As(err, &conformanceErr) {
+		t.Fatalf("SendRaw(%q) = %v, want a *ConformanceError", malformed, err)
+	}
+	if !errors.Is(err, ErrNonConforming) {
+		t.Errorf("%v does not wrap ErrNonConforming", err)
+	}
+	if conformanceErr.Code != msgCodeTouchline || len(conformanceErr.Violations) == 0 {
+		t.Errorf("ConformanceError = %+v, want code %d with violations", conformanceErr, msgCodeTouchline)
+	}
+
+	if err := tw.SendRaw(tw.requestHeader(msgCodeTouchline) + "1=1$7=22|230=1"); err != nil {
+		t.Fatalf("conforming request refused: %v", err)
+	}
+	if request := ms.next(t, msgCodeTouchline); !strings.HasSuffix(request, "230=1") {
+		t.Errorf("server received %q, want only the conforming request", request)
+	}
+}
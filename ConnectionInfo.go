@@ -0,0 +1,44 @@
+package ODINMarketFeed
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+// ConnectionInfo describes the currently established connection
+type ConnectionInfo struct {
+	LocalAddr   net.Addr
+	RemoteAddr  net.Addr
+	TLS         *tls.ConnectionState // nil for ws:// connections
+	ConnectedAt time.Time
+	URL         string
+	Endpoint    Endpoint
+	Generation  uint64 // incremented every time a new underlying connection is established
+}
+
+// ConnectionInfo returns metadata about the current connection, or false when disconnected
+func (tw *ODINMarketFeedClient) ConnectionInfo() (ConnectionInfo, bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.conn == nil {
+		return ConnectionInfo{}, false
+	}
+
+	info := ConnectionInfo{
+		LocalAddr:   tw.conn.LocalAddr(),
+		RemoteAddr:  tw.conn.RemoteAddr(),
+		ConnectedAt: tw.connectedAt,
+		URL:         tw.connURL,
+		Endpoint:    tw.endpoint,
+		Generation:  tw.generation,
+	}
+
+	if tlsConn, ok := tw.conn.UnderlyingConn().(*tls.Conn); ok {
+		state := tlsConn.ConnectionState()
+		info.TLS = &state
+	}
+
+	return info, true
+}
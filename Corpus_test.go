@@ -0,0 +1,175 @@
+package ODINMarketFeed
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// corpusOptions are the client options of the corpus sessions that need any
+var corpusOptions = map[string][]Option{
+	"touchline_extended": {WithExtendedTouchline(80, 79)},
+}
+
+// sessionFrame is a websocket frame of a recorded session
+type sessionFrame struct {
+	messageType int
+	data        []byte
+}
+
+// readSession reads a recorded session: one frame per line, "binary <hex>" or
+// "text <text>", with blank lines and # comments ignored. Sessions hold websocket frames
+// rather than ArchiveTo inner messages so that the corpus also covers the outer framing.
+func readSession(t *testing.T, path string) []sessionFrame {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var frames []sessionFrame
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		kind, payload, _ := strings.Cut(text, " ")
+		switch kind {
+		case "binary":
+			data, err := hex.DecodeString(payload)
+			if err != nil {
+				t.Fatalf("%s:%d: %v", path, line, err)
+			}
+			frames = append(frames, sessionFrame{websocket.BinaryMessage, data})
+		case "text":
+			frames = append(frames, sessionFrame{websocket.TextMessage, []byte(payload)})
+		default:
+			t.Fatalf("%s:%d: unknown frame type %q", path, line, kind)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return frames
+}
+
+// corpusMessage is the golden form of a delivered message
+type corpusMessage struct {
+	Seq       uint64         `json:"seq,omitempty"`
+	Kind      string         `json:"kind"`
+	Code      int            `json:"code,omitempty"`
+	Text      string         `json:"text"`
+	Touchline *TouchlineData `json:"touchline,omitempty"`
+	BestFive  *BestFiveData  `json:"best_five,omitempty"`
+	LTP       *LTPUpdate     `json:"ltp,omitempty"`
+}
+
+// corpusOutput is the golden output of a session
+type corpusOutput struct {
+	Messages []corpusMessage  `json:"messages"`
+	Errors   []string         `json:"errors,omitempty"`
+	Stats    map[string]int64 `json:"stats"`
+}
+
+// replaySession sends the frames of a session to a client through a mock gateway and
+// returns what the client delivered. Text frames reach OnMessage and the inner messages of
+// binary frames OnMessageBatch, so both keep their wire order.
+func replaySession(t *testing.T, frames []sessionFrame, opts ...Option) []byte {
+	t.Helper()
+
+	ms := newMockServer(t, nil)
+	tw := newTestClient(append([]Option{WithStrictErrors()}, opts...)...)
+	tw.decoder.Epoch = goldenEpoch
+
+	var mu sync.Mutex
+	output := corpusOutput{Messages: []corpusMessage{}}
+	tw.OnMessageBatch = func(msgs []ParsedMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, msg := range msgs {
+			output.Messages = append(output.Messages, corpusMessage{
+				Seq: msg.Seq, Kind: msg.Kind.String(), Code: msg.Code, Text: msg.Text,
+				Touchline: msg.Touchline, BestFive: msg.BestFive, LTP: msg.LTP,
+			})
+		}
+	}
+	tw.OnMessage = func(text string) {
+		mu.Lock()
+		defer mu.Unlock()
+		output.Messages = append(output.Messages, corpusMessage{Kind: "text", Text: text})
+	}
+	tw.OnError = func(message string) {
+		mu.Lock()
+		defer mu.Unlock()
+		output.Errors = append(output.Errors, message)
+	}
+
+	ms.connect(t, tw)
+	ms.next(t, msgCodeLogin)
+	c := ms.latest()
+	for _, frame := range frames {
+		if err := c.write(frame.messageType, frame.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	received := func() bool {
+		stats := tw.Stats()
+		return stats.BinaryFrames+stats.TextFrames == uint64(len(frames))
+	}
+	if !eventually(t, 5*time.Second, received) {
+		t.Fatalf("%d of %d frames received", tw.Stats().BinaryFrames+tw.Stats().TextFrames, len(frames))
+	}
+	// Close waits for the receive loop, so the last frame has been delivered when it returns
+	tw.Close(context.Background())
+
+	stats := tw.Stats()
+	output.Stats = map[string]int64{
+		"binary_frames":     int64(stats.BinaryFrames),
+		"text_frames":       int64(stats.TextFrames),
+		"keep_alive_frames": int64(stats.KeepAliveFrames),
+		"resync_bytes":      int64(stats.ResyncBytes),
+		"discards":          int64(stats.Discards),
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	data, err := json.MarshalIndent(output, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(data, '\n')
+}
+
+// TestSessionCorpus replays every recorded session of testdata/sessions and compares the
+// decoded output with its golden file. Run with -update after an intended change of the
+// output, and review the diff of the golden files.
+func TestSessionCorpus(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "sessions", "*.session"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no sessions in testdata/sessions")
+	}
+
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".session")
+		t.Run(name, func(t *testing.T) {
+			got := replaySession(t, readSession(t, path), corpusOptions[name]...)
+			checkGolden(t, filepath.Join("sessions", name+".golden.json"), got)
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package ODINMarketFeed
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultDayRollover is the exchange time of the daily reset, just before the 09:00
+// pre-open session
+const defaultDayRollover = 8*time.Hour + 55*time.Minute
+
+// DayRollover is emitted and passed to OnDayRollover when the WithDayRollover time has
+// passed and the daily state has been reset
+type DayRollover struct {
+	EventSource
+	At         time.Time // the boundary that was crossed, in ExchangeLocation
+	StaleBooks int       // cached Best Five books marked stale
+	StaleTicks int       // last touchlines of WithTickDedup marked stale
+	TokenStats int       // instruments whose TokenStats moved to PreviousDayTokenStats
+}
+
+func (DayRollover) isEvent() {}
+
+// dayRollover schedules the daily reset. next and timer are guarded by mu.
+type dayRollover struct {
+	at  time.Duration
+	now func() time.Time
+
+	mu    sync.Mutex
+	next  time.Time
+	timer *time.Timer
+}
+
+// WithDayRollover resets the daily-scoped state every day at the exchange time of day at
+// (ExchangeLocation; 0 means 08:55, just before pre-open), so that a client running for
+// days does not carry one session's data into the next. At the boundary the books of
+// WithDepthCache and the last touchlines compared by WithTickDedup are marked stale rather
+// than deleted, WithTokenStats counters move to PreviousDayTokenStats, the gap detection
+// state is cleared, and DayRollover is reported. A stale touchline is never suppressed as a
+// duplicate, and a stale book is replaced by the next update.
+//
+// The boundary is checked by a timer, and before each received frame in case the timer was
+// delayed, for example while the host slept. With WithManualReadLoop Tick checks it instead
+// of a timer.
+func WithDayRollover(at time.Duration) Option {
+	return func(tw *ODINMarketFeedClient) {
+		if at <= 0 || at >= 24*time.Hour {
+			at = defaultDayRollover
+		}
+		tw.rollover = &dayRollover{at: at}
+	}
+}
+
+// WithDayRolloverClock sets the clock the WithDayRollover boundary is checked against
+// (default time.Now), for example to replay a recorded session on its own timeline or to
+// test a rollover. The timer still waits on the host clock for the boundary it computes.
+func WithDayRolloverClock(now func() time.Time) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.rolloverClock = now
+	}
+}
+
+// startDayRollover arms the rollover timer of a new client
+func (tw *ODINMarketFeedClient) startDayRollover() {
+	if tw.rollover == nil {
+		return
+	}
+	tw.rollover.now = tw.rolloverClock
+	tw.checkDayRollover(tw.rollover.clock())
+}
+
+// clock returns the current time
+func (dr *dayRollover) clock() time.Time {
+	if dr.now != nil {
+		return dr.now()
+	}
+	return time.Now()
+}
+
+// nextBoundary returns the first rollover time after t
+func (dr *dayRollover) nextBoundary(t time.Time) time.Time {
+	t = t.In(ExchangeLocation)
+	boundary := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, ExchangeLocation).Add(dr.at)
+	if !boundary.After(t) {
+		boundary = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, ExchangeLocation).Add(dr.at)
+	}
+	return boundary
+}
+
+// checkDayRollover resets the daily state when now has reached the next boundary. When
+// several boundaries have passed, the state is reset once, for the latest of them.
+func (tw *ODINMarketFeedClient) checkDayRollover(now time.Time) {
+	dr := tw.rollover
+	if dr == nil {
+		return
+	}
+
+	dr.mu.Lock()
+	var crossed time.Time
+	if !dr.next.IsZero() && !now.Before(dr.next) {
+		// The latest boundary is the only one within the last 24 hours
+		crossed = dr.nextBoundary(now.Add(-24 * time.Hour))
+	}
+	rearm := dr.timer == nil
+	if dr.next.IsZero() || !crossed.IsZero() {
+		dr.next = dr.nextBoundary(now)
+		rearm = true
+	}
+	if rearm && !tw.manual.enabled && !tw.isDisposedNow() {
+		if dr.timer != nil {
+			dr.timer.Stop()
+		}
+		dr.timer = time.AfterFunc(dr.next.Sub(now), tw.dayRolloverTimer)
+	}
+	dr.mu.Unlock()
+
+	if !crossed.IsZero() {
+		tw.rollDay(crossed)
+	}
+}
+
+// dayRolloverTimer checks the boundary when the timer fires. The timer is re-armed even if
+// the host clock shows the boundary has not been reached yet.
+func (tw *ODINMarketFeedClient) dayRolloverTimer() {
+	dr := tw.rollover
+	dr.mu.Lock()
+	dr.timer = nil
+	dr.mu.Unlock()
+
+	tw.checkDayRollover(dr.clock())
+}
+
+// stopDayRollover stops the rollover timer
+func (tw *ODINMarketFeedClient) stopDayRollover() {
+	dr := tw.rollover
+	if dr == nil {
+		return
+	}
+
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	if dr.timer != nil {
+		dr.timer.Stop()
+		dr.timer = nil
+	}
+}
+
+// isDisposedNow reports whether Dispose has been called
+func (tw *ODINMarketFeedClient) isDisposedNow() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.isDisposed
+}
+
+// rollDay resets the daily state at the boundary at
+func (tw *ODINMarketFeedClient) rollDay(at time.Time) {
+	event := DayRollover{EventSource: tw.source(), At: at}
+	if tw.depthCache != nil {
+		event.StaleBooks = tw.depthCache.markStale()
+	}
+	if tw.dedup != nil {
+		event.StaleTicks = tw.dedup.markStale()
+	}
+	if tw.tokenStats != nil {
+		event.TokenStats = tw.tokenStats.rollover()
+	}
+	if tw.gapDetector != nil {
+		tw.gapDetector.clear()
+	}
+
+	tw.logf("Day rollover at %s: %d books and %d touchlines marked stale", at.Format(time.RFC3339), event.StaleBooks, event.StaleTicks)
+	tw.emit(event)
+	if tw.OnDayRollover != nil {
+		tw.invokeCallback("OnDayRollover", func() string {
+			return fmt.Sprintf("rollover at %s", at.Format(time.RFC3339))
+		}, func() { tw.OnDayRollover(event) })
+	}
+}
+
+// PreviousDayTokenStats returns the TokenStats counters as they were at the last
+// WithDayRollover boundary that followed a day with updates
+func (tw *ODINMarketFeedClient) PreviousDayTokenStats() map[Instrument]TokenStat {
+	result := make(map[Instrument]TokenStat)
+	if tw.tokenStats == nil {
+		return result
+	}
+
+	tw.tokenStats.mu.Lock()
+	defer tw.tokenStats.mu.Unlock()
+
+	for key, stat := range tw.tokenStats.previous {
+		result[Instrument{MarketSegmentID: int(key >> 32), Token: int(uint32(key))}] = stat
+	}
+	return result
+}
+
+// markStale flags every cached book as stale and returns their number
+func (dc *depthCache) markStale() int {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	for key, book := range dc.books {
+		book.Stale = true
+		dc.books[key] = book
+	}
+	return len(dc.books)
+}
+
+// markStale flags every remembered touchline as stale and returns their number
+func (td *tickDedup) markStale() int {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	for key := range td.last {
+		td.stale[key] = struct{}{}
+	}
+	return len(td.last)
+}
+
+// rollover moves the counters to the previous-day snapshot and returns their number. A
+// day without updates, such as a holiday, keeps the snapshot of the day before.
+func (tt *tokenTracker) rollover() int {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	if len(tt.stats) == 0 {
+		return 0
+	}
+	tt.previous = make(map[uint64]TokenStat, len(tt.stats))
+	for key, stat := range tt.stats {
+		tt.previous[key] = *stat
+	}
+	tt.stats = make(map[uint64]*TokenStat)
+	tt.lru.clear()
+	return len(tt.previous)
+}
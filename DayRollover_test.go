@@ -0,0 +1,43 @@
+package ODINMarketFeed
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDayRolloverAcrossMidnight(t *testing.T) {
+	now := time.Date(2026, 10, 16, 23, 59, 0, 0, ExchangeLocation)
+	tw := newTestClient(
+		WithDayRollover(30*time.Second),
+		WithDayRolloverClock(func() time.Time { return now }),
+		WithManualReadLoop(true),
+		WithTickDedup(DefaultTickFields),
+	)
+	var rollovers []DayRollover
+	tw.OnTouchline = func(TouchlineData) {}
+	tw.OnDayRollover = func(event DayRollover) { rollovers = append(rollovers, event) }
+
+	tw.responseReceived(frameOf(touchlineMessage(1, 22, 24500)), 0)
+	tw.checkDayRollover(tw.rollover.clock())
+	if len(rollovers) != 0 {
+		t.Fatalf("rollover before midnight: %+v", rollovers)
+	}
+
+	now = time.Date(2026, 10, 17, 0, 1, 0, 0, ExchangeLocation)
+	tw.checkDayRollover(tw.rollover.clock())
+	want := time.Date(2026, 10, 17, 0, 0, 30, 0, ExchangeLocation)
+	if len(rollovers) != 1 || !rollovers[0].At.Equal(want) {
+		t.Fatalf("rollovers %+v, want one at %s", rollovers, want)
+	}
+	if rollovers[0].StaleTicks != 1 {
+		t.Errorf("StaleTicks = %d, want 1", rollovers[0].StaleTicks)
+	}
+
+	// Several days later only the latest boundary is reported
+	now = time.Date(2026, 10, 20, 12, 0, 0, 0, ExchangeLocation)
+	tw.checkDayRollover(tw.rollover.clock())
+	want = time.Date(2026, 10, 20, 0, 0, 30, 0, ExchangeLocation)
+	if len(rollovers) != 2 || !rollovers[1].At.Equal(want) {
+		t.Fatalf("rollovers %+v, want a second one at %s", rollovers, want)
+	}
+}
@@ -0,0 +1,340 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MessageKind identifies the kind of a decoded message
+type MessageKind int
+
+const (
+	MessageUnknown MessageKind = iota
+	MessageTouchline
+	MessageBestFive
+	MessageLoginAck
+	MessageHeartbeat
+	MessageLTP
+	MessageDuplicateSession
+)
+
+// String returns the name of the message kind
+func (mk MessageKind) String() string {
+	switch mk {
+	case MessageUnknown:
+		return "Unknown"
+	case MessageTouchline:
+		return "Touchline"
+	case MessageBestFive:
+		return "BestFive"
+	case MessageLoginAck:
+		return "LoginAck"
+	case MessageHeartbeat:
+		return "Heartbeat"
+	case MessageLTP:
+		return "LTP"
+	case MessageDuplicateSession:
+		return "DuplicateSession"
+	default:
+		return fmt.Sprintf("MessageKind(%d)", int(mk))
+	}
+}
+
+// touchlineBlockSize is the length of the binary touchline block that follows the 50= tag
+const touchlineBlockSize = 64
+
+// ltpBlockSize is the length of the binary block of an LTP touchline (64=347) response:
+// market segment, token, LTT, LTP and decimal locator as little-endian uint32 values
+const ltpBlockSize = 20
+
+// LTPUpdate is a decoded LTP touchline (64=347) response
+type LTPUpdate struct {
+	MktSegID       uint32
+	Token          uint32
+	LTT            time.Time
+	LTP            uint32
+	DecimalLocator uint32
+}
+
+// String formats the LTP update as pipe-delimited tag=value pairs
+func (lu LTPUpdate) String() string {
+	return fmt.Sprintf("1=%d|7=%d|73=%s|8=%d|399=%d|",
+		lu.MktSegID, lu.Token, lu.LTT.Format("2006-01-02 150405"), lu.LTP, lu.DecimalLocator)
+}
+
+// ParsedMessage is a decoded inner message. Touchline and BestFive are set for the
+// corresponding kinds; Text is the textual form delivered to OnMessage.
+type ParsedMessage struct {
+	Kind      MessageKind
+	Code      int // value of the 64= tag, -1 when absent
+	Text      string
+	Touchline *TouchlineData
+	BestFive  *BestFiveData
+	LTP       *LTPUpdate
+	Raw       []byte
+	// Fields holds every textual tag of the message in wire order, including tags the
+	// decoder does not interpret. It is only set when Decoder.KeepFields is true.
+	Fields []Field
+	// CorrelationID is the value of the Decoder.CorrelationTag tag echoed by the gateway
+	CorrelationID string
+	// Seq is the position of the message among the inner messages and text frames received
+	// on its connection, starting at 1. It is zero for messages decoded outside the client.
+	Seq uint64
+}
+
+// Field is one tag=value pair of a message
+type Field struct {
+	Tag   int
+	Value string
+}
+
+// Decoder interprets defragmented inner messages. The client decodes live messages with a
+// Decoder, so payloads stored from the feed decode identically offline.
+type Decoder struct {
+	// Epoch is the reference time of the second counts in touchline packets
+	Epoch time.Time
+	// KeepFields fills ParsedMessage.Fields
+	KeepFields bool
+	// DuplicateSessionCode is the 64= code of the gateway notification that the session was
+	// replaced by another login for the same user; 0 disables detection
+	DuplicateSessionCode int
+	// TouchlineCodes are the 64= codes whose 50= block is decoded as a touchline; nil means
+	// 206. The binary block of other codes is left undecoded.
+	TouchlineCodes []int
+	// CorrelationTag is the tag whose value is returned as ParsedMessage.CorrelationID; 0
+	// disables extraction
+	CorrelationTag int
+	// PairDelimiter separates the fields of a repeating group entry; 0 means
+	// DefaultPairDelimiter
+	PairDelimiter byte
+	// NetChangeTag and PercentChangeTag append the change fields of touchlines to Text under
+	// these tags; 0 leaves them out
+	NetChangeTag     int
+	PercentChangeTag int
+	// ExtendedTouchline decodes the traded value and ATP that follow the 64 byte touchline
+	// block, see WithExtendedTouchline. TradedValueTag and ATPTag append them to Text under
+	// these tags; 0 leaves them out.
+	ExtendedTouchline bool
+	TradedValueTag    int
+	ATPTag            int
+
+	segments *segmentConfigs
+}
+
+// NewDecoder creates a Decoder using the feed epoch (1980-01-01 local time) and the built-in
+// segment configurations
+func NewDecoder() *Decoder {
+	return &Decoder{Epoch: feedEpoch, segments: newSegmentConfigs()}
+}
+
+// binaryTag introduces the binary block of touchline and LTP touchline responses
+var binaryTag = []byte("|50=")
+
+// Decode interprets one defragmented inner message. Prices are returned as raw integers;
+// divide by DecimalLocator (or use the JSON encoding) to scale them. On error the returned
+// message is still of kind MessageUnknown with Text and Raw set. Messages with unknown 64=
+// codes are returned as MessageUnknown without decoding their 50= block.
+//
+// The binary block is located and decoded on the raw bytes; only the textual part before
+// it is converted to a string, so Text never contains binary data. Trailing CR, LF, NUL and
+// space bytes are dropped from the text; the binary block is left as received, as it may
+// end in zero bytes.
+func (d *Decoder) Decode(raw []byte) (ParsedMessage, error) {
+	header, block, hasBlock := splitBinaryBlock(raw)
+	if !hasBlock {
+		header = trimMessagePadding(header)
+	}
+	text := string(header)
+	msg := ParsedMessage{Kind: MessageUnknown, Code: messageCode(text), Text: text, Raw: raw}
+
+	if d.KeepFields {
+		msg.Fields = parseFields(text, d.pairDelimiter())
+	}
+	if d.CorrelationTag != 0 {
+		msg.CorrelationID, _ = fieldValue(text, d.CorrelationTag, d.pairDelimiter())
+	}
+
+	if msg.Code == msgCodeLTPTouchline {
+		return d.decodeLTPMessage(msg, block, hasBlock)
+	}
+
+	if hasBlock && d.isTouchlineCode(msg.Code) {
+		if len(block) < touchlineBlockSize {
+			return msg, fmt.Errorf("touchline block too short: %d bytes, expected %d", len(block), touchlineBlockSize)
+		}
+
+		touchline := d.decodeTouchline(block)
+		consumed := touchlineBlockSize
+		if d.ExtendedTouchline && len(block) >= extendedTouchlineBlockSize {
+			d.decodeTouchlineExtension(&touchline, block[touchlineBlockSize:extendedTouchlineBlockSize])
+			consumed = extendedTouchlineBlockSize
+		}
+		touchline.computeChange()
+		msg.Kind = MessageTouchline
+		msg.Touchline = &touchline
+		msg.Text = text + touchline.String() + d.extensionTags(touchline) + d.changeTags(touchline)
+		d.appendTrailer(&msg, block[consumed:])
+		return msg, nil
+	}
+
+	switch msg.Code {
+	case msgCodeBestFive:
+		if bestFive, ok := decodeBestFive(text, d.pairDelimiter()); ok {
+			bestFive.DecimalLocator = segmentDecimalLocator(d.segmentConfig(bestFive.MktSegID), bestFive.DecimalLocator)
+			msg.Kind = MessageBestFive
+			msg.BestFive = &bestFive
+		}
+	case msgCodeLogin:
+		msg.Kind = MessageLoginAck
+	case msgCodeHeartbeat:
+		msg.Kind = MessageHeartbeat
+	}
+	if d.DuplicateSessionCode != 0 && msg.Code == d.DuplicateSessionCode {
+		msg.Kind = MessageDuplicateSession
+	}
+	return msg, nil
+}
+
+// splitBinaryBlock splits raw at the first |50= tag into the textual header (up to and
+// including the '|') and the binary block that follows the tag
+func splitBinaryBlock(raw []byte) (header, block []byte, ok bool) {
+	index := bytes.Index(raw, binaryTag)
+	if index < 0 {
+		return raw, nil, false
+	}
+	return raw[:index+1], raw[index+len(binaryTag):], true
+}
+
+// appendTrailer appends the textual fields that follow a binary block to the message
+func (d *Decoder) appendTrailer(msg *ParsedMessage, trailer []byte) {
+	trailer = trimMessagePadding(bytes.TrimLeft(trailer, string(FieldDelimiter)))
+	if len(trailer) == 0 {
+		return
+	}
+
+	text := string(trailer)
+	msg.Text += text
+	if d.CorrelationTag != 0 && msg.CorrelationID == "" {
+		msg.CorrelationID, _ = fieldValue(text, d.CorrelationTag, d.pairDelimiter())
+	}
+	if d.KeepFields {
+		msg.Fields = append(msg.Fields, parseFields(text, d.pairDelimiter())...)
+	}
+}
+
+// parseFields splits text into its tag=value pairs, skipping parts without a numeric tag.
+// pair is the repeating-group delimiter; whitespace around the tags is ignored.
+func parseFields(text string, pair byte) []Field {
+	var fields []Field
+	for _, part := range strings.FieldsFunc(text, func(r rune) bool { return r == FieldDelimiter || r == rune(pair) }) {
+		tag, value, found := strings.Cut(part, string(TagValueSeparator))
+		if !found {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSpace(tag))
+		if err != nil {
+			continue
+		}
+		fields = append(fields, Field{Tag: number, Value: value})
+	}
+	return fields
+}
+
+// decodeLTPMessage decodes an LTP touchline response, which carries either the compact
+// binary block after 50= or the textual 1, 7, 73, 8 and 399 tags
+func (d *Decoder) decodeLTPMessage(msg ParsedMessage, block []byte, hasBlock bool) (ParsedMessage, error) {
+	var update LTPUpdate
+
+	if hasBlock {
+		if len(block) < ltpBlockSize {
+			return msg, fmt.Errorf("LTP block too short: %d bytes, expected %d", len(block), ltpBlockSize)
+		}
+
+		segID := binary.LittleEndian.Uint32(block[0:4])
+		config := d.segmentConfig(segID)
+		update = LTPUpdate{
+			MktSegID:       segID,
+			Token:          binary.LittleEndian.Uint32(block[4:8]),
+			LTT:            d.segmentTime(config, int32(binary.LittleEndian.Uint32(block[8:12]))),
+			LTP:            binary.LittleEndian.Uint32(block[12:16]),
+			DecimalLocator: segmentDecimalLocator(config, binary.LittleEndian.Uint32(block[16:20])),
+		}
+		msg.Text += update.String()
+		d.appendTrailer(&msg, block[ltpBlockSize:])
+	} else {
+		var hasSegment, hasToken bool
+		for _, field := range strings.Split(msg.Text, string(FieldDelimiter)) {
+			tag, value, found := strings.Cut(field, string(TagValueSeparator))
+			if !found {
+				continue
+			}
+			tag = strings.TrimSpace(tag)
+			if tag == "73" {
+				if ltt, err := time.ParseInLocation("2006-01-02 150405", value, d.Epoch.Location()); err == nil {
+					update.LTT = ltt
+				}
+				continue
+			}
+
+			number, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+			if err != nil {
+				continue
+			}
+			switch tag {
+			case "1":
+				update.MktSegID = uint32(number)
+				hasSegment = true
+			case "7":
+				update.Token = uint32(number)
+				hasToken = true
+			case "8":
+				update.LTP = uint32(number)
+			case "399":
+				update.DecimalLocator = uint32(number)
+			}
+		}
+		if !hasSegment || !hasToken {
+			return msg, nil
+		}
+		update.DecimalLocator = segmentDecimalLocator(d.segmentConfig(update.MktSegID), update.DecimalLocator)
+	}
+
+	msg.Kind = MessageLTP
+	msg.LTP = &update
+	return msg, nil
+}
+
+// decodeTouchline decodes the fixed length binary touchline block that follows the 50= tag,
+// interpreting timestamps and the decimal locator per the segment configuration
+func (d *Decoder) decodeTouchline(data []byte) TouchlineData {
+	readUint32 := func(offset int) uint32 {
+		return binary.LittleEndian.Uint32(data[offset : offset+4])
+	}
+	config := d.segmentConfig(readUint32(0))
+	readTime := func(offset int) time.Time {
+		return d.segmentTime(config, int32(readUint32(offset)))
+	}
+
+	return TouchlineData{
+		MktSegID:             readUint32(0),
+		Token:                readUint32(4),
+		LUT:                  readTime(8),
+		LTT:                  readTime(12),
+		LTP:                  readUint32(16),
+		BuyQty:               readUint32(20),
+		BuyPrice:             readUint32(24),
+		SellQty:              readUint32(28),
+		SellPrice:            readUint32(32),
+		OpenPrice:            readUint32(36),
+		HighPrice:            readUint32(40),
+		LowPrice:             readUint32(44),
+		ClosePrice:           readUint32(48),
+		DecimalLocator:       segmentDecimalLocator(config, readUint32(52)),
+		PrevClosePrice:       readUint32(56),
+		IndicativeClosePrice: readUint32(60),
+	}
+}
@@ -0,0 +1,133 @@
+package ODINMarketFeed
+
+import (
+	"encoding/binary"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// touchlineFixture returns a touchline response with the given block fields
+func touchlineFixture(segment, token uint32, lut, ltt int32, ltp, decimalLocator uint32) []byte {
+	block := make([]byte, touchlineBlockSize)
+	binary.LittleEndian.PutUint32(block[0:], segment)
+	binary.LittleEndian.PutUint32(block[4:], token)
+	binary.LittleEndian.PutUint32(block[8:], uint32(lut))
+	binary.LittleEndian.PutUint32(block[12:], uint32(ltt))
+	binary.LittleEndian.PutUint32(block[16:], ltp)
+	binary.LittleEndian.PutUint32(block[52:], decimalLocator)
+	return append([]byte("63=FT3.0|64=206|50="), block...)
+}
+
+func TestDecodeSegmentFixtures(t *testing.T) {
+	feedEpochUTC := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	unixEpoch := time.Unix(0, 0).UTC()
+
+	tests := []struct {
+		name     string
+		config   *SegmentConfig
+		packet   []byte
+		wantTime time.Time
+		wantDL   uint32
+	}{
+		{"NSE CM", nil, touchlineFixture(SegmentNSECM, 22, 1460000000, 1460000000, 245650, 100),
+			time.Date(2026, 4, 7, 3, 33, 20, 0, time.UTC), 100},
+		{"NSE FO", nil, touchlineFixture(SegmentNSEFO, 35001, 1460000000, 1460000000, 1990005, 100),
+			time.Date(2026, 4, 7, 3, 33, 20, 0, time.UTC), 100},
+		{"BSE without configuration", nil, touchlineFixture(SegmentBSECM, 500325, 1460000000, 1460000000, 245650, 100),
+			time.Date(2026, 4, 7, 3, 33, 20, 0, time.UTC), 100},
+		{"MCX without configuration", nil, touchlineFixture(SegmentMCX, 234230, 1460000000, 1460000000, 7215000, 10000),
+			time.Date(2026, 4, 7, 3, 33, 20, 0, time.UTC), 10000},
+		{"BSE with Unix epoch and 2 decimals", &SegmentConfig{Epoch: unixEpoch, Decimals: 2},
+			touchlineFixture(SegmentBSECM, 500325, 1760000000, 1760000000, 245650, 1),
+			time.Date(2025, 10, 9, 8, 53, 20, 0, time.UTC), 100},
+		{"MCX with 4 decimals", &SegmentConfig{Decimals: 4},
+			touchlineFixture(SegmentMCX, 234230, 1460000000, 1460000000, 7215000, 1),
+			time.Date(2026, 4, 7, 3, 33, 20, 0, time.UTC), 10000},
+	}
+	for _, tt := range tests {
+		d := NewDecoder()
+		d.Epoch = feedEpochUTC
+		if tt.config != nil {
+			d.RegisterSegmentConfig(binary.LittleEndian.Uint32(tt.packet[19:]), *tt.config)
+		}
+
+		msg, err := d.Decode(tt.packet)
+		if err != nil || msg.Touchline == nil {
+			t.Fatalf("%s: Decode = %+v, %v", tt.name, msg, err)
+		}
+		tl := msg.Touchline
+		if !tl.LUT.Equal(tt.wantTime) || !tl.LTT.Equal(tt.wantTime) {
+			t.Errorf("%s: LUT %v, LTT %v; want %v", tt.name, tl.LUT, tl.LTT, tt.wantTime)
+		}
+		if tl.DecimalLocator != tt.wantDL {
+			t.Errorf("%s: DecimalLocator = %d, want %d", tt.name, tl.DecimalLocator, tt.wantDL)
+		}
+	}
+}
+
+func TestDecodeBestFiveSegmentDecimals(t *testing.T) {
+	raw := []byte("63=FT3.0|64=127|1=5|7=234230|399=1|2=10$3=7215000|5=4$6=7216000")
+
+	msg, _ := NewDecoder().Decode(raw)
+	if msg.BestFive == nil || msg.BestFive.DecimalLocator != 1 {
+		t.Fatalf("without configuration: %+v, want the wire decimal locator 1", msg.BestFive)
+	}
+
+	d := NewDecoder()
+	d.RegisterSegmentConfig(SegmentMCX, SegmentConfig{Decimals: 4})
+	msg, _ = d.Decode(raw)
+	if msg.BestFive == nil || msg.BestFive.DecimalLocator != 10000 {
+		t.Fatalf("with 4 decimals: %+v, want decimal locator 10000", msg.BestFive)
+	}
+}
+
+func TestDecodeBinaryBlockWithDelimiterBytes(t *testing.T) {
+	// Fields holding 0x00, 0x7C ('|') and 0xFF bytes, one of them spelling "|50=" on the wire
+	fields := map[int]uint32{
+		0:  1,
+		4:  0x7C,
+		16: 0xFF7C00FF,
+		20: 0x3D30357C,
+		24: 0x7C7C7C7C,
+		28: 0xFFFFFFFF,
+		32: 0x00FF007C,
+		52: 100,
+	}
+	block := make([]byte, touchlineBlockSize)
+	for offset, value := range fields {
+		binary.LittleEndian.PutUint32(block[offset:], value)
+	}
+	packet := append([]byte("63=FT3.0|64=206|50="), block...)
+
+	tw := newTestClient()
+	var touchlines []TouchlineData
+	var messages []string
+	tw.OnTouchline = func(touchline TouchlineData) { touchlines = append(touchlines, touchline) }
+	tw.OnMessage = func(message string) { messages = append(messages, message) }
+	tw.responseReceived(frameOf(packet, touchlineMessage(1, 22, 24500)), 0)
+
+	if len(touchlines) != 2 || len(messages) != 2 {
+		t.Fatalf("got %d touchlines and %d messages, want 2 of each", len(touchlines), len(messages))
+	}
+	tl := touchlines[0]
+	got := map[int]uint32{0: tl.MktSegID, 4: tl.Token, 16: tl.LTP, 20: tl.BuyQty, 24: tl.BuyPrice, 28: tl.SellQty, 32: tl.SellPrice, 52: tl.DecimalLocator}
+	for offset, want := range fields {
+		if got[offset] != want {
+			t.Errorf("field at offset %d = %#x, want %#x", offset, got[offset], want)
+		}
+	}
+	if touchlines[1].Token != 22 || touchlines[1].LTP != 24500 {
+		t.Errorf("next touchline %+v, want 1_22 at 24500", touchlines[1])
+	}
+
+	if strings.ContainsAny(messages[0], "\x00\xff") || strings.Contains(messages[0], "|50=") {
+		t.Errorf("OnMessage got %q, want the decoded text only", messages[0])
+	}
+	for _, tag := range []string{"|7=124|", "|8=" + strconv.Itoa(0xFF7C00FF) + "|", "|2=" + strconv.Itoa(0x3D30357C) + "|"} {
+		if !strings.Contains(messages[0], tag) {
+			t.Errorf("OnMessage got %q, want %s", messages[0], tag)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package ODINMarketFeed
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// TickField selects touchline fields compared by WithTickDedup
+type TickField uint32
+
+const (
+	TickLTP         TickField = 1 << iota // last traded price
+	TickLTT                               // last traded time
+	TickQuantities                        // best buy and sell quantities
+	TickTopOfBook                         // best buy and sell prices
+	TickOHLC                              // open, high, low and close prices
+	TickTradedValue                       // total traded value and average traded price
+
+	// DefaultTickFields are compared when WithTickDedup is given no fields
+	DefaultTickFields = TickLTP | TickLTT | TickQuantities | TickTopOfBook | TickTradedValue
+)
+
+// tickDedup remembers the last delivered touchline per token
+type tickDedup struct {
+	fields TickField
+	last   map[uint64]TouchlineData
+	stale  map[uint64]struct{} // last touchlines of a previous day, see WithDayRollover
+	mu     sync.Mutex
+}
+
+// WithTickDedup withholds from OnTouchline, OnJSON and Ticks the touchlines whose selected
+// fields equal those of the previous touchline of the same token, counting them in
+// Stats().DuplicateTicks. OnMessage still receives every message. fields of 0 selects
+// DefaultTickFields. The first touchline after each subscribe is always delivered, and
+// suppressed touchlines still count as updates for TokenStats and StaleTokens.
+func WithTickDedup(fields TickField) Option {
+	return func(tw *ODINMarketFeedClient) {
+		if fields == 0 {
+			fields = DefaultTickFields
+		}
+		tw.dedup = &tickDedup{fields: fields, last: make(map[uint64]TouchlineData), stale: make(map[uint64]struct{})}
+	}
+}
+
+// duplicate records the touchline and reports whether the selected fields are unchanged
+func (td *tickDedup) duplicate(data TouchlineData) bool {
+	key := depthKey(data.MktSegID, data.Token)
+
+	td.mu.Lock()
+	defer td.mu.Unlock()
+
+	last, ok := td.last[key]
+	td.last[key] = data
+	if _, stale := td.stale[key]; stale {
+		delete(td.stale, key)
+		return false
+	}
+	return ok && td.equal(last, data)
+}
+
+func (td *tickDedup) equal(a, b TouchlineData) bool {
+	if td.fields&TickLTP != 0 && a.LTP != b.LTP {
+		return false
+	}
+	if td.fields&TickLTT != 0 && !a.LTT.Equal(b.LTT) {
+		return false
+	}
+	if td.fields&TickQuantities != 0 && (a.BuyQty != b.BuyQty || a.SellQty != b.SellQty) {
+		return false
+	}
+	if td.fields&TickTopOfBook != 0 && (a.BuyPrice != b.BuyPrice || a.SellPrice != b.SellPrice) {
+		return false
+	}
+	if td.fields&TickOHLC != 0 && (a.OpenPrice != b.OpenPrice || a.HighPrice != b.HighPrice ||
+		a.LowPrice != b.LowPrice || a.ClosePrice != b.ClosePrice) {
+		return false
+	}
+	if td.fields&TickTradedValue != 0 && (a.TotalTradedValue != b.TotalTradedValue || a.ATP != b.ATP) {
+		return false
+	}
+	return true
+}
+
+func (td *tickDedup) forget(key uint64) {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+	delete(td.last, key)
+	delete(td.stale, key)
+}
+
+func (td *tickDedup) clear() {
+	td.mu.Lock()
+	defer td.mu.Unlock()
+	td.last = make(map[uint64]TouchlineData)
+	td.stale = make(map[uint64]struct{})
+}
+
+// isDuplicateTick reports whether WithTickDedup suppresses the touchline
+func (tw *ODINMarketFeedClient) isDuplicateTick(touchline TouchlineData) bool {
+	if tw.dedup == nil || !tw.dedup.duplicate(touchline) {
+		return false
+	}
+	atomic.AddUint64(&tw.stats.duplicateTicks, 1)
+	return true
+}
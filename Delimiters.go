@@ -0,0 +1,64 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+const (
+	// FieldDelimiter separates the tag=value fields of a message
+	FieldDelimiter = '|'
+	// DefaultPairDelimiter separates the fields of one entry of a repeating group, such as
+	// the 1= and 7= fields of an instrument
+	DefaultPairDelimiter = '$'
+	// TagValueSeparator separates the tag of a field from its value
+	TagValueSeparator = '='
+)
+
+// messagePadding holds the bytes some gateway builds leave after the text of a message or
+// between inner messages
+const messagePadding = "\r\n\x00 "
+
+// WithPairDelimiter sets the repeating-group delimiter of the gateway, for builds that do
+// not use DefaultPairDelimiter. It applies to the requests built by the client and to the
+// messages it decodes. Digits, TagValueSeparator and FieldDelimiter are ignored.
+func WithPairDelimiter(delimiter byte) Option {
+	return func(tw *ODINMarketFeedClient) {
+		if validPairDelimiter(delimiter) {
+			tw.decoder.PairDelimiter = delimiter
+		}
+	}
+}
+
+// validPairDelimiter reports whether delimiter can separate the fields of a group entry
+func validPairDelimiter(delimiter byte) bool {
+	return delimiter != FieldDelimiter && delimiter != TagValueSeparator && (delimiter < '0' || delimiter > '9')
+}
+
+// pairDelimiter returns the repeating-group delimiter of the decoder
+func (d *Decoder) pairDelimiter() byte {
+	if d.PairDelimiter == 0 || !validPairDelimiter(d.PairDelimiter) {
+		return DefaultPairDelimiter
+	}
+	return d.PairDelimiter
+}
+
+// trimMessagePadding drops the CR, LF, NUL and space bytes that end the text of a message
+func trimMessagePadding(text []byte) []byte {
+	return bytes.TrimRight(text, messagePadding)
+}
+
+// paddingLength returns the number of padding bytes data starts with
+func paddingLength(data []byte) int {
+	n := 0
+	for n < len(data) && strings.IndexByte(messagePadding, data[n]) >= 0 {
+		n++
+	}
+	return n
+}
+
+// instrumentPair formats an instrument as an entry of the 1=MarketSegmentID$7=Token group
+func (tw *ODINMarketFeedClient) instrumentPair(segID, token int) string {
+	return "1=" + strconv.Itoa(segID) + string(tw.decoder.pairDelimiter()) + "7=" + strconv.Itoa(token)
+}
@@ -0,0 +1,39 @@
+package ODINMarketFeed
+
+import "testing"
+
+func TestFieldValueUsesPairDelimiter(t *testing.T) {
+	tests := []struct {
+		text  string
+		tag   int
+		pair  byte
+		want  string
+		found bool
+	}{
+		{"63=FT3.0|64=206|1=1$7=22|9001=abc", 7, '$', "22", true},
+		{"63=FT3.0|64=206|1=1#7=22|9001=abc", 7, '#', "22", true},
+		{"63=FT3.0|64=206|1=1$7=22|9001=abc", 7, '#', "", false},
+		{"63=FT3.0|64=206|1=1#7=22|9001=abc", 9001, '#', "abc", true},
+		{"63=FT3.0|64=206|19001=x", 9001, '$', "", false},
+	}
+	for _, tt := range tests {
+		got, found := fieldValue(tt.text, tt.tag, tt.pair)
+		if got != tt.want || found != tt.found {
+			t.Errorf("fieldValue(%q, %d, %q) = %q, %v; want %q, %v", tt.text, tt.tag, tt.pair, got, found, tt.want, tt.found)
+		}
+	}
+}
+
+func TestDecoderCorrelationWithPairDelimiter(t *testing.T) {
+	d := NewDecoder()
+	d.PairDelimiter = '#'
+	d.CorrelationTag = 9001
+
+	msg, err := d.Decode([]byte("63=FT3.0|64=127|1=1#7=22|9001=req-7#8=1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.CorrelationID != "req-7" {
+		t.Errorf("CorrelationID = %q, want %q", msg.CorrelationID, "req-7")
+	}
+}
@@ -0,0 +1,117 @@
+package ODINMarketFeed
+
+import (
+	"fmt"
+	"sync/atomic"
+	"unsafe"
+)
+
+// MemoryPressure is emitted and passed to OnMemoryPressure when the messages buffered for
+// delivery reach the WithDeliveryMemoryLimit limit and the oldest start being dropped. It is
+// emitted again only after the buffers have drained below three quarters of the limit.
+type MemoryPressure struct {
+	EventSource
+	Limit   int64  // the WithDeliveryMemoryLimit limit in bytes
+	InUse   int64  // the estimated bytes buffered when the first message was dropped
+	Dropped uint64 // messages dropped for memory so far, see Stats.MemoryDrops
+}
+
+func (MemoryPressure) isEvent() {}
+
+// deliveryMemory accounts the estimated size of the messages buffered for delivery
+type deliveryMemory struct {
+	limit    int64
+	inUse    int64
+	dropped  uint64
+	pressure int32
+}
+
+// WithDeliveryMemoryLimit caps the estimated memory held by messages buffered for delivery,
+// in the AttachPublisher queue and the Ticks and Messages buffers, at limit bytes. A message
+// that would take the total past the limit makes the oldest messages of its queue be
+// dropped, counted in Stats.MemoryDrops, and MemoryPressure is reported. Sizes are estimated once per message when it is buffered, from
+// its struct size and the slices it holds. 0 means no limit.
+func WithDeliveryMemoryLimit(limit int64) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.memory.limit = limit
+	}
+}
+
+// reserve accounts size bytes for a buffered message. evictOldest is called to drop the
+// oldest buffered message, returning its size, or false when its queue is empty; ok is
+// false when the message does not fit even then. pressure is set when the limit was first
+// reached, for the caller to pass to memoryPressure once it holds no lock.
+func (tw *ODINMarketFeedClient) reserve(size int64, evictOldest func() (int64, bool)) (ok bool, pressure *MemoryPressure) {
+	dm := &tw.memory
+	if dm.limit <= 0 {
+		return true, nil
+	}
+
+	for atomic.AddInt64(&dm.inUse, size) > dm.limit {
+		inUse := atomic.AddInt64(&dm.inUse, -size)
+		if pressure == nil && atomic.CompareAndSwapInt32(&dm.pressure, 0, 1) {
+			pressure = &MemoryPressure{EventSource: tw.source(), Limit: dm.limit, InUse: inUse}
+		}
+
+		freed, evicted := evictOldest()
+		dropped := atomic.AddUint64(&dm.dropped, 1)
+		if pressure != nil {
+			pressure.Dropped = dropped
+		}
+		if !evicted {
+			return false, pressure
+		}
+		atomic.AddInt64(&dm.inUse, -freed)
+	}
+	return true, pressure
+}
+
+// release returns the bytes of a message that has left its buffer
+func (tw *ODINMarketFeedClient) release(size int64) {
+	dm := &tw.memory
+	if dm.limit <= 0 {
+		return
+	}
+	if atomic.AddInt64(&dm.inUse, -size) <= dm.limit/4*3 {
+		atomic.StoreInt32(&dm.pressure, 0)
+	}
+}
+
+// memoryPressure reports the MemoryPressure returned by reserve
+func (tw *ODINMarketFeedClient) memoryPressure(event *MemoryPressure) {
+	if event == nil {
+		return
+	}
+
+	tw.emit(*event)
+	if tw.OnMemoryPressure != nil {
+		tw.invokeCallback("OnMemoryPressure", func() string {
+			return fmt.Sprintf("%d of %d bytes buffered", event.InUse, event.Limit)
+		}, func() { tw.OnMemoryPressure(*event) })
+	}
+}
+
+// messageSize estimates the memory held by a buffered message value
+func messageSize(value interface{}) int64 {
+	switch v := value.(type) {
+	case TouchlineData:
+		return int64(unsafe.Sizeof(v)) + int64(len(v.Symbol))
+	case BestFiveData:
+		level := int64(unsafe.Sizeof(DepthLevel{}))
+		return int64(unsafe.Sizeof(v)) + int64(cap(v.Bids)+cap(v.Asks))*level
+	case ParsedMessage:
+		size := int64(unsafe.Sizeof(v)) + int64(len(v.Text)+cap(v.Raw)+len(v.CorrelationID))
+		size += int64(cap(v.Fields)) * int64(unsafe.Sizeof(Field{}))
+		if v.Touchline != nil {
+			size += messageSize(*v.Touchline)
+		}
+		if v.BestFive != nil {
+			size += messageSize(*v.BestFive)
+		}
+		if v.LTP != nil {
+			size += int64(unsafe.Sizeof(*v.LTP))
+		}
+		return size
+	}
+	return int64(unsafe.Sizeof(value))
+}
@@ -0,0 +1,50 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDeliveryMemoryCapHoldsWithStalledConsumers(t *testing.T) {
+	const limit = 32 << 10
+	tw := newTestClient(WithDeliveryMemoryLimit(limit))
+	var pressure []MemoryPressure
+	tw.OnMemoryPressure = func(event MemoryPressure) { pressure = append(pressure, event) }
+
+	release := make(chan struct{})
+	if err := tw.AttachPublisher(publisherFunc(func(string, []byte, []byte) error {
+		<-release
+		return nil
+	}), PublishConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		close(release)
+		tw.Close(context.Background())
+	}()
+	// An iterator whose loop body never returns leaves its buffer to fill up
+	sink := newDeliverySink[TouchlineData](tw, 0, &tw.stats.iteratorDrops)
+	tw.tickSink.Store(sink)
+
+	for i := 0; i < 20; i++ {
+		tw.responseReceived(frameOf(manyMessages(100)...), 0)
+		if inUse := tw.Stats().DeliveryMemory; inUse > limit {
+			t.Fatalf("DeliveryMemory = %d after frame %d, over the limit of %d", inUse, i, limit)
+		}
+	}
+
+	stats := tw.Stats()
+	if stats.MemoryDrops == 0 {
+		t.Error("no message dropped for memory with both consumers stalled")
+	}
+	if stats.IteratorDrops != 0 || stats.PublishDrops != 0 {
+		t.Errorf("IteratorDrops = %d and PublishDrops = %d, want the memory limit to drop first",
+			stats.IteratorDrops, stats.PublishDrops)
+	}
+	if sink.depth() == 0 {
+		t.Error("the iterator buffer holds nothing, want it to share the budget")
+	}
+	if len(pressure) != 1 || pressure[0].Limit != limit {
+		t.Errorf("OnMemoryPressure got %+v, want one report for the limit", pressure)
+	}
+}
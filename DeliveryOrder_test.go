@@ -0,0 +1,121 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// orderRecorder records the callbacks of a client in the order they run
+type orderRecorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *orderRecorder) record(format string, args ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, fmt.Sprintf(format, args...))
+}
+
+func (r *orderRecorder) snapshot() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.events...)
+}
+
+func TestDeliveryFollowsWireOrder(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithNoticeCode(900, SeverityWarning))
+	var r orderRecorder
+	tw.OnTouchline = func(touchline TouchlineData) { r.record("touchline %d", touchline.Token) }
+	tw.OnNotice = func(notice Notice) { r.record("notice %d seq=%d", notice.Code, notice.Seq) }
+	tw.OnMessage = func(msg string) { r.record("message %d", messageCode(msg)) }
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	c := ms.latest()
+	c.send(touchlineMessage(1, 22, 24500), []byte("63=FT3.0|64=900|100=Early close at 15:00"), touchlineMessage(1, 23, 24600))
+	c.sendText("maintenance at 17:00")
+	c.send([]byte("63=FT3.0|64=901|100=Free text"), touchlineMessage(1, 24, 24700))
+	c.sendText("session ends")
+
+	// OnMessage also receives every touchline, after OnTouchline
+	want := []string{
+		"touchline 22", "message 206",
+		"notice 900 seq=2",
+		"touchline 23", "message 206",
+		"notice -1 seq=4",
+		"message 901",
+		"touchline 24", "message 206",
+		"notice -1 seq=7",
+	}
+	eventually(t, 5*time.Second, func() bool { return len(r.snapshot()) >= len(want) })
+	if got := r.snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("delivered\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSeqRestartsOnReconnect(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	seqs := make(chan uint64, 4)
+	tw.OnMessageBatch = func(msgs []ParsedMessage) {
+		for _, msg := range msgs {
+			seqs <- msg.Seq
+		}
+	}
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	ms.latest().send([]byte("63=FT3.0|64=901|100=a"), []byte("63=FT3.0|64=901|100=b"))
+	for _, want := range []uint64{1, 2} {
+		if got := <-seqs; got != want {
+			t.Fatalf("Seq = %d, want %d", got, want)
+		}
+	}
+
+	tw.Disconnect()
+	ms.connect(t, tw)
+	ms.next(t, msgCodeLogin)
+	ms.latest().send([]byte("63=FT3.0|64=901|100=c"))
+	select {
+	case got := <-seqs:
+		if got != 1 {
+			t.Errorf("first Seq after reconnecting = %d, want 1", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message after reconnecting")
+	}
+}
+
+func TestOnOpenRunsBeforeFirstMessage(t *testing.T) {
+	// The gateway acknowledges the login as soon as it arrives, racing OnOpen
+	ms := newMockServer(t, func(c *mockConn, request string) {
+		if messageCode(request) == msgCodeLogin {
+			c.send([]byte("63=FT3.0|64=101|100=Login successful"))
+		}
+	})
+
+	for i := 0; i < 100; i++ {
+		tw := newTestClient()
+		var r orderRecorder
+		tw.OnOpen = func() {
+			time.Sleep(time.Millisecond)
+			r.record("open")
+		}
+		tw.OnMessage = func(msg string) { r.record("message %d", messageCode(msg)) }
+		ms.connect(t, tw)
+
+		eventually(t, 5*time.Second, func() bool { return len(r.snapshot()) >= 2 })
+		tw.Close(context.Background())
+		if got, want := r.snapshot(), []string{"open", "message 101"}; !reflect.DeepEqual(got, want) {
+			t.Fatalf("iteration %d delivered %q, want %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// dialerConfig holds the network dialer settings applied by Connect
+type dialerConfig struct {
+	netDialer *net.Dialer
+	localAddr string
+	keepAlive time.Duration
+	timeout   time.Duration
+}
+
+// WithLocalAddr binds outbound connections to the given local IP address. The address
+// must be assigned to an interface on this host; Connect fails otherwise.
+func WithLocalAddr(ip string) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.dialer.localAddr = ip
+	}
+}
+
+// WithTCPKeepAlive sets the TCP keep-alive period of outbound connections. A negative
+// period disables keep-alives.
+func WithTCPKeepAlive(period time.Duration) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.dialer.keepAlive = period
+	}
+}
+
+// WithDialTimeout limits each connection attempt, including the TLS and websocket
+// handshakes, to timeout
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.dialer.timeout = timeout
+	}
+}
+
+// WithNetDialer uses the given net.Dialer for outbound connections. WithLocalAddr and
+// WithTCPKeepAlive are applied on top of a copy of it.
+func WithNetDialer(dialer *net.Dialer) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.dialer.netDialer = dialer
+	}
+}
+
+// websocketDialer returns the websocket dialer for Connect
+func (tw *ODINMarketFeedClient) websocketDialer() (*websocket.Dialer, error) {
+	cfg := tw.dialer
+	if cfg.netDialer == nil && cfg.localAddr == "" && cfg.keepAlive == 0 && cfg.timeout == 0 {
+		return websocket.DefaultDialer, nil
+	}
+
+	netDialer := &net.Dialer{}
+	if cfg.netDialer != nil {
+		*netDialer = *cfg.netDialer
+	}
+	if cfg.keepAlive != 0 {
+		netDialer.KeepAlive = cfg.keepAlive
+	}
+	if cfg.timeout > 0 {
+		netDialer.Timeout = cfg.timeout
+	}
+
+	if cfg.localAddr != "" {
+		ip := net.ParseIP(cfg.localAddr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid local address: '%s'", cfg.localAddr)
+		}
+		if err := checkLocalIP(ip); err != nil {
+			return nil, err
+		}
+		netDialer.LocalAddr = &net.TCPAddr{IP: ip}
+	}
+
+	dialer := *websocket.DefaultDialer
+	if cfg.timeout > 0 {
+		dialer.HandshakeTimeout = cfg.timeout
+	}
+	dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := netDialer.DialContext(ctx, network, addr)
+		if err != nil && netDialer.LocalAddr != nil {
+			return nil, fmt.Errorf("failed to dial %s from local address %s: %w", addr, cfg.localAddr, err)
+		}
+		return conn, err
+	}
+	return &dialer, nil
+}
+
+// checkLocalIP verifies that ip is assigned to an interface on this host
+func checkLocalIP(ip net.IP) error {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return fmt.Errorf("failed to list local addresses: %w", err)
+	}
+
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return nil
+		}
+	}
+	return errors.New("local address " + ip.String() + " is not assigned to any interface on this host")
+}
+
+// upgradeBodyLimit caps the response body excerpt included in an UpgradeError
+const upgradeBodyLimit = 512
+
+// UpgradeError is returned by Connect when the server answered the websocket upgrade with
+// an HTTP status other than 101, e.g. a load balancer returning 403
+type UpgradeError struct {
+	StatusCode int
+	Status     string
+	Body       string // excerpt of the response body
+	Err        error
+}
+
+// Error describes the rejected upgrade
+func (e *UpgradeError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%v: HTTP %s", e.Err, e.Status)
+	}
+	return fmt.Sprintf("%v: HTTP %s: %s", e.Err, e.Status, e.Body)
+}
+
+// Unwrap returns the dial error
+func (e *UpgradeError) Unwrap() error {
+	return e.Err
+}
+
+// upgradeError wraps a dial error with the HTTP response of a rejected upgrade
+func upgradeError(err error, resp *http.Response) error {
+	if resp == nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, upgradeBodyLimit))
+	return &UpgradeError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       strings.TrimSpace(string(body)),
+		Err:        err,
+	}
+}
+
+// ErrTLSMismatch is wrapped by Connect errors that suggest useSSL does not match the server
+var ErrTLSMismatch = errors.New("TLS setting does not match the server")
+
+// CertificateError is returned by Connect when the server certificate could not be verified,
+// e.g. an unknown authority or a hostname mismatch. Err is the x509 or tls error.
+type CertificateError struct {
+	Err error
+}
+
+// Error describes the certificate problem
+func (e *CertificateError) Error() string {
+	return fmt.Sprintf("server certificate rejected: %v", e.Err)
+}
+
+// Unwrap returns the verification error
+func (e *CertificateError) Unwrap() error {
+	return e.Err
+}
+
+// diagnoseDialError adds a hint to dial errors caused by a TLS mismatch and wraps
+// certificate verification failures in a CertificateError
+func diagnoseDialError(err error, useSSL bool) error {
+	var verifyErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &verifyErr) || errors.As(err, &unknownAuthority) || errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return &CertificateError{Err: err}
+	}
+
+	if useSSL {
+		var recordErr tls.RecordHeaderError
+		if errors.As(err, &recordErr) {
+			return fmt.Errorf("%w: server appears to be plaintext - did you mean useSSL=false?: %w", ErrTLSMismatch, err)
+		}
+		return err
+	}
+
+	// A TLS server answers plaintext with an alert or handshake record, which the HTTP client
+	// reports as a malformed response starting with \x15\x03 or \x16\x03. HTTPS servers
+	// that detect plain HTTP answer 400 with an explanation instead.
+	message := err.Error()
+	tlsRecord := strings.Contains(message, "malformed HTTP") && (strings.Contains(message, `\x15\x03`) || strings.Contains(message, `\x16\x03`))
+	var upgradeErr *UpgradeError
+	plainToTLS := errors.As(err, &upgradeErr) && upgradeErr.StatusCode == http.StatusBadRequest &&
+		(strings.Contains(upgradeErr.Body, "HTTP request to an HTTPS server") || strings.Contains(upgradeErr.Body, "plain HTTP request was sent to HTTPS port"))
+	if tlsRecord || plainToTLS {
+		return fmt.Errorf("%w: server appears to expect TLS - did you mean useSSL=true?: %w", ErrTLSMismatch, err)
+	}
+	return err
+}
@@ -0,0 +1,158 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestNetDialerIsInvoked(t *testing.T) {
+	ms := newMockServer(t, nil)
+	var mu sync.Mutex
+	var dialed []string
+	dialer := &net.Dialer{ControlContext: func(ctx context.Context, network, address string, c syscall.RawConn) error {
+		mu.Lock()
+		defer mu.Unlock()
+		dialed = append(dialed, address)
+		return nil
+	}}
+	tw := newTestClient(WithNetDialer(dialer), WithLocalAddr("127.0.0.1"))
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(dialed) != 1 || !strings.HasSuffix(dialed[0], Endpoint{Host: ms.host, Port: ms.port}.String()) {
+		t.Errorf("injected dialer dialed %q, want the mock server once", dialed)
+	}
+	info, _ := tw.ConnectionInfo()
+	if addr, ok := info.LocalAddr.(*net.TCPAddr); !ok || !addr.IP.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("LocalAddr = %v, want 127.0.0.1", info.LocalAddr)
+	}
+}
+
+func TestLocalAddrNotOnHost(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithLocalAddr("192.0.2.1"))
+	defer tw.Close(context.Background())
+
+	err := tw.Connect(ms.host, ms.port, false, "u", "k")
+	if err == nil || !strings.Contains(err.Error(), "192.0.2.1") {
+		t.Errorf("Connect error %v, want the unassigned local address named", err)
+	}
+	if ms.connections() != 0 {
+		t.Error("the server was dialed from an unassigned local address")
+	}
+}
+
+func TestUpgradeRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "blocked by load balancer policy", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	portNumber, _ := strconv.Atoi(port)
+
+	tw := newTestClient(WithLegacyErrorCallbacks(true))
+	defer tw.Close(context.Background())
+	var attempts []int
+	tw.OnConnecting = func(attempt int, url string) { attempts = append(attempts, attempt) }
+	var reported []string
+	tw.OnError = func(message string) { reported = append(reported, message) }
+
+	for i := 0; i < 2; i++ {
+		err := tw.Connect(host, portNumber, false, "u", "k")
+		var upgradeErr *UpgradeError
+		if !errors.As(err, &upgradeErr) {
+			t.Fatalf("Connect = %v, want an *UpgradeError", err)
+		}
+		if upgradeErr.StatusCode != http.StatusForbidden || upgradeErr.Body != "blocked by load balancer policy" {
+			t.Errorf("UpgradeError %+v, want the 403 and its body", upgradeErr)
+		}
+		if !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "blocked by load balancer policy") {
+			t.Errorf("error %q, want the status and body", err)
+		}
+	}
+
+	if len(attempts) != 2 || attempts[0] != 1 || attempts[1] != 2 {
+		t.Errorf("OnConnecting attempts %v, want 1 and 2", attempts)
+	}
+	if len(reported) != 2 || !strings.Contains(reported[0], "403") {
+		t.Errorf("OnError got %q, want both rejections with the status", reported)
+	}
+}
+
+// serverAddr returns the host and port of an httptest server
+func serverAddr(t *testing.T, srv *httptest.Server) (string, int) {
+	t.Helper()
+	host, port, err := net.SplitHostPort(srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	portNumber, _ := strconv.Atoi(port)
+	return host, portNumber
+}
+
+// newTLSServer starts a TLS server that does not log the handshakes the tests fail on purpose
+func newTLSServer(handler http.Handler) *httptest.Server {
+	srv := httptest.NewUnstartedServer(handler)
+	srv.Config.ErrorLog = log.New(io.Discard, "", 0)
+	srv.StartTLS()
+	return srv
+}
+
+func TestTLSMismatchHints(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not a websocket gateway", http.StatusNotFound)
+	})
+	tlsServer := newTLSServer(handler)
+	defer tlsServer.Close()
+	plainServer := httptest.NewServer(handler)
+	defer plainServer.Close()
+
+	tests := []struct {
+		name   string
+		srv    *httptest.Server
+		useSSL bool
+		hint   string
+	}{
+		{"ws:// to a TLS server", tlsServer, false, "did you mean useSSL=true?"},
+		{"wss:// to a plain server", plainServer, true, "did you mean useSSL=false?"},
+	}
+	for _, tt := range tests {
+		host, port := serverAddr(t, tt.srv)
+		err := newTestClient().Connect(host, port, tt.useSSL, "u", "k")
+		if !errors.Is(err, ErrTLSMismatch) || !strings.Contains(err.Error(), tt.hint) {
+			t.Errorf("%s: Connect = %v, want ErrTLSMismatch with %q", tt.name, err, tt.hint)
+		}
+		var certErr *CertificateError
+		if errors.As(err, &certErr) {
+			t.Errorf("%s: a TLS mismatch was reported as a certificate problem", tt.name)
+		}
+	}
+}
+
+func TestUntrustedCertificate(t *testing.T) {
+	srv := newTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+	host, port := serverAddr(t, srv)
+
+	err := newTestClient().Connect(host, port, true, "u", "k")
+	var certErr *CertificateError
+	if !errors.As(err, &certErr) {
+		t.Fatalf("Connect = %v, want a *CertificateError", err)
+	}
+	if certErr.Unwrap() == nil || errors.Is(err, ErrTLSMismatch) {
+		t.Errorf("CertificateError %v, want the verification error without a mismatch hint", certErr)
+	}
+}
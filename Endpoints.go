@@ -0,0 +1,56 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// Endpoint is a feed server address
+type Endpoint struct {
+	Host   string
+	Port   int
+	UseSSL bool
+}
+
+// String returns the endpoint as host:port
+func (ep Endpoint) String() string {
+	return net.JoinHostPort(ep.Host, strconv.Itoa(ep.Port))
+}
+
+// ConnectAny connects to the first endpoint that accepts the connection and login, trying
+// them in order. The endpoint that succeeded is remembered and tried first by the next
+// ConnectAny call, with the others as fallbacks. The chosen endpoint is reported by
+// ConnectionInfo.
+func (tw *ODINMarketFeedClient) ConnectAny(endpoints []Endpoint, userID string, apiKey string) error {
+	if len(endpoints) == 0 {
+		return errors.New("endpoint list cannot be empty")
+	}
+
+	tw.mu.Lock()
+	start := tw.preferredEndpoint
+	tw.mu.Unlock()
+	if start >= len(endpoints) {
+		start = 0
+	}
+
+	var errs []error
+	for i := 0; i < len(endpoints); i++ {
+		index := (start + i) % len(endpoints)
+		endpoint := endpoints[index]
+
+		err := tw.Connect(endpoint.Host, endpoint.Port, endpoint.UseSSL, userID, apiKey)
+		if err == nil {
+			tw.mu.Lock()
+			tw.preferredEndpoint = index
+			tw.mu.Unlock()
+			return nil
+		}
+		if errors.Is(err, ErrClientDisposed) || errors.Is(err, ErrAlreadyConnected) {
+			return err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", endpoint, err))
+	}
+	return errors.Join(errs...)
+}
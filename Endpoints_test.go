@@ -0,0 +1,68 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+)
+
+// refusedPort returns a local port that nothing listens on
+func refusedPort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	return port
+}
+
+func TestConnectAnyFailsOverToTheNextEndpoint(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	var attempted []string
+	tw.OnConnecting = func(attempt int, url string) { attempted = append(attempted, url) }
+	defer tw.Close(context.Background())
+
+	refused := Endpoint{Host: "127.0.0.1", Port: refusedPort(t)}
+	accepting := Endpoint{Host: ms.host, Port: ms.port}
+	if err := tw.ConnectAny([]Endpoint{refused, accepting}, "u", "k"); err != nil {
+		t.Fatalf("ConnectAny: %v", err)
+	}
+	ms.next(t, msgCodeLogin)
+
+	if len(attempted) != 2 || !strings.Contains(attempted[0], refused.String()) || !strings.Contains(attempted[1], accepting.String()) {
+		t.Errorf("OnConnecting got %q, want the refused endpoint and then the accepting one", attempted)
+	}
+	info, ok := tw.ConnectionInfo()
+	if !ok || info.Endpoint != accepting {
+		t.Errorf("ConnectionInfo endpoint %+v, want %+v", info.Endpoint, accepting)
+	}
+
+	tw.Disconnect()
+	attempted = nil
+	if err := tw.ConnectAny([]Endpoint{refused, accepting}, "u", "k"); err != nil {
+		t.Fatalf("second ConnectAny: %v", err)
+	}
+	if len(attempted) != 1 || !strings.Contains(attempted[0], accepting.String()) {
+		t.Errorf("second ConnectAny dialed %q, want the endpoint that succeeded first", attempted)
+	}
+}
+
+func TestConnectAnyReportsEveryFailure(t *testing.T) {
+	tw := newTestClient()
+	defer tw.Close(context.Background())
+
+	endpoints := []Endpoint{{Host: "127.0.0.1", Port: refusedPort(t)}, {Host: "127.0.0.1", Port: refusedPort(t)}}
+	err := tw.ConnectAny(endpoints, "u", "k")
+	if err == nil {
+		t.Fatal("ConnectAny with no accepting endpoint succeeded")
+	}
+	for _, endpoint := range endpoints {
+		if !strings.Contains(err.Error(), endpoint.String()) {
+			t.Errorf("error %q does not name %s", err, endpoint)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ODINMarketFeed
+
+import "errors"
+
+// ErrClientDisposed is returned by client methods called after Dispose or Close. A disposed
+// client cannot be reconnected; create a new one instead.
+var ErrClientDisposed = errors.New("client is disposed")
+
+// ErrAlreadyConnected is returned by Connect while the client is connected or connecting,
+// unless WithSwitchOnConnect is set
+var ErrAlreadyConnected = errors.New("client is already connected")
+
+// checkDisposed returns ErrClientDisposed when the client has been disposed
+func (tw *ODINMarketFeedClient) checkDisposed() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.isDisposed {
+		return ErrClientDisposed
+	}
+	return nil
+}
+
+// WithLegacyErrorCallbacks makes methods that return an error also pass it to OnError, as
+// releases before 1.1 did. By default a failure is reported once: methods return their
+// validation and send failures without invoking OnError, and OnError and the Events
+// channel receive only the failures found asynchronously on the receive loop, the
+// heartbeat, reconnects and timers.
+func WithLegacyErrorCallbacks(enabled bool) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.legacyErrors = enabled
+	}
+}
+
+// returnedError passes a failure that the calling method also returns to OnError when
+// WithLegacyErrorCallbacks is set
+func (tw *ODINMarketFeedClient) returnedError(msg string) {
+	if tw.legacyErrors {
+		tw.reportError(msg)
+	}
+}
@@ -0,0 +1,129 @@
+package ODINMarketFeed
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// defaultEventBuffer is the capacity of the Events channel
+const defaultEventBuffer = 256
+
+// Event is a connection lifecycle event delivered on the Events channel. The concrete
+// types are Connected, Disconnected, ReconnectAttempt, Resubscribed, LoginFailed,
+// DuplicateSession, CallbackPanic, QuotaWarning, ResubscribeIncomplete, Throttled,
+// MemoryPressure, SubscriptionAck, DayRollover, SendFailed and StaleFeed. Each embeds the
+// EventSource of the client that emitted it.
+type Event interface {
+	isEvent()
+}
+
+// Connected is emitted after login, once OnOpen has returned
+type Connected struct {
+	EventSource
+	URL        string
+	Generation uint64
+}
+
+// Disconnected is emitted when the connection ends, locally or remotely. Code and Reason
+// come from the close frame when one was received; Cause classifies the end as a
+// ReconnectPolicy sees it.
+type Disconnected struct {
+	EventSource
+	Code   int
+	Reason string
+	Err    error
+	Cause  DisconnectCause
+}
+
+// ReconnectAttempt is emitted before each reconnect attempt; Err is the cause of the
+// previous failure
+type ReconnectAttempt struct {
+	EventSource
+	N   int
+	Err error
+}
+
+// Resubscribed is emitted after ResubscribeAll has replayed Count subscriptions
+type Resubscribed struct {
+	EventSource
+	Count int
+	Err   error
+}
+
+// LoginFailed is emitted when the login request could not be sent
+type LoginFailed struct {
+	EventSource
+	Reason string
+}
+
+func (Connected) isEvent()        {}
+func (Disconnected) isEvent()     {}
+func (ReconnectAttempt) isEvent() {}
+func (Resubscribed) isEvent()     {}
+func (LoginFailed) isEvent()      {}
+func (DuplicateSession) isEvent() {}
+
+// eventStream delivers events on a buffered channel created by the first Events call
+type eventStream struct {
+	ch      chan Event
+	size    int
+	closed  bool
+	dropped uint64
+	mu      sync.Mutex
+}
+
+// WithEventBuffer sets the capacity of the Events channel
+func WithEventBuffer(size int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.events.size = size
+	}
+}
+
+// Events returns the lifecycle event channel. Events are delivered in order; when the
+// buffer is full new events are dropped and counted in Stats().EventsDropped. The channel
+// is closed by Close and Dispose. The individual callbacks keep working alongside it.
+func (tw *ODINMarketFeedClient) Events() <-chan Event {
+	tw.events.mu.Lock()
+	defer tw.events.mu.Unlock()
+
+	if tw.events.ch == nil {
+		size := tw.events.size
+		if size <= 0 {
+			size = defaultEventBuffer
+		}
+		tw.events.ch = make(chan Event, size)
+		if tw.events.closed {
+			close(tw.events.ch)
+		}
+	}
+	return tw.events.ch
+}
+
+// emit delivers an event when Events has been called
+func (tw *ODINMarketFeedClient) emit(event Event) {
+	tw.events.mu.Lock()
+	defer tw.events.mu.Unlock()
+
+	if tw.events.ch == nil || tw.events.closed {
+		return
+	}
+	select {
+	case tw.events.ch <- event:
+	default:
+		atomic.AddUint64(&tw.events.dropped, 1)
+	}
+}
+
+// closeEvents closes the event channel
+func (tw *ODINMarketFeedClient) closeEvents() {
+	tw.events.mu.Lock()
+	defer tw.events.mu.Unlock()
+
+	if tw.events.closed {
+		return
+	}
+	tw.events.closed = true
+	if tw.events.ch != nil {
+		close(tw.events.ch)
+	}
+}
@@ -0,0 +1,101 @@
+package ODINMarketFeed
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// FeedGapStats holds the counters maintained by gap detection
+type FeedGapStats struct {
+	OutOfOrder uint64 // updates whose LUT is earlier than the last seen LUT for the token
+	Gaps       uint64 // consecutive updates further apart than the configured threshold
+}
+
+// gapDetector tracks the last update time per token. The touchline payload carries no
+// sequence number, so the LUT is used to detect missed or reordered updates.
+type gapDetector struct {
+	maxGap     time.Duration
+	marketOpen func(t time.Time) bool
+	lastSeen   map[uint64]*time.Time
+	outOfOrder uint64
+	gaps       uint64
+	mu         sync.Mutex
+}
+
+// WithGapDetection enables feed integrity checking on touchline updates. OnFeedGap fires
+// when an update arrives with an LUT earlier than the previous one for the same token, or
+// when consecutive updates are more than maxGap apart (0 disables the gap check). marketOpen
+// reports whether the market is open at the given LUT; nil treats the market as always open.
+func WithGapDetection(maxGap time.Duration, marketOpen func(t time.Time) bool) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.gapDetector = &gapDetector{
+			maxGap:     maxGap,
+			marketOpen: marketOpen,
+			lastSeen:   make(map[uint64]*time.Time),
+		}
+	}
+}
+
+// FeedGapStats returns the gap detection counters
+func (tw *ODINMarketFeedClient) FeedGapStats() FeedGapStats {
+	if tw.gapDetector == nil {
+		return FeedGapStats{}
+	}
+	return FeedGapStats{
+		OutOfOrder: atomic.LoadUint64(&tw.gapDetector.outOfOrder),
+		Gaps:       atomic.LoadUint64(&tw.gapDetector.gaps),
+	}
+}
+
+// check records the update and reports the previous LUT when a gap or reordering is detected.
+// The user supplied marketOpen function is called after the lock has been released.
+func (gd *gapDetector) check(segID, token uint32, lut time.Time) (time.Time, bool) {
+	gd.mu.Lock()
+	key := uint64(segID)<<32 | uint64(token)
+	last, ok := gd.lastSeen[key]
+	if !ok {
+		gd.lastSeen[key] = &lut
+		gd.mu.Unlock()
+		return time.Time{}, false
+	}
+
+	previous := *last
+	if lut.Before(previous) {
+		gd.mu.Unlock()
+		atomic.AddUint64(&gd.outOfOrder, 1)
+		return previous, true
+	}
+	*last = lut
+	gd.mu.Unlock()
+
+	if gd.maxGap > 0 && lut.Sub(previous) > gd.maxGap && (gd.marketOpen == nil || gd.marketOpen(lut)) {
+		atomic.AddUint64(&gd.gaps, 1)
+		return previous, true
+	}
+	return time.Time{}, false
+}
+
+func (gd *gapDetector) forget(key uint64) {
+	gd.mu.Lock()
+	defer gd.mu.Unlock()
+	delete(gd.lastSeen, key)
+}
+
+func (gd *gapDetector) clear() {
+	gd.mu.Lock()
+	defer gd.mu.Unlock()
+	gd.lastSeen = make(map[uint64]*time.Time)
+}
+
+func (tw *ODINMarketFeedClient) checkFeedGap(touchline TouchlineData) {
+	if tw.gapDetector == nil {
+		return
+	}
+
+	if lastSeen, gap := tw.gapDetector.check(touchline.MktSegID, touchline.Token, touchline.LUT); gap && tw.OnFeedGap != nil {
+		tw.invokeCallback("OnFeedGap", touchline.String, func() {
+			tw.OnFeedGap(touchline.MktSegID, touchline.Token, lastSeen, touchline.LUT)
+		})
+	}
+}
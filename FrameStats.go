@@ -0,0 +1,142 @@
+package ODINMarketFeed
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// frameMessageBounds are the upper bounds of the MessagesPerFrame buckets; the last bucket
+// counts the frames above the last bound
+var frameMessageBounds = [...]int{0, 1, 2, 4, 8, 16, 32}
+
+// frameStats measures the binary websocket frames received
+type frameStats struct {
+	mu       sync.Mutex
+	frames   uint64
+	bytes    uint64
+	min      int
+	max      int
+	perFrame [len(frameMessageBounds) + 1]uint64
+}
+
+// record counts a frame of size bytes that carried messages inner messages
+func (fs *frameStats) record(size, messages int) {
+	bucket := len(frameMessageBounds)
+	for i, bound := range frameMessageBounds {
+		if messages <= bound {
+			bucket = i
+			break
+		}
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.frames == 0 || size < fs.min {
+		fs.min = size
+	}
+	if size > fs.max {
+		fs.max = size
+	}
+	fs.frames++
+	fs.bytes += uint64(size)
+	fs.perFrame[bucket]++
+}
+
+// fill copies the frame statistics into stats
+func (fs *frameStats) fill(stats *Stats) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	stats.MinFrameSize = fs.min
+	stats.MaxFrameSize = fs.max
+	if fs.frames > 0 {
+		stats.AvgFrameSize = float64(fs.bytes) / float64(fs.frames)
+	}
+	stats.MessagesPerFrame = fs.perFrame
+}
+
+// reset zeros the frame statistics
+func (fs *frameStats) reset() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.frames, fs.bytes, fs.min, fs.max = 0, 0, 0, 0
+	fs.perFrame = [len(frameMessageBounds) + 1]uint64{}
+}
+
+// byteCounts returns the outer frame payload bytes decompressed and the bytes they
+// produced
+func (fh *FragmentationHandler) byteCounts() (compressed, decompressed uint64) {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	return fh.compressedIn, fh.decompressedOut
+}
+
+// keepAliveCount returns the number of keep-alive frames received
+func (fh *FragmentationHandler) keepAliveCount() uint64 {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	return fh.keepAlives
+}
+
+// resyncByteCount returns the number of bytes skipped while searching for an outer header
+func (fh *FragmentationHandler) resyncByteCount() uint64 {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	return fh.resyncBytes
+}
+
+// padByteCount returns the number of padding bytes skipped between inner messages
+func (fh *FragmentationHandler) padByteCount() uint64 {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	return fh.padBytes
+}
+
+// resetCounts zeros the decompression byte counts, the keep-alive count and the resync and
+// padding byte counts
+func (fh *FragmentationHandler) resetCounts() {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	fh.compressedIn, fh.decompressedOut, fh.keepAlives, fh.resyncBytes, fh.padBytes = 0, 0, 0, 0, 0
+}
+
+// ResetStats zeros the cumulative counters of Stats, for reporting per interval. The queue
+// depths, Goroutines, LastMessageAt, Reconnects and the server clock estimate are not
+// counters and are left as they are.
+func (tw *ODINMarketFeedClient) ResetStats() {
+	for _, counter := range []*uint64{
+		&tw.stats.binaryFrames,
+		&tw.stats.textFrames,
+		&tw.stats.otherFrames,
+		&tw.stats.heartbeatsAnswered,
+		&tw.stats.discards,
+		&tw.stats.skippedMessages,
+		&tw.stats.cacheEvictions,
+		&tw.stats.filteredMessages,
+		&tw.stats.duplicateTicks,
+		&tw.stats.published,
+		&tw.stats.publishDrops,
+		&tw.stats.publishErrors,
+		&tw.memory.dropped,
+		&tw.events.dropped,
+		&tw.sessions.dropped,
+	} {
+		atomic.StoreUint64(counter, 0)
+	}
+	if tw.gatewayThrottle != nil {
+		atomic.StoreUint64(&tw.gatewayThrottle.episodes, 0)
+	}
+
+	tw.unknownCodes.mu.Lock()
+	tw.unknownCodes.counts = nil
+	tw.unknownCodes.mu.Unlock()
+
+	tw.sendFailures.mu.Lock()
+	tw.sendFailures.counts = nil
+	tw.sendFailures.mu.Unlock()
+
+	tw.frames.reset()
+	tw.fragHandler.resetCounts()
+}
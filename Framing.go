@@ -0,0 +1,138 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Outer and inner frames share one layout:
+//
+//	byte 0     flag, the raw byte value FrameCompressed (5) or FrameUncompressed (2), not an ASCII digit
+//	bytes 1-5  payload length as 5 zero padded ASCII decimal digits
+//	bytes 6-   payload
+//
+// Outer frames are the websocket payloads; their payload is a zlib stream holding one or more
+// inner frames back to back. EncodeFrame and DecodeFrame accept only the two flag values. The
+// defragmenter decodes inner frames with the same parser but does not check their flag, which
+// some gateway builds leave at other values; inner payloads are never compressed again.
+const (
+	FrameCompressed   byte = 5
+	FrameUncompressed byte = 2
+
+	// FrameHeaderSize is the size of the flag byte and the length digits
+	FrameHeaderSize = 6
+	// MaxFramePayload is the largest payload the 5 length digits can describe
+	MaxFramePayload = 99999
+
+	// DefaultMaxDecompressedSize is the default limit on the decompressed payload of an
+	// outer frame for a Decompressor whose output cannot be read incrementally: 16 inner
+	// frames of the largest length an inner header can declare. The built-in decompressors
+	// derive the limit of each frame from its inner headers instead.
+	DefaultMaxDecompressedSize = 16 * (FrameHeaderSize + MaxFramePayload)
+)
+
+var (
+	// ErrFrameTooLarge is returned when a payload does not fit the 5 length digits
+	ErrFrameTooLarge = errors.New("frame payload exceeds 99999 bytes")
+	// ErrShortFrame is returned when the data ends before the header or the declared payload
+	ErrShortFrame = errors.New("frame is incomplete")
+	// ErrInvalidFrameHeader is returned when the flag or the length digits are invalid
+	ErrInvalidFrameHeader = errors.New("invalid frame header")
+	// ErrDecompressedTooLarge is returned by UncompressLimited when the payload decompresses
+	// to more than the limit
+	ErrDecompressedTooLarge = errors.New("decompressed payload exceeds the size limit")
+)
+
+// Frame is a decoded outer or inner frame
+type Frame struct {
+	Flag    byte
+	Payload []byte
+}
+
+// Compressed reports whether the payload is zlib compressed
+func (f Frame) Compressed() bool {
+	return f.Flag == FrameCompressed
+}
+
+// EncodeFrame returns the header followed by the payload
+func EncodeFrame(frame Frame) ([]byte, error) {
+	if frame.Flag != FrameCompressed && frame.Flag != FrameUncompressed {
+		return nil, fmt.Errorf("%w: flag %d", ErrInvalidFrameHeader, frame.Flag)
+	}
+	if len(frame.Payload) > MaxFramePayload {
+		return nil, fmt.Errorf("%w: %d bytes", ErrFrameTooLarge, len(frame.Payload))
+	}
+
+	result := make([]byte, FrameHeaderSize, FrameHeaderSize+len(frame.Payload))
+	result[0] = frame.Flag
+	length := len(frame.Payload)
+	for i := FrameHeaderSize - 1; i >= 1; i-- {
+		result[i] = byte('0' + length%10)
+		length /= 10
+	}
+	return append(result, frame.Payload...), nil
+}
+
+// DecodeFrame decodes the frame at the start of data and returns it with the number of bytes
+// it occupies. The payload aliases data.
+func DecodeFrame(data []byte) (Frame, int, error) {
+	frame, size, err := decodeFrame(data, false)
+	if err == ErrInvalidFrameHeader {
+		if flag := data[0]; flag != FrameCompressed && flag != FrameUncompressed {
+			return Frame{}, 0, fmt.Errorf("%w: flag %d", err, flag)
+		}
+		return Frame{}, 0, fmt.Errorf("%w: length %q", err, data[1:FrameHeaderSize])
+	}
+	return frame, size, err
+}
+
+// decodeFrame is DecodeFrame without the details of an invalid header, so that the resync
+// path does not allocate; with anyFlag set any flag byte is accepted, as for inner frames
+func decodeFrame(data []byte, anyFlag bool) (Frame, int, error) {
+	flag, length, err := parseFrameHeader(data, anyFlag)
+	if err != nil {
+		return Frame{}, 0, err
+	}
+
+	end := FrameHeaderSize + length
+	if len(data) < end {
+		return Frame{}, 0, ErrShortFrame
+	}
+	return Frame{Flag: flag, Payload: data[FrameHeaderSize:end]}, end, nil
+}
+
+// parseFrameHeader returns the flag and payload length of the header at the start of data
+func parseFrameHeader(data []byte, anyFlag bool) (byte, int, error) {
+	if len(data) < FrameHeaderSize {
+		return 0, 0, ErrShortFrame
+	}
+
+	flag := data[0]
+	if !anyFlag && flag != FrameCompressed && flag != FrameUncompressed {
+		return 0, 0, ErrInvalidFrameHeader
+	}
+
+	length, ok := ParseFrameLength(data[1:FrameHeaderSize])
+	if !ok {
+		return 0, 0, ErrInvalidFrameHeader
+	}
+	return flag, length, nil
+}
+
+// ParseFrameLength parses the 5 length digits of an outer or inner frame header without
+// allocating. It reports false unless digits holds exactly 5 ASCII digits; leading zeros are
+// allowed and "00000" parses as 0.
+func ParseFrameLength(digits []byte) (int, bool) {
+	if len(digits) != FrameHeaderSize-1 {
+		return 0, false
+	}
+
+	length := 0
+	for _, ch := range digits {
+		if ch < '0' || ch > '9' {
+			return 0, false
+		}
+		length = length*10 + int(ch-'0')
+	}
+	return length, true
+}
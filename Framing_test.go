@@ -0,0 +1,285 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"math/rand"
+	"runtime"
+	"strconv"
+	"testing"
+	"testing/quick"
+)
+
+// innerFrame returns msg behind an inner frame header
+func innerFrame(msg []byte) []byte {
+	frame, err := EncodeFrame(Frame{Flag: FrameUncompressed, Payload: msg})
+	if err != nil {
+		panic(err)
+	}
+	return frame
+}
+
+// frameOf returns an outer frame holding msgs as inner frames, as the gateway sends them
+func frameOf(msgs ...[]byte) []byte {
+	var inner []byte
+	for _, msg := range msgs {
+		inner = append(inner, innerFrame(msg)...)
+	}
+	compressed, err := (&ZLIBCompressor{}).Compress(inner)
+	if err != nil {
+		panic(err)
+	}
+	frame, err := EncodeFrame(Frame{Flag: FrameCompressed, Payload: compressed})
+	if err != nil {
+		panic(err)
+	}
+	return frame
+}
+
+func roundTrip(t *testing.T, flag byte, payload []byte) {
+	t.Helper()
+
+	encoded, err := EncodeFrame(Frame{Flag: flag, Payload: payload})
+	if err != nil {
+		t.Fatalf("EncodeFrame(%d bytes): %v", len(payload), err)
+	}
+	frame, size, err := DecodeFrame(encoded)
+	if err != nil {
+		t.Fatalf("DecodeFrame(%d bytes): %v", len(payload), err)
+	}
+	if size != len(encoded) || frame.Flag != flag || !bytes.Equal(frame.Payload, payload) {
+		t.Fatalf("round trip of %d bytes with flag %d returned flag %d, %d bytes, size %d",
+			len(payload), flag, frame.Flag, len(frame.Payload), size)
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	payload := make([]byte, MaxFramePayload)
+	rand.New(rand.NewSource(1)).Read(payload)
+
+	for _, flag := range []byte{FrameCompressed, FrameUncompressed} {
+		// Every size around the digit boundaries, and random sizes in between
+		for _, base := range []int{0, 10, 100, 1000, 10000, MaxFramePayload - 8} {
+			for n := base; n < base+9 && n <= MaxFramePayload; n++ {
+				roundTrip(t, flag, payload[:n])
+			}
+		}
+
+		check := func(n uint32) bool {
+			roundTrip(t, flag, payload[:int(n%(MaxFramePayload+1))])
+			return true
+		}
+		if err := quick.Check(check, &quick.Config{MaxCount: 500}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestEncodeFrameRejects(t *testing.T) {
+	if _, err := EncodeFrame(Frame{Flag: FrameCompressed, Payload: make([]byte, MaxFramePayload+1)}); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("oversized payload: got %v, want ErrFrameTooLarge", err)
+	}
+	if _, err := EncodeFrame(Frame{Flag: '5'}); !errors.Is(err, ErrInvalidFrameHeader) {
+		t.Errorf("ASCII flag: got %v, want ErrInvalidFrameHeader", err)
+	}
+}
+
+func TestDecodeFrameErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want error
+	}{
+		{"empty", "", ErrShortFrame},
+		{"short header", "\x0500", ErrShortFrame},
+		{"short payload", "\x0500005abc", ErrShortFrame},
+		{"ASCII flag", "500003abc", ErrInvalidFrameHeader},
+		{"length not digits", "\x050a003abc", ErrInvalidFrameHeader},
+		{"signed length", "\x05+0003abc", ErrInvalidFrameHeader},
+	}
+	for _, tt := range tests {
+		if _, _, err := DecodeFrame([]byte(tt.data)); !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestDefragmentAcceptsAnyInnerFlag(t *testing.T) {
+	inner := innerFrame([]byte("63=FT3.0|64=127"))
+	inner[0] = '#'
+	compressed, _ := (&ZLIBCompressor{}).Compress(inner)
+	outer, _ := EncodeFrame(Frame{Flag: FrameCompressed, Payload: compressed})
+
+	msgs, err := NewFragmentationHandler().Defragment(outer)
+	if err != nil || len(msgs) != 1 || string(msgs[0]) != "63=FT3.0|64=127" {
+		t.Fatalf("got %q, %v", msgs, err)
+	}
+}
+
+func TestDefragmentSplitAndResync(t *testing.T) {
+	first := frameOf([]byte("63=FT3.0|64=127|1=1"), []byte("63=FT3.0|64=127|1=2"))
+	second := frameOf([]byte("63=FT3.0|64=127|1=3"))
+
+	stream := append([]byte("garbage"), first...)
+	stream = append(stream, []byte("\x0500000")...) // keep-alive
+	stream = append(stream, second...)
+
+	fh := NewFragmentationHandler()
+	var got []string
+	for _, b := range stream {
+		msgs, err := fh.Defragment([]byte{b})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, msg := range msgs {
+			got = append(got, string(msg))
+		}
+	}
+
+	want := []string{"63=FT3.0|64=127|1=1", "63=FT3.0|64=127|1=2", "63=FT3.0|64=127|1=3"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %q, want %q", got, want)
+		}
+	}
+	if fh.keepAlives != 1 {
+		t.Errorf("keep-alives = %d, want 1", fh.keepAlives)
+	}
+}
+
+func TestParseFrameLength(t *testing.T) {
+	tests := []struct {
+		digits string
+		want   int
+		ok     bool
+	}{
+		{"00000", 0, true},
+		{"00007", 7, true},
+		{"01234", 1234, true},
+		{"99999", 99999, true},
+		{"0000", 0, false},
+		{"000000", 0, false},
+		{"", 0, false},
+		{"0001a", 0, false},
+		{" 0001", 0, false},
+		{"-0001", 0, false},
+		{"0001\x00", 0, false},
+		{"00\xd9\xa30", 0, false}, // Arabic-Indic digit three
+		{"\xff\xff\xff\xff\xff", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseFrameLength([]byte(tt.digits))
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseFrameLength(%q) = %d, %v; want %d, %v", tt.digits, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseFrameLengthAllocations(t *testing.T) {
+	digits := []byte("01234")
+	if allocs := testing.AllocsPerRun(100, func() { ParseFrameLength(digits) }); allocs != 0 {
+		t.Errorf("ParseFrameLength allocates %v times", allocs)
+	}
+}
+
+// BenchmarkParseFrameLength and BenchmarkParseFrameLengthAtoi compare the header parser with
+// the string conversion it replaced
+func BenchmarkParseFrameLength(b *testing.B) {
+	digits := []byte("01234")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, ok := ParseFrameLength(digits); !ok {
+			b.Fatal("rejected")
+		}
+	}
+}
+
+func BenchmarkParseFrameLengthAtoi(b *testing.B) {
+	digits := []byte("01234")
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := strconv.Atoi(string(digits)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// allocated returns the bytes allocated by fn
+func allocated(fn func()) uint64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	fn()
+	runtime.ReadMemStats(&after)
+	return after.TotalAlloc - before.TotalAlloc
+}
+
+// bomb returns an outer frame whose payload is a valid inner frame followed by 32 MB of
+// zero bytes, which compress to about 64 KB
+func bomb(t *testing.T) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	writer := zlib.NewWriter(&buf)
+	writer.Write(innerFrame([]byte("63=FT3.0|64=127|1=1")))
+	zeros := make([]byte, 1<<20)
+	for i := 0; i < 32; i++ {
+		writer.Write(zeros)
+	}
+	writer.Close()
+	frame, err := EncodeFrame(Frame{Flag: FrameCompressed, Payload: buf.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return frame
+}
+
+func TestUncompressLimitedRejectsHighRatioPayload(t *testing.T) {
+	frame, _, _ := DecodeFrame(bomb(t))
+	var err error
+	n := allocated(func() {
+		_, err = (&ZLIBCompressor{}).UncompressLimited(frame.Payload, 1<<20)
+	})
+	if !errors.Is(err, ErrDecompressedTooLarge) {
+		t.Fatalf("UncompressLimited = %v, want ErrDecompressedTooLarge", err)
+	}
+	if !raceEnabled && n > 8<<20 {
+		t.Errorf("UncompressLimited allocated %d bytes rejecting a 32 MB payload at 1 MB", n)
+	}
+}
+
+func TestDefragmentDerivesLimitFromInnerHeaders(t *testing.T) {
+	outer := bomb(t)
+	fh := NewFragmentationHandler()
+	fh.reportDiscards = true
+
+	var msgs [][]byte
+	n := allocated(func() { msgs, _ = fh.Defragment(outer) })
+	if len(msgs) != 0 {
+		t.Errorf("Defragment returned %d messages from an oversized frame", len(msgs))
+	}
+	discards := fh.takeDiscards()
+	if len(discards) != 1 || !errors.Is(discards[0].Err, ErrDecompressedTooLarge) {
+		t.Fatalf("discards %+v, want one ErrDecompressedTooLarge", discards)
+	}
+	if n > 1<<20 {
+		t.Errorf("Defragment allocated %d bytes rejecting a frame padded past its inner headers", n)
+	}
+}
+
+func TestDefragmentAcceptsLargeFrameOfValidInnerFrames(t *testing.T) {
+	payload := bytes.Repeat([]byte("a"), MaxFramePayload)
+	msgs := make([][]byte, 20)
+	for i := range msgs {
+		msgs[i] = payload
+	}
+	got, err := NewFragmentationHandler().Defragment(frameOf(msgs...))
+	if err != nil || len(got) != len(msgs) {
+		t.Fatalf("got %d messages and %v from %d bytes of inner frames, want %d",
+			len(got), err, len(msgs)*(FrameHeaderSize+MaxFramePayload), len(msgs))
+	}
+}
@@ -0,0 +1,127 @@
+package ODINMarketFeed
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// defaultThrottleCooldown is the pause after a throttle notice that carries no cooldown
+const defaultThrottleCooldown = 5 * time.Second
+
+// Throttled is emitted and passed to OnThrottled when the gateway throttles requests. The
+// bulk lane of the writer is paused for Cooldown; Held subscription requests were waiting
+// when the pause began. Request is the request the notice rejected, which is sent again
+// after the cooldown as retry Attempt unless it was Dropped for having used up its retries.
+// With WithRequestCorrelation a notice echoing the tag rejects the request with that ID;
+// otherwise it rejects the last subscription request written before it.
+type Throttled struct {
+	EventSource
+	Notice   Notice
+	Cooldown time.Duration
+	Held     int
+	Request  string
+	Attempt  int
+	Dropped  bool
+	Episode  uint64
+}
+
+func (Throttled) isEvent() {}
+
+// gatewayThrottle holds the settings of WithThrottleNotice
+type gatewayThrottle struct {
+	code        int
+	cooldownTag int
+	cooldown    time.Duration
+	maxRetries  int
+	episodes    uint64
+}
+
+// WithThrottleNotice treats messages with the 64= code as throttle notices of the gateway.
+// On a notice the client stops writing subscription requests for the cooldown given in
+// seconds by the cooldownTag of the notice, or for cooldown (default 5s) when the notice
+// carries none, then sends the rejected request again, at most maxRetries times. Login,
+// heartbeat and pause/resume requests keep flowing during the cooldown. The code is
+// registered as a SeverityWarning notice unless WithNoticeCode classifies it.
+func WithThrottleNotice(code, cooldownTag int, cooldown time.Duration, maxRetries int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		if cooldown <= 0 {
+			cooldown = defaultThrottleCooldown
+		}
+		if maxRetries < 0 {
+			maxRetries = 0
+		}
+		tw.gatewayThrottle = &gatewayThrottle{code: code, cooldownTag: cooldownTag, cooldown: cooldown, maxRetries: maxRetries}
+		if _, ok := tw.noticeCodes[code]; !ok {
+			WithNoticeCode(code, SeverityWarning)(tw)
+		}
+	}
+}
+
+// isThrottleNotice reports whether the message is a throttle notice
+func (tw *ODINMarketFeedClient) isThrottleNotice(msg ParsedMessage) (Notice, bool) {
+	if tw.gatewayThrottle == nil || msg.Code != tw.gatewayThrottle.code {
+		return Notice{}, false
+	}
+	return tw.parseNotice(msg)
+}
+
+// throttleReceived pauses the bulk lane for the cooldown of the notice and queues the
+// rejected request to be written first once it has elapsed
+func (tw *ODINMarketFeedClient) throttleReceived(notice Notice, text, correlationID string) {
+	gt := tw.gatewayThrottle
+	cooldown := gt.cooldown
+	if gt.cooldownTag != 0 {
+		if value, ok := fieldValue(text, gt.cooldownTag, tw.decoder.pairDelimiter()); ok {
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+				cooldown = time.Duration(seconds * float64(time.Second))
+			}
+		}
+	}
+
+	tw.mu.Lock()
+	queue := tw.sendQueue
+	tw.mu.Unlock()
+	if queue == nil {
+		return
+	}
+
+	event := Throttled{EventSource: tw.source(), Notice: notice, Cooldown: cooldown, Episode: atomic.AddUint64(&gt.episodes, 1)}
+	var retry *writeRequest
+	var match func(writeRequest) bool
+	if correlationID != "" {
+		match = func(request writeRequest) bool {
+			id, _ := fieldValue(request.display, tw.correlation.tag, tw.decoder.pairDelimiter())
+			return id == correlationID
+		}
+	}
+	if rejected, ok := queue.takeRecentBulk(match); ok {
+		event.Request = rejected.display
+		if rejected.attempt < gt.maxRetries {
+			rejected.attempt++
+			rejected.done = make(chan error, 1)
+			retry = &rejected
+			event.Attempt = rejected.attempt
+		} else {
+			event.Dropped = true
+		}
+	}
+	event.Held = queue.pauseBulk(cooldown, retry)
+
+	tw.emit(event)
+	if tw.OnThrottled != nil {
+		tw.invokeCallback("OnThrottled", notice.String, func() { tw.OnThrottled(event) })
+	}
+	if event.Dropped {
+		tw.reportError(fmt.Sprintf("Request dropped after %d throttle retries: %s", gt.maxRetries, event.Request))
+	}
+}
+
+// throttleEpisodes returns the number of throttle notices received
+func (tw *ODINMarketFeedClient) throttleEpisodes() uint64 {
+	if tw.gatewayThrottle == nil {
+		return 0
+	}
+	return atomic.LoadUint64(&tw.gatewayThrottle.episodes)
+}
@@ -0,0 +1,300 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrGroupExists is returned by CreateGroup when a group of that name exists
+var ErrGroupExists = errors.New("subscription group already exists")
+
+// SubscriptionGroup is a named set of subscriptions managed together. An instrument may
+// belong to several groups; it stays subscribed until it is removed from the last of them,
+// and beyond that when it was also subscribed directly. Membership is kept through
+// reconnects and saved with WithSubscriptionFile.
+type SubscriptionGroup struct {
+	name    string
+	client  *ODINMarketFeedClient
+	members map[subscriptionKey]struct{} // guarded by client.subMu
+	dropped bool                         // guarded by client.subMu
+}
+
+// CreateGroup creates an empty subscription group
+func (tw *ODINMarketFeedClient) CreateGroup(name string) (*SubscriptionGroup, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("group name cannot be empty")
+	}
+
+	tw.subMu.Lock()
+	defer tw.subMu.Unlock()
+
+	if _, ok := tw.groups[name]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrGroupExists, name)
+	}
+	return tw.groupLocked(name), nil
+}
+
+// Group returns the subscription group with the name
+func (tw *ODINMarketFeedClient) Group(name string) (*SubscriptionGroup, bool) {
+	tw.subMu.Lock()
+	defer tw.subMu.Unlock()
+
+	group, ok := tw.groups[strings.TrimSpace(name)]
+	return group, ok
+}
+
+// Groups returns the subscription groups ordered by name
+func (tw *ODINMarketFeedClient) Groups() []*SubscriptionGroup {
+	tw.subMu.Lock()
+	defer tw.subMu.Unlock()
+
+	result := make([]*SubscriptionGroup, 0, len(tw.groups))
+	for _, group := range tw.groups {
+		result = append(result, group)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result
+}
+
+// groupLocked returns the group with the name, creating it if needed. subMu must be held.
+func (tw *ODINMarketFeedClient) groupLocked(name string) *SubscriptionGroup {
+	if group, ok := tw.groups[name]; ok {
+		return group
+	}
+	if tw.groups == nil {
+		tw.groups = make(map[string]*SubscriptionGroup)
+	}
+	group := &SubscriptionGroup{name: name, client: tw, members: make(map[subscriptionKey]struct{})}
+	tw.groups[name] = group
+	return group
+}
+
+// Name returns the name of the group
+func (g *SubscriptionGroup) Name() string {
+	return g.name
+}
+
+// Members returns the subscriptions of the group, in the order of Subscriptions. Members
+// whose request is still queued for the connection have no ResponseType yet.
+func (g *SubscriptionGroup) Members() []Subscription {
+	tw := g.client
+	tw.subMu.Lock()
+	defer tw.subMu.Unlock()
+
+	result := make([]Subscription, 0, len(g.members))
+	for key := range g.members {
+		sub, ok := tw.subscriptions[key]
+		if !ok {
+			sub = Subscription{Type: key.subType, Instrument: Instrument{MarketSegmentID: key.marketSegmentID, Token: key.token}}
+		}
+		result = append(result, sub)
+	}
+	sortSubscriptions(result)
+	return result
+}
+
+// Add subscribes the instruments with the subscription type and adds them to the group.
+// opts applies to touchline subscriptions. Instruments that are already subscribed, through
+// another group or directly, are only added to the group. When the request is sent only in
+// part, the instruments sent are added and the *BatchError is returned.
+func (g *SubscriptionGroup) Add(instruments []Instrument, subType SubscriptionType, opts TouchlineOptions) error {
+	if len(instruments) == 0 {
+		return errors.New("instrument list cannot be empty")
+	}
+	for _, instrument := range instruments {
+		if instrument.MarketSegmentID <= 0 || instrument.Token <= 0 {
+			return fmt.Errorf("invalid instrument: '%s'", instrument)
+		}
+	}
+
+	tw := g.client
+	tw.subMu.Lock()
+	if g.dropped {
+		tw.subMu.Unlock()
+		return fmt.Errorf("subscription group %s was dropped", g.name)
+	}
+	pending := make(map[subscriptionKey]Instrument)
+	var request []Instrument
+	for _, instrument := range instruments {
+		key := subscriptionKey{subType: subType, marketSegmentID: instrument.MarketSegmentID, token: instrument.Token}
+		_, subscribed := tw.subscriptions[key]
+		switch {
+		case subscribed && !tw.inGroupLocked(key, nil):
+			// Subscribed directly before any group held it
+			tw.holdDirectlyLocked(key)
+		case !subscribed && !tw.inGroupLocked(key, nil):
+			if _, ok := pending[key]; !ok {
+				pending[key] = instrument
+				request = append(request, instrument)
+			}
+		}
+	}
+	tw.subMu.Unlock()
+
+	var err error
+	if len(request) > 0 {
+		err = tw.sendGroupRequest(subType, request, opts, true)
+		var batchErr *BatchError
+		if err != nil && !errors.As(err, &batchErr) {
+			return err
+		}
+		if batchErr != nil {
+			for _, instrument := range batchErr.Result.Sent {
+				delete(pending, subscriptionKey{subType: subType, marketSegmentID: instrument.MarketSegmentID, token: instrument.Token})
+			}
+		} else {
+			pending = nil
+		}
+	}
+
+	tw.subMu.Lock()
+	for _, instrument := range instruments {
+		key := subscriptionKey{subType: subType, marketSegmentID: instrument.MarketSegmentID, token: instrument.Token}
+		if _, failed := pending[key]; !failed {
+			g.members[key] = struct{}{}
+		}
+	}
+	tw.subMu.Unlock()
+
+	tw.subscriptionsChanged()
+	return err
+}
+
+// Remove removes the instruments from the group and unsubscribes those that no other group
+// holds
+func (g *SubscriptionGroup) Remove(instruments []Instrument, subType SubscriptionType) error {
+	keys := make([]subscriptionKey, len(instruments))
+	for i, instrument := range instruments {
+		keys[i] = subscriptionKey{subType: subType, marketSegmentID: instrument.MarketSegmentID, token: instrument.Token}
+	}
+	return g.remove(keys)
+}
+
+// Drop removes every member from the group, unsubscribes those that no other group holds
+// and deletes the group
+func (g *SubscriptionGroup) Drop() error {
+	tw := g.client
+	tw.subMu.Lock()
+	keys := make([]subscriptionKey, 0, len(g.members))
+	for key := range g.members {
+		keys = append(keys, key)
+	}
+	g.dropped = true
+	delete(tw.groups, g.name)
+	tw.subMu.Unlock()
+
+	return g.remove(keys)
+}
+
+// remove drops the keys from the group and unsubscribes the ones left without a group,
+// unless they were subscribed directly
+func (g *SubscriptionGroup) remove(keys []subscriptionKey) error {
+	tw := g.client
+	release := make(map[SubscriptionType][]Instrument)
+
+	tw.subMu.Lock()
+	for _, key := range keys {
+		if _, ok := g.members[key]; !ok {
+			continue
+		}
+		delete(g.members, key)
+		if _, direct := tw.direct[key]; !direct && !tw.inGroupLocked(key, g) {
+			release[key.subType] = append(release[key.subType], Instrument{MarketSegmentID: key.marketSegmentID, Token: key.token})
+		}
+	}
+	tw.subMu.Unlock()
+
+	var errs []error
+	for _, subType := range []SubscriptionType{SubscriptionTouchline, SubscriptionLTPTouchline, SubscriptionBestFive} {
+		if len(release[subType]) == 0 {
+			continue
+		}
+		if err := tw.sendGroupRequest(subType, release[subType], TouchlineOptions{}, false); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", subType, err))
+		}
+	}
+
+	tw.subscriptionsChanged()
+	return errors.Join(errs...)
+}
+
+// inGroupLocked reports whether a group other than except holds the key. subMu must be held.
+func (tw *ODINMarketFeedClient) inGroupLocked(key subscriptionKey, except *SubscriptionGroup) bool {
+	for _, group := range tw.groups {
+		if group == except {
+			continue
+		}
+		if _, ok := group.members[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// holdDirectlyLocked records that the application subscribed the key itself, so that no
+// group unsubscribes it. subMu must be held.
+func (tw *ODINMarketFeedClient) holdDirectlyLocked(key subscriptionKey) {
+	if tw.direct == nil {
+		tw.direct = make(map[subscriptionKey]struct{})
+	}
+	tw.direct[key] = struct{}{}
+}
+
+// leaveGroupsLocked removes the key from every group, e.g. after a direct unsubscribe.
+// subMu must be held.
+func (tw *ODINMarketFeedClient) leaveGroupsLocked(key subscriptionKey) {
+	for _, group := range tw.groups {
+		delete(group.members, key)
+	}
+}
+
+// sendGroupRequest subscribes or unsubscribes the instruments with the subscription type
+func (tw *ODINMarketFeedClient) sendGroupRequest(subType SubscriptionType, instruments []Instrument, opts TouchlineOptions, subscribe bool) error {
+	if subType == SubscriptionBestFive {
+		return tw.bestFiveBatch(instruments, subscribe, false)
+	}
+
+	tokenList := make([]string, len(instruments))
+	for i, instrument := range instruments {
+		tokenList[i] = instrument.String()
+	}
+
+	switch {
+	case subType == SubscriptionTouchline && subscribe:
+		return tw.subscribeTouchline(tokenList, opts, nil, false)
+	case subType == SubscriptionTouchline:
+		return tw.UnsubscribeTouchline(tokenList)
+	case subType == SubscriptionLTPTouchline && subscribe:
+		return tw.subscribeLTPTouchline(tokenList, false)
+	case subType == SubscriptionLTPTouchline:
+		return tw.UnsubscribeLTPTouchline(tokenList)
+	default:
+		return fmt.Errorf("unknown subscription type %s", subType)
+	}
+}
+
+// joinGroupsLocked adds the keys to the named groups, creating missing groups. subMu must
+// be held.
+func (tw *ODINMarketFeedClient) joinGroupsLocked(groups map[subscriptionKey][]string) {
+	for key, names := range groups {
+		for _, name := range names {
+			tw.groupLocked(strings.TrimSpace(name)).members[key] = struct{}{}
+		}
+	}
+}
+
+// groupNamesLocked returns the names of the groups holding the key in name order. subMu
+// must be held.
+func (tw *ODINMarketFeedClient) groupNamesLocked(key subscriptionKey) []string {
+	var names []string
+	for name, group := range tw.groups {
+		if _, ok := group.members[key]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,122 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+// subscribed reports whether the registry of tw holds the subscription
+func subscribed(tw *ODINMarketFeedClient, subType SubscriptionType, instrument Instrument) bool {
+	return tw.isSubscribed(instrument.MarketSegmentID, instrument.Token, subType)
+}
+
+func TestGroupKeepsDirectSubscriptions(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	before := Instrument{MarketSegmentID: 1, Token: 22}
+	after := Instrument{MarketSegmentID: 1, Token: 23}
+	owned := Instrument{MarketSegmentID: 1, Token: 24}
+
+	if err := tw.SubscribeTouchlineWithOptions([]string{before.String()}, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	group, _ := tw.CreateGroup("watch")
+	if err := group.Add([]Instrument{before, after, owned}, SubscriptionTouchline, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.SubscribeTouchlineWithOptions([]string{after.String()}, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := group.Drop(); err != nil {
+		t.Fatal(err)
+	}
+	if !subscribed(tw, SubscriptionTouchline, before) {
+		t.Error("instrument subscribed directly before Add was unsubscribed by Drop")
+	}
+	if !subscribed(tw, SubscriptionTouchline, after) {
+		t.Error("instrument subscribed directly after Add was unsubscribed by Drop")
+	}
+	if subscribed(tw, SubscriptionTouchline, owned) {
+		t.Error("instrument subscribed only by the group is still subscribed after Drop")
+	}
+}
+
+func TestGroupKeepsDirectSubscriptionsAcrossResubscribe(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	instrument := Instrument{MarketSegmentID: 1, Token: 22}
+	group, _ := tw.CreateGroup("watch")
+	if err := group.Add([]Instrument{instrument}, SubscriptionLTPTouchline, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.ResubscribeAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := group.Remove([]Instrument{instrument}, SubscriptionLTPTouchline); err != nil {
+		t.Fatal(err)
+	}
+	if subscribed(tw, SubscriptionLTPTouchline, instrument) {
+		t.Error("a resubscribe made the group's instrument a direct subscription")
+	}
+}
+
+func TestGroupAddRecordsPartialBatch(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	tw.SetPayloadTransform(func(msgType int, payload []byte) ([]byte, error) {
+		if msgType == msgCodeBestFive && bytes.Contains(payload, []byte("|7=23|")) {
+			return nil, errors.New("rejected")
+		}
+		return payload, nil
+	})
+
+	group, _ := tw.CreateGroup("depth")
+	err := group.Add([]Instrument{{MarketSegmentID: 1, Token: 22}, {MarketSegmentID: 1, Token: 23}}, SubscriptionBestFive, TouchlineOptions{})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Result.Sent) != 1 {
+		t.Fatalf("Add = %v, want a *BatchError with one instrument sent", err)
+	}
+
+	members := group.Members()
+	if len(members) != 1 || members[0].Instrument.Token != 22 {
+		t.Errorf("members %v, want only token 22", members)
+	}
+}
+
+func TestGroupDirectHoldIsSaved(t *testing.T) {
+	tw := newTestClient(WithPreConnectQueue(0))
+	instrument := Instrument{MarketSegmentID: 1, Token: 22}
+	err := tw.LoadSubscriptions(bytes.NewBufferString(`{"version":1,"subscriptions":[
+		{"type":"Touchline","segment_id":1,"token":22,"groups":["watch"],"direct":true}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var saved bytes.Buffer
+	if err := tw.SaveSubscriptions(&saved); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(saved.Bytes(), []byte(`"direct": true`)) {
+		t.Errorf("saved %s, want the direct hold", saved.String())
+	}
+
+	group, _ := tw.Group("watch")
+	if err := group.Drop(); err != nil {
+		t.Fatal(err)
+	}
+	if !subscribed(tw, SubscriptionTouchline, instrument) {
+		t.Error("loaded direct subscription was unsubscribed by Drop")
+	}
+}
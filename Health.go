@@ -0,0 +1,54 @@
+package ODINMarketFeed
+
+import (
+	"fmt"
+	"time"
+)
+
+// HealthPolicy defines the criteria checked by Healthy. Zero values disable a criterion.
+type HealthPolicy struct {
+	// MaxSilence is the maximum time since the last frame was received
+	MaxSilence time.Duration
+	// RequiredState is the connection state the client must be in, when RequireState is set
+	RequiredState ConnectionState
+	RequireState  bool
+	// MaxReconnects is the maximum number of reconnects allowed within ReconnectWindow
+	MaxReconnects   int
+	ReconnectWindow time.Duration
+	// MaxCallbackQueueDepth is the maximum number of deliveries waiting for the Ticks and
+	// Messages loops, see Stats.CallbackQueueDepth
+	MaxCallbackQueueDepth int
+}
+
+// Healthy evaluates the policy and returns a description of every failing criterion
+func (tw *ODINMarketFeedClient) Healthy(policy HealthPolicy) (bool, []string) {
+	var failures []string
+	now := time.Now()
+	stats := tw.Stats()
+
+	if policy.MaxSilence > 0 {
+		if stats.LastMessageAt.IsZero() {
+			failures = append(failures, "no message received yet")
+		} else if silence := now.Sub(stats.LastMessageAt); silence > policy.MaxSilence {
+			failures = append(failures, fmt.Sprintf("no message for %s (max %s)", silence.Round(time.Millisecond), policy.MaxSilence))
+		}
+	}
+
+	if policy.RequireState {
+		if state := tw.State(); state != policy.RequiredState {
+			failures = append(failures, fmt.Sprintf("connection state is %s (required %s)", state, policy.RequiredState))
+		}
+	}
+
+	if policy.MaxReconnects > 0 && policy.ReconnectWindow > 0 {
+		if reconnects := tw.reconnectsSince(now.Add(-policy.ReconnectWindow)); reconnects > policy.MaxReconnects {
+			failures = append(failures, fmt.Sprintf("%d reconnects in the last %s (max %d)", reconnects, policy.ReconnectWindow, policy.MaxReconnects))
+		}
+	}
+
+	if policy.MaxCallbackQueueDepth > 0 && stats.CallbackQueueDepth > policy.MaxCallbackQueueDepth {
+		failures = append(failures, fmt.Sprintf("callback queue depth is %d (max %d)", stats.CallbackQueueDepth, policy.MaxCallbackQueueDepth))
+	}
+
+	return len(failures) == 0, failures
+}
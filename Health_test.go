@@ -0,0 +1,24 @@
+package ODINMarketFeed
+
+import "testing"
+
+func TestCallbackQueueDepth(t *testing.T) {
+	tw := newTestClient()
+	ticks := newDeliverySink[TouchlineData](tw, 8, &tw.stats.iteratorDrops)
+	messages := newDeliverySink[ParsedMessage](tw, 8, &tw.stats.iteratorDrops)
+	tw.tickSink.Store(ticks)
+	tw.messageSink.Store(messages)
+	for i := 0; i < 3; i++ {
+		ticks.deliver(TouchlineData{})
+	}
+	messages.deliver(ParsedMessage{})
+
+	if depth := tw.Stats().CallbackQueueDepth; depth != 4 {
+		t.Fatalf("CallbackQueueDepth = %d, want 4", depth)
+	}
+	if healthy, failures := tw.Healthy(HealthPolicy{MaxCallbackQueueDepth: 3}); healthy {
+		t.Error("healthy with 4 deliveries waiting and a maximum of 3")
+	} else if len(failures) != 1 {
+		t.Errorf("failures %q, want the callback queue depth only", failures)
+	}
+}
@@ -0,0 +1,192 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// clockOffsetAlpha is the EWMA weight given to each new clock offset sample
+const clockOffsetAlpha = 0.2
+
+// WithHeartbeat sends a heartbeat request every interval while connected. The server's
+// heartbeat responses are used to estimate its clock offset (see Stats().ServerClockOffset).
+func WithHeartbeat(interval time.Duration) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.heartbeatInterval = interval
+	}
+}
+
+// ErrHeartbeatMissed is the cause of a connection closed by WithHeartbeatMissLimit
+var ErrHeartbeatMissed = errors.New("heartbeat not answered")
+
+// WithHeartbeatMissLimit closes the connection when limit heartbeats in a row have not been
+// answered by the time the next one is due, and reports it lost with ErrHeartbeatMissed. It
+// has no effect without WithHeartbeat.
+func WithHeartbeatMissLimit(limit int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.heartbeatMissLimit = limit
+	}
+}
+
+// WithClockSkewThreshold invokes OnClockSkew when the estimated server clock offset
+// exceeds threshold in either direction
+func WithClockSkewThreshold(threshold time.Duration) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.clock.threshold = threshold
+	}
+}
+
+// WithoutHeartbeatReply disables the automatic reply to heartbeat requests initiated by the
+// server, for applications that answer them from OnMessage
+func WithoutHeartbeatReply() Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.heartbeatReplyDisabled = true
+	}
+}
+
+// clockEstimator maintains an EWMA of the server clock offset
+type clockEstimator struct {
+	threshold time.Duration
+	offset    time.Duration
+	samples   uint64
+	skewed    bool
+	mu        sync.Mutex
+}
+
+// ServerTimeToLocal converts a server timestamp (such as a touchline LUT) to the local clock
+// by removing the estimated server clock offset
+func (tw *ODINMarketFeedClient) ServerTimeToLocal(t time.Time) time.Time {
+	tw.clock.mu.Lock()
+	defer tw.clock.mu.Unlock()
+	return t.Add(-tw.clock.offset)
+}
+
+// startHeartbeat sends heartbeat requests on conn until it is replaced or its receive loop,
+// which closes done, has ended
+func (tw *ODINMarketFeedClient) startHeartbeat(conn *websocket.Conn, done <-chan struct{}) {
+	if tw.heartbeatInterval <= 0 {
+		return
+	}
+
+	tw.routines.spawn(func() {
+		ticker := time.NewTicker(tw.heartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+			case <-done:
+				return
+			}
+
+			tw.mu.Lock()
+			current := tw.conn == conn && !tw.isDisposed
+			tw.mu.Unlock()
+			if !current {
+				return
+			}
+
+			if err := tw.heartbeatDue(conn); err != nil {
+				return
+			}
+			if err := tw.SendMessage(tw.heartbeatMessage()); err != nil {
+				tw.reportAsyncError(StageHeartbeat, err)
+			}
+		}
+	})
+}
+
+// heartbeatDue marks a heartbeat about to be sent on conn as outstanding. When the previous
+// one is still outstanding it counts a miss, and once WithHeartbeatMissLimit misses are
+// counted it closes conn and returns the error the connection is reported lost with.
+func (tw *ODINMarketFeedClient) heartbeatDue(conn *websocket.Conn) error {
+	if atomic.SwapInt32(&tw.heartbeatPending, 1) == 0 {
+		atomic.StoreInt32(&tw.heartbeatMisses, 0)
+		return nil
+	}
+	misses := atomic.AddInt32(&tw.heartbeatMisses, 1)
+	if tw.heartbeatMissLimit <= 0 || int(misses) < tw.heartbeatMissLimit {
+		return nil
+	}
+
+	err := fmt.Errorf("%w: %d heartbeats in a row", ErrHeartbeatMissed, misses)
+	tw.abandonConnection(conn, err)
+	return err
+}
+
+func (tw *ODINMarketFeedClient) heartbeatMessage() string {
+	return tw.requestHeader(msgCodeHeartbeat) + fmt.Sprintf("67=%s", tw.userID)
+}
+
+// heartbeatReceived handles an inbound heartbeat. When a heartbeat of ours is outstanding it
+// is taken as the response; otherwise it is a server initiated request, which is answered
+// unless disabled. Both carry the server time used for the clock offset estimate.
+func (tw *ODINMarketFeedClient) heartbeatReceived(message string, receivedAt time.Time) {
+	if !atomic.CompareAndSwapInt32(&tw.heartbeatPending, 1, 0) && !tw.heartbeatReplyDisabled {
+		if err := tw.SendMessage(tw.heartbeatMessage()); err != nil {
+			tw.reportAsyncError(StageHeartbeatReply, err)
+		} else {
+			atomic.AddUint64(&tw.stats.heartbeatsAnswered, 1)
+		}
+	}
+
+	serverTime, ok := parseServerTime(message, receivedAt)
+	if !ok {
+		return
+	}
+	sample := serverTime.Sub(receivedAt)
+
+	tw.clock.mu.Lock()
+	if tw.clock.samples == 0 {
+		tw.clock.offset = sample
+	} else {
+		tw.clock.offset += time.Duration(clockOffsetAlpha * float64(sample-tw.clock.offset))
+	}
+	tw.clock.samples++
+	offset := tw.clock.offset
+
+	exceeded := tw.clock.threshold > 0 && (offset > tw.clock.threshold || offset < -tw.clock.threshold)
+	notify := exceeded && !tw.clock.skewed
+	tw.clock.skewed = exceeded
+	tw.clock.mu.Unlock()
+
+	if notify && tw.OnClockSkew != nil {
+		tw.invokeCallback("OnClockSkew", offset.String, func() { tw.OnClockSkew(offset) })
+	}
+}
+
+// parseServerTime reads the HH:MM:SS exchange time from the 66= tag. The date is taken
+// from receivedAt, moved by a day when that puts the two more than 12 hours apart. As the
+// tag only has second resolution, the middle of the second is used.
+func parseServerTime(message string, receivedAt time.Time) (time.Time, bool) {
+	start := strings.Index(message, "66=")
+	if start < 0 || (start > 0 && message[start-1] != '|') {
+		return time.Time{}, false
+	}
+	value := message[start+3:]
+	if end := strings.IndexByte(value, '|'); end >= 0 {
+		value = value[:end]
+	}
+
+	clock, err := time.Parse("15:04:05", value)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	local := receivedAt.In(ExchangeLocation)
+	serverTime := time.Date(local.Year(), local.Month(), local.Day(),
+		clock.Hour(), clock.Minute(), clock.Second(), int(500*time.Millisecond), ExchangeLocation)
+
+	if diff := serverTime.Sub(receivedAt); diff > 12*time.Hour {
+		serverTime = serverTime.AddDate(0, 0, -1)
+	} else if diff < -12*time.Hour {
+		serverTime = serverTime.AddDate(0, 0, 1)
+	}
+	return serverTime, true
+}
@@ -0,0 +1,42 @@
+package ODINMarketFeed
+
+import "sync/atomic"
+
+// hasDataConsumer reports whether anything consumes market data. Without a consumer the
+// binary touchline packets are not decoded; control messages such as heartbeats still are.
+// Callbacks are checked per frame, so setting one resumes decoding with the next frame.
+func (tw *ODINMarketFeedClient) hasDataConsumer() bool {
+	if tw.OnMessage != nil || tw.OnMessageBatch != nil || tw.OnRawBatch != nil ||
+		tw.OnTouchline != nil || tw.OnLTP != nil || tw.OnBestFive != nil ||
+		tw.OnJSON != nil || tw.OnFeedGap != nil || tw.OnUnknownMessage != nil || tw.publisher.Load() != nil {
+		return true
+	}
+	if tw.tickSink.Load() != nil || tw.messageSink.Load() != nil || tw.wireDump.Load() != nil ||
+		tw.csvWriters.Load() != nil {
+		return true
+	}
+	if tw.depthCache != nil || tw.tokenStats != nil || tw.gapDetector != nil || tw.strictErrors ||
+		tw.conformance != nil {
+		return true
+	}
+
+	tw.interceptors.mu.RLock()
+	intercepted := len(tw.interceptors.receive) > 0
+	tw.interceptors.mu.RUnlock()
+	if intercepted {
+		return true
+	}
+
+	tw.quotes.mu.Lock()
+	waiting := len(tw.quotes.pending) > 0
+	tw.quotes.mu.Unlock()
+	return waiting
+}
+
+// skipIdleMessage counts a market data packet dropped for lack of a consumer and warns once
+func (tw *ODINMarketFeedClient) skipIdleMessage() {
+	atomic.AddUint64(&tw.stats.skippedMessages, 1)
+	if atomic.CompareAndSwapInt32(&tw.idleWarned, 0, 1) {
+		tw.logf("Warning: no callback consumes market data; packets are discarded without decoding")
+	}
+}
@@ -0,0 +1,64 @@
+package ODINMarketFeed
+
+import "testing"
+
+func TestIdleSkipsDecoding(t *testing.T) {
+	var warnings int
+	tw := NewODINMarketFeedClient(WithLogger(func(string) { warnings++ }))
+	tw.responseReceived(frameOf(manyMessages(10)...), 0)
+	if skipped := tw.Stats().SkippedMessages; skipped != 10 {
+		t.Errorf("SkippedMessages = %d, want 10", skipped)
+	}
+	if warnings != 1 {
+		t.Errorf("logged %d warnings, want 1", warnings)
+	}
+}
+
+func TestIdleConsumers(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+		set  func(tw *ODINMarketFeedClient)
+	}{
+		{"OnUnknownMessage", nil, func(tw *ODINMarketFeedClient) { tw.OnUnknownMessage = func(int, []byte) {} }},
+		{"conformance transcript", []Option{WithConformanceMode()}, func(*ODINMarketFeedClient) {}},
+		{"OnTouchline", nil, func(tw *ODINMarketFeedClient) { tw.OnTouchline = func(TouchlineData) {} }},
+	}
+	for _, tt := range tests {
+		tw := NewODINMarketFeedClient(tt.opts...)
+		tt.set(tw)
+		tw.responseReceived(frameOf(manyMessages(10)...), 0)
+		if skipped := tw.Stats().SkippedMessages; skipped != 0 {
+			t.Errorf("%s: SkippedMessages = %d, want 0", tt.name, skipped)
+		}
+	}
+}
+
+// BenchmarkIdlePath compares a 200 message frame without a consumer, which is only
+// defragmented, with the same frame decoded for OnTouchline and with defragmentation alone
+func BenchmarkIdlePath(b *testing.B) {
+	frame := frameOf(manyMessages(200)...)
+
+	b.Run("Idle", func(b *testing.B) {
+		tw := NewODINMarketFeedClient(WithLogger(func(string) {}))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tw.responseReceived(frame, 0)
+		}
+	})
+	b.Run("Decoded", func(b *testing.B) {
+		tw := NewODINMarketFeedClient()
+		tw.OnTouchline = func(TouchlineData) {}
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tw.responseReceived(frame, 0)
+		}
+	})
+	b.Run("DefragmentOnly", func(b *testing.B) {
+		fh := NewFragmentationHandler()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			fh.Defragment(frame)
+		}
+	})
+}
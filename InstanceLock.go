@@ -0,0 +1,49 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInstanceLocked is returned by Connect when another process holds the lock file set by
+// WithSingleInstanceLock
+var ErrInstanceLocked = errors.New("another instance holds the lock")
+
+// WithSingleInstanceLock makes Connect take an exclusive lock on the file at path, failing
+// with ErrInstanceLocked while another process holds it, so two processes on one host cannot
+// log in with the same user. The lock is released by Dispose or Close. Use one path per user
+// ID.
+func WithSingleInstanceLock(path string) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.instanceLockPath = path
+	}
+}
+
+// acquireInstanceLock takes the instance lock on the first Connect
+func (tw *ODINMarketFeedClient) acquireInstanceLock() error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.instanceLockPath == "" || tw.instanceLock != nil {
+		return nil
+	}
+
+	lock, err := lockFile(tw.instanceLockPath)
+	if err != nil {
+		if errors.Is(err, ErrInstanceLocked) {
+			return fmt.Errorf("%w: %s", ErrInstanceLocked, tw.instanceLockPath)
+		}
+		return fmt.Errorf("failed to lock %s: %w", tw.instanceLockPath, err)
+	}
+	tw.instanceLock = lock
+	return nil
+}
+
+// releaseInstanceLock releases the instance lock; the caller must hold tw.mu
+func (tw *ODINMarketFeedClient) releaseInstanceLock() {
+	if tw.instanceLock == nil {
+		return
+	}
+	tw.instanceLock.unlock()
+	tw.instanceLock = nil
+}
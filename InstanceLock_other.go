@@ -0,0 +1,30 @@
+//go:build !unix
+
+package ODINMarketFeed
+
+import (
+	"errors"
+	"os"
+)
+
+// instanceLock is a lock file created exclusively. Unlike an flock it is not released if the
+// process crashes; remove the stale file by hand in that case.
+type instanceLock struct {
+	path string
+}
+
+func lockFile(path string) (*instanceLock, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return nil, ErrInstanceLocked
+		}
+		return nil, err
+	}
+	file.Close()
+	return &instanceLock{path: path}, nil
+}
+
+func (l *instanceLock) unlock() {
+	os.Remove(l.path)
+}
@@ -0,0 +1,35 @@
+//go:build unix
+
+package ODINMarketFeed
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// instanceLock is an flock held on an open file
+type instanceLock struct {
+	file *os.File
+}
+
+func lockFile(path string) (*instanceLock, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		file.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrInstanceLocked
+		}
+		return nil, err
+	}
+	return &instanceLock{file: file}, nil
+}
+
+func (l *instanceLock) unlock() {
+	syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	l.file.Close()
+}
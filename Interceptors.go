@@ -0,0 +1,116 @@
+package ODINMarketFeed
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// InterceptorID identifies a registered interceptor so it can be removed
+type InterceptorID uint64
+
+// SendInterceptor inspects or rewrites an outgoing request before it is compressed and framed
+type SendInterceptor func(message string) string
+
+// ReceiveInterceptor inspects each defragmented packet before it is parsed.
+// The slice must not be modified or retained after the interceptor returns.
+type ReceiveInterceptor func(raw []byte)
+
+type interceptors struct {
+	nextID  uint64
+	send    []sendInterceptorEntry
+	receive []receiveInterceptorEntry
+	mu      sync.RWMutex
+}
+
+type sendInterceptorEntry struct {
+	id InterceptorID
+	fn SendInterceptor
+}
+
+type receiveInterceptorEntry struct {
+	id InterceptorID
+	fn ReceiveInterceptor
+}
+
+// AddSendInterceptor registers an interceptor applied, in registration order, to every
+// message sent through SendMessage
+func (tw *ODINMarketFeedClient) AddSendInterceptor(interceptor SendInterceptor) InterceptorID {
+	id := InterceptorID(atomic.AddUint64(&tw.interceptors.nextID, 1))
+
+	tw.interceptors.mu.Lock()
+	defer tw.interceptors.mu.Unlock()
+	tw.interceptors.send = append(tw.interceptors.send, sendInterceptorEntry{id: id, fn: interceptor})
+	return id
+}
+
+// AddReceiveInterceptor registers an interceptor invoked, in registration order, with every
+// defragmented packet before parsing
+func (tw *ODINMarketFeedClient) AddReceiveInterceptor(interceptor ReceiveInterceptor) InterceptorID {
+	id := InterceptorID(atomic.AddUint64(&tw.interceptors.nextID, 1))
+
+	tw.interceptors.mu.Lock()
+	defer tw.interceptors.mu.Unlock()
+	tw.interceptors.receive = append(tw.interceptors.receive, receiveInterceptorEntry{id: id, fn: interceptor})
+	return id
+}
+
+// RemoveInterceptor removes a send or receive interceptor, returning false if it was not registered
+func (tw *ODINMarketFeedClient) RemoveInterceptor(id InterceptorID) bool {
+	tw.interceptors.mu.Lock()
+	defer tw.interceptors.mu.Unlock()
+
+	for i, entry := range tw.interceptors.send {
+		if entry.id == id {
+			tw.interceptors.send = append(tw.interceptors.send[:i:i], tw.interceptors.send[i+1:]...)
+			return true
+		}
+	}
+	for i, entry := range tw.interceptors.receive {
+		if entry.id == id {
+			tw.interceptors.receive = append(tw.interceptors.receive[:i:i], tw.interceptors.receive[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (tw *ODINMarketFeedClient) applySendInterceptors(message string) string {
+	tw.interceptors.mu.RLock()
+	entries := tw.interceptors.send
+	tw.interceptors.mu.RUnlock()
+
+	for _, entry := range entries {
+		message = tw.runSendInterceptor(entry.fn, message)
+	}
+	return message
+}
+
+func (tw *ODINMarketFeedClient) runSendInterceptor(interceptor SendInterceptor, message string) (result string) {
+	result = message
+	defer func() {
+		if r := recover(); r != nil {
+			tw.reportError(fmt.Sprintf("Send interceptor panicked: %v", r))
+		}
+	}()
+	return interceptor(message)
+}
+
+func (tw *ODINMarketFeedClient) applyReceiveInterceptors(raw []byte) {
+	tw.interceptors.mu.RLock()
+	entries := tw.interceptors.receive
+	tw.interceptors.mu.RUnlock()
+
+	for _, entry := range entries {
+		tw.runReceiveInterceptor(entry.fn, raw)
+	}
+}
+
+func (tw *ODINMarketFeedClient) runReceiveInterceptor(interceptor ReceiveInterceptor, raw []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			tw.reportError(fmt.Sprintf("Receive interceptor panicked: %v", r))
+		}
+	}()
+	interceptor(raw)
+}
@@ -0,0 +1,70 @@
+//go:build go1.23
+
+package ODINMarketFeed
+
+import (
+	"context"
+	"iter"
+)
+
+// Ticks returns an iterator over decoded touchlines. Delivery to the iterator starts when
+// it is ranged over and stops when the loop breaks or ctx is done, in which case ctx.Err()
+// is yielded last. Touchlines wait in a buffer of WithIteratorBuffer values and are dropped
+// when it is full. The iterator yields ErrCallbackMode if OnTouchline is set or another
+// Ticks loop is running.
+func (tw *ODINMarketFeedClient) Ticks(ctx context.Context) iter.Seq2[TouchlineData, error] {
+	return func(yield func(TouchlineData, error) bool) {
+		if tw.OnTouchline != nil {
+			yield(TouchlineData{}, ErrCallbackMode)
+			return
+		}
+		sink := newDeliverySink[TouchlineData](tw, tw.iteratorBuffer, &tw.stats.iteratorDrops)
+		if !tw.tickSink.CompareAndSwap(nil, sink) {
+			yield(TouchlineData{}, ErrCallbackMode)
+			return
+		}
+		defer sink.close()
+		defer tw.tickSink.Store(nil)
+		rangeSink(ctx, sink, yield)
+	}
+}
+
+// Messages returns an iterator over every decoded message, like OnMessage. Delivery starts
+// when it is ranged over and stops when the loop breaks or ctx is done, in which case
+// ctx.Err() is yielded last. Messages are buffered and dropped like those of Ticks. The
+// iterator yields ErrCallbackMode if OnMessage or
+// OnMessageBatch is set or another Messages loop is running.
+func (tw *ODINMarketFeedClient) Messages(ctx context.Context) iter.Seq2[ParsedMessage, error] {
+	return func(yield func(ParsedMessage, error) bool) {
+		if tw.OnMessage != nil || tw.OnMessageBatch != nil {
+			yield(ParsedMessage{}, ErrCallbackMode)
+			return
+		}
+		sink := newDeliverySink[ParsedMessage](tw, tw.iteratorBuffer, &tw.stats.iteratorDrops)
+		if !tw.messageSink.CompareAndSwap(nil, sink) {
+			yield(ParsedMessage{}, ErrCallbackMode)
+			return
+		}
+		defer sink.close()
+		defer tw.messageSink.Store(nil)
+		rangeSink(ctx, sink, yield)
+	}
+}
+
+// rangeSink yields the values delivered to sink until yield returns false or ctx is done
+func rangeSink[T any](ctx context.Context, sink *deliverySink[T], yield func(T, error) bool) {
+	defer close(sink.stop)
+
+	for {
+		select {
+		case item := <-sink.ch:
+			if !yield(sink.receive(item), nil) {
+				return
+			}
+		case <-ctx.Done():
+			var zero T
+			yield(zero, ctx.Err())
+			return
+		}
+	}
+}
@@ -0,0 +1,64 @@
+//go:build go1.23
+
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTicksStopsOnBreak(t *testing.T) {
+	tw := newTestClient()
+	go func() {
+		eventually(t, time.Second, func() bool { return tw.tickSink.Load() != nil })
+		tw.responseReceived(frameOf(manyMessages(3)...), 0)
+	}()
+
+	var got []TouchlineData
+	for td, err := range tw.Ticks(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, td)
+		break
+	}
+	if len(got) != 1 || got[0].Token != 1000 {
+		t.Fatalf("got %+v, want the touchline of token 1000", got)
+	}
+	if tw.tickSink.Load() != nil {
+		t.Error("delivery to the iterator continues after break")
+	}
+	tw.responseReceived(frameOf(manyMessages(3)...), 0)
+}
+
+func TestTicksYieldsContextError(t *testing.T) {
+	tw := newTestClient()
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		eventually(t, time.Second, func() bool { return tw.tickSink.Load() != nil })
+		cancel()
+	}()
+
+	var last error
+	for _, err := range tw.Ticks(ctx) {
+		last = err
+	}
+	if !errors.Is(last, context.Canceled) {
+		t.Fatalf("last error %v, want context.Canceled", last)
+	}
+	if tw.tickSink.Load() != nil {
+		t.Error("delivery to the iterator continues after ctx was cancelled")
+	}
+}
+
+func TestMessagesCallbackMode(t *testing.T) {
+	tw := newTestClient()
+	tw.OnMessage = func(string) {}
+	for _, err := range tw.Messages(context.Background()) {
+		if !errors.Is(err, ErrCallbackMode) {
+			t.Fatalf("got %v, want ErrCallbackMode", err)
+		}
+	}
+}
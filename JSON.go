@@ -0,0 +1,191 @@
+package ODINMarketFeed
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ExchangeLocation is the time zone used for timestamps in JSON output
+var ExchangeLocation = time.FixedZone("IST", 5*60*60+30*60)
+
+// JSONOptions controls the JSON encoding of parsed market data
+type JSONOptions struct {
+	// IncludeRawPrices adds the unscaled integer prices under "raw_prices"
+	IncludeRawPrices bool
+}
+
+// touchlineJSON is the stable JSON shape of TouchlineData. Prices are divided by the
+// decimal locator and timestamps are RFC3339 in ExchangeLocation.
+type touchlineJSON struct {
+	SegmentID       uint32            `json:"segment_id"`
+	Token           uint32            `json:"token"`
+	Symbol          string            `json:"symbol,omitempty"`
+	LUT             string            `json:"lut"`
+	LTT             string            `json:"ltt"`
+	LTP             float64           `json:"ltp"`
+	BuyQty          uint32            `json:"buy_qty"`
+	BuyPrice        float64           `json:"buy_price"`
+	SellQty         uint32            `json:"sell_qty"`
+	SellPrice       float64           `json:"sell_price"`
+	Open            float64           `json:"open"`
+	High            float64           `json:"high"`
+	Low             float64           `json:"low"`
+	Close           float64           `json:"close"`
+	DecimalLocator  uint32            `json:"decimal_locator"`
+	PrevClose       float64           `json:"prev_close"`
+	IndicativeClose float64           `json:"indicative_close"`
+	TradedValue     *float64          `json:"traded_value,omitempty"`
+	ATP             *float64          `json:"atp,omitempty"`
+	OutOfSession    bool              `json:"out_of_session,omitempty"`
+	RawPrices       *touchlineRawJSON `json:"raw_prices,omitempty"`
+}
+
+type touchlineRawJSON struct {
+	LTP             uint32  `json:"ltp"`
+	BuyPrice        uint32  `json:"buy_price"`
+	SellPrice       uint32  `json:"sell_price"`
+	Open            uint32  `json:"open"`
+	High            uint32  `json:"high"`
+	Low             uint32  `json:"low"`
+	Close           uint32  `json:"close"`
+	PrevClose       uint32  `json:"prev_close"`
+	IndicativeClose uint32  `json:"indicative_close"`
+	TradedValue     *int64  `json:"traded_value,omitempty"`
+	ATP             *uint32 `json:"atp,omitempty"`
+}
+
+type depthLevelJSON struct {
+	Price    float64 `json:"price"`
+	Qty      uint32  `json:"qty"`
+	RawPrice *uint32 `json:"raw_price,omitempty"`
+}
+
+type bestFiveJSON struct {
+	SegmentID      uint32           `json:"segment_id"`
+	Token          uint32           `json:"token"`
+	Symbol         string           `json:"symbol,omitempty"`
+	DecimalLocator uint32           `json:"decimal_locator"`
+	Bids           []depthLevelJSON `json:"bids"`
+	Asks           []depthLevelJSON `json:"asks"`
+}
+
+// scalePrice divides a raw price by the decimal locator, returning the raw value when no locator is known
+func scalePrice(raw, decimalLocator uint32) float64 {
+	if decimalLocator == 0 {
+		return float64(raw)
+	}
+	return float64(raw) / float64(decimalLocator)
+}
+
+func formatJSONTime(t time.Time) string {
+	return t.In(ExchangeLocation).Format(time.RFC3339)
+}
+
+// MarshalJSON encodes the touchline with decimal-scaled prices
+func (td TouchlineData) MarshalJSON() ([]byte, error) {
+	return td.MarshalJSONWithOptions(JSONOptions{})
+}
+
+// MarshalJSONWithOptions encodes the touchline using the given options
+func (td TouchlineData) MarshalJSONWithOptions(opts JSONOptions) ([]byte, error) {
+	dl := td.DecimalLocator
+	out := touchlineJSON{
+		SegmentID:       td.MktSegID,
+		Token:           td.Token,
+		Symbol:          td.Symbol,
+		LUT:             formatJSONTime(td.LUT),
+		LTT:             formatJSONTime(td.LTT),
+		LTP:             scalePrice(td.LTP, dl),
+		BuyQty:          td.BuyQty,
+		BuyPrice:        scalePrice(td.BuyPrice, dl),
+		SellQty:         td.SellQty,
+		SellPrice:       scalePrice(td.SellPrice, dl),
+		Open:            scalePrice(td.OpenPrice, dl),
+		High:            scalePrice(td.HighPrice, dl),
+		Low:             scalePrice(td.LowPrice, dl),
+		Close:           scalePrice(td.ClosePrice, dl),
+		DecimalLocator:  dl,
+		PrevClose:       scalePrice(td.PrevClosePrice, dl),
+		IndicativeClose: scalePrice(td.IndicativeClosePrice, dl),
+		OutOfSession:    td.OutOfSession,
+	}
+	if td.Extended {
+		tradedValue := float64(td.TotalTradedValue)
+		if dl != 0 {
+			tradedValue /= float64(dl)
+		}
+		atp := scalePrice(td.ATP, dl)
+		out.TradedValue = &tradedValue
+		out.ATP = &atp
+	}
+
+	if opts.IncludeRawPrices {
+		out.RawPrices = &touchlineRawJSON{
+			LTP:             td.LTP,
+			BuyPrice:        td.BuyPrice,
+			SellPrice:       td.SellPrice,
+			Open:            td.OpenPrice,
+			High:            td.HighPrice,
+			Low:             td.LowPrice,
+			Close:           td.ClosePrice,
+			PrevClose:       td.PrevClosePrice,
+			IndicativeClose: td.IndicativeClosePrice,
+		}
+		if td.Extended {
+			out.RawPrices.TradedValue = &td.TotalTradedValue
+			out.RawPrices.ATP = &td.ATP
+		}
+	}
+
+	return json.Marshal(out)
+}
+
+// MarshalJSON encodes the book with decimal-scaled prices
+func (bf BestFiveData) MarshalJSON() ([]byte, error) {
+	return bf.MarshalJSONWithOptions(JSONOptions{})
+}
+
+// MarshalJSONWithOptions encodes the book using the given options
+func (bf BestFiveData) MarshalJSONWithOptions(opts JSONOptions) ([]byte, error) {
+	levels := func(depth []DepthLevel) []depthLevelJSON {
+		out := make([]depthLevelJSON, len(depth))
+		for i, level := range depth {
+			out[i] = depthLevelJSON{Price: scalePrice(level.Price, bf.DecimalLocator), Qty: level.Qty}
+			if opts.IncludeRawPrices {
+				rawPrice := level.Price
+				out[i].RawPrice = &rawPrice
+			}
+		}
+		return out
+	}
+
+	return json.Marshal(bestFiveJSON{
+		SegmentID:      bf.MktSegID,
+		Token:          bf.Token,
+		Symbol:         bf.Symbol,
+		DecimalLocator: bf.DecimalLocator,
+		Bids:           levels(bf.Bids),
+		Asks:           levels(bf.Asks),
+	})
+}
+
+// WithJSONOptions sets the options used to encode the payloads delivered to OnJSON
+func WithJSONOptions(opts JSONOptions) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.jsonOptions = opts
+	}
+}
+
+// deliverJSON encodes the value and invokes OnJSON
+func (tw *ODINMarketFeedClient) deliverJSON(encode func(JSONOptions) ([]byte, error)) {
+	if tw.OnJSON == nil {
+		return
+	}
+
+	payload, err := encode(tw.jsonOptions)
+	if err != nil {
+		tw.reportError("Failed to encode JSON: " + err.Error())
+		return
+	}
+	tw.invokeCallback("OnJSON", func() string { return string(payload) }, func() { tw.OnJSON(payload) })
+}
@@ -0,0 +1,131 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// goroutineGroup counts goroutines owned by the client. routines holds the receive loop,
+// writer and heartbeat of each connection and the resubscribe check; workers holds the
+// goroutines that outlive connections, such as the publisher and shard reconnects.
+type goroutineGroup struct {
+	mu     sync.Mutex
+	exited *sync.Cond
+	live   int
+	ids    map[uint64]struct{} // the goroutine IDs of the running goroutines
+}
+
+// spawn runs fn on a new goroutine owned by the group
+func (g *goroutineGroup) spawn(fn func()) {
+	g.mu.Lock()
+	g.live++
+	g.mu.Unlock()
+
+	go func() {
+		id := goroutineID()
+		g.mu.Lock()
+		if g.ids == nil {
+			g.ids = make(map[uint64]struct{})
+		}
+		g.ids[id] = struct{}{}
+		g.mu.Unlock()
+
+		defer func() {
+			g.mu.Lock()
+			g.live--
+			delete(g.ids, id)
+			if g.exited != nil {
+				g.exited.Broadcast()
+			}
+			g.mu.Unlock()
+		}()
+		fn()
+	}()
+}
+
+// ownsCaller reports whether the calling goroutine belongs to the group, so that it must
+// not wait for the group to exit
+func (g *goroutineGroup) ownsCaller() bool {
+	id := goroutineID()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, ok := g.ids[id]
+	return ok
+}
+
+// goroutineID returns the ID of the calling goroutine, read from the "goroutine N [...]"
+// header of its stack trace
+func goroutineID() uint64 {
+	var buf [64]byte
+	header := buf[:runtime.Stack(buf[:], false)]
+	header = bytes.TrimPrefix(header, []byte("goroutine "))
+	if i := bytes.IndexByte(header, ' '); i >= 0 {
+		header = header[:i]
+	}
+	id, _ := strconv.ParseUint(string(header), 10, 64)
+	return id
+}
+
+// count returns the number of running goroutines
+func (g *goroutineGroup) count() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.live
+}
+
+// waitFor blocks until at most n goroutines of the group are running or the timeout has
+// passed, and reports whether they were
+func (g *goroutineGroup) waitFor(n int, timeout time.Duration) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.exited == nil {
+		g.exited = sync.NewCond(&g.mu)
+	}
+	expired := false
+	timer := time.AfterFunc(timeout, func() {
+		g.mu.Lock()
+		expired = true
+		g.exited.Broadcast()
+		g.mu.Unlock()
+	})
+	defer timer.Stop()
+
+	for g.live > n && !expired {
+		g.exited.Wait()
+	}
+	return g.live <= n
+}
+
+// Goroutines returns the number of running goroutines owned by the client. It drops to 0
+// once Close has returned, or once Disconnect has returned and the receive loop has seen the
+// connection close, when no publisher is attached.
+func (tw *ODINMarketFeedClient) Goroutines() int {
+	return tw.routines.count() + tw.workers.count()
+}
+
+// awaitGoroutines waits for the goroutines of previous connections to exit, for at most the
+// close timeout. A call from one of them, such as a callback on the receive loop, leaves
+// that goroutine running: it exits once the callback has returned.
+func (tw *ODINMarketFeedClient) awaitGoroutines() {
+	tw.awaitGroup(&tw.routines, "connection")
+}
+
+// awaitWorkers waits like awaitGoroutines for the goroutines that outlive connections
+func (tw *ODINMarketFeedClient) awaitWorkers() {
+	tw.awaitGroup(&tw.workers, "worker")
+}
+
+func (tw *ODINMarketFeedClient) awaitGroup(g *goroutineGroup, kind string) {
+	remaining := 0
+	if g.ownsCaller() {
+		remaining = 1
+	}
+	timeout := tw.closeTimeoutOrDefault()
+	if !g.waitFor(remaining, timeout) {
+		tw.logf("%d %s goroutines still running after %v", g.count(), kind, timeout)
+	}
+}
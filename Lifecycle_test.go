@@ -0,0 +1,218 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// within runs fn and reports an error when it has not returned after timeout
+func within(t *testing.T, timeout time.Duration, name string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		t.Errorf("%s did not return within %v", name, timeout)
+	}
+}
+
+func TestGoroutineGroupWaitFor(t *testing.T) {
+	var g goroutineGroup
+	release := make(chan struct{})
+	g.spawn(func() { <-release })
+	g.spawn(func() {})
+
+	if !g.waitFor(1, time.Second) {
+		t.Fatalf("%d goroutines running, want the blocked one only", g.count())
+	}
+	if g.waitFor(0, 20*time.Millisecond) {
+		t.Fatal("waitFor(0) returned true with a goroutine blocked")
+	}
+	close(release)
+	if !g.waitFor(0, time.Second) {
+		t.Fatalf("%d goroutines running after release", g.count())
+	}
+}
+
+func TestCloseFromCallback(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithCloseTimeout(5 * time.Second))
+	closed := make(chan time.Duration, 1)
+	tw.OnTouchline = func(TouchlineData) {
+		start := time.Now()
+		tw.Close(context.Background())
+		closed <- time.Since(start)
+	}
+	ms.connect(t, tw)
+	ms.next(t, msgCodeLogin)
+
+	ms.latest().send(touchlineMessage(1, 22, 24500))
+	select {
+	case elapsed := <-closed:
+		if elapsed > time.Second {
+			t.Errorf("Close from OnTouchline took %v", elapsed)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no touchline delivered")
+	}
+	if !eventually(t, time.Second, func() bool { return tw.Goroutines() == 0 }) {
+		t.Errorf("%d goroutines left after Close from a callback", tw.Goroutines())
+	}
+}
+
+func TestReconnectFromOnClose(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithCloseTimeout(5 * time.Second))
+	reconnected := make(chan error, 1)
+	tw.OnClose = func(int, string) {
+		if ms.connections() > 1 {
+			return
+		}
+		start := time.Now()
+		err := tw.Connect(ms.host, ms.port, false, "u", "k")
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Connect from OnClose took %v", elapsed)
+		}
+		reconnected <- err
+	}
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	ms.dropAll()
+	select {
+	case err := <-reconnected:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no reconnect")
+	}
+	ms.next(t, msgCodeLogin)
+}
+
+func TestMultiClientCloseStopsReconnect(t *testing.T) {
+	ms := newMockServer(t, nil)
+	mc := NewMultiClient(1, WithLogger(func(string) {}))
+	mc.ReconnectDelay = time.Minute
+	if err := mc.Connect(ms.host, ms.port, false, "u", "k"); err != nil {
+		t.Fatal(err)
+	}
+	ms.next(t, msgCodeLogin)
+
+	client := mc.Client(0)
+	ms.dropAll()
+	if !eventually(t, time.Second, func() bool { return client.workers.count() == 1 }) {
+		t.Fatal("no reconnect scheduled after the connection was dropped")
+	}
+	within(t, time.Second, "MultiClient.Close", func() { mc.Close() })
+	if n := client.Goroutines(); n != 0 {
+		t.Errorf("Goroutines = %d after Close, want 0", n)
+	}
+}
+
+func TestConnectDisconnectCyclesLeaveNoGoroutines(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithHeartbeat(time.Second), WithStaleFeedTimeout(time.Minute))
+	defer tw.Close(context.Background())
+	before := runtime.NumGoroutine()
+
+	for i := 0; i < 100; i++ {
+		ms.connect(t, tw)
+		ms.next(t, msgCodeLogin)
+		if n := tw.Goroutines(); n == 0 {
+			t.Fatalf("cycle %d: no goroutines while connected", i)
+		}
+		if err := tw.Disconnect(); err != nil {
+			t.Fatalf("cycle %d: %v", i, err)
+		}
+		if !eventually(t, time.Second, func() bool { return tw.Goroutines() == 0 }) {
+			t.Fatalf("cycle %d: %d goroutines left after Disconnect", i, tw.Goroutines())
+		}
+	}
+	// The mock server's connection goroutines exit once they have read the close frame
+	if !eventually(t, 5*time.Second, func() bool { return runtime.NumGoroutine() <= before+5 }) {
+		t.Errorf("%d goroutines after 100 cycles, %d before", runtime.NumGoroutine(), before)
+	}
+}
+
+func TestCloseDuringDispatchWaitsForReceiveLoop(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithCloseTimeout(5 * time.Second))
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	tw.OnTouchline = func(TouchlineData) {
+		close(entered)
+		<-release
+	}
+	ms.connect(t, tw)
+	ms.next(t, msgCodeLogin)
+
+	ms.latest().send(touchlineMessage(1, 22, 24500))
+	<-entered
+	closed := make(chan struct{})
+	go func() {
+		tw.Close(context.Background())
+		close(closed)
+	}()
+	select {
+	case <-closed:
+		t.Fatal("Close from another goroutine returned while a callback was running")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Close did not return after the callback")
+	}
+	if n := tw.Goroutines(); n != 0 {
+		t.Errorf("Goroutines = %d when Close returned, want 0", n)
+	}
+}
+
+func TestOnErrorOnlyForUnexpectedClose(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	defer tw.Close(context.Background())
+	var mu sync.Mutex
+	var events []string
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+	snapshot := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), events...)
+	}
+	tw.OnError = func(string) { record("error") }
+	tw.OnClose = func(code int, reason string) { record("close " + strconv.Itoa(code)) }
+
+	ms.connect(t, tw)
+	ms.next(t, msgCodeLogin)
+	tw.Disconnect()
+	eventually(t, 5*time.Second, func() bool { return len(snapshot()) > 0 })
+	if got, want := snapshot(), []string{"close 1000"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("local Disconnect delivered %q, want %q", got, want)
+	}
+
+	ms.connect(t, tw)
+	ms.next(t, msgCodeLogin)
+	ms.dropAll()
+	eventually(t, 5*time.Second, func() bool { return len(snapshot()) > 2 })
+	if got, want := snapshot()[1:], []string{"error", "close 1006"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dropped connection delivered %q, want %q", got, want)
+	}
+}
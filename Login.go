@@ -0,0 +1,162 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrLoginFailed is returned by Connect when the login request could not be sent
+var ErrLoginFailed = errors.New("login failed")
+
+// CredentialMode selects how the login secret is interpreted by the gateway
+type CredentialMode int
+
+const (
+	// CredentialAPIKey sends the secret as an API key (68=key|401=2)
+	CredentialAPIKey CredentialMode = iota
+	// CredentialPassword sends the secret as a plain user password (68=password)
+	CredentialPassword
+	// CredentialPasswordHash sends the secret as a hashed password (68=hash|401=1)
+	CredentialPasswordHash
+)
+
+// authModeFlags holds the 401= value emitted per credential mode; empty means no 401 tag
+var authModeFlags = map[CredentialMode]string{
+	CredentialAPIKey:       "2",
+	CredentialPassword:     "",
+	CredentialPasswordHash: "1",
+}
+
+// Credentials holds the login secret and how it is sent
+type Credentials struct {
+	Mode   CredentialMode
+	Secret string
+	// Tags holds mode-specific login tags; a 401 entry overrides the default auth-mode flag
+	Tags map[int]string
+}
+
+// LoginOptions configures the login message sent by ConnectWithLogin
+type LoginOptions struct {
+	Credentials Credentials
+	// Extra holds additional login tags, written in ascending tag order
+	Extra map[int]string
+}
+
+// fields builds the credential and extra tags of the login message
+func (lo LoginOptions) fields() (string, error) {
+	creds := lo.Credentials
+	flag, ok := authModeFlags[creds.Mode]
+	if !ok {
+		return "", fmt.Errorf("unknown credential mode: %d", creds.Mode)
+	}
+
+	if err := validateLoginValue("secret", creds.Secret); err != nil {
+		return "", err
+	}
+
+	// The secret is sent as given; surrounding whitespace is almost always a copy and paste
+	// mistake, which the gateway would only report as a failed login
+	secret := creds.Secret
+	if strings.TrimSpace(secret) != secret {
+		return "", errors.New("login secret has leading or trailing whitespace")
+	}
+	if creds.Mode == CredentialAPIKey && secret == "" {
+		// No API key: send an empty password field, as before API keys were supported
+		flag = ""
+	}
+	if creds.Mode != CredentialAPIKey && secret == "" {
+		return "", fmt.Errorf("secret cannot be empty for password login")
+	}
+
+	tags := make(map[int]string, len(creds.Tags)+len(lo.Extra))
+	for tag, value := range lo.Extra {
+		tags[tag] = value
+	}
+	for tag, value := range creds.Tags {
+		tags[tag] = value
+	}
+	if override, ok := tags[401]; ok {
+		flag = override
+		delete(tags, 401)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("68=" + secret)
+	if flag != "" {
+		sb.WriteString("|401=" + flag)
+	}
+
+	tagNumbers := make([]int, 0, len(tags))
+	for tag := range tags {
+		if tag == 67 || tag == 68 {
+			return "", fmt.Errorf("login tag %d cannot be overridden", tag)
+		}
+		if err := validateLoginValue("tag "+strconv.Itoa(tag), tags[tag]); err != nil {
+			return "", err
+		}
+		tagNumbers = append(tagNumbers, tag)
+	}
+	sort.Ints(tagNumbers)
+	for _, tag := range tagNumbers {
+		sb.WriteString("|" + strconv.Itoa(tag) + "=" + tags[tag])
+	}
+
+	return sb.String(), nil
+}
+
+// validateLoginValue rejects values that would inject extra fields into the message
+func validateLoginValue(name, value string) error {
+	if strings.ContainsAny(value, "|$\r\n\x00") {
+		return fmt.Errorf("login %s contains a reserved delimiter character", name)
+	}
+	return nil
+}
+
+var secretTagPattern = regexp.MustCompile(`(^|\|)68=[^|]*`)
+
+// maskSecrets hides the login secret before a message is logged
+func maskSecrets(message string) string {
+	return secretTagPattern.ReplaceAllString(message, "${1}68=****")
+}
+
+// maxUserIDLength is the longest user ID accepted by the gateway
+const maxUserIDLength = 12
+
+// WithNormalizeUserID controls whether Connect upper-cases the user ID (the default, as the
+// gateway rejects lowercase IDs). Whitespace is trimmed either way.
+func WithNormalizeUserID(enabled bool) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.keepUserIDCase = !enabled
+	}
+}
+
+// normalizeUserID trims and, unless disabled, upper-cases the user ID, then checks its
+// length and that it only contains A-Z, 0-9, '_' and '-'
+func (tw *ODINMarketFeedClient) normalizeUserID(userID string) (string, error) {
+	userID = strings.TrimSpace(userID)
+	if userID == "" {
+		return "", errors.New("userID cannot be empty")
+	}
+	if !tw.keepUserIDCase {
+		userID = strings.ToUpper(userID)
+	}
+
+	for i, r := range userID {
+		valid := (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-'
+		if tw.keepUserIDCase {
+			valid = valid || (r >= 'a' && r <= 'z')
+		}
+		if !valid {
+			return "", fmt.Errorf("userID contains invalid character %q at position %d (allowed: A-Z, 0-9, '_', '-')", r, i)
+		}
+	}
+
+	if len(userID) > maxUserIDLength {
+		return "", fmt.Errorf("userID is too long (max %d characters)", maxUserIDLength)
+	}
+	return userID, nil
+}
@@ -0,0 +1,116 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoginSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		creds   Credentials
+		want    string
+		wantErr bool
+	}{
+		{"API key", Credentials{Mode: CredentialAPIKey, Secret: "key"}, "68=key|401=2", false},
+		{"empty API key", Credentials{Mode: CredentialAPIKey}, "68=", false},
+		{"password", Credentials{Mode: CredentialPassword, Secret: "pw"}, "68=pw", false},
+		{"inner space", Credentials{Mode: CredentialPassword, Secret: "p w"}, "68=p w", false},
+		{"leading space", Credentials{Mode: CredentialAPIKey, Secret: " key"}, "", true},
+		{"trailing tab", Credentials{Mode: CredentialPasswordHash, Secret: "hash\t"}, "", true},
+		{"blank API key", Credentials{Mode: CredentialAPIKey, Secret: "  "}, "", true},
+		{"empty password", Credentials{Mode: CredentialPassword}, "", true},
+	}
+	for _, tt := range tests {
+		got, err := LoginOptions{Credentials: tt.creds}.fields()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("%s: fields() = %q, %v; want %q, error %v", tt.name, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLoginSendFailureDisconnects(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	tw.SetPayloadTransform(func(msgType int, payload []byte) ([]byte, error) {
+		if msgType == msgCodeLogin {
+			return nil, errors.New("no key")
+		}
+		return payload, nil
+	})
+
+	err := tw.Connect(ms.host, ms.port, false, "u", "k")
+	if !errors.Is(err, ErrLoginFailed) {
+		t.Fatalf("Connect = %v, want ErrLoginFailed", err)
+	}
+	if state := tw.State(); state != StateDisconnected {
+		t.Errorf("state after failed login = %v, want %v", state, StateDisconnected)
+	}
+	if !eventually(t, 2*time.Second, func() bool { return tw.Goroutines() == 0 }) {
+		t.Errorf("%d goroutines left after failed login", tw.Goroutines())
+	}
+
+	tw.SetPayloadTransform(nil)
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	if state := tw.State(); state != StateConnected {
+		t.Errorf("state after second Connect = %v, want %v", state, StateConnected)
+	}
+}
+
+func TestNormalizeUserID(t *testing.T) {
+	tests := []struct {
+		name     string
+		userID   string
+		keepCase bool
+		want     string
+		wantErr  string // substring of the error, empty when valid
+	}{
+		{"upper case", "ABC123", false, "ABC123", ""},
+		{"mixed case", "aBc_12-x", false, "ABC_12-X", ""},
+		{"surrounding spaces", "  abc12\t", false, "ABC12", ""},
+		{"case kept", "aBc12", true, "aBc12", ""},
+		{"embedded space", "AB C12", false, "", "' ' at position 2"},
+		{"unicode letter", "ABCÉ12", false, "", "'É' at position 3"},
+		{"unicode digit", "AB١٢", false, "", "'١' at position 2"},
+		{"dot", "AB.C", false, "", "'.' at position 2"},
+		{"12 characters", "ABCDEFGHIJ12", false, "ABCDEFGHIJ12", ""},
+		{"13 characters", "ABCDEFGHIJ123", false, "", "too long"},
+		{"empty", "  ", false, "", "empty"},
+	}
+	for _, tt := range tests {
+		tw := newTestClient(WithNormalizeUserID(!tt.keepCase))
+		got, err := tw.normalizeUserID(tt.userID)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: normalizeUserID(%q) = %q, %v, want an error with %q", tt.name, tt.userID, got, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: normalizeUserID(%q) = %q, %v, want %q", tt.name, tt.userID, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoginCarriesNormalizedUserID(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	defer tw.Close(context.Background())
+
+	if err := tw.Connect(ms.host, ms.port, false, " trader01 ", "k"); err != nil {
+		t.Fatal(err)
+	}
+	if login := ms.next(t, msgCodeLogin); !strings.Contains(login, "|67=TRADER01|") {
+		t.Errorf("login %q, want the upper-cased user ID", login)
+	}
+
+	ms.dropAll()
+	eventually(t, 5*time.Second, func() bool { return tw.State() == StateDisconnected })
+	if err := tw.Connect(ms.host, ms.port, false, "trader 01", "k"); err == nil {
+		t.Error("Connect with a space in the user ID succeeded")
+	}
+}
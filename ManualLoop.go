@@ -0,0 +1,158 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrManualReadLoopDisabled is returned by ReadAndDispatch and Tick without WithManualReadLoop
+var ErrManualReadLoopDisabled = errors.New("manual read loop is not enabled")
+
+// manualLoop holds the state of a WithManualReadLoop client. fragGeneration and
+// nextHeartbeat are guarded by tw.mu.
+type manualLoop struct {
+	enabled        bool
+	fragGeneration uint64
+	nextHeartbeat  time.Time
+}
+
+// WithManualReadLoop makes Connect start no goroutines: the caller reads and dispatches each
+// frame with ReadAndDispatch and drives the heartbeat with Tick, for example from the
+// scheduler of a simulation framework. Requests are written on the goroutine that sends
+// them; requests held back by a gateway throttle pause are written by a later Tick. The
+// callbacks run on the goroutine calling ReadAndDispatch.
+//
+// Features that run on timers, such as WithResubscribeVerification, the subscription file
+// and AttachPublisher, still use goroutines of their own; leave them unset where no
+// goroutine may be started. Throttled touchlines (WithDefaultThrottle) are delivered by Tick.
+func WithManualReadLoop(enabled bool) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.manual.enabled = enabled
+	}
+}
+
+// startManualLoop records the state of a new connection for ReadAndDispatch and Tick
+func (tw *ODINMarketFeedClient) startManualLoop(fragGeneration uint64) {
+	if !tw.manual.enabled {
+		return
+	}
+
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.manual.fragGeneration = fragGeneration
+	tw.manual.nextHeartbeat = tw.connectedAt.Add(tw.heartbeatInterval)
+}
+
+// ReadAndDispatch reads one frame and delivers it to the callbacks before returning. It
+// returns io.EOF once the connection has been closed, by either side, and the read error
+// when it has failed. The connection is reported as lost through OnError, OnClose and the
+// Events channel as with the receive goroutine. A deadline of ctx bounds the read; as a
+// websocket read cannot resume after a timeout, reaching it ends the connection and
+// ctx.Err() is returned.
+func (tw *ODINMarketFeedClient) ReadAndDispatch(ctx context.Context) error {
+	if !tw.manual.enabled {
+		return ErrManualReadLoopDisabled
+	}
+
+	tw.mu.Lock()
+	disposed := tw.isDisposed
+	conn := tw.conn
+	queue := tw.sendQueue
+	fragGeneration := tw.manual.fragGeneration
+	tw.mu.Unlock()
+
+	if disposed {
+		return ErrClientDisposed
+	}
+	if conn == nil {
+		return io.EOF
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return err
+	}
+
+	messageType, message, err := conn.ReadMessage()
+	if err != nil {
+		// A read on a connection detached by a local close fails as expected
+		if !tw.readFailed(conn, err) {
+			return io.EOF
+		}
+		queue.close()
+
+		// Unlike the receive goroutine, later calls must not read the failed connection again
+		tw.mu.Lock()
+		if tw.conn == conn {
+			tw.conn = nil
+		}
+		tw.mu.Unlock()
+		conn.Close()
+
+		var closeErr *websocket.CloseError
+		var netErr net.Error
+		switch {
+		case errors.As(err, &closeErr):
+			return io.EOF
+		case errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() != nil:
+			return ctx.Err()
+		}
+		return err
+	}
+
+	tw.dispatchFrame(messageType, message, fragGeneration)
+	return nil
+}
+
+// Tick performs the timed work of a WithManualReadLoop client as of now: it sends a
+// heartbeat once the WithHeartbeat interval has passed since the connection or the previous
+// heartbeat, writes the requests whose gateway throttle pause has ended, delivers the
+// throttled touchlines that have fallen due and checks WithStaleFeedTimeout. Tick does
+// nothing else while disconnected, apart from the WithDayRollover reset once its time has
+// passed.
+func (tw *ODINMarketFeedClient) Tick(now time.Time) error {
+	if !tw.manual.enabled {
+		return ErrManualReadLoopDisabled
+	}
+
+	tw.mu.Lock()
+	if tw.isDisposed {
+		tw.mu.Unlock()
+		return ErrClientDisposed
+	}
+	conn := tw.conn
+	queue := tw.sendQueue
+	heartbeat := conn != nil && tw.heartbeatInterval > 0 && !now.Before(tw.manual.nextHeartbeat)
+	if heartbeat {
+		tw.manual.nextHeartbeat = now.Add(tw.heartbeatInterval)
+	}
+	tw.mu.Unlock()
+
+	tw.checkDayRollover(now)
+	if conn == nil {
+		return nil
+	}
+	tw.checkStaleFeed(now)
+	tw.flushThrottle(now)
+
+	tw.drainSendQueue(conn, queue)
+	if heartbeat {
+		if err := tw.heartbeatDue(conn); err != nil {
+			return err
+		}
+		if err := tw.SendMessage(tw.heartbeatMessage()); err != nil {
+			return fmt.Errorf("heartbeat failed: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package ODINMarketFeed
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// mockServer is a gateway stand-in that records the requests it receives. handle, when set,
+// is invoked on the connection's read goroutine for every request.
+type mockServer struct {
+	srv      *httptest.Server
+	host     string
+	port     int
+	requests chan string
+	handle   func(c *mockConn, request string)
+
+	mu    sync.Mutex
+	conns []*mockConn
+}
+
+// mockConn is one client connection of a mockServer
+type mockConn struct {
+	ws *websocket.Conn
+	mu sync.Mutex
+}
+
+func newMockServer(t *testing.T, handle func(c *mockConn, request string)) *mockServer {
+	t.Helper()
+
+	ms := &mockServer{requests: make(chan string, 1024), handle: handle}
+	upgrader := websocket.Upgrader{}
+	ms.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		c := &mockConn{ws: ws}
+		ms.mu.Lock()
+		ms.conns = append(ms.conns, c)
+		ms.mu.Unlock()
+
+		for {
+			_, data, err := ws.ReadMessage()
+			if err != nil {
+				ws.Close()
+				return
+			}
+			request, err := decodeRequest(data)
+			if err != nil {
+				t.Errorf("mock server: %v", err)
+				continue
+			}
+			ms.requests <- request
+			if ms.handle != nil {
+				ms.handle(c, request)
+			}
+		}
+	}))
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(ms.srv.URL, "http://"))
+	ms.host = host
+	ms.port, _ = strconv.Atoi(port)
+	t.Cleanup(func() {
+		ms.dropAll()
+		ms.srv.Close()
+	})
+	return ms
+}
+
+// decodeRequest returns the request carried by a frame written by the client
+func decodeRequest(data []byte) (string, error) {
+	frame, _, err := DecodeFrame(data)
+	if err != nil {
+		return "", err
+	}
+	payload, err := (&ZLIBCompressor{}).Uncompress(frame.Payload)
+	if err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+// connect connects tw to the server
+func (ms *mockServer) connect(t *testing.T, tw *ODINMarketFeedClient) {
+	t.Helper()
+	if err := tw.Connect(ms.host, ms.port, false, "u", "k"); err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+}
+
+// next returns the next request whose 64= code is code, skipping the others
+func (ms *mockServer) next(t *testing.T, code int) string {
+	t.Helper()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case request := <-ms.requests:
+			if messageCode(request) == code {
+				return request
+			}
+		case <-timeout:
+			t.Fatalf("no 64=%d request received", code)
+			return ""
+		}
+	}
+}
+
+// connections returns the number of connections accepted so far
+func (ms *mockServer) connections() int {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return len(ms.conns)
+}
+
+// latest returns the most recently accepted connection
+func (ms *mockServer) latest() *mockConn {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if len(ms.conns) == 0 {
+		return nil
+	}
+	return ms.conns[len(ms.conns)-1]
+}
+
+// dropAll closes every connection without a close handshake, as a crashed gateway would
+func (ms *mockServer) dropAll() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	for _, c := range ms.conns {
+		c.ws.NetConn().Close()
+	}
+}
+
+// send writes msgs to the client as the inner frames of one binary frame
+func (c *mockConn) send(msgs ...[]byte) error {
+	return c.write(websocket.BinaryMessage, frameOf(msgs...))
+}
+
+// sendText writes a text frame to the client
+func (c *mockConn) sendText(text string) error {
+	return c.write(websocket.TextMessage, []byte(text))
+}
+
+func (c *mockConn) write(messageType int, data []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ws.WriteMessage(messageType, data)
+}
+
+// eventually polls cond until it holds or the timeout expires
+func eventually(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return cond()
+}
+
+// newTestClient creates a client that does not log
+func newTestClient(opts ...Option) *ODINMarketFeedClient {
+	return NewODINMarketFeedClient(append([]Option{WithLogger(func(string) {})}, opts...)...)
+}
@@ -0,0 +1,346 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MultiClient spreads subscriptions over several feed connections. Each instrument is
+// assigned to a shard by hashing its market segment and token, so the assignment is stable
+// across reconnects. Messages from all connections are delivered to the MultiClient callbacks.
+type MultiClient struct {
+	clients []*ODINMarketFeedClient
+
+	OnOpen      func(shard int)
+	OnMessage   func(message string)
+	OnError     func(err string)
+	OnTouchline func(data TouchlineData)
+	OnBestFive  func(data BestFiveData)
+	// OnReconnect is invoked after a shard has reconnected and replayed its subscriptions
+	OnReconnect func(shard int, err error)
+
+	// ReconnectDelay is the wait before each reconnect attempt of a lost shard, unless
+	// ReconnectPolicy returns a delay
+	ReconnectDelay time.Duration
+	// ReconnectOnDuplicateSession reconnects shards whose session was replaced by another
+	// login for the same user (ErrDuplicateSession); by default they stay disconnected. It
+	// is ignored when ReconnectPolicy is set.
+	ReconnectOnDuplicateSession bool
+	// ReconnectPolicy decides whether a lost shard is reconnected and is asked again after
+	// each failed attempt; nil uses DefaultReconnectPolicy
+	ReconnectPolicy ReconnectPolicy
+
+	endpoints   []Endpoint
+	credentials []Credential
+	closed      bool
+	stop        chan struct{} // closed by Close, ends the reconnect delays
+	stopOnce    sync.Once
+	mu          sync.Mutex
+}
+
+// Credential holds the login details for one feed connection
+type Credential struct {
+	UserID string
+	APIKey string
+}
+
+// NewMultiClient creates a MultiClient with the given number of connections. The options
+// are applied to every underlying client; a WithName label is suffixed with "/" and the
+// shard number when there are several connections.
+func NewMultiClient(connections int, opts ...Option) *MultiClient {
+	if connections < 1 {
+		connections = 1
+	}
+
+	mc := &MultiClient{
+		clients:        make([]*ODINMarketFeedClient, connections),
+		ReconnectDelay: 5 * time.Second,
+		stop:           make(chan struct{}),
+	}
+
+	for i := range mc.clients {
+		shard := i
+		client := NewODINMarketFeedClient(opts...)
+		if client.name != "" && connections > 1 {
+			client.name = fmt.Sprintf("%s/%d", client.name, shard)
+		}
+		client.OnOpen = func() {
+			if mc.OnOpen != nil {
+				mc.OnOpen(shard)
+			}
+		}
+		client.OnMessage = func(message string) {
+			if mc.OnMessage != nil {
+				mc.OnMessage(message)
+			}
+		}
+		client.OnError = func(err string) {
+			if mc.OnError != nil {
+				mc.OnError(fmt.Sprintf("[shard %d] %s", shard, err))
+			}
+		}
+		client.OnTouchline = func(data TouchlineData) {
+			if mc.OnTouchline != nil {
+				mc.OnTouchline(data)
+			}
+		}
+		client.OnBestFive = func(data BestFiveData) {
+			if mc.OnBestFive != nil {
+				mc.OnBestFive(data)
+			}
+		}
+		client.onConnectionLost = func(err error) {
+			if reconnect, delay := mc.reconnectPolicy().ShouldReconnect(classifyDisconnect(err)); reconnect {
+				client.workers.spawn(func() { mc.reconnectShard(shard, delay) })
+			}
+		}
+		mc.clients[i] = client
+	}
+
+	return mc
+}
+
+// Shards returns the number of connections
+func (mc *MultiClient) Shards() int {
+	return len(mc.clients)
+}
+
+// Client returns the underlying client for a shard
+func (mc *MultiClient) Client(shard int) *ODINMarketFeedClient {
+	return mc.clients[shard]
+}
+
+// ShardFor returns the shard an instrument is assigned to
+func (mc *MultiClient) ShardFor(marketSegmentID, token int) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(strconv.Itoa(marketSegmentID) + "_" + strconv.Itoa(token)))
+	return int(hash.Sum32() % uint32(len(mc.clients)))
+}
+
+// Connect connects every shard with the same credentials
+func (mc *MultiClient) Connect(host string, port int, useSSL bool, userID string, apiKey string) error {
+	credentials := make([]Credential, len(mc.clients))
+	for i := range credentials {
+		credentials[i] = Credential{UserID: userID, APIKey: apiKey}
+	}
+	return mc.ConnectWithCredentials(host, port, useSSL, credentials)
+}
+
+// ConnectAny connects every shard to the first endpoint that accepts it. Lost shards
+// reconnect to the endpoint that last worked for them and fall back to the others.
+func (mc *MultiClient) ConnectAny(endpoints []Endpoint, userID string, apiKey string) error {
+	credentials := make([]Credential, len(mc.clients))
+	for i := range credentials {
+		credentials[i] = Credential{UserID: userID, APIKey: apiKey}
+	}
+	return mc.connect(endpoints, credentials)
+}
+
+// ConnectWithCredentials connects shard i using credentials[i]
+func (mc *MultiClient) ConnectWithCredentials(host string, port int, useSSL bool, credentials []Credential) error {
+	return mc.connect([]Endpoint{{Host: host, Port: port, UseSSL: useSSL}}, credentials)
+}
+
+func (mc *MultiClient) connect(endpoints []Endpoint, credentials []Credential) error {
+	if len(credentials) != len(mc.clients) {
+		return fmt.Errorf("expected %d credentials, got %d", len(mc.clients), len(credentials))
+	}
+
+	mc.mu.Lock()
+	if mc.closed {
+		mc.mu.Unlock()
+		return ErrClientDisposed
+	}
+	mc.endpoints = endpoints
+	mc.credentials = credentials
+	mc.mu.Unlock()
+
+	var errs []error
+	for i, client := range mc.clients {
+		if err := client.ConnectAny(endpoints, credentials[i].UserID, credentials[i].APIKey); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reconnectPolicy returns the ReconnectPolicy of the shards
+func (mc *MultiClient) reconnectPolicy() ReconnectPolicy {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	if mc.ReconnectPolicy != nil {
+		return mc.ReconnectPolicy
+	}
+	return DefaultReconnectPolicy{ReconnectOnDuplicateSession: mc.ReconnectOnDuplicateSession}
+}
+
+// reconnectShard reconnects a lost shard and replays only that shard's subscriptions. delay
+// replaces ReconnectDelay before the first attempt when non-zero.
+func (mc *MultiClient) reconnectShard(shard int, delay time.Duration) {
+	client := mc.clients[shard]
+	client.Disconnect()
+
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		mc.mu.Lock()
+		if mc.closed {
+			mc.mu.Unlock()
+			return
+		}
+		endpoints, credential := mc.endpoints, mc.credentials[shard]
+		if delay <= 0 {
+			delay = mc.ReconnectDelay
+		}
+		mc.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-mc.stop:
+			timer.Stop()
+			return
+		}
+
+		client.emit(ReconnectAttempt{EventSource: client.source(), N: attempt, Err: lastErr})
+		if err := client.ConnectAny(endpoints, credential.UserID, credential.APIKey); err != nil {
+			lastErr = err
+			reason := classifyDisconnect(err)
+			reason.Attempt = attempt
+
+			var reconnect bool
+			if reconnect, delay = mc.reconnectPolicy().ShouldReconnect(reason); !reconnect {
+				client.reportError(fmt.Sprintf("Reconnect abandoned after %d attempts (%s): %v", attempt, reason.Cause, err))
+				return
+			}
+			continue
+		}
+
+		err := client.ResubscribeAll()
+		if mc.OnReconnect != nil {
+			mc.OnReconnect(shard, err)
+		}
+		return
+	}
+}
+
+// shardTokens groups 'MarketSegmentID_Token' items by shard. Items that cannot be parsed go
+// to shard 0 so that its client reports them.
+func (mc *MultiClient) shardTokens(tokenList []string) map[int][]string {
+	shards := make(map[int][]string)
+	for _, item := range tokenList {
+		shard := 0
+		if instrument, err := ParseInstrument(item); err == nil {
+			shard = mc.ShardFor(instrument.MarketSegmentID, instrument.Token)
+		}
+		shards[shard] = append(shards[shard], item)
+	}
+	return shards
+}
+
+func (mc *MultiClient) forEachShard(tokenList []string, send func(client *ODINMarketFeedClient, tokenList []string) error) error {
+	if len(tokenList) == 0 {
+		return fmt.Errorf("token list cannot be empty")
+	}
+
+	var errs []error
+	for shard, tokens := range mc.shardTokens(tokenList) {
+		if err := send(mc.clients[shard], tokens); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", shard, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SubscribeTouchline subscribes each token on its shard
+//
+// Deprecated: use SubscribeTouchlineWithOptions.
+func (mc *MultiClient) SubscribeTouchline(tokenList []string, responseType string, ltpChangeOnly bool) error {
+	return mc.forEachShard(tokenList, func(client *ODINMarketFeedClient, tokens []string) error {
+		return client.SubscribeTouchline(tokens, responseType, ltpChangeOnly)
+	})
+}
+
+// SubscribeTouchlineWithOptions subscribes each token on its shard
+func (mc *MultiClient) SubscribeTouchlineWithOptions(tokenList []string, opts TouchlineOptions) error {
+	return mc.forEachShard(tokenList, func(client *ODINMarketFeedClient, tokens []string) error {
+		return client.SubscribeTouchlineWithOptions(tokens, opts)
+	})
+}
+
+// UnsubscribeTouchline unsubscribes each token on its shard
+func (mc *MultiClient) UnsubscribeTouchline(tokenList []string) error {
+	return mc.forEachShard(tokenList, (*ODINMarketFeedClient).UnsubscribeTouchline)
+}
+
+// SubscribeLTPTouchline subscribes each token to LTP touchline on its shard
+func (mc *MultiClient) SubscribeLTPTouchline(tokenList []string) error {
+	return mc.forEachShard(tokenList, (*ODINMarketFeedClient).SubscribeLTPTouchline)
+}
+
+// UnsubscribeLTPTouchline unsubscribes each token from LTP touchline on its shard
+func (mc *MultiClient) UnsubscribeLTPTouchline(tokenList []string) error {
+	return mc.forEachShard(tokenList, (*ODINMarketFeedClient).UnsubscribeLTPTouchline)
+}
+
+// SubscribeBestFive subscribes to Best Five on the token's shard
+func (mc *MultiClient) SubscribeBestFive(token string, marketSegmentID int) error {
+	return mc.bestFiveClient(token, marketSegmentID).SubscribeBestFive(token, marketSegmentID)
+}
+
+// UnsubscribeBestFive unsubscribes from Best Five on the token's shard
+func (mc *MultiClient) UnsubscribeBestFive(token string, marketSegmentID int) error {
+	return mc.bestFiveClient(token, marketSegmentID).UnsubscribeBestFive(token, marketSegmentID)
+}
+
+func (mc *MultiClient) bestFiveClient(token string, marketSegmentID int) *ODINMarketFeedClient {
+	tokenID, err := strconv.Atoi(strings.TrimSpace(token))
+	if err != nil {
+		return mc.clients[0]
+	}
+	return mc.clients[mc.ShardFor(marketSegmentID, tokenID)]
+}
+
+// Subscriptions returns the tracked subscriptions of all shards
+func (mc *MultiClient) Subscriptions() []Subscription {
+	var result []Subscription
+	for _, client := range mc.clients {
+		result = append(result, client.Subscriptions()...)
+	}
+	return result
+}
+
+// FeedGapStats returns the gap detection counters summed over all shards
+func (mc *MultiClient) FeedGapStats() FeedGapStats {
+	var total FeedGapStats
+	for _, client := range mc.clients {
+		stats := client.FeedGapStats()
+		total.OutOfOrder += stats.OutOfOrder
+		total.Gaps += stats.Gaps
+	}
+	return total
+}
+
+// Close disconnects and disposes every shard. The MultiClient cannot be connected again.
+func (mc *MultiClient) Close() error {
+	mc.mu.Lock()
+	mc.closed = true
+	mc.mu.Unlock()
+	mc.stopOnce.Do(func() { close(mc.stop) })
+
+	var errs []error
+	for i, client := range mc.clients {
+		if err := client.Disconnect(); err != nil {
+			errs = append(errs, fmt.Errorf("shard %d: %w", i, err))
+		}
+		client.Dispose()
+		client.awaitGoroutines()
+		client.awaitWorkers()
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,183 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMultiClientReconnectsOnceAfterServerDrop(t *testing.T) {
+	ms := newMockServer(t, nil)
+	mc := NewMultiClient(1, WithLogger(func(string) {}), WithHeartbeat(40*time.Millisecond))
+	mc.ReconnectDelay = 10 * time.Millisecond
+	var reconnects int32
+	mc.OnReconnect = func(int, error) { atomic.AddInt32(&reconnects, 1) }
+	if err := mc.Connect(ms.host, ms.port, false, "u", "k"); err != nil {
+		t.Fatal(err)
+	}
+	defer mc.Close()
+	ms.next(t, msgCodeLogin)
+
+	// Between two heartbeats, so that the writer and the receive loop both see the drop
+	ms.next(t, msgCodeHeartbeat)
+	time.Sleep(20 * time.Millisecond)
+	ms.dropAll()
+
+	if !eventually(t, 2*time.Second, func() bool { return atomic.LoadInt32(&reconnects) == 1 }) {
+		t.Fatal("the shard did not reconnect")
+	}
+	ms.next(t, msgCodeLogin)
+	time.Sleep(200 * time.Millisecond)
+	if n := atomic.LoadInt32(&reconnects); n != 1 {
+		t.Errorf("%d reconnects, want 1", n)
+	}
+	if n := ms.connections(); n != 2 {
+		t.Errorf("%d connections accepted, want 2", n)
+	}
+	if state := mc.Client(0).State(); state != StateConnected {
+		t.Errorf("shard state %s after the reconnect", state)
+	}
+}
+
+func TestMultiClientConnectAfterClose(t *testing.T) {
+	ms := newMockServer(t, nil)
+	mc := NewMultiClient(2, WithLogger(func(string) {}))
+	if err := mc.Connect(ms.host, ms.port, false, "u", "k"); err != nil {
+		t.Fatal(err)
+	}
+	mc.Close()
+
+	if err := mc.Connect(ms.host, ms.port, false, "u", "k"); !errors.Is(err, ErrClientDisposed) {
+		t.Errorf("Connect after Close: got %v, want ErrClientDisposed", err)
+	}
+	within(t, time.Second, "second Close", func() { mc.Close() })
+}
+
+// shardTestTokens returns instruments 1_1000 to 1_1029
+func shardTestTokens() []string {
+	tokens := make([]string, 30)
+	for i := range tokens {
+		tokens[i] = fmt.Sprintf("1_%d", 1000+i)
+	}
+	return tokens
+}
+
+func TestMultiClientSpreadsTokensOverShards(t *testing.T) {
+	ms := newMockServer(t, nil)
+	mc := NewMultiClient(3, WithLogger(func(string) {}))
+	if err := mc.Connect(ms.host, ms.port, false, "u", "k"); err != nil {
+		t.Fatal(err)
+	}
+	defer mc.Close()
+
+	if err := mc.SubscribeTouchlineWithOptions(shardTestTokens(), TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	total := 0
+	for shard := 0; shard < mc.Shards(); shard++ {
+		subscriptions := mc.Client(shard).Subscriptions()
+		if len(subscriptions) == 0 {
+			t.Errorf("shard %d holds no subscriptions", shard)
+		}
+		for _, sub := range subscriptions {
+			if owner := mc.ShardFor(sub.Instrument.MarketSegmentID, sub.Instrument.Token); owner != shard {
+				t.Errorf("%s subscribed on shard %d, assigned to shard %d", sub.Instrument, shard, owner)
+			}
+		}
+		total += len(subscriptions)
+	}
+	if total != 30 {
+		t.Errorf("%d subscriptions over all shards, want 30", total)
+	}
+}
+
+func TestMultiClientReplaysOnlyTheDroppedShard(t *testing.T) {
+	var mu sync.Mutex
+	touchlines := make(map[*mockConn][]string)
+	ms := newMockServer(t, func(c *mockConn, request string) {
+		if messageCode(request) == msgCodeTouchline {
+			mu.Lock()
+			touchlines[c] = append(touchlines[c], request)
+			mu.Unlock()
+		}
+	})
+	mc := NewMultiClient(2, WithLogger(func(string) {}))
+	mc.ReconnectDelay = 10 * time.Millisecond
+	reconnected := make(chan int, 2)
+	mc.OnReconnect = func(shard int, err error) {
+		if err != nil {
+			t.Errorf("shard %d resubscribe: %v", shard, err)
+		}
+		reconnected <- shard
+	}
+	if err := mc.Connect(ms.host, ms.port, false, "u", "k"); err != nil {
+		t.Fatal(err)
+	}
+	defer mc.Close()
+	if err := mc.SubscribeTouchlineWithOptions(shardTestTokens(), TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	shardOf := func(request string) map[int]bool {
+		shards := make(map[int]bool)
+		for _, item := range shardTestTokens() {
+			instrument, _ := ParseInstrument(item)
+			if strings.Contains(request, fmt.Sprintf("7=%d", instrument.Token)) {
+				shards[mc.ShardFor(instrument.MarketSegmentID, instrument.Token)] = true
+			}
+		}
+		return shards
+	}
+
+	// The shards connect in order, so the first connection is shard 0
+	ms.mu.Lock()
+	first, second := ms.conns[0], ms.conns[1]
+	ms.mu.Unlock()
+	eventually(t, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(touchlines[first]) > 0 && len(touchlines[second]) > 0
+	})
+	mu.Lock()
+	before := len(touchlines[second])
+	mu.Unlock()
+
+	first.ws.NetConn().Close()
+	select {
+	case shard := <-reconnected:
+		if shard != 0 {
+			t.Fatalf("shard %d reconnected, want shard 0", shard)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shard 0 did not reconnect")
+	}
+
+	replacement := ms.latest()
+	if !eventually(t, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(touchlines[replacement]) > 0
+	}) {
+		t.Fatal("no subscriptions replayed on the new connection")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	replayed := 0
+	for _, request := range touchlines[replacement] {
+		for shard := range shardOf(request) {
+			if shard != 0 {
+				t.Errorf("shard 0 replayed an instrument of shard %d: %q", shard, request)
+			}
+		}
+		replayed += strings.Count(request, "7=")
+	}
+	if want := len(mc.Client(0).Subscriptions()); replayed != want {
+		t.Errorf("%d instruments replayed, want the %d of shard 0", replayed, want)
+	}
+	if n := len(touchlines[second]); n != before {
+		t.Errorf("shard 1 sent %d more touchline requests", n-before)
+	}
+}
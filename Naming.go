@@ -0,0 +1,50 @@
+package ODINMarketFeed
+
+import "fmt"
+
+// EventSource identifies the client that emitted an event. Client is the WithName label of
+// the client, empty when none was set.
+type EventSource struct {
+	Client string
+}
+
+// WithName labels the client, so that several clients in one process can be told apart. The
+// name prefixes the log lines of the client and is reported in events, Stats, tracing spans
+// and health responses.
+func WithName(name string) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.name = name
+	}
+}
+
+// WithLogger passes the log lines of the client, such as "Connected" and the requests sent,
+// to logger instead of printing them on standard output. Like the callbacks, logger is
+// never invoked while the client holds one of its locks.
+func WithLogger(logger func(line string)) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.logger = logger
+	}
+}
+
+// Name returns the WithName label of the client
+func (tw *ODINMarketFeedClient) Name() string {
+	return tw.name
+}
+
+// source returns the EventSource of the events emitted by the client
+func (tw *ODINMarketFeedClient) source() EventSource {
+	return EventSource{Client: tw.name}
+}
+
+// logf writes a log line, prefixed with the client name, to the logger or standard output
+func (tw *ODINMarketFeedClient) logf(format string, args ...interface{}) {
+	line := fmt.Sprintf(format, args...)
+	if tw.name != "" {
+		line = "[" + tw.name + "] " + line
+	}
+	if tw.logger != nil {
+		tw.logger(line)
+		return
+	}
+	fmt.Println(line)
+}
@@ -0,0 +1,5 @@
+//go:build !race
+
+package ODINMarketFeed
+
+const raceEnabled = false
@@ -0,0 +1,115 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Severity classifies an administrative notice
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+)
+
+// String returns the name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityUnknown:
+		return "Unknown"
+	case SeverityInfo:
+		return "Info"
+	case SeverityWarning:
+		return "Warning"
+	case SeverityError:
+		return "Error"
+	default:
+		return fmt.Sprintf("Severity(%d)", int(s))
+	}
+}
+
+// Notice is an administrative message of the gateway, such as an early market close or a
+// rejected token. Code is the 64= code, or -1 for text frames. Instrument is set when the
+// notice names a market segment and token.
+type Notice struct {
+	Severity   Severity
+	Code       int
+	Text       string
+	Instrument *Instrument
+	// Seq is the ParsedMessage.Seq of the message or text frame that carried the notice
+	Seq uint64
+}
+
+// String formats the notice for logging
+func (n Notice) String() string {
+	if n.Instrument != nil {
+		return fmt.Sprintf("[%s] 64=%d %s: %s", n.Severity, n.Code, n.Instrument, n.Text)
+	}
+	return fmt.Sprintf("[%s] 64=%d: %s", n.Severity, n.Code, n.Text)
+}
+
+// WithNoticeCode classifies messages with the 64= code as notices of the given severity.
+// The gateway's notice codes are not documented, so none are registered by default and
+// only the messages of registered codes, and text frames, are notices.
+func WithNoticeCode(code int, severity Severity) Option {
+	return func(tw *ODINMarketFeedClient) {
+		if tw.noticeCodes == nil {
+			tw.noticeCodes = make(map[int]Severity)
+		}
+		tw.noticeCodes[code] = severity
+	}
+}
+
+// WithNoticePassthrough keeps delivering notices to OnMessage when OnNotice is set
+func WithNoticePassthrough() Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.noticePassthrough = true
+	}
+}
+
+// parseNotice interprets a message of a WithNoticeCode code as a notice. Messages with a
+// binary block or a code the decoder handles are never notices.
+func (tw *ODINMarketFeedClient) parseNotice(msg ParsedMessage) (Notice, bool) {
+	if msg.Kind != MessageUnknown || tw.decoder.knownCode(msg.Code) || bytes.Contains(msg.Raw, binaryTag) {
+		return Notice{}, false
+	}
+
+	severity, registered := tw.noticeCodes[msg.Code]
+	if !registered {
+		return Notice{}, false
+	}
+	notice := Notice{Severity: severity, Code: msg.Code, Seq: msg.Seq}
+
+	var segID, token string
+	var parts []string
+	for _, field := range parseFields(msg.Text, tw.decoder.pairDelimiter()) {
+		switch field.Tag {
+		case 63, 64, 65, 66:
+			continue
+		case 1:
+			segID = field.Value
+			continue
+		case 7:
+			token = field.Value
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%d=%s", field.Tag, field.Value))
+	}
+
+	notice.Text = strings.Join(parts, "|")
+	if segID != "" && token != "" {
+		if instrument, err := ParseInstrument(segID + "_" + token); err == nil {
+			notice.Instrument = &instrument
+		}
+	}
+	return notice, true
+}
+
+// deliverNotice passes a notice to OnNotice
+func (tw *ODINMarketFeedClient) deliverNotice(notice Notice) {
+	tw.invokeCallback("OnNotice", notice.String, func() { tw.OnNotice(notice) })
+}
@@ -0,0 +1,40 @@
+package ODINMarketFeed
+
+import "testing"
+
+func TestNoticesAreRegisteredCodesOnly(t *testing.T) {
+	tw := newTestClient(WithNoticeCode(900, SeverityWarning))
+	var notices []Notice
+	var unknown []int
+	tw.OnNotice = func(notice Notice) { notices = append(notices, notice) }
+	tw.OnUnknownMessage = func(code int, _ []byte) { unknown = append(unknown, code) }
+
+	tw.responseReceived(frameOf(
+		[]byte("63=FT3.0|64=900|1=1|7=22|100=Early close at 15:00"),
+		[]byte("63=FT3.0|64=901|100=Free text of an unregistered code"),
+	), 0)
+
+	if len(notices) != 1 || notices[0].Severity != SeverityWarning || notices[0].Instrument == nil || notices[0].Instrument.Token != 22 {
+		t.Fatalf("notices %+v, want one warning for token 22", notices)
+	}
+	if len(unknown) != 1 || unknown[0] != 901 {
+		t.Errorf("unknown codes %v, want [901]", unknown)
+	}
+}
+
+func TestConsumedNoticeIsLeftOutOfBatch(t *testing.T) {
+	tw := newTestClient(WithNoticeCode(900, SeverityInfo))
+	var batch []ParsedMessage
+	var notices int
+	tw.OnNotice = func(Notice) { notices++ }
+	tw.OnMessageBatch = func(msgs []ParsedMessage) { batch = append(batch, msgs...) }
+
+	tw.responseReceived(frameOf([]byte("63=FT3.0|64=900|100=Market halted"), touchlineMessage(1, 22, 24500)), 0)
+
+	if notices != 1 {
+		t.Errorf("%d notices, want 1", notices)
+	}
+	if len(batch) != 1 || batch[0].Kind != MessageTouchline {
+		t.Errorf("batch %+v, want only the touchline", batch)
+	}
+}
@@ -34,7 +34,7 @@ package ODINMarketFeed
 import (
 	"bytes"
 	"compress/zlib"
-	"encoding/binary"
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -43,6 +43,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -57,6 +58,9 @@ const (
 )
 
 // MarketData represents market data structure
+//
+// Deprecated: MarketData is not produced by the feed decoder. Use TouchlineData, or
+// TouchlineData.MarketData to convert to this layout.
 type MarketData struct {
 	MktSegID       uint32
 	Token          uint32
@@ -66,7 +70,96 @@ type MarketData struct {
 	DecimalLocator uint32
 }
 
-// ZLIBCompressor handles ZLIB compression/decompression
+// TouchlineData represents a decoded touchline packet
+type TouchlineData struct {
+	MktSegID             uint32
+	Token                uint32
+	Symbol               string
+	LUT                  time.Time
+	LTT                  time.Time
+	LTP                  uint32
+	BuyQty               uint32
+	BuyPrice             uint32
+	SellQty              uint32
+	SellPrice            uint32
+	OpenPrice            uint32
+	HighPrice            uint32
+	LowPrice             uint32
+	ClosePrice           uint32
+	DecimalLocator       uint32
+	PrevClosePrice       uint32
+	IndicativeClosePrice uint32
+
+	// Extended reports whether the extended touchline fields below were decoded, which
+	// requires WithExtendedTouchline. TotalTradedValue and ATP are raw values, scaled by
+	// DecimalLocator like the prices.
+	Extended         bool
+	TotalTradedValue int64
+	ATP              uint32
+
+	// OutOfSession reports whether the LTT falls outside the session windows configured for
+	// the segment (see SetSessionWindow)
+	OutOfSession bool
+
+	// NetChange is the LTP, or before the first trade the indicative price, minus the
+	// previous close, scaled by DecimalLocator. PercentChange is the change relative to the
+	// previous close in percent. Both are only valid when ChangeOk is set; without a
+	// previous close, e.g. for a new listing, PercentChange is NaN.
+	NetChange     float64
+	PercentChange float64
+	ChangeOk      bool
+}
+
+// String formats the touchline data as pipe-delimited tag=value pairs
+func (td TouchlineData) String() string {
+	var sb strings.Builder
+	writeTag := func(tag string, value uint32) {
+		sb.WriteString(tag + "=" + strconv.FormatUint(uint64(value), 10) + "|")
+	}
+
+	writeTag("1", td.MktSegID)
+	writeTag("7", td.Token)
+	sb.WriteString("74=" + td.LUT.Format("2006-01-02 150405") + "|")
+	sb.WriteString("73=" + td.LTT.Format("2006-01-02 150405") + "|")
+	writeTag("8", td.LTP)
+	writeTag("2", td.BuyQty)
+	writeTag("3", td.BuyPrice)
+	writeTag("5", td.SellQty)
+	writeTag("6", td.SellPrice)
+	writeTag("75", td.OpenPrice)
+	writeTag("77", td.HighPrice)
+	writeTag("78", td.LowPrice)
+	writeTag("76", td.ClosePrice)
+	writeTag("399", td.DecimalLocator)
+	writeTag("250", td.PrevClosePrice)
+	writeTag("88", td.IndicativeClosePrice)
+	return sb.String()
+}
+
+// feedEpoch is the reference time of the second counts carried in touchline packets
+var feedEpoch = time.Date(1980, 1, 1, 0, 0, 0, 0, time.Local)
+
+// MarketData converts the touchline to the legacy MarketData layout, with LUT as seconds
+// since the feed epoch
+//
+// Deprecated: use TouchlineData directly.
+func (td TouchlineData) MarketData() MarketData {
+	var lut uint32
+	if !td.LUT.IsZero() {
+		lut = uint32(int32(td.LUT.Sub(feedEpoch) / time.Second))
+	}
+
+	return MarketData{
+		MktSegID:       td.MktSegID,
+		Token:          td.Token,
+		LUT:            lut,
+		LTP:            td.LTP,
+		ClosePrice:     td.ClosePrice,
+		DecimalLocator: td.DecimalLocator,
+	}
+}
+
+// ZLIBCompressor handles ZLIB compression/decompression. It is the default Decompressor.
 type ZLIBCompressor struct{}
 
 // Compress compresses data using ZLIB
@@ -100,61 +193,159 @@ func (z *ZLIBCompressor) Uncompress(data []byte) ([]byte, error) {
 	return buf.Bytes(), nil
 }
 
+// UncompressLimited decompresses data using ZLIB, reading at most maxSize bytes of output.
+// It returns ErrDecompressedTooLarge as soon as the payload is found to exceed maxSize, so
+// a small packet that decompresses to a huge payload is rejected without buffering it.
+func (z *ZLIBCompressor) UncompressLimited(data []byte, maxSize int) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return readLimited(reader, maxSize)
+}
+
+func (z *ZLIBCompressor) reader(data []byte) (io.ReadCloser, error) {
+	return zlib.NewReader(bytes.NewReader(data))
+}
+
 // FragmentationHandler handles message fragmentation
 type FragmentationHandler struct {
 	memoryStream        *bytes.Buffer
 	lastWrittenIndex    int
 	isDisposed          bool
-	zlibCompressor      *ZLIBCompressor
 	UnCompressMsgLength int
-	HeaderLength        int
-	mu                  sync.Mutex
-	headerChar          []byte
-	IsUncompress        bool
+	// HeaderLength is the size of a frame header. It is informational: headers are always
+	// parsed as FrameHeaderSize bytes by DecodeFrame.
+	HeaderLength int
+	mu           sync.Mutex
+	IsUncompress bool
+
+	// MaxDecompressedSize limits the decompressed payload of each outer frame; larger frames
+	// are discarded. 0 derives the limit of each frame from its inner headers, see
+	// WithMaxDecompressedSize.
+	MaxDecompressedSize int
+
+	// reportDiscards makes defragmentData record dropped bytes for takeDiscards
+	reportDiscards bool
+	discards       []DiscardError
+
+	// primary is the configured Decompressor and decompressor the one in use on the current
+	// connection, which WithDecompressorAutoDetect may switch to one of the fallbacks.
+	// detected is set once the first frame of the connection has been decoded; switchNote
+	// describes a switch until the client logs it.
+	primary      Decompressor
+	decompressor Decompressor
+	fallbacks    []Decompressor
+	detected     bool
+	switchNote   string
+
+	// generation is incremented by Reset so that frames of a previous connection are dropped
+	generation uint64
+
+	// compressedIn and decompressedOut count the outer frame payloads decompressed and the
+	// bytes they produced
+	compressedIn    uint64
+	decompressedOut uint64
+
+	// keepAlives counts the empty frames and the outer frames declaring a zero length, which
+	// the gateway sends as keep-alives
+	keepAlives uint64
+
+	// resyncBytes counts the bytes skipped while searching for a valid outer header
+	resyncBytes uint64
+
+	// padBytes counts the CR, LF, NUL and space bytes skipped between inner messages
+	padBytes uint64
+
+	// inboundTransform is applied to each decompressed payload, see SetInboundPayloadTransform
+	inboundTransform PayloadTransform
 }
 
-const (
-	minimumPacketSize = 5
-	packetHeaderSize  = 5
-	//messageLengthLen  = 5
-)
-
 // NewFragmentationHandler creates a new FragmentationHandler
 func NewFragmentationHandler() *FragmentationHandler {
 	return &FragmentationHandler{
 		memoryStream:     bytes.NewBuffer(nil),
 		lastWrittenIndex: -1,
 		isDisposed:       false,
-		zlibCompressor:   &ZLIBCompressor{},
-		headerChar:       make([]byte, 5),
+		primary:          &ZLIBCompressor{},
+		decompressor:     &ZLIBCompressor{},
 		IsUncompress:     false,
-		HeaderLength:     6,
+		HeaderLength:     FrameHeaderSize,
 	}
 }
 
-// FragmentData fragments and compresses data for sending
+// FragmentData compresses data and wraps it in an outer frame for sending
 func (fh *FragmentationHandler) FragmentData(data []byte) ([]byte, error) {
-	compressed, err := fh.zlibCompressor.Compress(data)
+	compressed, err := fh.compressor().Compress(data)
 	if err != nil {
 		return nil, err
 	}
+	return EncodeFrame(Frame{Flag: FrameCompressed, Payload: compressed})
+}
+
+// Defragment defragments received data. Defragment, Reset and Dispose may be called from
+// different goroutines; after Dispose, Defragment returns no messages.
+func (fh *FragmentationHandler) Defragment(data []byte) ([][]byte, error) {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+
+	return fh.defragmentLocked(data)
+}
+
+// defragmentGeneration defragments data received on the connection that was started with
+// the given Reset generation; data of an older connection is dropped
+func (fh *FragmentationHandler) defragmentGeneration(generation uint64, data []byte) ([][]byte, error) {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+
+	if generation != fh.generation {
+		return nil, nil
+	}
+	return fh.defragmentLocked(data)
+}
 
-	lengthString := fmt.Sprintf("%06d", len(compressed))
-	lenBytes := []byte(lengthString)
-	lenBytes[0] = 5 // compression flag
+// Reset discards buffered partial frames and pending discards, so that the first frame of a
+// new connection is not appended to bytes left over from the previous one
+func (fh *FragmentationHandler) Reset() {
+	fh.reset()
+}
+
+// reset clears the buffer and returns the new generation
+func (fh *FragmentationHandler) reset() uint64 {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
 
-	result := append(lenBytes, compressed...)
-	return result, nil
+	fh.memoryStream = bytes.NewBuffer(nil)
+	fh.lastWrittenIndex = -1
+	fh.UnCompressMsgLength = 0
+	fh.IsUncompress = false
+	fh.discards = nil
+	fh.decompressor = fh.primary
+	fh.detected = false
+	fh.generation++
+	return fh.generation
 }
 
-// Defragment defragments received data
-func (fh *FragmentationHandler) Defragment(data []byte) ([][]byte, error) {
+// Dispose releases the buffer. Later Defragment calls return no messages.
+func (fh *FragmentationHandler) Dispose() {
 	fh.mu.Lock()
 	defer fh.mu.Unlock()
 
+	fh.isDisposed = true
+	fh.memoryStream = bytes.NewBuffer(nil)
+	fh.lastWrittenIndex = -1
+	fh.discards = nil
+}
+
+func (fh *FragmentationHandler) defragmentLocked(data []byte) ([][]byte, error) {
 	if fh.isDisposed {
 		return nil, nil
 	}
+	if len(data) == 0 {
+		fh.keepAlives++
+		return nil, nil
+	}
 
 	// Write data to memory stream
 	fh.memoryStream.Write(data)
@@ -171,128 +362,144 @@ func (fh *FragmentationHandler) defragmentData() ([][]byte, error) {
 	streamData := fh.memoryStream.Bytes()
 	position := 0
 	packetCount := 0
+	skipStart := -1
 
-	for position < fh.lastWrittenIndex-minimumPacketSize && !parseDone {
-		headerEnd := position + packetHeaderSize + 1
-		if headerEnd > len(streamData) {
-			break
-		}
-
-		header := streamData[position:headerEnd]
-		packetSize := fh.isLength(header)
+	for position+FrameHeaderSize <= fh.lastWrittenIndex+1 && !parseDone {
+		frame, size, err := decodeFrame(streamData[position:fh.lastWrittenIndex+1], false)
 
-		if packetSize <= 0 {
-			position++
-			bytesParsed++
+		if errors.Is(err, ErrShortFrame) {
+			// The rest of the frame has not been received yet
+			parseDone = true
+		} else if err != nil {
+			if skipStart < 0 {
+				skipStart = position
+			}
+			// Jump to the next byte that can start an outer header rather than testing the
+			// header at every offset of the garbage
+			skip := fh.lastWrittenIndex + 1 - position
+			if next := nextFrameFlag(streamData[position+1 : fh.lastWrittenIndex+1]); next >= 0 {
+				skip = next + 1
+			}
+			fh.resyncBytes += uint64(skip)
+			position += skip
+			bytesParsed += skip
+		} else if len(frame.Payload) == 0 {
+			// A header declaring no payload is a keep-alive; it is consumed here so that it
+			// cannot shift the offsets of the frames that follow
+			fh.discardSkipped(streamData, skipStart, position)
+			skipStart = -1
+
+			fh.keepAlives++
+			bytesParsed += size
+			position += size
 		} else {
-			dataStart := headerEnd
-			dataEnd := dataStart + packetSize
-
-			if dataEnd <= fh.lastWrittenIndex+1 {
-				compressData := streamData[dataStart:dataEnd]
-				messageData, err := fh.defragmentInnerData(compressData)
-				if err == nil {
-					//packetList = append(packetList, messageData)
-					for {
+			fh.discardSkipped(streamData, skipStart, position)
+			skipStart = -1
+
+			compressData := frame.Payload
+			messageData, err := fh.defragmentInnerData(compressData)
+			if err != nil {
+				fh.discard("decompress", compressData, err)
+			} else {
+				fh.compressedIn += uint64(len(compressData))
+				fh.decompressedOut += uint64(len(messageData))
+				if fh.inboundTransform != nil {
+					if messageData, err = fh.inboundTransform(-1, messageData); err != nil {
+						fh.discard("transform", compressData, err)
+					}
+				}
+			}
+			if err == nil {
+				// The decompressed buffer is owned by this frame, so the messages are sliced
+				// from it directly. The capacity is capped so that appending to one message
+				// cannot overwrite the next.
+				offset := 0
+				for offset < len(messageData) {
+					// Some gateway builds pad inner messages; the padding is not a header
+					if pad := paddingLength(messageData[offset:]); pad > 0 {
+						fh.padBytes += uint64(pad)
+						offset += pad
+						continue
+					}
+					inner, innerSize, err := decodeFrame(messageData[offset:], true)
+					if err != nil || len(inner.Payload) == 0 {
 						fh.UnCompressMsgLength = 0
-						fh.UnCompressMsgLength = fh.GetMessageLength(messageData)
-
-						if fh.UnCompressMsgLength <= 0 {
-							messageData = nil
-							break
-						}
-
-						unCompressBytes := make([]byte, fh.UnCompressMsgLength)
-						copy(unCompressBytes, messageData[fh.HeaderLength:fh.HeaderLength+fh.UnCompressMsgLength])
-						packetList = append(packetList, unCompressBytes)
-						packetCount++
-
-						remainingLength := len(messageData) - fh.UnCompressMsgLength - fh.HeaderLength
-						if remainingLength <= 0 {
-							messageData = nil
-							break
-						}
-
-						unCompressNewBytes := make([]byte, remainingLength)
-						copy(unCompressNewBytes, messageData[fh.UnCompressMsgLength+fh.HeaderLength:])
-						messageData = unCompressNewBytes
+						fh.discard("inner header", messageData[offset:], err)
+						break
 					}
+					fh.IsUncompress = inner.Flag != FrameCompressed
+					fh.UnCompressMsgLength = len(inner.Payload)
+
+					packetList = append(packetList, inner.Payload[:len(inner.Payload):len(inner.Payload)])
+					packetCount++
+					offset += innerSize
 				}
-				bytesParsed += packetHeaderSize + 1 + packetSize
-				position = dataEnd
-			} else {
-				parseDone = true
 			}
+			bytesParsed += size
+			position += size
 		}
 	}
 
+	if skipStart >= 0 {
+		fh.discardSkipped(streamData, skipStart, position)
+	}
+
 	fh.clearProcessedData(bytesParsed)
 	return packetList, nil
 }
 
-func (fh *FragmentationHandler) isLength(header []byte) int {
-	if len(header) != packetHeaderSize+1 {
-		return -1
-	}
-
-	if header[0] != 5 && header[0] != 2 {
-		return -1
-	}
-
-	lengthStr := string(header[1:6])
-	for _, ch := range lengthStr {
-		if ch < '0' || ch > '9' {
-			return -1
-		}
+// discard records dropped data when reportDiscards is set
+func (fh *FragmentationHandler) discard(stage string, data []byte, err error) {
+	if !fh.reportDiscards {
+		return
 	}
+	fh.discards = append(fh.discards, newDiscardError(stage, data, err))
+}
 
-	length, err := strconv.Atoi(lengthStr)
-	if err != nil {
-		return -1
+// discardSkipped records the bytes skipped while searching for a valid outer header
+func (fh *FragmentationHandler) discardSkipped(streamData []byte, skipStart, position int) {
+	if skipStart < 0 {
+		return
 	}
-
-	return length
+	fh.discard("outer header", streamData[skipStart:position], nil)
 }
 
-// GetMessageLength extracts the message length from the message data
-// You'll need to implement this based on your protocol
-func (fh *FragmentationHandler) GetMessageLength(messageData []byte) int {
-	if len(messageData) == 0 {
-		return 0
-	}
+// takeDiscards returns and clears the recorded discards
+func (fh *FragmentationHandler) takeDiscards() []DiscardError {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
 
-	// Check first byte to determine compression
-	if messageData[0] == 5 {
-		fh.IsUncompress = false
-	} else {
-		fh.IsUncompress = true
-	}
+	discards := fh.discards
+	fh.discards = nil
+	return discards
+}
 
-	// Extract length from header
-	defer func() {
-		if r := recover(); r != nil {
-			// Handle panic (equivalent to catch block)
+// nextFrameFlag returns the index of the first byte of data that is an outer frame flag, or
+// -1 if there is none
+func nextFrameFlag(data []byte) int {
+	// One pass that stops at the first flag of either kind, so that each call scans only
+	// the bytes it skips and a resync stays linear in the garbage
+	for i, b := range data {
+		if b == FrameCompressed || b == FrameUncompressed {
+			return i
 		}
-	}()
-
-	startIndex := 0
-	for i := 1; i < fh.HeaderLength && i < len(messageData); i++ {
-		fh.headerChar[startIndex] = messageData[i]
-		startIndex++
 	}
+	return -1
+}
 
-	// Convert bytes to string and parse as integer
-	sLength := string(fh.headerChar[:startIndex])
-	iLength, err := strconv.Atoi(sLength)
+// GetMessageLength returns the payload length declared by the inner frame header at the
+// start of messageData, or 0 if the header is missing or invalid
+func (fh *FragmentationHandler) GetMessageLength(messageData []byte) int {
+	flag, length, err := parseFrameHeader(messageData, true)
 	if err != nil {
 		return 0
 	}
-
-	return iLength
+	fh.IsUncompress = flag != FrameCompressed
+	return length
 }
 
 func (fh *FragmentationHandler) defragmentInnerData(compressData []byte) ([]byte, error) {
-	return fh.zlibCompressor.Uncompress(compressData)
+	return fh.uncompress(compressData, fh.MaxDecompressedSize)
 }
 
 func (fh *FragmentationHandler) clearProcessedData(length int) {
@@ -312,34 +519,242 @@ func (fh *FragmentationHandler) clearProcessedData(length int) {
 	fh.lastWrittenIndex = size - 1
 }
 
-// ODINMarketFeedClient represents the WebSocket client
+// ODINMarketFeedClient represents the WebSocket client.
+//
+// Callbacks and other user supplied functions (resolvers, interceptors, tracers) are never
+// invoked while the client holds one of its internal locks, so they may call any client
+// method, including Disconnect and the Subscribe methods.
+//
+// Within one connection each callback observes messages in wire order, including the
+// interleaving of binary frames and text notices, because every frame is decoded and
+// delivered on the receive loop before the next one is read. Two delivery modes relax this:
+// throttled touchlines (SetTokenThrottle, WithDefaultThrottle) are coalesced per token and
+// delivered from a flusher goroutine of the connection, and with OnMessageBatch the typed
+// callbacks for a frame run before the batch for that frame is delivered.
 type ODINMarketFeedClient struct {
 	conn              *websocket.Conn
 	compressionStatus CompressionStatus
 	channelID         string
 	userID            string
+	name              string
+	logger            func(line string)
 	isDisposed        bool
 	receiveBufferSize int
-	dteNSE            time.Time
 	fragHandler       *FragmentationHandler
+	decoder           Decoder
 
+	// OnOpen is invoked after login; no other callback fires for the connection until it
+	// returns, so it must not block waiting for feed data
 	OnOpen    func()
 	OnMessage func(message string)
-	OnError   func(err string)
-	OnClose   func(code int, reason string)
+	// OnError receives the failures found asynchronously: on the receive loop, by the
+	// heartbeat, while replaying subscriptions after a reconnect and in background timers.
+	// Failures of a method call, such as an invalid token list or a failed send, are only
+	// returned by the method unless WithLegacyErrorCallbacks is set.
+	OnError func(err string)
+	// OnClose is invoked once when a connection's receive loop ends: with
+	// CloseNormalClosure after Disconnect or Close, and after OnError with the close code
+	// (CloseAbnormalClosure if the server sent none) when the connection is lost
+	OnClose func(code int, reason string)
+
+	// OnConnecting is invoked before each dial with the number of consecutive attempts
+	// since the last successful connection, starting at 1
+	OnConnecting func(attempt int, url string)
+
+	// OnTouchline receives each decoded touchline packet
+	OnTouchline func(data TouchlineData)
+	// OnFeedGap is invoked when gap detection finds a missed or out-of-order update
+	OnFeedGap func(segID, token uint32, lastSeen, now time.Time)
+	// OnLTP receives each decoded LTP touchline (64=347) response
+	OnLTP func(update LTPUpdate)
+	// OnBestFive receives each decoded Market Depth (Best Five) response
+	OnBestFive func(data BestFiveData)
+	// OnServerNotice receives text frames sent by the server, such as plain-text error
+	// notifications. When nil, text frames are delivered to OnMessage.
+	OnServerNotice func(notice string)
+	// OnClockSkew is invoked when the estimated server clock offset first exceeds the
+	// WithClockSkewThreshold threshold
+	OnClockSkew func(offset time.Duration)
+
+	// OnMessageBatch receives every message decoded from one websocket frame in a single
+	// call. When set, OnMessage is not invoked; the typed callbacks still are. The slice is
+	// a copy unless WithUnsafeZeroCopy is set for the callback.
+	OnMessageBatch func(msgs []ParsedMessage)
+	// OnRawBatch receives the defragmented inner messages of each websocket frame before
+	// they are decoded, as copies unless WithUnsafeZeroCopy is set for the callback
+	OnRawBatch func(msgs [][]byte)
+
+	// OnJSON receives each decoded touchline and Best Five response encoded as JSON
+	OnJSON func(payload []byte)
+
+	// OnInnerMessage receives each inner message exactly as produced by the defragmenter,
+	// before filtering and decoding, as a copy unless WithUnsafeZeroCopy is set for the
+	// callback
+	OnInnerMessage func(raw []byte)
+
+	// OnNotice receives the administrative messages of the gateway registered with
+	// WithNoticeCode, such as early close announcements, and text frames. Unless
+	// WithNoticePassthrough is set they are not delivered to OnMessage, OnMessageBatch or
+	// OnUnknownMessage; OnServerNotice still receives text frames.
+	OnNotice func(notice Notice)
+
+	// OnUnknownMessage receives messages whose 64= code the decoder does not recognise, with
+	// a copy of the raw inner message unless WithUnsafeZeroCopy is set for the callback. When
+	// nil they are delivered to OnMessage.
+	OnUnknownMessage func(code int, raw []byte)
+
+	// OnQuotaWarning is invoked when the subscribed instruments approach or exceed the
+	// WithSubscriptionLimit limit
+	OnQuotaWarning func(warning QuotaWarning)
+
+	// OnResubscribeIncomplete receives the instruments that stayed silent after
+	// ResubscribeAll when WithResubscribeVerification is set
+	OnResubscribeIncomplete func(silent []Instrument)
+
+	// OnThrottled receives the throttle episodes detected by WithThrottleNotice
+	OnThrottled func(event Throttled)
+
+	// OnMemoryPressure is invoked when buffered messages start being dropped to stay within
+	// the WithDeliveryMemoryLimit limit
+	OnMemoryPressure func(event MemoryPressure)
+
+	// OnSubscriptionAck receives the accepted and rejected instruments of each touchline
+	// subscription ack decoded with WithSubscriptionAcks
+	OnSubscriptionAck func(ack SubscriptionAck)
+
+	// OnDayRollover is invoked after the daily state has been reset at the WithDayRollover
+	// time
+	OnDayRollover func(event DayRollover)
+
+	resolver SymbolResolver
+
+	subscriptions map[subscriptionKey]Subscription
+	direct        map[subscriptionKey]struct{} // subscribed by the application, see trackSubscribe
+	universe      map[subscriptionKey]struct{} // subscribed by SetTouchlineUniverse
+	subMu         sync.Mutex
+	quota         subscriptionQuota
+	groups        map[string]*SubscriptionGroup
+
+	gatewayThrottle *gatewayThrottle
+
+	queueEnabled    bool
+	maxQueued       int
+	flushing        bool
+	preConnectQueue []queuedRequest
+
+	connURL     string
+	connectedAt time.Time
+	generation  uint64
+	wireSeq     uint64            // Seq of the last message received on the connection
+	lastLoss    *DisconnectReason // why the last connection was lost, until a new one is established
+
+	connectAttempts   int
+	receiveDone       chan struct{}
+	endConn           func() // stops the writer and heartbeat of the connection
+	sendQueue         *sendQueue
+	closing           int32
+	manual            manualLoop
+	memory            deliveryMemory
+	manualWriteMu     sync.Mutex
+	sessionErr        error
+	instanceLock      *instanceLock
+	instanceLockPath  string
+	endpoint          Endpoint
+	preferredEndpoint int
+
+	gapDetector *gapDetector
+	depthCache  *depthCache
+
+	interceptors interceptors
+	tracer       Tracer
+	jsonOptions  JSONOptions
+	quotes       quoteWaiters
+	acks         subscriptionAcks
+	header       requestHeaderConfig
+
+	unsubscribeOnClose  bool
+	maxTokensPerRequest int
+	lenientBatchErrors  bool
+	strictErrors        bool
+	failFastCallbacks   bool
+	sessions            sessionWindows
+	tickSink            atomic.Pointer[deliverySink[TouchlineData]]
+	messageSink         atomic.Pointer[deliverySink[ParsedMessage]]
+	iteratorBuffer      int
+	staleFeed           staleFeedWatch
+	idleWarned          int32
+	segmentFilter       atomic.Pointer[map[uint32]struct{}]
+	unknownCodes        unknownCodes
+	sendFailures        sendFailures
+	archive             atomic.Pointer[archiveWriter]
+	publisher           atomic.Pointer[publisherAttachment]
+	csvWriters          atomic.Pointer[[]*TickCSVWriter]
+	sendTransform       atomic.Pointer[PayloadTransform]
+	closeTimeout        time.Duration
+	noticeCodes         map[int]Severity
+	noticePassthrough   bool
+	routines            goroutineGroup
+	workers             goroutineGroup
+	switchOnConnect     bool
+	versionTag          int
+	subFile             *subscriptionFile
+	keepUserIDCase      bool
+	priceScaler         *PriceScaler
+	tokenStats          *tokenTracker
+	resubCheck          *resubscribeCheck
+	rollover            *dayRollover
+	rolloverClock       func() time.Time
+	correlation         *requestCorrelation
+	conformance         *conformance
+	legacyErrors        bool
+	cacheCapacity       int
+	cachePurgeDelay     time.Duration
+	dedup               *tickDedup
+
+	events   eventStream
+	frames   frameStats
+	batchBuf []ParsedMessage
+	zeroCopy map[string]bool
+	throttle tokenThrottle
+
+	wireDump atomic.Pointer[wireDumper]
+
+	dialer                 dialerConfig
+	heartbeatInterval      time.Duration
+	heartbeatReplyDisabled bool
+	heartbeatPending       int32
+	heartbeatMisses        int32
+	heartbeatMissLimit     int
+	clock                  clockEstimator
+	requestClock           requestClock
+
+	stats clientStats
+
+	state          ConnectionState
+	reconnectTimes []time.Time
+
+	// onConnectionLost is invoked when the receive loop ends without a local Disconnect
+	onConnectionLost func(err error)
 
 	mu sync.Mutex
 }
 
 // NewODINMarketFeedClient creates a new ODINMarketFeedClient instance
-func NewODINMarketFeedClient() *ODINMarketFeedClient {
-	return &ODINMarketFeedClient{
+func NewODINMarketFeedClient(opts ...Option) *ODINMarketFeedClient {
+	tw := &ODINMarketFeedClient{
 		compressionStatus: CompressionON,
 		channelID:         "Broadcast",
 		receiveBufferSize: 8192,
 		fragHandler:       NewFragmentationHandler(),
-		dteNSE:            time.Date(1980, 1, 1, 0, 0, 0, 0, time.Local),
+		decoder:           Decoder{Epoch: feedEpoch, segments: newSegmentConfigs()},
+		subscriptions:     make(map[subscriptionKey]Subscription),
 	}
+
+	for _, opt := range opts {
+		opt(tw)
+	}
+	tw.startDayRollover()
+	return tw
 }
 
 // SetCompression enables or disables compression
@@ -351,354 +766,415 @@ func (tw *ODINMarketFeedClient) SetCompression(enabled bool) {
 	}
 }
 
-// Connect connects to the WebSocket server
+// Connect connects to the WebSocket server and logs in with the API key (may be empty)
 func (tw *ODINMarketFeedClient) Connect(host string, port int, useSSL bool, userID string, apiKey string) error {
+	return tw.ConnectWithLogin(host, port, useSSL, userID, LoginOptions{
+		Credentials: Credentials{Mode: CredentialAPIKey, Secret: apiKey},
+	})
+}
 
-	// Validate host
-	if strings.TrimSpace(host) == "" {
-		return errors.New("host cannot be empty")
+// ConnectWithLogin connects to the WebSocket server and logs in using the given login options.
+// Dial and login failures are returned without invoking OnError. Before dialing it waits for
+// the goroutines of the previous connection to exit.
+func (tw *ODINMarketFeedClient) ConnectWithLogin(host string, port int, useSSL bool, userID string, login LoginOptions) (err error) {
+	if err := tw.checkDisposed(); err != nil {
+		return err
 	}
 
-	// Validate host format (hostname or IP address)
-	if net.ParseIP(host) == nil {
-		// Not a valid IP, check if it's a valid hostname
-		hostnameRegex := regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?$`)
-		if !hostnameRegex.MatchString(host) {
-			return fmt.Errorf("invalid host format: %s", host)
-		}
+	url, err := buildURL(host, port, useSSL)
+	if err != nil {
+		return err
 	}
 
-	// Validate port range
-	if port < 1 || port > 65535 {
-		return fmt.Errorf("port must be between 1 and 65535, got: %d", port)
+	userID, err = tw.normalizeUserID(userID)
+	if err != nil {
+		return err
 	}
 
-	// Validate userID
-	if strings.TrimSpace(userID) == "" {
-		return errors.New("userID cannot be empty")
+	loginFields, err := tw.withVersionTag(login).fields()
+	if err != nil {
+		return err
 	}
 
-	// Validate userID length
+	dialer, err := tw.websocketDialer()
+	if err != nil {
+		tw.returnedError(fmt.Sprintf("Connection failed: %v", err))
+		return err
+	}
 
-	if len(userID) > 12 {
-		return errors.New("userID is too long (max 12 characters)")
+	if err := tw.acquireInstanceLock(); err != nil {
+		tw.returnedError(fmt.Sprintf("Connection failed: %v", err))
+		return err
+	}
+
+	replay, err := tw.beginConnect()
+	if err != nil {
+		return err
 	}
+	tw.stopResubscribeCheck()
+	tw.awaitGoroutines()
+
+	span := tw.startSpan(SpanConnect, map[string]interface{}{
+		"odin.host": host,
+		"odin.port": port,
+		"odin.ssl":  useSSL,
+		"odin.user": userID,
+	})
+	defer func() { spanEnd(span, err) }()
 
 	tw.userID = userID
-	protocol := "ws"
-	if useSSL {
-		protocol = "wss"
+
+	tw.mu.Lock()
+	tw.connectAttempts++
+	attempt := tw.connectAttempts
+	loss := tw.lastLoss
+	tw.mu.Unlock()
+
+	if loss != nil && span != nil {
+		spanEvent(span, "reconnect", map[string]interface{}{
+			"odin.cause":   loss.Cause.String(),
+			"odin.reason":  loss.Text,
+			"odin.attempt": attempt,
+		})
+	}
+
+	if tw.OnConnecting != nil {
+		tw.OnConnecting(attempt, url)
 	}
-	url := fmt.Sprintf("%s://%s:%d", protocol, host, port)
 
-	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	tw.logf("ODINMarketFeed client %s connecting to %s", Version(), url)
+	spanEvent(span, "dial", map[string]interface{}{"odin.url": url, "odin.attempt": attempt})
+	conn, resp, err := dialer.Dial(url, nil)
 	if err != nil {
+		tw.mu.Lock()
+		tw.setState(StateDisconnected)
+		tw.mu.Unlock()
+
+		err = diagnoseDialError(upgradeError(err, resp), useSSL)
+
 		errMsg := fmt.Sprintf("Connection failed: %v", err)
-		if tw.OnError != nil {
-			tw.OnError(errMsg)
-		}
+		tw.returnedError(errMsg)
 		return err
 	}
 
+	tw.mu.Lock()
 	tw.conn = conn
-	fmt.Println("Connected")
-
-	// Start receiving messages
-	go tw.receiveMessages()
-
-	currentTime := tw.formatTime(time.Now())
-
-	password := "68="
-	if apiKey != "" && strings.TrimSpace(apiKey) != "" {
-		password = fmt.Sprintf("68=%s|401=2", apiKey)
+	tw.sessionErr = nil
+	atomic.StoreInt32(&tw.heartbeatPending, 0)
+	atomic.StoreInt32(&tw.heartbeatMisses, 0)
+	tw.connectAttempts = 0
+	tw.connURL = url
+	tw.lastLoss = nil
+	tw.endpoint = Endpoint{Host: host, Port: port, UseSSL: useSSL}
+	tw.connectedAt = time.Now()
+	tw.generation++
+	atomic.StoreUint64(&tw.wireSeq, 0)
+	tw.recordConnected(tw.connectedAt)
+	tw.flushing = tw.queueEnabled
+	var receiveDone, connDone chan struct{}
+	if !tw.manual.enabled {
+		receiveDone = make(chan struct{})
+		connDone = make(chan struct{})
+	}
+	endConn := sync.OnceFunc(func() {
+		if connDone != nil {
+			close(connDone)
+		}
+	})
+	tw.receiveDone = receiveDone
+	tw.endConn = endConn
+	sendQueue := newSendQueue()
+	tw.sendQueue = sendQueue
+	tw.throttle.connected(connDone)
+	tw.mu.Unlock()
+
+	if !tw.manual.enabled {
+		tw.routines.spawn(func() { tw.writeLoop(conn, sendQueue, connDone) })
+	}
+
+	// A frame split across the end of the previous connection must not be joined with
+	// the first frame of this one
+	fragGeneration := tw.fragHandler.reset()
+	tw.resetCorrelation()
+	tw.startManualLoop(fragGeneration)
+
+	if tw.depthCache != nil && tw.depthCache.clearOnReconnect {
+		tw.depthCache.clear()
+	}
+	tw.logf("Connected")
+
+	// Start receiving messages. Frames are delivered by this single goroutine, which keeps
+	// callbacks in wire order. Delivery is held back until OnOpen has returned (or the
+	// login has failed) so that OnOpen always runs before the first OnMessage.
+	opened := make(chan struct{})
+	defer close(opened)
+	if !tw.manual.enabled {
+		tw.routines.spawn(func() {
+			defer close(receiveDone)
+			defer endConn()
+			tw.receiveMessages(conn, opened, fragGeneration, endConn)
+		})
 	}
 
 	// Build login message
-	loginMsg := fmt.Sprintf("63=FT3.0|64=101|65=74|66=%s|67=%s|%s", currentTime, userID, password)
+	tw.requestClock.anchor(tw.serverClockOffset())
+	loginMsg := tw.requestHeader(msgCodeLogin) + fmt.Sprintf("67=%s|%s", userID, loginFields)
 	// Send login message
 	//loginMsg := fmt.Sprintf("63=FT3.0|64=101|65=74|66=14:59:22|67=%s|68=|4=|400=0|396=HO|51=4|395=127.0.0.1", tw.userID)
+	spanEvent(span, "login", nil)
 	err = tw.SendMessage(loginMsg)
 	if err != nil {
-		return err
+		// The connection is detached before it is closed, so the receive loop treats the
+		// close as local and the client can connect again
+		tw.mu.Lock()
+		tw.flushing = false
+		if tw.conn == conn {
+			tw.conn = nil
+			if tw.manual.enabled {
+				tw.sendQueue.close()
+			}
+			tw.setState(StateDisconnected)
+		}
+		tw.mu.Unlock()
+		conn.Close()
+		tw.emit(LoginFailed{EventSource: tw.source(), Reason: err.Error()})
+		return fmt.Errorf("%w: %w", ErrLoginFailed, err)
+	}
+
+	tw.flushPreConnectQueue()
+	if !tw.manual.enabled {
+		tw.startHeartbeat(conn, connDone)
+		tw.startStaleFeedCheck(connDone)
 	}
 
 	if tw.OnOpen != nil {
 		tw.OnOpen()
 	}
 
+	if replay {
+		if err := tw.ResubscribeAll(); err != nil {
+			tw.reportAsyncError(StageResubscribe, err)
+		}
+	}
+	tw.restoreSubscriptionFile()
+
+	tw.mu.Lock()
+	generation := tw.generation
+	tw.mu.Unlock()
+	tw.emit(Connected{EventSource: tw.source(), URL: url, Generation: generation})
+
 	return nil
 }
 
-// Disconnect disconnects from the WebSocket server
-func (tw *ODINMarketFeedClient) Disconnect() error {
-	tw.mu.Lock()
-	defer tw.mu.Unlock()
+var hostnameRegex = regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?\.)*[a-zA-Z0-9]([a-zA-Z0-9\-]{0,61}[a-zA-Z0-9])?$`)
 
-	if tw.conn != nil {
-		err := tw.conn.WriteMessage(websocket.CloseMessage,
-			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
-		if err != nil {
-			return err
+// buildURL validates the host and port and returns the WebSocket URL to dial.
+// IPv6 literals may be passed with or without brackets.
+func buildURL(host string, port int, useSSL bool) (string, error) {
+	// Validate host
+	host = strings.TrimSpace(host)
+	if host == "" {
+		return "", errors.New("host cannot be empty")
+	}
+
+	// Validate host format (hostname or IP address)
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		bracketed := host[1 : len(host)-1]
+		if ip := net.ParseIP(bracketed); ip == nil || ip.To4() != nil {
+			return "", fmt.Errorf("invalid host format: %s", host)
+		}
+		host = bracketed
+	} else if net.ParseIP(host) == nil {
+		// Not a valid IP, check if it's a valid hostname
+		if !hostnameRegex.MatchString(host) {
+			return "", fmt.Errorf("invalid host format: %s", host)
 		}
-		return tw.conn.Close()
 	}
-	return nil
+
+	// Validate port range
+	if port < 1 || port > 65535 {
+		return "", fmt.Errorf("port must be between 1 and 65535, got: %d", port)
+	}
+
+	protocol := "ws"
+	if useSSL {
+		protocol = "wss"
+	}
+	return fmt.Sprintf("%s://%s", protocol, net.JoinHostPort(host, strconv.Itoa(port))), nil
+}
+
+// Disconnect disconnects from the WebSocket server. It sends a close frame and waits up to
+// the WithCloseTimeout timeout for the server's close frame before closing the connection.
+func (tw *ODINMarketFeedClient) Disconnect() error {
+	return tw.disconnect(context.Background())
 }
 
 // SubscribeTouchline subscribes to touchline for the provided tokens
+// Malformed tokens are skipped and returned in a *BatchError rather than passed to OnError.
 func (tw *ODINMarketFeedClient) SubscribeTouchlineOld(tokenList []string) error {
 	if tokenList == nil || len(tokenList) == 0 {
 		errMsg := "Token list cannot be null or empty."
-		if tw.OnError != nil {
-			tw.OnError(errMsg)
-		}
+		tw.returnedError(errMsg)
 		return fmt.Errorf(errMsg)
 	}
 
-	strTokenToSubscribe := ""
-
-	for _, item := range tokenList {
-		if strings.TrimSpace(item) == "" {
-			continue
-		}
-
-		parts := strings.Split(item, "_")
-		if len(parts) != 2 {
-			errMsg := fmt.Sprintf("Invalid token format: '%s'. Expected format: 'MarketSegmentID_Token'.", item)
-			if tw.OnError != nil {
-				tw.OnError(errMsg)
-			}
-			continue
-		}
-
-		marketSegmentID, err1 := strconv.Atoi(parts[0])
-		token, err2 := strconv.Atoi(parts[1])
+	instruments, skipped, parseErrs := tw.parseTokenList(tokenList)
 
-		if err1 != nil || err2 != nil {
-			errMsg := fmt.Sprintf("Invalid token format: '%s'. Expected format: 'MarketSegmentID_Token'.", item)
-			if tw.OnError != nil {
-				tw.OnError(errMsg)
-			}
-			continue
-		}
-
-		strTokenToSubscribe += fmt.Sprintf("1=%d$7=%d|", marketSegmentID, token)
-	}
-
-	if strTokenToSubscribe != "" {
-		currentTime := time.Now().Format("15:04:05")
-		tlRequest := fmt.Sprintf("63=FT3.0|64=206|65=84|66=%s|4=|%s230=1", currentTime, strTokenToSubscribe)
+	if len(instruments) > 0 {
+		tlRequest := tw.requestHeader(msgCodeTouchline) + "4=|" + tw.formatTokenGroup(instruments) + "230=1"
 
 		err := tw.SendMessage(tlRequest)
 		if err != nil {
 			return err
 		}
 
-		fmt.Printf("Subscribed to touchline tokens: %s\n", strings.Join(tokenList, ", "))
-		return nil
+		tw.logf("Subscribed to touchline tokens: %s", joinInstruments(instruments))
+		return tw.batchResult(len(tokenList), instruments, skipped, parseErrs)
 	}
 
-	errMsg := "No valid tokens found to subscribe."
-	if tw.OnError != nil {
-		tw.OnError(errMsg)
-	}
-	return fmt.Errorf(errMsg)
+	tw.returnedError("No valid tokens found to subscribe.")
+	return noValidTokens("no valid tokens found", len(tokenList), skipped, parseErrs)
 }
 
 // SubscribeTouchline sends touchline request for market data
 // tokenList: List of tokens to subscribe (e.g., "1_22", "1_2885")
 // responseType: "1" = Touchline with fixed length native data, "0" = Normal touchline
 // ltpChangeOnly: Send response on LTP change only if true
+//
+// Deprecated: use SubscribeTouchlineWithOptions.
 func (tw *ODINMarketFeedClient) SubscribeTouchline(tokenList []string, responseType string, ltpChangeOnly bool) error {
 	if len(tokenList) == 0 {
-		if tw.OnError != nil {
-			tw.OnError("Token list cannot be null or empty.")
-		}
+		tw.returnedError("Token list cannot be null or empty.")
 		return fmt.Errorf("token list cannot be empty")
 	}
 
-	if responseType != "0" && responseType != "1" {
-		if tw.OnError != nil {
-			tw.OnError("Invalid response type passed. Valid values are 0 or 1")
-		}
+	opts, ok := touchlineOptions(responseType, ltpChangeOnly)
+	if !ok {
+		tw.returnedError("Invalid response type passed. Valid values are 0 or 1")
 		return fmt.Errorf("invalid response type")
 	}
 
-	var strTokenToSubscribe strings.Builder
-
-	for _, item := range tokenList {
-		if tw.isNullOrWhiteSpace(item) {
-			continue
-		}
-
-		parts := strings.Split(item, "_")
-
-		if len(parts) != 2 {
-			if tw.OnError != nil {
-				tw.OnError(fmt.Sprintf("Invalid token format: '%s'. Expected format: 'MarketSegmentID_Token'.", item))
-			}
-			continue
-		}
-
-		marketSegmentID, err1 := strconv.Atoi(parts[0])
-		token, err2 := strconv.Atoi(parts[1])
+	return tw.SubscribeTouchlineWithOptions(tokenList, opts)
+}
 
-		if err1 != nil || err2 != nil {
-			if tw.OnError != nil {
-				tw.OnError(fmt.Sprintf("Invalid token format: '%s'. Expected format: 'MarketSegmentID_Token'.", item))
-			}
-			continue
-		}
+// SubscribeTouchlineWithOptions sends touchline request for market data
+// tokenList: List of tokens to subscribe (e.g., "1_22", "1_2885")
+// Malformed tokens are skipped and returned in a *BatchError rather than passed to OnError.
+func (tw *ODINMarketFeedClient) SubscribeTouchlineWithOptions(tokenList []string, opts TouchlineOptions) error {
+	return tw.subscribeTouchline(tokenList, opts, nil, true)
+}
 
-		strTokenToSubscribe.WriteString(fmt.Sprintf("1=%d$7=%d|", marketSegmentID, token))
+// subscribeTouchline sends a touchline subscription. A non-nil waiter is registered for
+// the ack of the sent instruments before the request is written; direct is passed to
+// trackSubscribe.
+func (tw *ODINMarketFeedClient) subscribeTouchline(tokenList []string, opts TouchlineOptions, waiter *ackWaiter, direct bool) error {
+	if len(tokenList) == 0 {
+		tw.returnedError("Token list cannot be null or empty.")
+		return fmt.Errorf("token list cannot be empty")
 	}
 
-	strResponseType := ""
-	if responseType == "1" {
-		strResponseType = "49=1"
+	if err := opts.Validate(); err != nil {
+		tw.returnedError(fmt.Sprintf("Invalid touchline options: %v", err))
+		return err
 	}
 
-	sLTChangeOnly := "200=0"
-	if ltpChangeOnly {
-		sLTChangeOnly = "200=1"
+	instruments, skipped, parseErrs := tw.parseTokenList(tokenList)
+	reservation, err := tw.reserveQuota(instruments)
+	if err != nil {
+		return err
 	}
 
-	if strTokenToSubscribe.Len() > 0 {
-		currentTime := tw.formatTime(time.Now())
-		var tlRequest string
-
-		if strResponseType != "" {
-			tlRequest = fmt.Sprintf("63=FT3.0|64=206|65=84|66=%s|%s|%s|%s230=1",
-				currentTime, strResponseType, sLTChangeOnly, strTokenToSubscribe.String())
-		} else {
-			tlRequest = fmt.Sprintf("63=FT3.0|64=206|65=84|66=%s|%s|%s230=1",
-				currentTime, sLTChangeOnly, strTokenToSubscribe.String())
+	if len(instruments) > 0 {
+		header := tw.requestHeader(msgCodeTouchline)
+		if waiter != nil {
+			var correlationID string
+			header, correlationID = tw.correlatedRequestHeader(msgCodeTouchline)
+			tw.addAckWaiter(waiter, correlationID, instruments)
 		}
+		tlRequest := tw.touchlineRequest(header, opts, instruments)
 
-		if err := tw.SendMessage(tlRequest); err != nil {
+		queued, err := tw.sendRequest(tlRequest, len(instruments), func() {
+			tw.trackSubscribe(SubscriptionTouchline, instruments, opts.responseType(), opts.LTPChangeOnly, direct)
+		}, reservation)
+		if err != nil {
 			return err
 		}
-		fmt.Printf("Subscribed to touchline tokens: %s\n", strings.Join(tokenList, ", "))
-		return nil
+		if !queued {
+			tw.logf("Subscribed to touchline tokens: %s", joinInstruments(instruments))
+		}
+		return tw.batchResult(len(tokenList), instruments, skipped, parseErrs)
 	}
 
-	if tw.OnError != nil {
-		tw.OnError("No valid tokens found to subscribe.")
-	}
-	return fmt.Errorf("no valid tokens found")
+	tw.returnedError("No valid tokens found to subscribe.")
+	return noValidTokens("no valid tokens found", len(tokenList), skipped, parseErrs)
 }
 
 // SubscribeLTPTouchline sends LTP touchline request for market data
 // tokenList: List of tokens to subscribe (e.g., "1_22", "1_2885")
+// Malformed tokens are skipped and returned in a *BatchError rather than passed to OnError.
 func (c *ODINMarketFeedClient) SubscribeLTPTouchline(tokenList []string) error {
+	return c.subscribeLTPTouchline(tokenList, true)
+}
+
+// subscribeLTPTouchline sends an LTP touchline subscription; direct is passed to
+// trackSubscribe
+func (c *ODINMarketFeedClient) subscribeLTPTouchline(tokenList []string, direct bool) error {
 	if len(tokenList) == 0 {
-		if c.OnError != nil {
-			c.OnError("Token list cannot be null or empty.")
-		}
+		c.returnedError("Token list cannot be null or empty.")
 		return fmt.Errorf("token list cannot be empty")
 	}
 
-	var strTokenToSubscribe strings.Builder
-
-	for _, item := range tokenList {
-		if c.isNullOrWhiteSpace(item) {
-			continue
-		}
-
-		parts := strings.Split(item, "_")
-
-		if len(parts) != 2 {
-			if c.OnError != nil {
-				c.OnError(fmt.Sprintf("Invalid token format: '%s'. Expected format: 'MarketSegmentID_Token'.", item))
-			}
-			continue
-		}
-
-		marketSegmentID, err1 := strconv.Atoi(parts[0])
-		token, err2 := strconv.Atoi(parts[1])
-
-		if err1 != nil || err2 != nil {
-			if c.OnError != nil {
-				c.OnError(fmt.Sprintf("Invalid token format: '%s'. Expected format: 'MarketSegmentID_Token'.", item))
-			}
-			continue
-		}
-
-		strTokenToSubscribe.WriteString(fmt.Sprintf("1=%d$7=%d|", marketSegmentID, token))
+	instruments, skipped, parseErrs := c.parseTokenList(tokenList)
+	reservation, err := c.reserveQuota(instruments)
+	if err != nil {
+		return err
 	}
 
-	if strTokenToSubscribe.Len() > 0 {
-		currentTime := c.formatTime(time.Now())
-		tlRequest := fmt.Sprintf("63=FT3.0|64=347|65=84|66=%s|%s230=1",
-			currentTime, strTokenToSubscribe.String())
+	if len(instruments) > 0 {
+		tlRequest := c.ltpTouchlineRequest(c.requestHeader(msgCodeLTPTouchline), instruments, 1)
 
-		if err := c.SendMessage(tlRequest); err != nil {
+		queued, err := c.sendRequest(tlRequest, len(instruments), func() {
+			c.trackSubscribe(SubscriptionLTPTouchline, instruments, "", false, direct)
+		}, reservation)
+		if err != nil {
 			return err
 		}
-		fmt.Printf("Subscribed to LTP touchline tokens: %s\n", strings.Join(tokenList, ", "))
-		return nil
+		if !queued {
+			c.logf("Subscribed to LTP touchline tokens: %s", joinInstruments(instruments))
+		}
+		return c.batchResult(len(tokenList), instruments, skipped, parseErrs)
 	}
 
-	if c.OnError != nil {
-		c.OnError("No valid tokens found to subscribe.")
-	}
-	return fmt.Errorf("no valid tokens found")
+	c.returnedError("No valid tokens found to subscribe.")
+	return noValidTokens("no valid tokens found", len(tokenList), skipped, parseErrs)
 }
 
 // UnsubscribeLTPTouchline unsubscribes from LTP touchline tokens
 func (c *ODINMarketFeedClient) UnsubscribeLTPTouchline(tokenList []string) error {
 	if len(tokenList) == 0 {
-		if c.OnError != nil {
-			c.OnError("Token list cannot be null or empty.")
-		}
+		c.returnedError("Token list cannot be null or empty.")
 		return fmt.Errorf("token list cannot be empty")
 	}
 
-	var strTokenToSubscribe strings.Builder
-
-	for _, item := range tokenList {
-		if c.isNullOrWhiteSpace(item) {
-			continue
-		}
-
-		parts := strings.Split(item, "_")
-
-		if len(parts) != 2 {
-			if c.OnError != nil {
-				c.OnError(fmt.Sprintf("Invalid token format: '%s'. Expected format: 'MarketSegmentID_Token'.", item))
-			}
-			continue
-		}
-
-		marketSegmentID, err1 := strconv.Atoi(parts[0])
-		token, err2 := strconv.Atoi(parts[1])
-
-		if err1 != nil || err2 != nil {
-			if c.OnError != nil {
-				c.OnError(fmt.Sprintf("Invalid token format: '%s'. Expected format: 'MarketSegmentID_Token'.", item))
-			}
-			continue
-		}
+	instruments, skipped, parseErrs := c.parseTokenList(tokenList)
 
-		strTokenToSubscribe.WriteString(fmt.Sprintf("1=%d$7=%d|", marketSegmentID, token))
-	}
+	if len(instruments) > 0 {
+		tlRequest := c.ltpTouchlineRequest(c.requestHeader(msgCodeLTPTouchline), instruments, 2)
 
-	if strTokenToSubscribe.Len() > 0 {
-		currentTime := c.formatTime(time.Now())
-		tlRequest := fmt.Sprintf("63=FT3.0|64=347|65=84|66=%s|%s230=2",
-			currentTime, strTokenToSubscribe.String())
-
-		if err := c.SendMessage(tlRequest); err != nil {
+		queued, err := c.sendRequest(tlRequest, len(instruments), func() {
+			c.trackUnsubscribe(SubscriptionLTPTouchline, instruments)
+		}, nil)
+		if err != nil {
 			return err
 		}
-		fmt.Printf("Unsubscribed from LTP touchline tokens: %s\n", strings.Join(tokenList, ", "))
-		return nil
+		if !queued {
+			c.logf("Unsubscribed from LTP touchline tokens: %s", joinInstruments(instruments))
+		}
+		return c.batchResult(len(tokenList), instruments, skipped, parseErrs)
 	}
 
-	if c.OnError != nil {
-		c.OnError("No valid tokens found to subscribe.")
-	}
-	return fmt.Errorf("no valid tokens found")
+	c.returnedError("No valid tokens found to subscribe.")
+	return noValidTokens("no valid tokens found", len(tokenList), skipped, parseErrs)
 }
 
 // SubscribePauseResume pauses or resumes the broadcast subscription
@@ -709,8 +1185,7 @@ func (c *ODINMarketFeedClient) SubscribePauseResume(isPause bool) error {
 		sIsPause = "230=1"
 	}
 
-	currentTime := c.formatTime(time.Now())
-	tlRequest := fmt.Sprintf("63=FT3.0|64=106|65=84|66=%s|%s", currentTime, sIsPause)
+	tlRequest := c.requestHeader(msgCodePauseResume) + sIsPause
 
 	if err := c.SendMessage(tlRequest); err != nil {
 		return err
@@ -720,7 +1195,7 @@ func (c *ODINMarketFeedClient) SubscribePauseResume(isPause bool) error {
 	if isPause {
 		action = "Pause"
 	}
-	fmt.Printf("%s request sent\n", action)
+	c.logf("%s request sent", action)
 	return nil
 }
 
@@ -730,339 +1205,573 @@ func (c *ODINMarketFeedClient) isNullOrWhiteSpace(str string) bool {
 	return len(strings.TrimSpace(str)) == 0
 }
 
-func (c *ODINMarketFeedClient) formatTime(t time.Time) string {
-	return t.Format("15:04:05")
-}
-
-// UnsubscribeTouchline unsubscribes from touchline for the provided tokens
-func (tw *ODINMarketFeedClient) UnsubscribeTouchline(tokenList []string) error {
-	if tokenList == nil || len(tokenList) == 0 {
-		errMsg := "Token list cannot be null or empty."
-		if tw.OnError != nil {
-			tw.OnError(errMsg)
+// messageCode returns the value of the 64= message code tag, or -1 when absent
+func messageCode(message string) int {
+	start := 0
+	if !strings.HasPrefix(message, "64=") {
+		index := strings.Index(message, "|64=")
+		if index < 0 {
+			return -1
 		}
-		return fmt.Errorf(errMsg)
+		start = index + 1
 	}
 
-	strTokenToSubscribe := ""
+	value := message[start+3:]
+	if end := strings.IndexByte(value, '|'); end >= 0 {
+		value = value[:end]
+	}
+
+	code, err := strconv.Atoi(value)
+	if err != nil {
+		return -1
+	}
+	return code
+}
+
+// parseTokenList parses 'MarketSegmentID_Token' items, returning invalid ones as skipped
+func (c *ODINMarketFeedClient) parseTokenList(tokenList []string) (instruments []Instrument, skipped []string, errs []error) {
+	instruments = make([]Instrument, 0, len(tokenList))
 
 	for _, item := range tokenList {
-		if strings.TrimSpace(item) == "" {
+		if c.isNullOrWhiteSpace(item) {
 			continue
 		}
 
-		parts := strings.Split(item, "_")
-		if len(parts) != 2 {
-			errMsg := fmt.Sprintf("Invalid token format: '%s'. Expected format: 'MarketSegmentID_Token'.", item)
-			if tw.OnError != nil {
-				tw.OnError(errMsg)
-			}
-			continue
+		instrument, err := ParseInstrument(item)
+		if err == nil {
+			err = instrument.validate()
 		}
-
-		marketSegmentID, err1 := strconv.Atoi(parts[0])
-		token, err2 := strconv.Atoi(parts[1])
-
-		if err1 != nil || err2 != nil {
-			errMsg := fmt.Sprintf("Invalid token format: '%s'. Expected format: 'MarketSegmentID_Token'.", item)
-			if tw.OnError != nil {
-				tw.OnError(errMsg)
-			}
+		if err != nil {
+			c.returnedError(fmt.Sprintf("Invalid token format: '%s'. Expected format: 'MarketSegmentID_Token'.", item))
+			skipped = append(skipped, item)
+			errs = append(errs, err)
 			continue
 		}
 
-		strTokenToSubscribe += fmt.Sprintf("1=%d$7=%d|", marketSegmentID, token)
+		instruments = append(instruments, instrument)
 	}
 
-	if strTokenToSubscribe != "" {
-		currentTime := time.Now().Format("15:04:05")
-		tlRequest := fmt.Sprintf("63=FT3.0|64=206|65=84|66=%s|4=|%s230=2", currentTime, strTokenToSubscribe)
-
-		err := tw.SendMessage(tlRequest)
-		if err != nil {
-			return err
-		}
+	return instruments, skipped, errs
+}
 
-		fmt.Printf("Unsubscribed from touchline tokens: %s\n", strings.Join(tokenList, ", "))
-		return nil
+// joinInstruments formats instruments in canonical 'MarketSegmentID_Token' form for logging
+func joinInstruments(instruments []Instrument) string {
+	items := make([]string, len(instruments))
+	for i, instrument := range instruments {
+		items[i] = instrument.String()
 	}
+	return strings.Join(items, ", ")
+}
 
-	errMsg := "No valid tokens found to unsubscribe."
-	if tw.OnError != nil {
-		tw.OnError(errMsg)
+// formatTokenGroup formats instruments as the 1=MarketSegmentID$7=Token| repeating group
+func (tw *ODINMarketFeedClient) formatTokenGroup(instruments []Instrument) string {
+	var sb strings.Builder
+	for _, instrument := range instruments {
+		sb.WriteString(tw.instrumentPair(instrument.MarketSegmentID, instrument.Token) + "|")
 	}
-	return fmt.Errorf(errMsg)
+	return sb.String()
 }
 
-// SubscribeBestFive subscribes to Market Depth (Best Five) for the provided token and market segment
-func (tw *ODINMarketFeedClient) SubscribeBestFive(token string, marketSegmentID int) error {
-	if strings.TrimSpace(token) == "" {
-		errMsg := "Token cannot be null or empty."
-		if tw.OnError != nil {
-			tw.OnError(errMsg)
-		}
+// touchlineRequest builds a touchline subscribe request with the given header
+func (tw *ODINMarketFeedClient) touchlineRequest(header string, opts TouchlineOptions, instruments []Instrument) string {
+	return header + opts.requestFields() + tw.formatTokenGroup(instruments) + "230=1"
+}
+
+// ltpTouchlineRequest builds an LTP touchline request with the given header; action is 1 to
+// subscribe and 2 to unsubscribe
+func (tw *ODINMarketFeedClient) ltpTouchlineRequest(header string, instruments []Instrument, action int) string {
+	return header + tw.formatTokenGroup(instruments) + "230=" + strconv.Itoa(action)
+}
+
+// bestFiveRequest builds the Best Five request of one instrument with the given header;
+// action is 1 to subscribe and 2 to unsubscribe
+func (tw *ODINMarketFeedClient) bestFiveRequest(header string, instrument Instrument, action int) string {
+	return header + fmt.Sprintf("1=%d|7=%d|230=%d", instrument.MarketSegmentID, instrument.Token, action)
+}
+
+// UnsubscribeTouchline unsubscribes from touchline for the provided tokens
+func (tw *ODINMarketFeedClient) UnsubscribeTouchline(tokenList []string) error {
+	if tokenList == nil || len(tokenList) == 0 {
+		errMsg := "Token list cannot be null or empty."
+		tw.returnedError(errMsg)
 		return fmt.Errorf(errMsg)
 	}
 
-	if marketSegmentID <= 0 {
-		errMsg := "Invalid MarketSegment."
-		if tw.OnError != nil {
-			tw.OnError(errMsg)
+	instruments, skipped, parseErrs := tw.parseTokenList(tokenList)
+
+	if len(instruments) > 0 {
+		tlRequest := tw.requestHeader(msgCodeTouchline) + fmt.Sprintf("4=|%s230=2", tw.formatTokenGroup(instruments))
+
+		queued, err := tw.sendRequest(tlRequest, len(instruments), func() {
+			tw.trackUnsubscribe(SubscriptionTouchline, instruments)
+		}, nil)
+		if err != nil {
+			return err
 		}
-		return fmt.Errorf(errMsg)
+
+		if !queued {
+			tw.logf("Unsubscribed from touchline tokens: %s", joinInstruments(instruments))
+		}
+		return tw.batchResult(len(tokenList), instruments, skipped, parseErrs)
 	}
 
-	currentTime := time.Now().Format("15:04:05")
-	tlRequest := fmt.Sprintf("63=FT3.0|64=127|65=84|66=%s|1=%d|7=%s|230=1", currentTime, marketSegmentID, token)
+	errMsg := "No valid tokens found to unsubscribe."
+	tw.returnedError(errMsg)
+	return noValidTokens(errMsg, len(tokenList), skipped, parseErrs)
+}
 
-	err := tw.SendMessage(tlRequest)
+// SubscribeBestFive subscribes to Market Depth (Best Five) for the provided token and market segment
+func (tw *ODINMarketFeedClient) SubscribeBestFive(token string, marketSegmentID int) error {
+	instrument, err := tw.bestFiveArgs(token, marketSegmentID)
 	if err != nil {
 		return err
 	}
-
-	fmt.Printf("Subscribed to BestFive tokens: %s, MarketSegmentId: %d\n", token, marketSegmentID)
-	return nil
+	return tw.bestFiveBatch([]Instrument{instrument}, true, true)
 }
 
 // UnsubscribeBestFive unsubscribes from Market Depth (Best Five) for the provided token and market segment
 func (tw *ODINMarketFeedClient) UnsubscribeBestFive(token string, marketSegmentID int) error {
-	if strings.TrimSpace(token) == "" {
-		errMsg := "Token cannot be null or empty."
-		if tw.OnError != nil {
-			tw.OnError(errMsg)
-		}
-		return fmt.Errorf(errMsg)
+	instrument, err := tw.bestFiveArgs(token, marketSegmentID)
+	if err != nil {
+		return err
 	}
+	return tw.bestFiveBatch([]Instrument{instrument}, false, false)
+}
 
-	if marketSegmentID <= 0 {
-		errMsg := "Invalid MarketSegment."
-		if tw.OnError != nil {
-			tw.OnError(errMsg)
-		}
-		return fmt.Errorf(errMsg)
+// bestFiveArgs validates the arguments of the single-token Best Five methods
+func (tw *ODINMarketFeedClient) bestFiveArgs(token string, marketSegmentID int) (Instrument, error) {
+	if strings.TrimSpace(token) == "" {
+		errMsg := "Token cannot be null or empty."
+		tw.returnedError(errMsg)
+		return Instrument{}, fmt.Errorf(errMsg)
 	}
 
-	currentTime := time.Now().Format("15:04:05")
-	tlRequest := fmt.Sprintf("63=FT3.0|64=127|65=84|66=%s|1=%d|7=%s|230=2", currentTime, marketSegmentID, token)
-
-	err := tw.SendMessage(tlRequest)
-	if err != nil {
-		return err
+	// Validated like the items of a touchline token list
+	item := strconv.Itoa(marketSegmentID) + "_" + strings.TrimSpace(token)
+	instruments, skipped, parseErrs := tw.parseTokenList([]string{item})
+	if len(instruments) == 0 {
+		return Instrument{}, noValidTokens("no valid instruments found", 1, skipped, parseErrs)
 	}
-
-	fmt.Printf("Unsubscribed from BestFive tokens: %s, MarketSegmentId: %d\n", token, marketSegmentID)
-	return nil
+	return instruments[0], nil
 }
 
-// SendMessage sends a message to the WebSocket server
+// SendMessage sends a message to the WebSocket server and waits until it has been written.
+// Login, heartbeat and pause/resume requests are written ahead of queued subscription
+// requests. Send failures are returned, not passed to OnError.
 func (tw *ODINMarketFeedClient) SendMessage(message string) error {
-	tw.mu.Lock()
-	defer tw.mu.Unlock()
+	return tw.sendMessage(context.Background(), message)
+}
+
+// sendMessage is SendMessage, no longer waiting for the write once ctx is done. The message
+// then stays queued and may still be written.
+func (tw *ODINMarketFeedClient) sendMessage(ctx context.Context, message string) error {
+	message = tw.applySendInterceptors(message)
 
-	if tw.conn == nil {
+	tw.mu.Lock()
+	if tw.isDisposed {
+		tw.mu.Unlock()
+		return ErrClientDisposed
+	}
+	if tw.conn == nil || tw.sendQueue == nil {
+		tw.mu.Unlock()
 		return fmt.Errorf("WebSocket is not connected")
 	}
+	conn := tw.conn
+	queue := tw.sendQueue
+	tw.mu.Unlock()
 
-	fmt.Println("Sending Message:", message)
-	packet, err := tw.fragHandler.FragmentData([]byte(message))
+	if err := tw.checkConformance(message); err != nil {
+		return err
+	}
+	payload, err := tw.transformPayload(message)
+	if err != nil {
+		return err
+	}
+	packet, err := tw.fragHandler.FragmentData(payload)
 	if err != nil {
 		return err
 	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	return tw.conn.WriteMessage(websocket.BinaryMessage, packet)
+	request := writeRequest{packet: packet, display: maskSecrets(message), done: make(chan error, 1)}
+	if err := queue.push(tw.sendPriority(message), request); err != nil {
+		return err
+	}
+	if tw.manual.enabled {
+		// Without a writer goroutine the request is written now, unless it is held back by
+		// a throttle pause, in which case Tick writes it later
+		tw.drainSendQueue(conn, queue)
+		select {
+		case err := <-request.done:
+			return err
+		default:
+			return nil
+		}
+	}
+	select {
+	case err := <-request.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-func (tw *ODINMarketFeedClient) receiveMessages() {
+// receiveMessages reads frames from conn and delivers them once opened is closed
+func (tw *ODINMarketFeedClient) receiveMessages(conn *websocket.Conn, opened <-chan struct{}, fragGeneration uint64, endConn func()) {
+	defer conn.Close()
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Println("Recovered in receiveMessages:", r)
+			tw.logf("Recovered in receiveMessages: %v", r)
 		}
 	}()
 
 	for {
-		_, message, err := tw.conn.ReadMessage()
+		messageType, message, err := conn.ReadMessage()
+		<-opened
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				fmt.Printf("Error in receive loop: %v\n", err)
-			}
-			if tw.OnError != nil {
-				tw.OnError(err.Error())
-			}
+			// The writer and heartbeat stop before the callbacks run, so that a reconnect
+			// from a callback only waits for this loop
+			endConn()
+			tw.readFailed(conn, err)
 			break
 		}
+		tw.dispatchFrame(messageType, message, fragGeneration)
+	}
+}
+
+// readFailed reports the end of conn after a failed read. It reports whether conn was still
+// the current connection, rather than detached by a local close.
+func (tw *ODINMarketFeedClient) readFailed(conn *websocket.Conn, err error) (lost bool) {
+	// Disconnect, Dispose and a connection switch detach conn before closing it, so
+	// a read error on a detached connection is the expected result of a local close
+	tw.mu.Lock()
+	lost = tw.conn == conn
+	if lost {
+		tw.setState(StateDisconnected)
+	}
+	onConnectionLost := tw.onConnectionLost
+	sessionErr := tw.sessionErr
+	tw.mu.Unlock()
+
+	if !lost {
+		tw.failPendingQuotes(err)
+		tw.failPendingAcks(err)
+		if tw.OnClose != nil {
+			tw.invokeCallback("OnClose", err.Error, func() { tw.OnClose(websocket.CloseNormalClosure, "") })
+		}
+		return false
+	}
+
+	if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+		tw.logf("Error in receive loop: %v", err)
+	}
+	tw.reportError(err.Error())
+	tw.failPendingQuotes(err)
+	tw.failPendingAcks(err)
+
+	// A duplicate session or missed heartbeats ended the session before the read failed
+	cause := err
+	if sessionErr != nil {
+		cause = sessionErr
+	}
+	loss := classifyDisconnect(cause)
+	tw.mu.Lock()
+	tw.lastLoss = &loss
+	tw.mu.Unlock()
+
+	disconnected := Disconnected{EventSource: tw.source(), Code: websocket.CloseAbnormalClosure, Reason: err.Error(), Err: err}
+	disconnected.Cause = loss.Cause
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		disconnected.Code = closeErr.Code
+		disconnected.Reason = closeErr.Text
+	}
+	tw.emit(disconnected)
 
-		tw.responseReceived(message)
+	if tw.OnClose != nil {
+		tw.invokeCallback("OnClose", err.Error, func() { tw.OnClose(disconnected.Code, disconnected.Reason) })
+	}
+	if onConnectionLost != nil {
+		onConnectionLost(cause)
+	}
+	return true
+}
+
+// dispatchFrame delivers a received websocket frame
+func (tw *ODINMarketFeedClient) dispatchFrame(messageType int, message []byte, fragGeneration uint64) {
+	atomic.StoreInt64(&tw.stats.lastMessageAt, time.Now().UnixNano())
+	if tw.rollover != nil {
+		tw.checkDayRollover(tw.rollover.clock())
+	}
+
+	switch messageType {
+	case websocket.BinaryMessage:
+		atomic.AddUint64(&tw.stats.binaryFrames, 1)
+		tw.responseReceived(message, fragGeneration)
+	case websocket.TextMessage:
+		atomic.AddUint64(&tw.stats.textFrames, 1)
+		tw.noticeReceived(string(message))
+	default:
+		atomic.AddUint64(&tw.stats.otherFrames, 1)
+	}
+}
+
+// noticeReceived delivers a text frame without defragmentation
+func (tw *ODINMarketFeedClient) noticeReceived(notice string) {
+	seq := tw.nextSeq()
+	text := func() string { return notice }
+	if tw.OnNotice != nil {
+		tw.deliverNotice(Notice{Severity: SeverityUnknown, Code: -1, Text: notice, Seq: seq})
+	}
+	if tw.OnServerNotice != nil {
+		tw.invokeCallback("OnServerNotice", text, func() { tw.OnServerNotice(notice) })
+	} else if tw.OnMessage != nil && (tw.OnNotice == nil || tw.noticePassthrough) {
+		tw.invokeCallback("OnMessage", text, func() { tw.OnMessage(notice) })
 	}
 }
-func (tw *ODINMarketFeedClient) responseReceived(data []byte) {
+
+// nextSeq returns the ParsedMessage.Seq of the next message received on the connection
+func (tw *ODINMarketFeedClient) nextSeq() uint64 {
+	return atomic.AddUint64(&tw.wireSeq, 1)
+}
+
+func (tw *ODINMarketFeedClient) responseReceived(data []byte, fragGeneration uint64) {
 
 	defer func() {
 		if r := recover(); r != nil {
-			fmt.Println("Error in responseReceived:", r)
+			tw.logf("Error in responseReceived: %v", r)
+			if tw.strictErrors {
+				tw.reportDiscard(newDiscardError("panic", data, fmt.Errorf("%v", r)))
+			}
 		}
 	}()
 
-	arrData, err := tw.fragHandler.Defragment(data)
+	tw.dumpFrame("IN", data, nil)
+	arrData, err := tw.fragHandler.defragmentGeneration(fragGeneration, data)
+	tw.reportFrameDiscards()
+	if note := tw.fragHandler.takeSwitchNote(); note != "" {
+		tw.logf("%s", note)
+	}
 	if err != nil {
-		fmt.Printf("Error defragmenting data: %v\n", err)
+		tw.logf("Error defragmenting data: %v", err)
 		return
 	}
+	tw.dumpInner(arrData)
+	tw.frames.record(len(data), len(arrData))
+
+	if tw.OnRawBatch != nil && len(arrData) > 0 {
+		tw.invokeCallback("OnRawBatch", batchDescription(len(arrData)), func() {
+			tw.deliverBatch("OnRawBatch", arrData, tw.OnRawBatch)
+		})
+	}
+	batch := tw.OnMessageBatch != nil
+	if batch {
+		tw.batchBuf = tw.batchBuf[:0]
+	}
+	idle := !tw.hasDataConsumer()
+	receivedAt := time.Now()
 
 	for i := 0; i < len(arrData); i++ {
-		strMsg := string(arrData[i])
-
-		if strings.Contains(strMsg, "|50=") {
-			data := arrData[i]
-			dataIndex := strings.Index(strMsg, "|50=") + 4
-			strNewMsg := strMsg[:strings.Index(strMsg, "|50=")+1]
-
-			// Market Segment ID
-			mktSegID := binary.LittleEndian.Uint32(data[dataIndex : dataIndex+4])
-			strNewMsg += "1=" + strconv.FormatUint(uint64(mktSegID), 10) + "|"
-
-			// Token
-			token := binary.LittleEndian.Uint32(data[dataIndex+4 : dataIndex+8])
-			strNewMsg += "7=" + strconv.FormatUint(uint64(token), 10) + "|"
-
-			// Last Update Time (LUT)
-			lutSeconds := int32(binary.LittleEndian.Uint32(data[dataIndex+8 : dataIndex+12]))
-			lutDate := tw.dteNSE.Add(time.Duration(lutSeconds) * time.Second)
-			lut := lutDate.Format("2006-01-02 150405")
-			strNewMsg += "74=" + lut + "|"
-
-			// Last Trade Time (LTT)
-			lttSeconds := int32(binary.LittleEndian.Uint32(data[dataIndex+12 : dataIndex+16]))
-			lttDate := tw.dteNSE.Add(time.Duration(lttSeconds) * time.Second)
-			ltt := lttDate.Format("2006-01-02 150405")
-			strNewMsg += "73=" + ltt + "|"
-
-			// Last Traded Price (LTP)
-			ltp := binary.LittleEndian.Uint32(data[dataIndex+16 : dataIndex+20])
-			strNewMsg += "8=" + strconv.FormatUint(uint64(ltp), 10) + "|"
-
-			// Buy Quantity (BQty)
-			bQty := binary.LittleEndian.Uint32(data[dataIndex+20 : dataIndex+24])
-			strNewMsg += "2=" + strconv.FormatUint(uint64(bQty), 10) + "|"
-
-			// Buy Price (BPrice)
-			bPrice := binary.LittleEndian.Uint32(data[dataIndex+24 : dataIndex+28])
-			strNewMsg += "3=" + strconv.FormatUint(uint64(bPrice), 10) + "|"
-
-			// Sell Quantity (SQty) - but using BQty (bug in original code)
-			sQty := binary.LittleEndian.Uint32(data[dataIndex+28 : dataIndex+32])
-			strNewMsg += "5=" + strconv.FormatUint(uint64(sQty), 10) + "|" // Note: Original code uses BQty here (appears to be a bug)
-
-			// Sell Price (SPrice) - but using BPrice (bug in original code)
-			sPrice := binary.LittleEndian.Uint32(data[dataIndex+32 : dataIndex+36])
-			strNewMsg += "6=" + strconv.FormatUint(uint64(sPrice), 10) + "|" // Note: Original code uses BPrice here (appears to be a bug)
-
-			// Open Price (OPrice) - but using BQty (bug in original code)
-			oPrice := binary.LittleEndian.Uint32(data[dataIndex+36 : dataIndex+40])
-			strNewMsg += "75=" + strconv.FormatUint(uint64(oPrice), 10) + "|" // Note: Original code uses BQty here (appears to be a bug)
-
-			// High Price (HPrice) - but using BPrice (bug in original code)
-			hPrice := binary.LittleEndian.Uint32(data[dataIndex+40 : dataIndex+44])
-			strNewMsg += "77=" + strconv.FormatUint(uint64(hPrice), 10) + "|" // Note: Original code uses BPrice here (appears to be a bug)
-
-			// Low Price (LPrice) - but using BQty (bug in original code)
-			lPrice := binary.LittleEndian.Uint32(data[dataIndex+44 : dataIndex+48])
-			strNewMsg += "78=" + strconv.FormatUint(uint64(lPrice), 10) + "|" // Note: Original code uses BQty here (appears to be a bug)
-
-			// Close Price (CPrice) - but using BPrice (bug in original code)
-			cPrice := binary.LittleEndian.Uint32(data[dataIndex+48 : dataIndex+52])
-			strNewMsg += "76=" + strconv.FormatUint(uint64(cPrice), 10) + "|" // Note: Original code uses BPrice here (appears to be a bug)
-
-			// Decimal Locator
-			decLocator := binary.LittleEndian.Uint32(data[dataIndex+52 : dataIndex+56])
-			strNewMsg += "399=" + strconv.FormatUint(uint64(decLocator), 10) + "|"
-
-			// Previous Close Price
-			prvClosePrice := binary.LittleEndian.Uint32(data[dataIndex+56 : dataIndex+60])
-			strNewMsg += "250=" + strconv.FormatUint(uint64(prvClosePrice), 10) + "|"
-
-			// Indicative Close Price
-			indicativeClosePrice := binary.LittleEndian.Uint32(data[dataIndex+60 : dataIndex+64])
-			strNewMsg += "88=" + strconv.FormatUint(uint64(indicativeClosePrice), 10) + "|"
-
-			strMsg = strNewMsg
+		seq := tw.nextSeq()
+		tw.innerMessageReceived(arrData[i], receivedAt)
+		if idle && bytes.Contains(arrData[i], binaryTag) {
+			tw.skipIdleMessage()
+			continue
 		}
-
-		if tw.OnMessage != nil {
-			tw.OnMessage(strMsg)
+		if tw.segmentFiltered(arrData[i]) {
+			continue
 		}
-	}
+		tw.applyReceiveInterceptors(arrData[i])
 
-}
+		msg, err := tw.decoder.Decode(arrData[i])
+		msg.Seq = seq
+		if err != nil {
+			if tw.strictErrors {
+				tw.reportDiscard(newDiscardError("decode", arrData[i], err))
+			} else {
+				tw.reportError(fmt.Sprintf("Failed to decode message: %v", err))
+			}
+		}
 
-func parseData(data string) ([]string, error) {
-	messages := make([]string, 0)
+		tw.transcribeResponse(msg, receivedAt)
+		tw.correlationReceived(msg)
+		tw.subscriptionAckReceived(msg)
+		switch msg.Kind {
+		case MessageTouchline:
+			tw.touchlineReceived(*msg.Touchline, msg.CorrelationID)
+		case MessageBestFive:
+			tw.bestFiveReceived(*msg.BestFive)
+		case MessageHeartbeat:
+			tw.heartbeatReceived(msg.Text, time.Now())
+		case MessageLTP:
+			tw.ltpReceived(*msg.LTP)
+		case MessageDuplicateSession:
+			tw.duplicateSessionReceived(msg.Text)
+		}
 
-	for len(data) > 6 {
-		// Ensure we have at least 6 characters
-		if len(data) < 6 {
-			break
+		if notice, ok := tw.isThrottleNotice(msg); ok {
+			tw.throttleReceived(notice, msg.Text, msg.CorrelationID)
 		}
 
-		// Extract length from characters at index 1-5 (5 characters)
-		lengthStr := data[1:6]
-		messageLength, err := strconv.Atoi(lengthStr)
-		if err != nil {
-			return messages, fmt.Errorf("failed to parse message length '%s': %w", lengthStr, err)
+		if tw.OnNotice != nil {
+			if notice, ok := tw.parseNotice(msg); ok {
+				tw.deliverNotice(notice)
+				if !tw.noticePassthrough {
+					continue
+				}
+			}
 		}
 
-		// Check if we have a complete message
-		totalLength := 6 + messageLength
+		if msg.Kind == MessageUnknown && !tw.decoder.knownCode(msg.Code) && tw.unknownMessageReceived(msg) {
+			if batch {
+				tw.batchBuf = append(tw.batchBuf, msg)
+			}
+			continue
+		}
 
-		if len(data) < totalLength {
-			// Not enough data yet, wait for more
-			break
+		if batch {
+			tw.batchBuf = append(tw.batchBuf, msg)
+		} else if tw.OnMessage != nil {
+			tw.invokeCallback("OnMessage", func() string { return msg.Text }, func() { tw.OnMessage(msg.Text) })
+		} else if sink := tw.messageSink.Load(); sink != nil {
+			sink.deliver(msg)
 		}
+	}
 
-		// Extract the message data (skip first 6 characters: # + 5-digit length)
-		messageData := data[6:totalLength]
-		messages = append(messages, messageData)
+	if batch && len(tw.batchBuf) > 0 {
+		tw.invokeCallback("OnMessageBatch", batchDescription(len(tw.batchBuf)), func() {
+			tw.deliverMessages(tw.batchBuf, tw.OnMessageBatch)
+		})
+	}
+}
 
-		// Remove processed message from buffer
-		data = data[totalLength:]
+// touchlineReceived runs the touchline consumers for a decoded touchline. correlationID is
+// the echoed correlation tag of the message, if any.
+func (tw *ODINMarketFeedClient) touchlineReceived(touchline TouchlineData, correlationID string) {
+	if tw.priceScaler != nil {
+		touchline.DecimalLocator = tw.priceScaler.Divisor(touchline.MktSegID, touchline.DecimalLocator)
+		touchline.computeChange()
+	}
+	if !tw.checkSession(&touchline) {
+		return
+	}
+	tw.checkFeedGap(touchline)
+	tw.deliverQuote(touchline, correlationID)
+	tw.recordTokenUpdate(touchline.MktSegID, touchline.Token, touchline.LTP)
+	if tw.isDuplicateTick(touchline) {
+		return
 	}
 
-	return messages, nil
+	if tw.OnTouchline != nil || tw.OnJSON != nil || tw.tickSink.Load() != nil || tw.publisher.Load() != nil ||
+		tw.csvWriters.Load() != nil {
+		tw.throttleTouchline(touchline)
+	}
 }
 
-// splitByFIXStart splits the input by the delimiter and keeps the delimiter at start of each message
-func splitByFIXStart(input, delimiter string) []string {
-	var result []string
+// ltpReceived passes a decoded LTP touchline response to OnLTP
+func (tw *ODINMarketFeedClient) ltpReceived(update LTPUpdate) {
+	tw.recordTokenUpdate(update.MktSegID, update.Token, update.LTP)
+	if tw.priceScaler != nil {
+		update.DecimalLocator = tw.priceScaler.Divisor(update.MktSegID, update.DecimalLocator)
+	}
+	if tw.OnLTP != nil {
+		tw.invokeCallback("OnLTP", update.String, func() { tw.OnLTP(update) })
+	}
+}
 
-	// Find indices where delimiter starts
-	indices := []int{}
-	for i := 0; i < len(input); i++ {
-		if len(input[i:]) >= len(delimiter) && input[i:i+len(delimiter)] == delimiter {
-			indices = append(indices, i)
-		}
+// deliverTouchline passes a touchline to OnTouchline, OnJSON, the publisher and the
+// TickCSVWriters, enriched with its symbol
+func (tw *ODINMarketFeedClient) deliverTouchline(touchline TouchlineData) {
+	tw.mu.Lock()
+	resolver := tw.resolver
+	tw.mu.Unlock()
+
+	if reverse, ok := resolver.(ResolverWithReverse); ok {
+		touchline.Symbol, _ = reverse.Symbol(int(touchline.MktSegID), int(touchline.Token))
 	}
+	if tw.OnTouchline != nil {
+		tw.invokeCallback("OnTouchline", touchline.String, func() { tw.OnTouchline(touchline) })
+	} else if sink := tw.tickSink.Load(); sink != nil {
+		sink.deliver(touchline)
+	}
+	tw.deliverJSON(touchline.MarshalJSONWithOptions)
+	tw.publish(MessageTouchline, touchline.MktSegID, touchline.Token, touchline)
+	tw.writeCSV(touchline)
+}
 
-	// Split the string at those indices
-	for i, startIdx := range indices {
-		var endIdx int
-		if i+1 < len(indices) {
-			endIdx = indices[i+1]
-		} else {
-			endIdx = len(input)
+// SplitMessages splits input at every occurrence of delimiter, keeping the delimiter at the
+// start of each returned message (e.g. splitting on "63=FT3.0"). Text before the first
+// delimiter is discarded, occurrences are matched left to right without overlap, and
+// consecutive delimiters yield messages consisting of the delimiter alone. Input without
+// the delimiter, or an empty delimiter, returns nil. The returned strings share memory
+// with input and the scan is linear in the input length.
+func SplitMessages(input, delimiter string) []string {
+	if delimiter == "" {
+		return nil
+	}
+
+	start := strings.Index(input, delimiter)
+	if start < 0 {
+		return nil
+	}
+
+	var result []string
+	for {
+		next := strings.Index(input[start+len(delimiter):], delimiter)
+		if next < 0 {
+			result = append(result, input[start:])
+			return result
 		}
-		result = append(result, input[startIdx:endIdx])
+
+		end := start + len(delimiter) + next
+		result = append(result, input[start:end])
+		start = end
 	}
+}
 
-	return result
+// Close unsubscribes from the tracked subscriptions when WithUnsubscribeOnClose is set,
+// flushes the send queue, disconnects and releases resources. When ctx is done the remaining
+// unsubscribes are skipped and the close frame is sent at once; see CloseWithReport. Close
+// returns once the goroutines of the client have exited, unless it is called from a
+// callback on the receive loop.
+func (tw *ODINMarketFeedClient) Close(ctx context.Context) error {
+	_, err := tw.CloseWithReport(ctx)
+	return err
 }
 
-// Dispose releases resources
+// Dispose releases resources. Further calls to Dispose have no effect; other methods
+// return ErrClientDisposed.
 func (tw *ODINMarketFeedClient) Dispose() {
-	if !tw.isDisposed {
-		if tw.conn != nil {
-			tw.conn.Close()
-		}
-		tw.isDisposed = true
+	tw.mu.Lock()
+	if tw.isDisposed {
+		tw.mu.Unlock()
+		return
 	}
+
+	if tw.conn != nil {
+		tw.conn.Close()
+		tw.conn = nil
+		tw.endConn()
+	}
+	defer releaseQueued(tw.preConnectQueue)
+	tw.preConnectQueue = nil
+	tw.isDisposed = true
+	tw.setState(StateDisposed)
+	tw.releaseInstanceLock()
+	tw.mu.Unlock()
+
+	tw.fragHandler.Dispose()
+	tw.DetachPublisher()
+	tw.csvWriters.Store(nil)
+	tw.throttle.stop()
+	tw.stopResubscribeCheck()
+	tw.stopDayRollover()
+	tw.flushSubscriptionFile()
+	tw.failPendingQuotes(ErrClientDisposed)
+	tw.failPendingAcks(ErrClientDisposed)
+	tw.closeEvents()
 }
 
 // Example usage
@@ -0,0 +1,252 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitMessages(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		delimiter string
+		want      []string
+	}{
+		{"empty input", "", "63=", nil},
+		{"empty delimiter", "63=FT3.0", "", nil},
+		{"no delimiter", "64=127|1=2", "63=", nil},
+		{"delimiter at start", "63=a|63=b|", "63=", []string{"63=a|", "63=b|"}},
+		{"text before first", "xx63=a|63=b", "63=", []string{"63=a|", "63=b"}},
+		{"repeated delimiters", "63=63=63=a", "63=", []string{"63=", "63=", "63=a"}},
+		{"delimiter only", "63=", "63=", []string{"63="}},
+		{"no overlap", "aaaa", "aa", []string{"aa", "aa"}},
+		{"odd overlap", "aaaaa", "aa", []string{"aa", "aaa"}},
+	}
+	for _, tt := range tests {
+		if got := SplitMessages(tt.input, tt.delimiter); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: SplitMessages(%q, %q) = %q, want %q", tt.name, tt.input, tt.delimiter, got, tt.want)
+		}
+	}
+}
+
+// BenchmarkSplitMessages splits 256 KB and 1 MB of messages; the time per byte stays flat
+func BenchmarkSplitMessages(b *testing.B) {
+	message := "63=FT3.0|64=206|65=84|66=14:59:22|1=1|7=22|8=245650|"
+	for _, size := range []struct {
+		name  string
+		bytes int
+	}{{"256KB", 256 << 10}, {"1MB", 1 << 20}} {
+		input := strings.Repeat(message, size.bytes/len(message))
+		b.Run(size.name, func(b *testing.B) {
+			b.SetBytes(int64(len(input)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				SplitMessages(input, "63=FT3.0")
+			}
+		})
+	}
+}
+
+// touchlineMessage returns a touchline response carrying the 64 byte binary block
+func touchlineMessage(segment, token, ltp uint32) []byte {
+	block := make([]byte, touchlineBlockSize)
+	binary.LittleEndian.PutUint32(block[0:], segment)
+	binary.LittleEndian.PutUint32(block[4:], token)
+	binary.LittleEndian.PutUint32(block[16:], ltp)
+	binary.LittleEndian.PutUint32(block[52:], 100)
+	return append([]byte("63=FT3.0|64=206|50="), block...)
+}
+
+// manyMessages returns n touchline responses for different tokens
+func manyMessages(n int) [][]byte {
+	msgs := make([][]byte, n)
+	for i := range msgs {
+		msgs[i] = touchlineMessage(1, uint32(1000+i), uint32(24000+i))
+	}
+	return msgs
+}
+
+func TestDefragmentManyMessages(t *testing.T) {
+	want := manyMessages(100)
+	got, err := NewFragmentationHandler().Defragment(frameOf(want...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d messages, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Fatalf("message %d = %q, want %q", i, got[i], want[i])
+		}
+		if cap(got[i]) != len(got[i]) {
+			t.Fatalf("message %d has spare capacity %d, appending to it would overwrite the next", i, cap(got[i])-len(got[i]))
+		}
+	}
+}
+
+// BenchmarkDefragmentManyMessages splits a frame of 100 inner messages
+func BenchmarkDefragmentManyMessages(b *testing.B) {
+	frame := frameOf(manyMessages(100)...)
+	fh := NewFragmentationHandler()
+	b.SetBytes(int64(len(frame)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if msgs, err := fh.Defragment(frame); err != nil || len(msgs) != 100 {
+			b.Fatalf("got %d messages, %v", len(msgs), err)
+		}
+	}
+}
+
+func TestMessageBatchReplacesOnMessage(t *testing.T) {
+	tw := NewODINMarketFeedClient()
+	var single int
+	var batches [][]ParsedMessage
+	tw.OnMessage = func(string) { single++ }
+	tw.OnMessageBatch = func(msgs []ParsedMessage) { batches = append(batches, msgs) }
+
+	tw.responseReceived(frameOf(manyMessages(200)...), 0)
+
+	if single != 0 {
+		t.Errorf("OnMessage invoked %d times with OnMessageBatch set", single)
+	}
+	if len(batches) != 1 || len(batches[0]) != 200 {
+		t.Fatalf("got %d batches, want one of 200 messages", len(batches))
+	}
+	if tl := batches[0][199].Touchline; tl == nil || tl.Token != 1199 {
+		t.Errorf("last message decoded as %+v", batches[0][199])
+	}
+}
+
+// BenchmarkDelivery compares per-message and batch delivery of a 200 message frame
+func BenchmarkDelivery(b *testing.B) {
+	frame := frameOf(manyMessages(200)...)
+
+	b.Run("PerMessage", func(b *testing.B) {
+		tw := NewODINMarketFeedClient()
+		var delivered int
+		tw.OnMessage = func(string) { delivered++ }
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tw.responseReceived(frame, 0)
+		}
+	})
+	b.Run("Batch", func(b *testing.B) {
+		tw := NewODINMarketFeedClient()
+		var delivered int
+		tw.OnMessageBatch = func(msgs []ParsedMessage) { delivered += len(msgs) }
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tw.responseReceived(frame, 0)
+		}
+	})
+}
+
+// garbagePrefixed returns 64 KB of random bytes followed by a valid frame
+func garbagePrefixed() []byte {
+	garbage := make([]byte, 64<<10)
+	rand.New(rand.NewSource(7)).Read(garbage)
+	return append(garbage, frameOf(touchlineMessage(1, 22, 24500))...)
+}
+
+func TestResyncAfterGarbage(t *testing.T) {
+	tw := NewODINMarketFeedClient()
+	var got []TouchlineData
+	tw.OnTouchline = func(data TouchlineData) { got = append(got, data) }
+
+	tw.responseReceived(garbagePrefixed(), 0)
+
+	if len(got) != 1 || got[0].Token != 22 {
+		t.Fatalf("got %+v, want the touchline of token 22", got)
+	}
+	if skipped := tw.Stats().ResyncBytes; skipped != 64<<10 {
+		t.Errorf("ResyncBytes = %d, want %d", skipped, 64<<10)
+	}
+}
+
+// BenchmarkResyncGarbage defragments a valid packet behind a 64 KB garbage prefix
+func BenchmarkResyncGarbage(b *testing.B) {
+	data := garbagePrefixed()
+	fh := NewFragmentationHandler()
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if msgs, err := fh.Defragment(data); err != nil || len(msgs) != 1 {
+			b.Fatalf("got %d messages, %v", len(msgs), err)
+		}
+	}
+}
+
+func TestBuildURL(t *testing.T) {
+	tests := []struct {
+		host   string
+		port   int
+		useSSL bool
+		want   string // empty when the host or port is invalid
+	}{
+		{"172.25.100.26", 4509, false, "ws://172.25.100.26:4509"},
+		{" 10.0.0.1 ", 443, true, "wss://10.0.0.1:443"},
+		{"fd00::12", 4509, false, "ws://[fd00::12]:4509"},
+		{"[fd00::12]", 4509, true, "wss://[fd00::12]:4509"},
+		{"::1", 80, false, "ws://[::1]:80"},
+		{"feed-primary.example-broker.in", 4509, true, "wss://feed-primary.example-broker.in:4509"},
+		{"localhost", 8080, false, "ws://localhost:8080"},
+		{"", 4509, false, ""},
+		{"-feed.example.in", 4509, false, ""},
+		{"feed_host", 4509, false, ""},
+		{"[10.0.0.1]", 4509, false, ""},
+		{"[fd00::12", 4509, false, ""},
+		{"fd00::12:4509", 0, false, ""},
+		{"10.0.0.1", 65536, false, ""},
+	}
+	for _, tt := range tests {
+		got, err := buildURL(tt.host, tt.port, tt.useSSL)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("buildURL(%q, %d) = %q, want an error", tt.host, tt.port, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("buildURL(%q, %d) = %q, %v, want %q", tt.host, tt.port, got, err, tt.want)
+		}
+	}
+}
+
+func TestNextFrameFlag(t *testing.T) {
+	tests := []struct {
+		data string
+		want int
+	}{
+		{"", -1},
+		{"abc", -1},
+		{"ab\x05c\x02", 2},
+		{"ab\x02c\x05", 2},
+		{"\x02", 0},
+		{"abc\x05", 3},
+	}
+	for _, tt := range tests {
+		if got := nextFrameFlag([]byte(tt.data)); got != tt.want {
+			t.Errorf("nextFrameFlag(%q) = %d, want %d", tt.data, got, tt.want)
+		}
+	}
+}
+
+// BenchmarkResyncFlagGarbage defragments a valid packet behind 64 KB of compressed flags
+// with invalid headers and no uncompressed flag, which a search for each flag kind over the
+// rest of the buffer would make quadratic
+func BenchmarkResyncFlagGarbage(b *testing.B) {
+	garbage := bytes.Repeat([]byte{FrameCompressed, 'x'}, 32<<10)
+	data := append(garbage, frameOf(touchlineMessage(1, 22, 24500))...)
+	fh := NewFragmentationHandler()
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		if msgs, err := fh.Defragment(data); err != nil || len(msgs) != 1 {
+			b.Fatalf("got %d messages, %v", len(msgs), err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package ODINMarketFeed
+
+// Option configures an ODINMarketFeedClient at construction time
+type Option func(*ODINMarketFeedClient)
+
+// WithPreConnectQueue queues subscription requests made while the client is not
+// connected and sends them in order immediately after login. maxQueued caps the
+// number of queued requests (0 means unlimited). Without this option subscription
+// requests fail with "WebSocket is not connected" while disconnected.
+func WithPreConnectQueue(maxQueued int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.queueEnabled = true
+		tw.maxQueued = maxQueued
+	}
+}
+
+// WithUnsubscribeOnClose makes Close send unsubscribe requests for every tracked
+// subscription before the websocket close frame. The file of WithSubscriptionFile keeps the
+// subscriptions as they were before the unsubscribes.
+func WithUnsubscribeOnClose() Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.unsubscribeOnClose = true
+	}
+}
+
+// WithSwitchOnConnect makes Connect on a connected client close the current connection, wait
+// for its receive loop to exit, connect and replay the tracked subscriptions, instead of
+// returning ErrAlreadyConnected. Connect must then not be called from a callback, which
+// runs on the receive loop it would wait for.
+func WithSwitchOnConnect() Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.switchOnConnect = true
+	}
+}
+
+// WithMaxDecompressedSize limits the decompressed payload of each received frame to n bytes.
+// Frames that decompress to more are discarded and reported like other undecodable frames.
+// By default the limit of a frame grows with its inner headers as they are decompressed:
+// each allows its declared payload and a small slack, so a frame that decompresses to
+// anything but inner frames is rejected early. A custom Decompressor is limited to
+// DefaultMaxDecompressedSize instead.
+func WithMaxDecompressedSize(n int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.fragHandler.MaxDecompressedSize = n
+	}
+}
+
+// WithRawFields fills ParsedMessage.Fields with every textual tag of each message in wire
+// order, including tags the decoder does not interpret
+func WithRawFields() Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.decoder.KeepFields = true
+	}
+}
@@ -0,0 +1,89 @@
+package ODINMarketFeed
+
+import "bytes"
+
+// poisonByte overwrites zero-copy payloads after their callback returns in strict mode
+const poisonByte = 0xDD
+
+// WithUnsafeZeroCopy delivers the payloads of the named callbacks without copying them.
+// The callbacks are "OnRawBatch", "OnInnerMessage", "OnUnknownMessage" and
+// "OnMessageBatch"; other names are ignored. By default these callbacks receive copies they
+// own and may retain. With zero copy the slices belong to the client and are only valid
+// until the callback returns; they must not be modified or retained. With WithStrictErrors
+// the callbacks receive private buffers that are overwritten with 0xDD when they return,
+// so code that retains them fails loudly in tests.
+//
+// Typed callbacks such as OnTouchline and OnBestFive, and OnJSON, always receive values
+// they own.
+func WithUnsafeZeroCopy(callbacks ...string) Option {
+	return func(tw *ODINMarketFeedClient) {
+		if tw.zeroCopy == nil {
+			tw.zeroCopy = make(map[string]bool)
+		}
+		for _, callback := range callbacks {
+			tw.zeroCopy[callback] = true
+		}
+	}
+}
+
+// deliverBytes invokes fn with the payload to deliver to the callback: a copy, data itself
+// with zero copy, or a private buffer poisoned after fn returns with zero copy in strict
+// mode
+func (tw *ODINMarketFeedClient) deliverBytes(callback string, data []byte, fn func([]byte)) {
+	if !tw.zeroCopy[callback] || tw.strictErrors {
+		data = bytes.Clone(data)
+	}
+	fn(data)
+	if tw.zeroCopy[callback] && tw.strictErrors {
+		poison(data)
+	}
+}
+
+// deliverBatch is deliverBytes for the inner messages of a frame
+func (tw *ODINMarketFeedClient) deliverBatch(callback string, msgs [][]byte, fn func([][]byte)) {
+	if tw.zeroCopy[callback] && !tw.strictErrors {
+		fn(msgs)
+		return
+	}
+
+	owned := make([][]byte, len(msgs))
+	for i, msg := range msgs {
+		owned[i] = bytes.Clone(msg)
+	}
+	fn(owned)
+	if tw.zeroCopy[callback] {
+		for _, msg := range owned {
+			poison(msg)
+		}
+	}
+}
+
+// deliverMessages is deliverBytes for the messages of OnMessageBatch, whose slice and Raw
+// fields alias buffers of the client
+func (tw *ODINMarketFeedClient) deliverMessages(msgs []ParsedMessage, fn func([]ParsedMessage)) {
+	const callback = "OnMessageBatch"
+	if tw.zeroCopy[callback] && !tw.strictErrors {
+		fn(msgs)
+		return
+	}
+
+	owned := make([]ParsedMessage, len(msgs))
+	for i, msg := range msgs {
+		msg.Raw = bytes.Clone(msg.Raw)
+		owned[i] = msg
+	}
+	fn(owned)
+	if tw.zeroCopy[callback] {
+		for i := range owned {
+			poison(owned[i].Raw)
+			owned[i] = ParsedMessage{Raw: owned[i].Raw}
+		}
+	}
+}
+
+// poison overwrites a buffer whose delivery has ended
+func poison(data []byte) {
+	for i := range data {
+		data[i] = poisonByte
+	}
+}
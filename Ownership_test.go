@@ -0,0 +1,67 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"testing"
+)
+
+// retainRaw runs a frame through a client and returns the slices OnRawBatch retained
+func retainRaw(opts ...Option) (retained [][]byte, sent []byte) {
+	tw := NewODINMarketFeedClient(opts...)
+	tw.OnRawBatch = func(msgs [][]byte) { retained = append(retained, msgs...) }
+
+	sent = touchlineMessage(1, 22, 24500)
+	tw.responseReceived(frameOf(sent), 0)
+	return retained, sent
+}
+
+func TestRetainedPayloadIsCopyByDefault(t *testing.T) {
+	retained, sent := retainRaw()
+	if len(retained) != 1 || !bytes.Equal(retained[0], sent) {
+		t.Fatalf("retained %q, want %q", retained, sent)
+	}
+}
+
+func TestRetainedZeroCopyPayloadIsPoisonedInStrictMode(t *testing.T) {
+	retained, _ := retainRaw(WithUnsafeZeroCopy("OnRawBatch"), WithStrictErrors())
+	if len(retained) != 1 {
+		t.Fatalf("retained %d messages, want 1", len(retained))
+	}
+	if want := bytes.Repeat([]byte{poisonByte}, len(retained[0])); !bytes.Equal(retained[0], want) {
+		t.Errorf("retained zero-copy payload was not poisoned: %q", retained[0])
+	}
+}
+
+func TestRetainedZeroCopyBatchIsPoisonedInStrictMode(t *testing.T) {
+	tw := NewODINMarketFeedClient(WithUnsafeZeroCopy("OnMessageBatch"), WithStrictErrors())
+	var retained []ParsedMessage
+	tw.OnMessageBatch = func(msgs []ParsedMessage) { retained = msgs }
+
+	tw.responseReceived(frameOf(touchlineMessage(1, 22, 24500)), 0)
+
+	if len(retained) != 1 || retained[0].Touchline != nil || retained[0].Raw[0] != poisonByte {
+		t.Errorf("retained zero-copy batch was not poisoned: %+v", retained)
+	}
+}
+
+// BenchmarkRawBatchCopies measures the default copies of OnRawBatch against zero copy on a
+// 200 message frame
+func BenchmarkRawBatchCopies(b *testing.B) {
+	frame := frameOf(manyMessages(200)...)
+	for _, mode := range []struct {
+		name string
+		opts []Option
+	}{
+		{"Copy", nil},
+		{"ZeroCopy", []Option{WithUnsafeZeroCopy("OnRawBatch")}},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			tw := NewODINMarketFeedClient(mode.opts...)
+			tw.OnRawBatch = func([][]byte) {}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				tw.responseReceived(frame, 0)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package ODINMarketFeed
+
+import (
+	"fmt"
+)
+
+// PayloadTransform rewrites the payload of a message, for example to encrypt it for a gateway
+// that requires it. msgType is the 64= code of an outgoing request, or -1 for received
+// payloads, whose code cannot be read before the transform.
+type PayloadTransform func(msgType int, payload []byte) ([]byte, error)
+
+// SetPayloadTransform sets the transform applied to each outgoing request after it has been
+// built and passed through the send interceptors, and before it is compressed and framed.
+// The transform decides from msgType which requests to rewrite and returns the others
+// unchanged. When it fails, the request is not sent and the send returns the error. nil
+// removes the transform.
+func (tw *ODINMarketFeedClient) SetPayloadTransform(transform PayloadTransform) {
+	if transform == nil {
+		tw.sendTransform.Store(nil)
+		return
+	}
+	tw.sendTransform.Store(&transform)
+}
+
+// SetInboundPayloadTransform sets the transform applied to the decompressed payload of each
+// received frame before it is split into inner messages, for gateways that encrypt their
+// responses. A payload the transform fails on is dropped and reported like undecodable data
+// (see WithStrictErrors). nil removes the transform.
+func (tw *ODINMarketFeedClient) SetInboundPayloadTransform(transform PayloadTransform) {
+	tw.fragHandler.setInboundTransform(transform)
+}
+
+// transformPayload applies the SetPayloadTransform transform to an outgoing request
+func (tw *ODINMarketFeedClient) transformPayload(message string) ([]byte, error) {
+	transform := tw.sendTransform.Load()
+	if transform == nil {
+		return []byte(message), nil
+	}
+
+	code := messageCode(message)
+	payload, err := (*transform)(code, []byte(message))
+	if err != nil {
+		return nil, fmt.Errorf("payload transform failed for 64=%d: %w", code, err)
+	}
+	return payload, nil
+}
+
+// setInboundTransform sets the transform applied to decompressed payloads
+func (fh *FragmentationHandler) setInboundTransform(transform PayloadTransform) {
+	fh.mu.Lock()
+	defer fh.mu.Unlock()
+	fh.inboundTransform = transform
+}
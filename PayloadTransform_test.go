@@ -0,0 +1,63 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestInboundTransformErrorDropsFrame(t *testing.T) {
+	tw := newTestClient(WithStrictErrors())
+	var reported []string
+	tw.OnError = func(err string) { reported = append(reported, err) }
+	var got []TouchlineData
+	tw.OnTouchline = func(td TouchlineData) { got = append(got, td) }
+
+	calls := 0
+	tw.SetInboundPayloadTransform(func(msgType int, payload []byte) ([]byte, error) {
+		if calls++; calls == 1 {
+			return nil, errors.New("bad key")
+		}
+		return payload, nil
+	})
+	tw.responseReceived(frameOf(touchlineMessage(1, 22, 24500)), 0)
+	tw.responseReceived(frameOf(touchlineMessage(1, 23, 24600)), 0)
+
+	if len(got) != 1 || got[0].Token != 23 {
+		t.Fatalf("delivered %+v, want only the touchline of token 23", got)
+	}
+	if discards := tw.Stats().Discards; discards != 1 {
+		t.Errorf("Discards = %d, want 1", discards)
+	}
+	if len(reported) != 1 || !strings.Contains(reported[0], "bad key") {
+		t.Errorf("OnError received %q, want the transform failure", reported)
+	}
+}
+
+func TestSendTransformErrorFailsSend(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	tw.SetPayloadTransform(func(msgType int, payload []byte) ([]byte, error) {
+		if msgType == msgCodeTouchline {
+			return nil, errors.New("no key for 64=206")
+		}
+		return payload, nil
+	})
+	err := tw.SubscribeTouchlineWithOptions([]string{"1_22"}, TouchlineOptions{})
+	if err == nil || !strings.Contains(err.Error(), "no key for 64=206") {
+		t.Fatalf("got %v, want the transform failure", err)
+	}
+	if n := tw.SubscribedInstruments(); n != 0 {
+		t.Errorf("%d instruments tracked after the send failed", n)
+	}
+
+	if err := tw.SubscribeLTPTouchline([]string{"1_22"}); err != nil {
+		t.Fatal(err)
+	}
+	ms.next(t, msgCodeLTPTouchline)
+}
@@ -0,0 +1,289 @@
+package ODINMarketFeed
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// subscriptionFileVersion is the version of the SaveSubscriptions format
+const subscriptionFileVersion = 1
+
+// subscriptionSaveDelay is how long WithSubscriptionFile waits for further registry changes
+// before writing the file
+const subscriptionSaveDelay = 500 * time.Millisecond
+
+type subscriptionFileJSON struct {
+	Version       int                `json:"version"`
+	Subscriptions []subscriptionJSON `json:"subscriptions"`
+}
+
+type subscriptionJSON struct {
+	Type          string   `json:"type"`
+	SegmentID     int      `json:"segment_id"`
+	Token         int      `json:"token"`
+	Symbol        string   `json:"symbol,omitempty"`
+	ResponseType  string   `json:"response_type,omitempty"`
+	LTPChangeOnly bool     `json:"ltp_change_only,omitempty"`
+	Groups        []string `json:"groups,omitempty"`
+	Direct        bool     `json:"direct,omitempty"` // grouped and also subscribed directly
+}
+
+// subscriptionFile holds the state of WithSubscriptionFile
+type subscriptionFile struct {
+	path     string
+	mu       sync.Mutex
+	timer    *time.Timer
+	restored bool
+	closed   bool // no more saves, see closeSubscriptionFile
+}
+
+// WithSubscriptionFile saves the subscription registry to path shortly after every change
+// and, after the first successful login, restores and re-subscribes the subscriptions saved
+// by a previous process. A missing file is not an error. The unsubscribes sent by Close with
+// WithUnsubscribeOnClose are not saved, so the file keeps the subscriptions for the next
+// process.
+func WithSubscriptionFile(path string) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.subFile = &subscriptionFile{path: path}
+	}
+}
+
+// SaveSubscriptions writes the tracked subscriptions and their group memberships as JSON
+func (tw *ODINMarketFeedClient) SaveSubscriptions(w io.Writer) error {
+	file := subscriptionFileJSON{Version: subscriptionFileVersion, Subscriptions: []subscriptionJSON{}}
+	for _, sub := range tw.Subscriptions() {
+		tw.subMu.Lock()
+		groups := tw.groupNamesLocked(sub.key())
+		_, direct := tw.direct[sub.key()]
+		tw.subMu.Unlock()
+
+		file.Subscriptions = append(file.Subscriptions, subscriptionJSON{
+			Type:          sub.Type.String(),
+			SegmentID:     sub.Instrument.MarketSegmentID,
+			Token:         sub.Instrument.Token,
+			Symbol:        sub.Instrument.Symbol,
+			ResponseType:  sub.ResponseType,
+			LTPChangeOnly: sub.LTPChangeOnly,
+			Groups:        groups,
+			Direct:        direct && len(groups) > 0,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(file)
+}
+
+// LoadSubscriptions adds the subscriptions written by SaveSubscriptions to the registry
+// and their groups, creating missing groups, without sending requests; call ResubscribeAll
+// to subscribe them. Nothing is added if any entry is invalid.
+func (tw *ODINMarketFeedClient) LoadSubscriptions(r io.Reader) error {
+	subscriptions, groups, direct, err := readSubscriptions(r)
+	if err != nil {
+		return err
+	}
+
+	tw.subMu.Lock()
+	for _, sub := range subscriptions {
+		tw.subscriptions[sub.key()] = sub
+	}
+	tw.joinGroupsLocked(groups)
+	for _, key := range direct {
+		tw.holdDirectlyLocked(key)
+	}
+	tw.subMu.Unlock()
+
+	tw.subscriptionsChanged()
+	return nil
+}
+
+// readSubscriptions decodes and validates a subscription file. The group names of each
+// subscription are returned by key, with the grouped subscriptions also held directly.
+func readSubscriptions(r io.Reader) ([]Subscription, map[subscriptionKey][]string, []subscriptionKey, error) {
+	var file subscriptionFileJSON
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid subscription file: %w", err)
+	}
+	if file.Version != subscriptionFileVersion {
+		return nil, nil, nil, fmt.Errorf("unsupported subscription file version %d (expected %d)", file.Version, subscriptionFileVersion)
+	}
+
+	subscriptions := make([]Subscription, 0, len(file.Subscriptions))
+	groups := make(map[subscriptionKey][]string)
+	var direct []subscriptionKey
+	for i, entry := range file.Subscriptions {
+		subType, ok := parseSubscriptionType(entry.Type)
+		if !ok {
+			return nil, nil, nil, fmt.Errorf("invalid subscription file: entry %d has unknown type '%s'", i, entry.Type)
+		}
+		if entry.SegmentID <= 0 || entry.Token <= 0 {
+			return nil, nil, nil, fmt.Errorf("invalid subscription file: entry %d has invalid segment %d or token %d", i, entry.SegmentID, entry.Token)
+		}
+
+		subscriptions = append(subscriptions, Subscription{
+			Type:          subType,
+			Instrument:    Instrument{MarketSegmentID: entry.SegmentID, Token: entry.Token, Symbol: entry.Symbol},
+			ResponseType:  entry.ResponseType,
+			LTPChangeOnly: entry.LTPChangeOnly,
+		})
+		for _, name := range entry.Groups {
+			if strings.TrimSpace(name) == "" {
+				return nil, nil, nil, fmt.Errorf("invalid subscription file: entry %d has an empty group name", i)
+			}
+		}
+		if len(entry.Groups) > 0 {
+			groups[subscriptions[len(subscriptions)-1].key()] = entry.Groups
+			if entry.Direct {
+				direct = append(direct, subscriptions[len(subscriptions)-1].key())
+			}
+		}
+	}
+	return subscriptions, groups, direct, nil
+}
+
+func parseSubscriptionType(name string) (SubscriptionType, bool) {
+	for _, subType := range []SubscriptionType{SubscriptionTouchline, SubscriptionLTPTouchline, SubscriptionBestFive} {
+		if subType.String() == name {
+			return subType, true
+		}
+	}
+	return 0, false
+}
+
+// subscriptionsChanged re-evaluates the subscription quota and schedules a save of the
+// subscription file
+func (tw *ODINMarketFeedClient) subscriptionsChanged() {
+	tw.updateQuota()
+
+	sf := tw.subFile
+	if sf == nil {
+		return
+	}
+
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	if sf.closed {
+		return
+	}
+	if sf.timer != nil {
+		sf.timer.Stop()
+	}
+	sf.timer = time.AfterFunc(subscriptionSaveDelay, tw.saveSubscriptionFile)
+}
+
+// closeSubscriptionFile writes a pending save and stops saving, so that emptying the
+// registry on close does not empty the file
+func (tw *ODINMarketFeedClient) closeSubscriptionFile() {
+	sf := tw.subFile
+	if sf == nil {
+		return
+	}
+
+	sf.mu.Lock()
+	pending := sf.timer != nil && sf.timer.Stop()
+	sf.timer = nil
+	sf.closed = true
+	sf.mu.Unlock()
+
+	if pending {
+		tw.saveSubscriptionFile()
+	}
+}
+
+// flushSubscriptionFile writes a pending save immediately
+func (tw *ODINMarketFeedClient) flushSubscriptionFile() {
+	sf := tw.subFile
+	if sf == nil {
+		return
+	}
+
+	sf.mu.Lock()
+	pending := sf.timer != nil && sf.timer.Stop()
+	sf.timer = nil
+	sf.mu.Unlock()
+
+	if pending {
+		tw.saveSubscriptionFile()
+	}
+}
+
+// saveSubscriptionFile writes the registry to a temporary file and renames it over the
+// subscription file, so a crash never leaves a partial file behind
+func (tw *ODINMarketFeedClient) saveSubscriptionFile() {
+	path := tw.subFile.path
+	err := func() error {
+		tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+
+		if err := tw.SaveSubscriptions(tmp); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		return os.Rename(tmp.Name(), path)
+	}()
+
+	if err != nil {
+		tw.reportError(fmt.Sprintf("Failed to save subscriptions to %s: %v", path, err))
+	}
+}
+
+// restoreSubscriptionFile subscribes the saved subscriptions after the first login
+func (tw *ODINMarketFeedClient) restoreSubscriptionFile() {
+	sf := tw.subFile
+	if sf == nil {
+		return
+	}
+
+	sf.mu.Lock()
+	restored := sf.restored
+	sf.restored = true
+	sf.mu.Unlock()
+	if restored {
+		return
+	}
+
+	err := func() error {
+		file, err := os.Open(sf.path)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		subscriptions, groups, direct, err := readSubscriptions(file)
+		if err != nil {
+			return err
+		}
+
+		tw.subMu.Lock()
+		tw.joinGroupsLocked(groups)
+		for _, key := range direct {
+			tw.holdDirectlyLocked(key)
+		}
+		tw.subMu.Unlock()
+
+		return tw.resubscribe(subscriptions)
+	}()
+
+	if err != nil {
+		tw.reportError(fmt.Sprintf("Failed to restore subscriptions from %s: %v", sf.path, err))
+	}
+}
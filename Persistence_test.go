@@ -0,0 +1,42 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestUnsubscribeOnCloseKeepsSubscriptionFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "subscriptions.json")
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithSubscriptionFile(path), WithUnsubscribeOnClose())
+	ms.connect(t, tw)
+
+	if err := tw.SubscribeTouchlineWithOptions([]string{"1_22", "1_23"}, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if request := ms.next(t, msgCodeTouchline); !strings.Contains(request, "230=1") {
+		t.Fatalf("first touchline request %q is not the subscribe", request)
+	}
+	if request := ms.next(t, msgCodeTouchline); !strings.Contains(request, "230=2") {
+		t.Fatalf("Close sent %q, want the unsubscribe", request)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	subscriptions, _, _, err := readSubscriptions(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(subscriptions) != 2 {
+		t.Errorf("subscription file holds %d subscriptions after Close, want 2", len(subscriptions))
+	}
+}
@@ -0,0 +1,56 @@
+package ODINMarketFeed
+
+import (
+	"math"
+	"strconv"
+)
+
+// WithChangeTags appends the NetChange and PercentChange of each touchline to the message
+// text delivered to OnMessage, under the given tags. By default the text carries only the
+// fields of the packet, so existing string parsers are unaffected. A tag of 0 leaves that
+// field out.
+func WithChangeTags(netChangeTag, percentChangeTag int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.decoder.NetChangeTag = netChangeTag
+		tw.decoder.PercentChangeTag = percentChangeTag
+	}
+}
+
+// computeChange sets NetChange, PercentChange and ChangeOk from the last traded price, or
+// the indicative price before the first trade, relative to the previous close
+func (td *TouchlineData) computeChange() {
+	price := td.LTP
+	if price == 0 {
+		price = td.IndicativeClosePrice
+	}
+	if td.PrevClosePrice == 0 || price == 0 {
+		td.NetChange = 0
+		td.PercentChange = math.NaN()
+		td.ChangeOk = false
+		return
+	}
+
+	diff := float64(price) - float64(td.PrevClosePrice)
+	td.NetChange = diff
+	if td.DecimalLocator != 0 {
+		td.NetChange /= float64(td.DecimalLocator)
+	}
+	td.PercentChange = diff / float64(td.PrevClosePrice) * 100
+	td.ChangeOk = true
+}
+
+// changeTags formats the change fields under the configured tags
+func (d *Decoder) changeTags(td TouchlineData) string {
+	if !td.ChangeOk {
+		return ""
+	}
+
+	var tags string
+	if d.NetChangeTag != 0 {
+		tags += strconv.Itoa(d.NetChangeTag) + "=" + strconv.FormatFloat(td.NetChange, 'f', -1, 64) + "|"
+	}
+	if d.PercentChangeTag != 0 {
+		tags += strconv.Itoa(d.PercentChangeTag) + "=" + strconv.FormatFloat(td.PercentChange, 'f', 2, 64) + "|"
+	}
+	return tags
+}
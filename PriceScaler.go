@@ -0,0 +1,74 @@
+package ODINMarketFeed
+
+import (
+	"math"
+	"sync"
+)
+
+// PriceScaler converts raw integer prices to decimal values. By default the divisor is the
+// decimal locator carried on the wire. A segment registered with RegisterSegmentDecimals
+// always uses 10^decimals instead, so the override wins over the wire value.
+type PriceScaler struct {
+	decimals map[uint32]int
+	mu       sync.RWMutex
+}
+
+// NewPriceScaler creates a PriceScaler without segment overrides
+func NewPriceScaler() *PriceScaler {
+	return &PriceScaler{decimals: make(map[uint32]int)}
+}
+
+// WithPriceScaler rewrites the DecimalLocator of decoded touchline and Best Five data to the
+// scaler's divisor before it is delivered, so typed callbacks and JSON output use it
+func WithPriceScaler(scaler *PriceScaler) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.priceScaler = scaler
+	}
+}
+
+// RegisterSegmentDecimals fixes the number of decimal places of a market segment
+func (ps *PriceScaler) RegisterSegmentDecimals(segID uint32, decimals int) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.decimals[segID] = decimals
+}
+
+// Divisor returns the divisor for prices of the segment. A nil scaler uses the locator.
+// The result is never 0.
+func (ps *PriceScaler) Divisor(segID, decimalLocator uint32) uint32 {
+	if ps != nil {
+		ps.mu.RLock()
+		decimals, ok := ps.decimals[segID]
+		ps.mu.RUnlock()
+		if ok {
+			return uint32(math.Pow10(decimals))
+		}
+	}
+
+	if decimalLocator == 0 {
+		return 1
+	}
+	return decimalLocator
+}
+
+// Float returns the raw price as a decimal value
+func (ps *PriceScaler) Float(segID, decimalLocator, raw uint32) float64 {
+	return float64(raw) / float64(ps.Divisor(segID, decimalLocator))
+}
+
+// Fixed returns the raw price as a fixed-point value in minor units together with the
+// number of decimal places, e.g. 123450 and 2 for 1234.50 rupees (paise). When the divisor
+// is not a power of ten the value is rounded to 4 decimal places.
+func (ps *PriceScaler) Fixed(segID, decimalLocator, raw uint32) (value int64, decimals int) {
+	divisor := ps.Divisor(segID, decimalLocator)
+
+	for power := uint32(1); decimals <= 9; decimals++ {
+		if power == divisor {
+			return int64(raw), decimals
+		}
+		power *= 10
+	}
+
+	const fallbackDecimals = 4
+	return int64(math.Round(float64(raw) * math.Pow10(fallbackDecimals) / float64(divisor))), fallbackDecimals
+}
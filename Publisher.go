@@ -0,0 +1,218 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultPublishQueue is the number of messages waiting for a publisher by default
+const defaultPublishQueue = 1024
+
+// Publisher sends encoded market data to a message broker such as Kafka or NATS
+type Publisher interface {
+	Publish(topic string, key []byte, value []byte) error
+}
+
+// PublishConfig configures AttachPublisher
+type PublishConfig struct {
+	// Topics maps MessageTouchline and MessageBestFive to their topics; kinds without a
+	// topic are not published. nil publishes both, to "touchline" and "bestfive".
+	Topics map[MessageKind]string
+	// Encoder encodes a TouchlineData or BestFiveData value; nil encodes JSON with the
+	// WithJSONOptions options
+	Encoder func(value interface{}) ([]byte, error)
+	// QueueSize is the number of messages that may wait for the publisher (default 1024).
+	// Messages arriving while the queue is full are dropped and counted in
+	// Stats.PublishDrops, so a slow broker never stalls the receive loop.
+	QueueSize int
+}
+
+// publishItem is a message waiting for the publisher
+type publishItem struct {
+	topic string
+	key   []byte
+	value interface{}
+	size  int64 // estimated memory, accounted against WithDeliveryMemoryLimit
+}
+
+// publisherAttachment runs a Publisher on its own goroutine. mu guards closed, so that no
+// message is queued after the queue is closed.
+type publisherAttachment struct {
+	publisher Publisher
+	topics    map[MessageKind]string
+	encoder   func(value interface{}) ([]byte, error)
+	queue     chan publishItem
+	done      chan struct{}
+	reporting int32 // OnError is running on the publisher goroutine
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// AttachPublisher publishes every touchline and Best Five response delivered by the client,
+// after throttling and deduplication, keyed by "MarketSegmentID_Token". Messages are
+// encoded and published on a goroutine of the publisher; its failures are reported through
+// OnError and counted in Stats.PublishErrors. A publisher that is already attached is
+// detached first. Close detaches the publisher.
+func (tw *ODINMarketFeedClient) AttachPublisher(p Publisher, cfg PublishConfig) error {
+	if p == nil {
+		return errors.New("publisher cannot be nil")
+	}
+
+	tw.mu.Lock()
+	disposed := tw.isDisposed
+	tw.mu.Unlock()
+	if disposed {
+		return ErrClientDisposed
+	}
+
+	topics := cfg.Topics
+	if topics == nil {
+		topics = map[MessageKind]string{MessageTouchline: "touchline", MessageBestFive: "bestfive"}
+	}
+	encoder := cfg.Encoder
+	if encoder == nil {
+		encoder = tw.encodeJSON
+	}
+	size := cfg.QueueSize
+	if size <= 0 {
+		size = defaultPublishQueue
+	}
+
+	attachment := &publisherAttachment{
+		publisher: p,
+		topics:    topics,
+		encoder:   encoder,
+		queue:     make(chan publishItem, size),
+		done:      make(chan struct{}),
+	}
+	tw.workers.spawn(func() { tw.runPublisher(attachment) })
+
+	if previous := tw.publisher.Swap(attachment); previous != nil {
+		tw.stopPublisher(previous)
+	}
+	return nil
+}
+
+// DetachPublisher stops publishing and returns once the queued messages have been passed to
+// the publisher, or after the WithCloseTimeout timeout. Called from OnError while it reports
+// a publish failure, it returns at once and the queue is drained after OnError returns.
+func (tw *ODINMarketFeedClient) DetachPublisher() {
+	if attachment := tw.publisher.Swap(nil); attachment != nil {
+		tw.stopPublisher(attachment)
+	}
+}
+
+// stopPublisher stops an attachment, logging when its queue is not drained in time
+func (tw *ODINMarketFeedClient) stopPublisher(attachment *publisherAttachment) {
+	timeout := tw.closeTimeoutOrDefault()
+	if !attachment.stop(timeout) {
+		tw.logf("Publisher did not drain its queue within %v", timeout)
+	}
+}
+
+// publish queues a message for the attached publisher, dropping it when the queue is full
+func (tw *ODINMarketFeedClient) publish(kind MessageKind, segID, token uint32, value interface{}) {
+	attachment := tw.publisher.Load()
+	if attachment == nil {
+		return
+	}
+	topic, ok := attachment.topics[kind]
+	if !ok || topic == "" {
+		return
+	}
+	key := []byte(strconv.FormatUint(uint64(segID), 10) + "_" + strconv.FormatUint(uint64(token), 10))
+	item := publishItem{topic: topic, key: key, value: value}
+	item.size = messageSize(value) + int64(len(key)+len(topic))
+
+	var pressure *MemoryPressure
+	defer func() { tw.memoryPressure(pressure) }()
+
+	attachment.mu.RLock()
+	defer attachment.mu.RUnlock()
+
+	if attachment.closed {
+		return
+	}
+	evictOldest := func() (int64, bool) {
+		select {
+		case oldest := <-attachment.queue:
+			return oldest.size, true
+		default:
+			return 0, false
+		}
+	}
+	var fits bool
+	if fits, pressure = tw.reserve(item.size, evictOldest); !fits {
+		return
+	}
+	select {
+	case attachment.queue <- item:
+	default:
+		tw.release(item.size)
+		atomic.AddUint64(&tw.stats.publishDrops, 1)
+	}
+}
+
+// runPublisher encodes and publishes the queued messages until the queue is closed
+func (tw *ODINMarketFeedClient) runPublisher(attachment *publisherAttachment) {
+	defer close(attachment.done)
+
+	for item := range attachment.queue {
+		tw.release(item.size)
+		value, err := attachment.encoder(item.value)
+		if err == nil {
+			err = attachment.publisher.Publish(item.topic, item.key, value)
+		}
+		if err != nil {
+			atomic.AddUint64(&tw.stats.publishErrors, 1)
+			if tw.OnError != nil {
+				atomic.StoreInt32(&attachment.reporting, 1)
+				tw.reportError(fmt.Sprintf("Failed to publish %s to %s: %v", item.key, item.topic, err))
+				atomic.StoreInt32(&attachment.reporting, 0)
+			}
+			continue
+		}
+		atomic.AddUint64(&tw.stats.published, 1)
+	}
+}
+
+// stop closes the queue and waits, for at most timeout, for the publisher goroutine to
+// drain it. It does not wait while the goroutine is in OnError, which may be the caller.
+// It reports false when the timeout passed first.
+func (pa *publisherAttachment) stop(timeout time.Duration) bool {
+	pa.mu.Lock()
+	if !pa.closed {
+		pa.closed = true
+		close(pa.queue)
+	}
+	pa.mu.Unlock()
+
+	if atomic.LoadInt32(&pa.reporting) != 0 {
+		return true
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-pa.done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// encodeJSON encodes a TouchlineData or BestFiveData value with the WithJSONOptions options
+func (tw *ODINMarketFeedClient) encodeJSON(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case TouchlineData:
+		return v.MarshalJSONWithOptions(tw.jsonOptions)
+	case BestFiveData:
+		return v.MarshalJSONWithOptions(tw.jsonOptions)
+	default:
+		return nil, fmt.Errorf("cannot encode %T", value)
+	}
+}
@@ -0,0 +1,68 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCloseWaitsForPublisher(t *testing.T) {
+	tw := newTestClient()
+	if err := tw.AttachPublisher(publisherFunc(func(string, []byte, []byte) error { return nil }), PublishConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if n := tw.Goroutines(); n != 1 {
+		t.Errorf("Goroutines = %d with a publisher attached, want 1", n)
+	}
+	tw.Close(context.Background())
+	if n := tw.Goroutines(); n != 0 {
+		t.Errorf("Goroutines = %d after Close, want 0", n)
+	}
+}
+
+// publisherFunc adapts a function to Publisher
+type publisherFunc func(topic string, key []byte, value []byte) error
+
+func (f publisherFunc) Publish(topic string, key []byte, value []byte) error {
+	return f(topic, key, value)
+}
+
+func TestDisposeFromPublisherOnError(t *testing.T) {
+	tw := newTestClient()
+	if err := tw.AttachPublisher(publisherFunc(func(string, []byte, []byte) error {
+		return errors.New("broker down")
+	}), PublishConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	disposed := make(chan struct{})
+	tw.OnError = func(string) {
+		tw.Dispose()
+		close(disposed)
+	}
+
+	tw.publish(MessageTouchline, 1, 22, TouchlineData{MktSegID: 1, Token: 22})
+	select {
+	case <-disposed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Dispose from OnError of the publisher did not return")
+	}
+	if !eventually(t, time.Second, func() bool { return tw.Goroutines() == 0 }) {
+		t.Errorf("%d goroutines left after the publisher was detached", tw.Goroutines())
+	}
+}
+
+func TestDetachPublisherIsBounded(t *testing.T) {
+	tw := newTestClient(WithCloseTimeout(50 * time.Millisecond))
+	release := make(chan struct{})
+	defer close(release)
+	if err := tw.AttachPublisher(publisherFunc(func(string, []byte, []byte) error {
+		<-release
+		return nil
+	}), PublishConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	tw.publish(MessageTouchline, 1, 22, TouchlineData{MktSegID: 1, Token: 22})
+	within(t, time.Second, "DetachPublisher with a stuck publisher", tw.DetachPublisher)
+}
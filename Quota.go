@@ -0,0 +1,211 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// defaultQuotaWarningPercent is the share of the subscription limit at which QuotaWarning is
+// emitted
+const defaultQuotaWarningPercent = 90
+
+// ErrSubscriptionLimitExceeded is returned by the Subscribe methods when the request would
+// take the number of subscribed instruments past WithSubscriptionLimit
+var ErrSubscriptionLimitExceeded = errors.New("subscription limit exceeded")
+
+// QuotaWarning is emitted and passed to OnQuotaWarning when the number of subscribed
+// instruments reaches the warning threshold, and again when a subscription beyond the limit
+// is allowed by WithSubscriptionLimitOverflow
+type QuotaWarning struct {
+	EventSource
+	Subscribed int
+	Limit      int
+	Exceeded   bool
+}
+
+func (QuotaWarning) isEvent() {}
+
+// subscriptionQuota tracks the subscription limit. warned and reserved are guarded by subMu.
+type subscriptionQuota struct {
+	limit          int
+	warningPercent int
+	allowOverflow  bool
+	warned         bool
+
+	// reserved counts the reservations held per instrument by subscribe requests that
+	// passed the limit check but are not tracked yet
+	reserved map[[2]int]int
+}
+
+// quotaReservation holds the subscription limit slots of a subscribe request from the limit
+// check until the request is tracked, so that concurrent requests cannot together exceed
+// the limit. A nil reservation holds nothing.
+type quotaReservation struct {
+	tw          *ODINMarketFeedClient
+	instruments []Instrument
+	once        sync.Once
+}
+
+// release returns the slots of the reservation. It may be called more than once.
+func (r *quotaReservation) release() {
+	if r == nil {
+		return
+	}
+	r.once.Do(func() {
+		tw := r.tw
+		tw.subMu.Lock()
+		for _, instrument := range r.instruments {
+			key := [2]int{instrument.MarketSegmentID, instrument.Token}
+			if tw.quota.reserved[key]--; tw.quota.reserved[key] <= 0 {
+				delete(tw.quota.reserved, key)
+			}
+		}
+		tw.subMu.Unlock()
+	})
+}
+
+// split replaces the reservation with one per instrument, for requests sent per instrument
+func (r *quotaReservation) split() []*quotaReservation {
+	if r == nil {
+		return nil
+	}
+	parts := make([]*quotaReservation, len(r.instruments))
+	for i, instrument := range r.instruments {
+		parts[i] = &quotaReservation{tw: r.tw, instruments: []Instrument{instrument}}
+	}
+	return parts
+}
+
+// WithSubscriptionLimit limits the number of distinct instruments subscribed across
+// touchline, LTP touchline and Best Five. Subscribe requests that would exceed it fail with
+// ErrSubscriptionLimitExceeded unless WithSubscriptionLimitOverflow is set.
+func WithSubscriptionLimit(n int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.quota.limit = n
+	}
+}
+
+// WithQuotaWarningPercent sets the share of the subscription limit, in percent, at which
+// QuotaWarning is emitted (default 90)
+func WithQuotaWarningPercent(percent int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.quota.warningPercent = percent
+	}
+}
+
+// WithSubscriptionLimitOverflow sends subscribe requests beyond the subscription limit and
+// reports them with QuotaWarning instead of refusing them
+func WithSubscriptionLimitOverflow() Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.quota.allowOverflow = true
+	}
+}
+
+// SubscribedInstruments returns the number of distinct instruments with a tracked
+// subscription of any type
+func (tw *ODINMarketFeedClient) SubscribedInstruments() int {
+	tw.subMu.Lock()
+	defer tw.subMu.Unlock()
+	return tw.distinctInstrumentsLocked(nil)
+}
+
+// distinctInstrumentsLocked counts the distinct instruments in the registry together with
+// the additional ones. subMu must be held.
+func (tw *ODINMarketFeedClient) distinctInstrumentsLocked(additional []Instrument) int {
+	seen := make(map[[2]int]struct{}, len(tw.subscriptions)+len(additional))
+	for key := range tw.subscriptions {
+		seen[[2]int{key.marketSegmentID, key.token}] = struct{}{}
+	}
+	for _, instrument := range additional {
+		seen[[2]int{instrument.MarketSegmentID, instrument.Token}] = struct{}{}
+	}
+	return len(seen)
+}
+
+// committedInstrumentsLocked counts the distinct instruments in the registry, those reserved
+// by requests in flight and the additional ones. subMu must be held.
+func (tw *ODINMarketFeedClient) committedInstrumentsLocked(additional []Instrument) int {
+	seen := make(map[[2]int]struct{}, len(tw.subscriptions)+len(tw.quota.reserved)+len(additional))
+	for key := range tw.subscriptions {
+		seen[[2]int{key.marketSegmentID, key.token}] = struct{}{}
+	}
+	for key := range tw.quota.reserved {
+		seen[key] = struct{}{}
+	}
+	for _, instrument := range additional {
+		seen[[2]int{instrument.MarketSegmentID, instrument.Token}] = struct{}{}
+	}
+	return len(seen)
+}
+
+// reserveQuota reports whether subscribing the instruments stays within the limit and
+// reserves their slots until the returned reservation is released. The check and the
+// reservation are made under subMu.
+func (tw *ODINMarketFeedClient) reserveQuota(instruments []Instrument) (*quotaReservation, error) {
+	if tw.quota.limit <= 0 {
+		return nil, nil
+	}
+
+	tw.subMu.Lock()
+	subscribed := tw.committedInstrumentsLocked(instruments)
+	allowed := subscribed <= tw.quota.limit || tw.quota.allowOverflow
+	var reservation *quotaReservation
+	if allowed {
+		if tw.quota.reserved == nil {
+			tw.quota.reserved = make(map[[2]int]int)
+		}
+		for _, instrument := range instruments {
+			tw.quota.reserved[[2]int{instrument.MarketSegmentID, instrument.Token}]++
+		}
+		reservation = &quotaReservation{tw: tw, instruments: instruments}
+	}
+	tw.subMu.Unlock()
+
+	if subscribed <= tw.quota.limit {
+		return reservation, nil
+	}
+
+	if allowed {
+		tw.quotaWarning(QuotaWarning{Subscribed: subscribed, Limit: tw.quota.limit, Exceeded: true})
+		return reservation, nil
+	}
+
+	err := fmt.Errorf("%w: %d instruments requested, limit is %d", ErrSubscriptionLimitExceeded, subscribed, tw.quota.limit)
+	tw.returnedError(err.Error())
+	return nil, err
+}
+
+// updateQuota emits QuotaWarning when the registry first reaches the warning threshold. The
+// warning is re-armed once the count drops below the threshold again.
+func (tw *ODINMarketFeedClient) updateQuota() {
+	if tw.quota.limit <= 0 {
+		return
+	}
+
+	percent := tw.quota.warningPercent
+	if percent <= 0 {
+		percent = defaultQuotaWarningPercent
+	}
+	threshold := (tw.quota.limit*percent + 99) / 100
+
+	tw.subMu.Lock()
+	subscribed := tw.distinctInstrumentsLocked(nil)
+	warn := subscribed >= threshold && !tw.quota.warned
+	tw.quota.warned = subscribed >= threshold
+	tw.subMu.Unlock()
+
+	if warn {
+		tw.quotaWarning(QuotaWarning{Subscribed: subscribed, Limit: tw.quota.limit, Exceeded: subscribed > tw.quota.limit})
+	}
+}
+
+func (tw *ODINMarketFeedClient) quotaWarning(warning QuotaWarning) {
+	warning.EventSource = tw.source()
+	tw.emit(warning)
+	if tw.OnQuotaWarning != nil {
+		tw.invokeCallback("OnQuotaWarning", func() string {
+			return fmt.Sprintf("%d/%d", warning.Subscribed, warning.Limit)
+		}, func() { tw.OnQuotaWarning(warning) })
+	}
+}
@@ -0,0 +1,75 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentSubscribeStaysWithinLimit(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithSubscriptionLimit(10))
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var succeeded, refused int
+	for i := 0; i < 40; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := tw.SubscribeTouchlineWithOptions([]string{fmt.Sprintf("1_%d", 1000+i)}, TouchlineOptions{})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, ErrSubscriptionLimitExceeded):
+				refused++
+			default:
+				t.Errorf("SubscribeTouchline: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if succeeded != 10 || refused != 30 {
+		t.Errorf("%d subscribed and %d refused, want 10 and 30", succeeded, refused)
+	}
+	if n := tw.SubscribedInstruments(); n != 10 {
+		t.Errorf("SubscribedInstruments = %d, want 10", n)
+	}
+	if len(tw.quota.reserved) != 0 {
+		t.Errorf("%d reservations left after the requests were tracked", len(tw.quota.reserved))
+	}
+}
+
+func TestQueuedSubscribeKeepsReservation(t *testing.T) {
+	tw := newTestClient(WithSubscriptionLimit(1), WithPreConnectQueue(0))
+
+	if err := tw.SubscribeTouchlineWithOptions([]string{"1_22"}, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.SubscribeTouchlineWithOptions([]string{"1_23"}, TouchlineOptions{}); !errors.Is(err, ErrSubscriptionLimitExceeded) {
+		t.Fatalf("second subscribe while the first is queued: got %v, want ErrSubscriptionLimitExceeded", err)
+	}
+
+	tw.Dispose()
+	if len(tw.quota.reserved) != 0 {
+		t.Errorf("%d reservations left after the queue was dropped", len(tw.quota.reserved))
+	}
+}
+
+func TestFailedSubscribeReleasesReservation(t *testing.T) {
+	tw := newTestClient(WithSubscriptionLimit(1))
+
+	if err := tw.SubscribeTouchlineWithOptions([]string{"1_22"}, TouchlineOptions{}); err == nil {
+		t.Fatal("subscribe without a connection succeeded")
+	}
+	if len(tw.quota.reserved) != 0 {
+		t.Errorf("%d reservations left after the send failed", len(tw.quota.reserved))
+	}
+}
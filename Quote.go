@@ -0,0 +1,138 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type quoteResult struct {
+	data TouchlineData
+	err  error
+}
+
+// quoteWaiters holds the GetQuote calls waiting per instrument, and the correlation ID of
+// the quote subscription sent for an instrument
+type quoteWaiters struct {
+	pending    map[uint64][]chan quoteResult
+	correlated map[uint64]string
+	mu         sync.Mutex
+}
+
+// GetQuote requests the current touchline for a single instrument and waits for the reply.
+// Unless the instrument already has a tracked touchline subscription, a native touchline
+// subscription is sent and removed again once the quote has arrived. With
+// WithRequestCorrelation and a gateway that echoes the tag, only the reply to that
+// subscription completes the call. The call fails when ctx is done or the connection drops
+// while waiting.
+func (tw *ODINMarketFeedClient) GetQuote(ctx context.Context, segID, token int) (TouchlineData, error) {
+	if err := tw.checkDisposed(); err != nil {
+		return TouchlineData{}, err
+	}
+	if segID <= 0 || token <= 0 {
+		return TouchlineData{}, fmt.Errorf("invalid instrument %d_%d", segID, token)
+	}
+
+	key := depthKey(uint32(segID), uint32(token))
+	result := make(chan quoteResult, 1)
+
+	tw.quotes.mu.Lock()
+	if tw.quotes.pending == nil {
+		tw.quotes.pending = make(map[uint64][]chan quoteResult)
+	}
+	tw.quotes.pending[key] = append(tw.quotes.pending[key], result)
+	tw.quotes.mu.Unlock()
+	defer tw.removeQuoteWaiter(key, result)
+
+	tw.subMu.Lock()
+	_, subscribed := tw.subscriptions[subscriptionKey{subType: SubscriptionTouchline, marketSegmentID: segID, token: token}]
+	tw.subMu.Unlock()
+
+	if !subscribed {
+		header, correlationID := tw.correlatedRequestHeader(msgCodeTouchline)
+		if correlationID != "" {
+			tw.quotes.mu.Lock()
+			if tw.quotes.correlated == nil {
+				tw.quotes.correlated = make(map[uint64]string)
+			}
+			tw.quotes.correlated[key] = correlationID
+			tw.quotes.mu.Unlock()
+		}
+
+		request := header + fmt.Sprintf("49=1|200=0|%s|230=1", tw.instrumentPair(segID, token))
+		if err := tw.SendMessage(request); err != nil {
+			return TouchlineData{}, err
+		}
+
+		defer func() {
+			request := tw.requestHeader(msgCodeTouchline) + fmt.Sprintf("4=|%s|230=2", tw.instrumentPair(segID, token))
+			if err := tw.SendMessage(request); err != nil {
+				tw.reportAsyncError(StageQuoteRelease, fmt.Errorf("%d_%d: %w", segID, token, err))
+			}
+		}()
+	}
+
+	select {
+	case r := <-result:
+		return r.data, r.err
+	case <-ctx.Done():
+		return TouchlineData{}, ctx.Err()
+	}
+}
+
+func (tw *ODINMarketFeedClient) removeQuoteWaiter(key uint64, result chan quoteResult) {
+	tw.quotes.mu.Lock()
+	defer tw.quotes.mu.Unlock()
+
+	waiters := tw.quotes.pending[key]
+	for i, waiter := range waiters {
+		if waiter == result {
+			waiters = append(waiters[:i:i], waiters[i+1:]...)
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(tw.quotes.pending, key)
+		delete(tw.quotes.correlated, key)
+	} else {
+		tw.quotes.pending[key] = waiters
+	}
+}
+
+// deliverQuote completes the GetQuote calls waiting for this instrument. When the gateway
+// echoes correlation IDs, a quote subscription is only answered by a touchline carrying its
+// ID; otherwise any touchline of the instrument answers it.
+func (tw *ODINMarketFeedClient) deliverQuote(data TouchlineData, correlationID string) {
+	key := depthKey(data.MktSegID, data.Token)
+	echoed := tw.correlationEchoed()
+
+	tw.quotes.mu.Lock()
+	if expected, ok := tw.quotes.correlated[key]; ok && echoed && correlationID != expected {
+		tw.quotes.mu.Unlock()
+		return
+	}
+	waiters := tw.quotes.pending[key]
+	delete(tw.quotes.pending, key)
+	delete(tw.quotes.correlated, key)
+	tw.quotes.mu.Unlock()
+
+	for _, waiter := range waiters {
+		waiter <- quoteResult{data: data}
+	}
+}
+
+// failPendingQuotes fails every waiting GetQuote call
+func (tw *ODINMarketFeedClient) failPendingQuotes(err error) {
+	tw.quotes.mu.Lock()
+	pending := tw.quotes.pending
+	tw.quotes.pending = nil
+	tw.quotes.correlated = nil
+	tw.quotes.mu.Unlock()
+
+	for _, waiters := range pending {
+		for _, waiter := range waiters {
+			waiter <- quoteResult{err: errors.Join(errors.New("connection lost while waiting for quote"), err)}
+		}
+	}
+}
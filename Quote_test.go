@@ -0,0 +1,119 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// quoteGateway answers each touchline subscribe of segment 1 with a touchline whose LTP is
+// the token times 10
+func quoteGateway(c *mockConn, request string) {
+	if messageCode(request) != msgCodeTouchline || !strings.HasSuffix(request, "230=1") {
+		return
+	}
+	var token uint32
+	for _, field := range strings.FieldsFunc(request, func(r rune) bool { return r == '|' || r == '$' }) {
+		if strings.HasPrefix(field, "7=") {
+			fmt.Sscan(field[2:], &token)
+		}
+	}
+	c.send(touchlineMessage(1, token, token*10))
+}
+
+func TestGetQuote(t *testing.T) {
+	ms := newMockServer(t, quoteGateway)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	quote, err := tw.GetQuote(ctx, 1, 22)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.MktSegID != 1 || quote.Token != 22 || quote.LTP != 220 {
+		t.Errorf("quote %+v, want the touchline of 1_22", quote)
+	}
+	if request := ms.next(t, msgCodeTouchline); !strings.Contains(request, "7=22") || !strings.HasSuffix(request, "230=1") {
+		t.Errorf("request %q, want the quote subscription", request)
+	}
+	if request := ms.next(t, msgCodeTouchline); !strings.Contains(request, "7=22") || !strings.HasSuffix(request, "230=2") {
+		t.Errorf("request %q, want the quote subscription released", request)
+	}
+}
+
+func TestGetQuoteConcurrentTokens(t *testing.T) {
+	ms := newMockServer(t, quoteGateway)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var wg sync.WaitGroup
+	for token := 100; token < 120; token++ {
+		wg.Add(1)
+		go func(token int) {
+			defer wg.Done()
+			quote, err := tw.GetQuote(ctx, 1, token)
+			if err != nil {
+				t.Errorf("GetQuote(1, %d): %v", token, err)
+				return
+			}
+			if int(quote.Token) != token || quote.LTP != uint32(token*10) {
+				t.Errorf("GetQuote(1, %d) = %+v, want the quote of its own token", token, quote)
+			}
+		}(token)
+	}
+	wg.Wait()
+}
+
+func TestGetQuoteTimeout(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := tw.GetQuote(ctx, 1, 22); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetQuote error %v, want context.DeadlineExceeded", err)
+	}
+	tw.quotes.mu.Lock()
+	defer tw.quotes.mu.Unlock()
+	if len(tw.quotes.pending) != 0 {
+		t.Errorf("%d instruments still waiting after the timeout", len(tw.quotes.pending))
+	}
+}
+
+func TestGetQuoteFailsOnDisconnect(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := tw.GetQuote(context.Background(), 1, 22)
+		done <- err
+	}()
+	ms.next(t, msgCodeTouchline)
+	ms.dropAll()
+
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "connection lost") {
+			t.Errorf("GetQuote error %v, want the connection loss", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetQuote still waits after the connection dropped")
+	}
+}
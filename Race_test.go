@@ -0,0 +1,7 @@
+//go:build race
+
+package ODINMarketFeed
+
+// raceEnabled is true when the tests run with the race detector, whose instrumentation
+// allocates on its own
+const raceEnabled = true
@@ -0,0 +1,91 @@
+package ODINMarketFeed
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// headerTags are the tags written by the request header builder
+var headerTags = map[int]bool{63: true, 64: true, 65: true, 66: true}
+
+// SendRaw sends a request that has no dedicated method, such as a vendor supplied one-off
+// request. The message must be a complete request in tag=value|... form carrying a 64= code.
+// It is compressed, framed, queued and logged like the requests of the other methods, and
+// its responses reach the usual callbacks, but it is not tracked as a subscription: it is
+// neither replayed after a reconnect nor unsubscribed by Close.
+func (tw *ODINMarketFeedClient) SendRaw(msg string) error {
+	if err := validateRawRequest(msg); err != nil {
+		tw.returnedError(err.Error())
+		return err
+	}
+
+	_, err := tw.sendRequest(msg, 0, nil, nil)
+	return err
+}
+
+// SendRawWithHeader sends a request with the given 64= code and fields through SendRaw. The
+// 63=, 65= and 66= header fields, the WithExtraHeaderTags tags and the correlation tag are
+// added as for any other request, and the fields follow in ascending tag order.
+func (tw *ODINMarketFeedClient) SendRawWithHeader(code int, fields map[int]string) error {
+	message, err := tw.buildRawRequest(code, fields)
+	if err != nil {
+		tw.returnedError(err.Error())
+		return err
+	}
+	return tw.SendRaw(message)
+}
+
+// buildRawRequest builds the message sent by SendRawWithHeader
+func (tw *ODINMarketFeedClient) buildRawRequest(code int, fields map[int]string) (string, error) {
+	if code <= 0 {
+		return "", fmt.Errorf("invalid request code %d", code)
+	}
+
+	tags := make([]int, 0, len(fields))
+	for tag, value := range fields {
+		if headerTags[tag] {
+			return "", fmt.Errorf("tag %d is set by the request header", tag)
+		}
+		if tag <= 0 {
+			return "", fmt.Errorf("invalid tag %d", tag)
+		}
+		if strings.ContainsAny(value, "|\r\n\x00") {
+			return "", fmt.Errorf("value of tag %d contains a reserved delimiter character", tag)
+		}
+		tags = append(tags, tag)
+	}
+	sort.Ints(tags)
+
+	items := make([]string, len(tags))
+	for i, tag := range tags {
+		items[i] = strconv.Itoa(tag) + "=" + fields[tag]
+	}
+	return tw.requestHeader(code) + strings.Join(items, "|"), nil
+}
+
+// validateRawRequest checks that msg is a sequence of tag=value fields carrying a 64= code
+func validateRawRequest(msg string) error {
+	if strings.TrimSpace(msg) == "" {
+		return fmt.Errorf("request cannot be empty")
+	}
+	if strings.ContainsAny(msg, "\r\n\x00") {
+		return fmt.Errorf("request contains a line break or NUL character")
+	}
+
+	for i, field := range strings.Split(strings.TrimSuffix(msg, "|"), "|") {
+		tag, _, ok := strings.Cut(field, "=")
+		if !ok {
+			return fmt.Errorf("request field %d (%q) is not a tag=value pair", i+1, field)
+		}
+		if _, err := strconv.Atoi(tag); err != nil {
+			return fmt.Errorf("request field %d (%q) has a non-numeric tag", i+1, field)
+		}
+	}
+
+	if messageCode(msg) <= 0 {
+		return fmt.Errorf("request has no 64= message code")
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DisconnectCause classifies why a connection ended or a reconnect attempt failed
+type DisconnectCause int
+
+const (
+	// CauseNetworkError is a failed dial or a connection lost without a close frame
+	CauseNetworkError DisconnectCause = iota
+	// CauseLocalClose is a Disconnect, Close or Dispose of the client
+	CauseLocalClose
+	// CauseServerClose is a close frame sent by the server or a proxy; see Code
+	CauseServerClose
+	// CauseReadTimeout is a read that timed out
+	CauseReadTimeout
+	// CauseLoginFailure is a connection whose login request failed (ErrLoginFailed)
+	CauseLoginFailure
+	// CauseHeartbeatMiss is a connection closed by WithHeartbeatMissLimit (ErrHeartbeatMissed)
+	CauseHeartbeatMiss
+	// CauseDuplicateSession is a session replaced by another login (ErrDuplicateSession)
+	CauseDuplicateSession
+)
+
+var disconnectCauseNames = map[DisconnectCause]string{
+	CauseNetworkError:     "network error",
+	CauseLocalClose:       "local close",
+	CauseServerClose:      "server close",
+	CauseReadTimeout:      "read timeout",
+	CauseLoginFailure:     "login failure",
+	CauseHeartbeatMiss:    "heartbeat miss",
+	CauseDuplicateSession: "duplicate session",
+}
+
+// String returns the name of the cause
+func (c DisconnectCause) String() string {
+	if name, ok := disconnectCauseNames[c]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// DisconnectReason is the classified cause passed to a ReconnectPolicy
+type DisconnectReason struct {
+	Cause DisconnectCause
+	Code  int    // close code, for CauseServerClose and for connections lost without a close frame
+	Text  string // close reason, or the error text
+	Err   error
+
+	// Attempt is the number of reconnect attempts made since the connection was lost; 0
+	// when the reason is the loss itself
+	Attempt int
+}
+
+// ReconnectPolicy decides whether a lost connection is reconnected. ShouldReconnect is
+// called when the connection is lost and again after each failed reconnect attempt; a
+// non-zero delay replaces the reconnect delay before the next attempt.
+type ReconnectPolicy interface {
+	ShouldReconnect(reason DisconnectReason) (bool, time.Duration)
+}
+
+// ReconnectPolicyFunc adapts a function to a ReconnectPolicy
+type ReconnectPolicyFunc func(reason DisconnectReason) (bool, time.Duration)
+
+// ShouldReconnect calls f
+func (f ReconnectPolicyFunc) ShouldReconnect(reason DisconnectReason) (bool, time.Duration) {
+	return f(reason)
+}
+
+// DefaultReconnectPolicy reconnects after every cause except a local close, a normal
+// closure by the server and, unless ReconnectOnDuplicateSession is set, a duplicate
+// session. It never changes the delay. Wrap it to override single causes:
+//
+//	policy := ReconnectPolicyFunc(func(reason DisconnectReason) (bool, time.Duration) {
+//		if reason.Cause == CauseLoginFailure {
+//			return false, 0
+//		}
+//		return DefaultReconnectPolicy{}.ShouldReconnect(reason)
+//	})
+type DefaultReconnectPolicy struct {
+	ReconnectOnDuplicateSession bool
+}
+
+// ShouldReconnect implements ReconnectPolicy
+func (p DefaultReconnectPolicy) ShouldReconnect(reason DisconnectReason) (bool, time.Duration) {
+	switch reason.Cause {
+	case CauseLocalClose:
+		return false, 0
+	case CauseServerClose:
+		return reason.Code != websocket.CloseNormalClosure, 0
+	case CauseDuplicateSession:
+		return p.ReconnectOnDuplicateSession, 0
+	}
+	return true, 0
+}
+
+// classifyDisconnect returns the DisconnectReason of err
+func classifyDisconnect(err error) DisconnectReason {
+	reason := DisconnectReason{Cause: CauseNetworkError, Err: err}
+	if err != nil {
+		reason.Text = err.Error()
+	}
+
+	var closeErr *websocket.CloseError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrDuplicateSession):
+		reason.Cause = CauseDuplicateSession
+	case errors.Is(err, ErrHeartbeatMissed):
+		reason.Cause = CauseHeartbeatMiss
+	case errors.Is(err, ErrLoginFailed):
+		reason.Cause = CauseLoginFailure
+	case errors.Is(err, ErrClientDisposed):
+		reason.Cause = CauseLocalClose
+	case errors.As(err, &closeErr):
+		// gorilla/websocket reports a connection that ended without a close frame as 1006
+		reason.Code, reason.Text = closeErr.Code, closeErr.Text
+		if closeErr.Code != websocket.CloseAbnormalClosure {
+			reason.Cause = CauseServerClose
+		}
+	case errors.As(err, &netErr) && netErr.Timeout():
+		reason.Cause = CauseReadTimeout
+	}
+	return reason
+}
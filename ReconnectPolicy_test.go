@@ -0,0 +1,97 @@
+package ODINMarketFeed
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestClassifyDisconnect(t *testing.T) {
+	tests := []struct {
+		err   error
+		cause DisconnectCause
+		code  int
+	}{
+		{&websocket.CloseError{Code: websocket.ClosePolicyViolation, Text: "proxy"}, CauseServerClose, websocket.ClosePolicyViolation},
+		{&websocket.CloseError{Code: websocket.CloseAbnormalClosure}, CauseNetworkError, websocket.CloseAbnormalClosure},
+		{fmt.Errorf("login: %w", ErrLoginFailed), CauseLoginFailure, 0},
+		{fmt.Errorf("%w: 3 heartbeats in a row", ErrHeartbeatMissed), CauseHeartbeatMiss, 0},
+		{ErrDuplicateSession, CauseDuplicateSession, 0},
+		{ErrClientDisposed, CauseLocalClose, 0},
+	}
+	for _, tt := range tests {
+		reason := classifyDisconnect(tt.err)
+		if reason.Cause != tt.cause || reason.Code != tt.code {
+			t.Errorf("classifyDisconnect(%v) = %s %d, want %s %d", tt.err, reason.Cause, reason.Code, tt.cause, tt.code)
+		}
+	}
+}
+
+func TestCustomReconnectPolicy(t *testing.T) {
+	ms := newMockServer(t, nil)
+	mc := NewMultiClient(1, WithLogger(func(string) {}))
+	// Only the policy delay lets the shard reconnect within the test
+	mc.ReconnectDelay = time.Hour
+	var mu sync.Mutex
+	var reasons []DisconnectReason
+	mc.ReconnectPolicy = ReconnectPolicyFunc(func(reason DisconnectReason) (bool, time.Duration) {
+		mu.Lock()
+		reasons = append(reasons, reason)
+		mu.Unlock()
+		if reason.Cause == CauseServerClose && reason.Code == websocket.ClosePolicyViolation {
+			return true, 10 * time.Millisecond
+		}
+		return false, 0
+	})
+	reconnected := make(chan struct{}, 2)
+	mc.OnReconnect = func(int, error) { reconnected <- struct{}{} }
+	if err := mc.Connect(ms.host, ms.port, false, "u", "k"); err != nil {
+		t.Fatal(err)
+	}
+	defer mc.Close()
+	ms.next(t, msgCodeLogin)
+
+	closeWith := func(code int, text string) {
+		t.Helper()
+		if err := ms.latest().write(websocket.CloseMessage, websocket.FormatCloseMessage(code, text)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lastReason := func() (DisconnectReason, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if len(reasons) == 0 {
+			return DisconnectReason{}, 0
+		}
+		return reasons[len(reasons)-1], len(reasons)
+	}
+
+	// A policy violation from a flaky proxy is retried after the policy delay
+	closeWith(websocket.ClosePolicyViolation, "proxy policy")
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the shard did not reconnect after a policy violation close")
+	}
+	ms.next(t, msgCodeLogin)
+	if reason, n := lastReason(); n != 1 || reason.Cause != CauseServerClose || reason.Text != "proxy policy" {
+		t.Errorf("policy got %+v as call %d, want the policy violation close", reason, n)
+	}
+
+	// A close the policy rejects leaves the shard disconnected
+	closeWith(4001, "authentication failed")
+	eventually(t, 5*time.Second, func() bool { _, n := lastReason(); return n == 2 })
+	if reason, _ := lastReason(); reason.Cause != CauseServerClose || reason.Code != 4001 {
+		t.Errorf("policy got %+v, want the 4001 close", reason)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := ms.connections(); n != 2 {
+		t.Errorf("%d connections accepted, want no reconnect after the 4001 close", n)
+	}
+	if state := mc.Client(0).State(); state != StateDisconnected {
+		t.Errorf("shard state %s, want disconnected", state)
+	}
+}
@@ -0,0 +1,129 @@
+package ODINMarketFeed
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Request message codes (64= tag)
+const (
+	msgCodeHeartbeat    = 1
+	msgCodeLogin        = 101
+	msgCodePauseResume  = 106
+	msgCodeBestFive     = 127
+	msgCodeTouchline    = 206
+	msgCodeLTPTouchline = 347
+)
+
+const defaultProtocolVersion = "FT3.0"
+
+// defaultMessageTypes holds the 65= value per request code; codes not listed use "84"
+var defaultMessageTypes = map[int]string{
+	msgCodeLogin: "74",
+}
+
+const defaultMessageType = "84"
+
+// requestHeaderConfig holds the configurable parts of the request header
+type requestHeaderConfig struct {
+	protocolVersion string
+	messageTypes    map[int]string
+	extraTags       map[int]string
+	timeFormat      string
+}
+
+// WithProtocolVersion sets the 63= protocol version sent with every request (default FT3.0)
+func WithProtocolVersion(version string) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.header.protocolVersion = version
+	}
+}
+
+// WithMessageType overrides the 65= value sent with requests of the given 64= code
+func WithMessageType(code int, messageType string) Option {
+	return func(tw *ODINMarketFeedClient) {
+		if tw.header.messageTypes == nil {
+			tw.header.messageTypes = make(map[int]string)
+		}
+		tw.header.messageTypes[code] = messageType
+	}
+}
+
+// WithExtraHeaderTags adds tags to the header of every request, after the 66= time field.
+// Tags are written in ascending tag order.
+func WithExtraHeaderTags(tags map[int]string) Option {
+	return func(tw *ODINMarketFeedClient) {
+		if tw.header.extraTags == nil {
+			tw.header.extraTags = make(map[int]string)
+		}
+		for tag, value := range tags {
+			tw.header.extraTags[tag] = value
+		}
+	}
+}
+
+// requestHeader builds the 63=|64=|65=|66=| header for a request code, including the trailing delimiter
+func (tw *ODINMarketFeedClient) requestHeader(code int) string {
+	header, _ := tw.correlatedRequestHeader(code)
+	return header
+}
+
+// correlatedRequestHeader builds the request header and returns the correlation ID it
+// carries, empty without WithRequestCorrelation
+func (tw *ODINMarketFeedClient) correlatedRequestHeader(code int) (string, string) {
+	var id string
+	if tw.correlation != nil {
+		id = tw.correlation.gen()
+	}
+	return tw.buildRequestHeader(code, id), id
+}
+
+// previewRequestHeader builds the header the next request would carry without using up a
+// correlation ID, for ValidateSubscriptions
+func (tw *ODINMarketFeedClient) previewRequestHeader(code int) string {
+	var id string
+	if tw.correlation != nil {
+		id = tw.correlation.peek()
+	}
+	return tw.buildRequestHeader(code, id)
+}
+
+// buildRequestHeader builds the request header carrying the correlation ID id
+func (tw *ODINMarketFeedClient) buildRequestHeader(code int, id string) string {
+	version := tw.header.protocolVersion
+	if version == "" {
+		version = defaultProtocolVersion
+	}
+
+	messageType, ok := tw.header.messageTypes[code]
+	if !ok {
+		messageType, ok = defaultMessageTypes[code]
+	}
+	if !ok {
+		messageType = defaultMessageType
+	}
+
+	var sb strings.Builder
+	sb.WriteString("63=" + version + "|")
+	sb.WriteString("64=" + strconv.Itoa(code) + "|")
+	sb.WriteString("65=" + messageType + "|")
+	sb.WriteString("66=" + tw.requestTime() + "|")
+
+	if len(tw.header.extraTags) > 0 {
+		tags := make([]int, 0, len(tw.header.extraTags))
+		for tag := range tw.header.extraTags {
+			tags = append(tags, tag)
+		}
+		sort.Ints(tags)
+		for _, tag := range tags {
+			sb.WriteString(strconv.Itoa(tag) + "=" + tw.header.extraTags[tag] + "|")
+		}
+	}
+
+	if tw.correlation != nil {
+		sb.WriteString(strconv.Itoa(tw.correlation.tag) + "=" + id + "|")
+	}
+
+	return sb.String()
+}
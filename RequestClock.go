@@ -0,0 +1,101 @@
+package ODINMarketFeed
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultRequestTimeFormat is the layout of the 66= time field of requests
+const defaultRequestTimeFormat = "15:04:05"
+
+// requestClock supplies the 66= time of requests. It is anchored to the exchange time at
+// login, as estimated from the server heartbeats, and advanced with the monotonic clock, so
+// host clock steps do not affect it. It re-anchors when the exchange date rolls over or the
+// offset estimate changes.
+type requestClock struct {
+	now          func() time.Time
+	anchorLocal  time.Time
+	anchorServer time.Time
+	offset       time.Duration
+	mu           sync.Mutex
+}
+
+// WithRequestTimeFormat sets the time layout of the 66= request field (default "15:04:05"),
+// e.g. "15:04:05.000" for protocol versions that expect milliseconds
+func WithRequestTimeFormat(layout string) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.header.timeFormat = layout
+	}
+}
+
+// anchor re-anchors the clock at the current time using the server clock offset
+func (rc *requestClock) anchor(offset time.Duration) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.anchorLocked(offset)
+}
+
+func (rc *requestClock) anchorLocked(offset time.Duration) {
+	now := time.Now
+	if rc.now != nil {
+		now = rc.now
+	}
+	rc.anchorLocal = now()
+	rc.anchorServer = rc.anchorLocal.Add(offset).In(ExchangeLocation)
+	rc.offset = offset
+}
+
+// current returns the exchange time for a request
+func (rc *requestClock) current(offset time.Duration) time.Time {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.anchorLocal.IsZero() || offset != rc.offset {
+		rc.anchorLocked(offset)
+		return rc.anchorServer
+	}
+
+	now := time.Now
+	if rc.now != nil {
+		now = rc.now
+	}
+	current := rc.anchorServer.Add(now().Sub(rc.anchorLocal))
+	if current.YearDay() != rc.anchorServer.YearDay() {
+		rc.anchorLocked(offset)
+		return rc.anchorServer
+	}
+	return current
+}
+
+// requestTime returns the 66= value for a request sent now
+func (tw *ODINMarketFeedClient) requestTime() string {
+	layout := tw.header.timeFormat
+	if layout == "" {
+		layout = defaultRequestTimeFormat
+	}
+	return tw.requestClock.current(tw.serverClockOffset()).Format(layout)
+}
+
+// restampRequestTime replaces the 66= time of a request built earlier, such as a queued
+// request, with the time of a request sent now
+func (tw *ODINMarketFeedClient) restampRequestTime(message string) string {
+	const tag = "|66="
+	start := strings.Index(message, tag)
+	if start < 0 {
+		return message
+	}
+	start += len(tag)
+	end := strings.IndexByte(message[start:], FieldDelimiter)
+	if end < 0 {
+		end = len(message) - start
+	}
+	return message[:start] + tw.requestTime() + message[start+end:]
+}
+
+// serverClockOffset returns the estimated server clock offset
+func (tw *ODINMarketFeedClient) serverClockOffset() time.Duration {
+	tw.clock.mu.Lock()
+	defer tw.clock.mu.Unlock()
+	return tw.clock.offset
+}
@@ -0,0 +1,75 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeAcrossMidnight(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 2, 23, 59, 58, 0, ExchangeLocation)}
+	tw := newTestClient()
+	tw.requestClock.now = clock.Now
+	tw.requestClock.anchor(0)
+
+	steps := []struct {
+		advance time.Duration
+		want    string
+	}{
+		{0, "23:59:58"},
+		{time.Second, "23:59:59"},
+		{1500 * time.Millisecond, "00:00:00"},
+		{2 * time.Second, "00:00:02"},
+	}
+	for _, step := range steps {
+		clock.advance(step.advance)
+		if got := tw.requestTime(); got != step.want {
+			t.Errorf("requestTime at %s = %q, want %q", clock.Now().Format("15:04:05.000"), got, step.want)
+		}
+	}
+	tw.requestClock.mu.Lock()
+	anchorDay := tw.requestClock.anchorServer.Day()
+	tw.requestClock.mu.Unlock()
+	if anchorDay != 3 {
+		t.Errorf("clock anchored on day %d after midnight, want it re-anchored on the 3rd", anchorDay)
+	}
+}
+
+func TestRequestTimeFollowsServerOffset(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 2, 23, 59, 50, 0, ExchangeLocation)}
+	tw := newTestClient(WithRequestTimeFormat("15:04:05.000"))
+	tw.requestClock.now = clock.Now
+	tw.clock.mu.Lock()
+	tw.clock.offset = 12*time.Second + 250*time.Millisecond
+	tw.clock.mu.Unlock()
+
+	// The host clock is before midnight while the server has rolled to the next day
+	if got := tw.requestTime(); got != "00:00:02.250" {
+		t.Errorf("requestTime = %q, want the server time with milliseconds", got)
+	}
+	clock.advance(time.Second)
+	if got := tw.requestTime(); got != "00:00:03.250" {
+		t.Errorf("requestTime = %q a second later, want 00:00:03.250", got)
+	}
+}
+
+func TestRequestsAfterMidnightCarryTheNewDayTime(t *testing.T) {
+	ms := newMockServer(t, nil)
+	clock := &fakeClock{now: time.Date(2026, 3, 2, 23, 59, 58, 0, ExchangeLocation)}
+	tw := newTestClient()
+	tw.requestClock.now = clock.Now
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	if login := ms.next(t, msgCodeLogin); !strings.Contains(login, "|66=23:59:58|") {
+		t.Errorf("login %q, want 66=23:59:58", login)
+	}
+	clock.advance(5 * time.Second)
+	if err := tw.SubscribeTouchlineWithOptions([]string{"1_22"}, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if request := ms.next(t, msgCodeTouchline); !strings.Contains(request, "|66=00:00:03|") {
+		t.Errorf("request %q, want 66=00:00:03", request)
+	}
+}
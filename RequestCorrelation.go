@@ -0,0 +1,86 @@
+package ODINMarketFeed
+
+import (
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// requestCorrelation holds the tag and ID generator of WithRequestCorrelation. echoed is
+// set once the gateway has echoed the tag on the current connection.
+type requestCorrelation struct {
+	tag    int
+	gen    func() string
+	custom bool
+	next   uint64
+	echoed int32
+}
+
+// WithRequestCorrelation adds the tag with a generated ID to the header of every request,
+// after the extra header tags, and returns the echoed value as ParsedMessage.CorrelationID.
+// Once the gateway has echoed the tag on the connection, the ID is matched in three places
+// only: GetQuote waits for the touchline carrying its ID, SubscribeTouchlineSync for the
+// ack carrying its ID, and WithThrottleNotice retries the request whose ID the notice
+// carries. Other replies are not matched by ID; against gateways that do not echo the tag,
+// these three match by instrument or by the most recent request as before. A nil gen
+// numbers the requests of the client from 1.
+func WithRequestCorrelation(tagNumber int, gen func() string) Option {
+	return func(tw *ODINMarketFeedClient) {
+		rc := &requestCorrelation{tag: tagNumber, gen: gen, custom: gen != nil}
+		if rc.gen == nil {
+			rc.gen = func() string {
+				return strconv.FormatUint(atomic.AddUint64(&rc.next, 1), 10)
+			}
+		}
+		tw.correlation = rc
+		tw.decoder.CorrelationTag = tagNumber
+	}
+}
+
+// peek returns the ID the next request will carry without generating it. A custom
+// generator is not called and an empty ID is returned.
+func (rc *requestCorrelation) peek() string {
+	if rc.custom {
+		return ""
+	}
+	return strconv.FormatUint(atomic.LoadUint64(&rc.next)+1, 10)
+}
+
+// correlationEchoed reports whether the gateway has echoed the correlation tag since the
+// connection was opened
+func (tw *ODINMarketFeedClient) correlationEchoed() bool {
+	return tw.correlation != nil && atomic.LoadInt32(&tw.correlation.echoed) == 1
+}
+
+// correlationReceived records that the gateway echoes the correlation tag
+func (tw *ODINMarketFeedClient) correlationReceived(msg ParsedMessage) {
+	if tw.correlation != nil && msg.CorrelationID != "" {
+		atomic.StoreInt32(&tw.correlation.echoed, 1)
+	}
+}
+
+// resetCorrelation forgets whether the gateway echoes the tag, e.g. after connecting to
+// another endpoint
+func (tw *ODINMarketFeedClient) resetCorrelation() {
+	if tw.correlation != nil {
+		atomic.StoreInt32(&tw.correlation.echoed, 0)
+	}
+}
+
+// fieldValue returns the value of the first tag=value pair of text with the given tag. The
+// pairs are separated by FieldDelimiter or by pair, the repeating-group delimiter.
+func fieldValue(text string, tag int, pair byte) (string, bool) {
+	prefix := strconv.Itoa(tag) + "="
+	for text != "" {
+		part := text
+		if end := strings.IndexFunc(text, func(r rune) bool { return r == FieldDelimiter || r == rune(pair) }); end >= 0 {
+			part, text = text[:end], text[end+1:]
+		} else {
+			text = ""
+		}
+		if strings.HasPrefix(part, prefix) {
+			return part[len(prefix):], true
+		}
+	}
+	return "", false
+}
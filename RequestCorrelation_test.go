@@ -0,0 +1,56 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSubscribeTouchlineSyncMatchesAckByID(t *testing.T) {
+	ms := newMockServer(t, func(c *mockConn, request string) {
+		if messageCode(request) != msgCodeTouchline {
+			return
+		}
+		id, _ := fieldValue(request, 9000, DefaultPairDelimiter)
+		// The ack of another request arrives first
+		c.send([]byte("63=FT3.0|64=206|9000=" + id + "0|1=1$7=22$230=3"))
+		c.send([]byte("63=FT3.0|64=206|9000=" + id + "|1=1$7=22$230=0"))
+	})
+	tw := newTestClient(WithRequestCorrelation(9000, nil), WithSubscriptionAcks(230, 0, "0"))
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	// The gateway echoes the tag on the login ack
+	ms.latest().send([]byte("63=FT3.0|64=101|9000=1"))
+	if !eventually(t, 2*time.Second, tw.correlationEchoed) {
+		t.Fatal("correlation tag not recorded as echoed")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	result, err := tw.SubscribeTouchlineSync(ctx, []string{"1_22"}, TouchlineOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Accepted) != 1 || len(result.Rejected) != 0 {
+		t.Errorf("accepted %v, rejected %v; want the verdict of the ack carrying the request's ID", result.Accepted, result.Rejected)
+	}
+}
+
+func TestCorrelationTagInRequests(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithRequestCorrelation(9000, nil))
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	if request := ms.next(t, msgCodeLogin); !strings.Contains(request, "|9000=1|") {
+		t.Errorf("login %q, want correlation ID 1", request)
+	}
+	if err := tw.SubscribeLTPTouchline([]string{"1_22"}); err != nil {
+		t.Fatal(err)
+	}
+	if request := ms.next(t, msgCodeLTPTouchline); !strings.Contains(request, "|9000=2|") {
+		t.Errorf("subscription %q, want correlation ID 2", request)
+	}
+}
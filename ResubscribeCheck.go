@@ -0,0 +1,176 @@
+package ODINMarketFeed
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultResubscribeWindow is the time ResubscribeAll allows for updates to resume
+const defaultResubscribeWindow = 10 * time.Second
+
+// ResubscribeIncomplete is emitted and its Silent instruments are passed to
+// OnResubscribeIncomplete when fewer than the configured fraction of the replayed touchline
+// and LTP touchline subscriptions received an update within the verification window
+type ResubscribeIncomplete struct {
+	EventSource
+	Silent  []Instrument
+	Checked int
+	Retried bool
+}
+
+func (ResubscribeIncomplete) isEvent() {}
+
+// resubscribeCheck verifies that data resumes after ResubscribeAll
+type resubscribeCheck struct {
+	window   time.Duration
+	fraction float64
+	retry    bool
+
+	mu   sync.Mutex
+	stop chan struct{} // closed to cancel the pending verification
+}
+
+// WithResubscribeVerification checks, window after ResubscribeAll (default 10s), that at
+// least fraction of the replayed touchline and LTP touchline subscriptions received an
+// update. Otherwise ResubscribeIncomplete is emitted and OnResubscribeIncomplete receives the
+// silent instruments; with retry their subscriptions are sent once more. Instruments whose
+// segment is outside its session windows are not checked. Enables WithTokenStats.
+func WithResubscribeVerification(window time.Duration, fraction float64, retry bool) Option {
+	return func(tw *ODINMarketFeedClient) {
+		if window <= 0 {
+			window = defaultResubscribeWindow
+		}
+		if fraction <= 0 || fraction > 1 {
+			fraction = 1
+		}
+		tw.resubCheck = &resubscribeCheck{window: window, fraction: fraction, retry: retry}
+		if tw.tokenStats == nil {
+			WithTokenStats()(tw)
+		}
+	}
+}
+
+// scheduleResubscribeCheck arms the verification of the replayed subscriptions, replacing
+// a pending one. It waits on a goroutine of the client, which also exits when the
+// connection ends.
+func (tw *ODINMarketFeedClient) scheduleResubscribeCheck(subscriptions []Subscription) {
+	rc := tw.resubCheck
+	if rc == nil {
+		return
+	}
+
+	var replayed []Subscription
+	for _, sub := range subscriptions {
+		if sub.Type == SubscriptionTouchline || sub.Type == SubscriptionLTPTouchline {
+			replayed = append(replayed, sub)
+		}
+	}
+	if len(replayed) == 0 {
+		return
+	}
+
+	tw.mu.Lock()
+	generation := tw.generation
+	done := tw.receiveDone
+	tw.mu.Unlock()
+	since := time.Now()
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.stop != nil {
+		close(rc.stop)
+	}
+	stop := make(chan struct{})
+	rc.stop = stop
+
+	tw.routines.spawn(func() {
+		timer := time.NewTimer(rc.window)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			tw.verifyResubscribe(replayed, since, generation)
+		case <-stop:
+		case <-done:
+		}
+	})
+}
+
+// stopResubscribeCheck cancels a pending verification
+func (tw *ODINMarketFeedClient) stopResubscribeCheck() {
+	rc := tw.resubCheck
+	if rc == nil {
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.stop != nil {
+		close(rc.stop)
+		rc.stop = nil
+	}
+}
+
+// verifyResubscribe reports the replayed subscriptions without an update since the replay.
+// The check is abandoned when the connection was lost or replaced in the meantime.
+func (tw *ODINMarketFeedClient) verifyResubscribe(replayed []Subscription, since time.Time, generation uint64) {
+	tw.mu.Lock()
+	current := tw.generation == generation && tw.conn != nil && !tw.isDisposed
+	tw.mu.Unlock()
+	if !current {
+		return
+	}
+
+	now := time.Now()
+	seen := make(map[[2]int]bool)
+	silentSeen := make(map[[2]int]bool)
+	var silent []Subscription
+	var silentInstruments []Instrument
+	checked := 0
+
+	for _, sub := range replayed {
+		instrument := sub.Instrument
+		if tw.sessions.outOfSession(uint32(instrument.MarketSegmentID), now) {
+			continue
+		}
+		if !tw.isSubscribed(instrument.MarketSegmentID, instrument.Token, sub.Type) {
+			continue
+		}
+
+		key := [2]int{instrument.MarketSegmentID, instrument.Token}
+		if !seen[key] {
+			seen[key] = true
+			checked++
+		}
+
+		stat, ok := tw.TokenStats(uint32(instrument.MarketSegmentID), uint32(instrument.Token))
+		if ok && !stat.LastUpdate.Before(since) {
+			continue
+		}
+		silent = append(silent, sub)
+		if !silentSeen[key] {
+			silentSeen[key] = true
+			silentInstruments = append(silentInstruments, instrument)
+		}
+	}
+
+	silentCount := len(silentInstruments)
+	if checked == 0 || float64(checked-silentCount) >= tw.resubCheck.fraction*float64(checked) {
+		return
+	}
+
+	incomplete := ResubscribeIncomplete{EventSource: tw.source(), Silent: silentInstruments, Checked: checked, Retried: tw.resubCheck.retry}
+	tw.emit(incomplete)
+	if tw.OnResubscribeIncomplete != nil {
+		tw.invokeCallback("OnResubscribeIncomplete", func() string {
+			return fmt.Sprintf("%d/%d silent", silentCount, checked)
+		}, func() { tw.OnResubscribeIncomplete(silentInstruments) })
+	}
+
+	if tw.resubCheck.retry {
+		if err := tw.resubscribe(silent); err != nil {
+			tw.reportAsyncError(StageResubscribe, err)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestResubscribeVerificationReportsSilentHalf(t *testing.T) {
+	ms := newMockServer(t, func(c *mockConn, request string) {
+		if messageCode(request) == msgCodeTouchline && strings.HasSuffix(request, "230=1") {
+			c.send(touchlineMessage(1, 22, 24500), touchlineMessage(1, 23, 24600))
+		}
+	})
+	tw := newTestClient(WithResubscribeVerification(200*time.Millisecond, 1, true))
+	var mu sync.Mutex
+	var silent []Instrument
+	tw.OnResubscribeIncomplete = func(instruments []Instrument) {
+		mu.Lock()
+		silent = append(silent, instruments...)
+		mu.Unlock()
+	}
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	if err := tw.SubscribeTouchlineWithOptions([]string{"1_22", "1_23", "1_24", "1_25"}, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	ms.next(t, msgCodeTouchline)
+	if err := tw.ResubscribeAll(); err != nil {
+		t.Fatal(err)
+	}
+	ms.next(t, msgCodeTouchline)
+
+	retry := ms.next(t, msgCodeTouchline)
+	if !strings.Contains(retry, "7=24") || !strings.Contains(retry, "7=25") || strings.Contains(retry, "7=22|") {
+		t.Errorf("retry request %q, want tokens 24 and 25 only", retry)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(silent) != 2 || silent[0].Token+silent[1].Token != 24+25 {
+		t.Errorf("silent instruments %v, want tokens 24 and 25", silent)
+	}
+}
+
+func TestResubscribeVerificationStopsOnDisconnect(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithResubscribeVerification(time.Minute, 1, false))
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	if err := tw.SubscribeLTPTouchline([]string{"1_22"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.ResubscribeAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if !eventually(t, time.Second, func() bool { return tw.Goroutines() == 0 }) {
+		t.Errorf("%d goroutines left after Disconnect with a pending verification", tw.Goroutines())
+	}
+}
@@ -0,0 +1,113 @@
+package ODINMarketFeed
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Market segment IDs known to the decoder
+const (
+	SegmentNSECM = 1
+	SegmentNSEFO = 2
+	SegmentBSECM = 3
+	SegmentMCX   = 5
+)
+
+// SegmentConfig describes how the binary fields of a market segment are encoded
+type SegmentConfig struct {
+	// Epoch is the reference time of the timestamps; zero uses Decoder.Epoch
+	Epoch time.Time
+	// TimeUnit is the unit of the timestamp counts; zero means seconds
+	TimeUnit time.Duration
+	// Decimals, when non-zero, sets the decimal locator of decoded prices to 10^Decimals in
+	// place of the value carried on the wire
+	Decimals int
+}
+
+// defaultSegmentConfigs lists the segments known without a registered configuration. All of
+// them use the feed epoch, seconds and the decimal locator carried on the wire; gateways
+// whose BSE or MCX feeds use other conventions need a registered SegmentConfig.
+var defaultSegmentConfigs = map[uint32]SegmentConfig{
+	SegmentNSECM: {},
+	SegmentNSEFO: {},
+	SegmentBSECM: {},
+	SegmentMCX:   {},
+}
+
+// segmentConfigs holds the configurations registered on a Decoder
+type segmentConfigs struct {
+	configs map[uint32]SegmentConfig
+	mu      sync.RWMutex
+}
+
+func newSegmentConfigs() *segmentConfigs {
+	return &segmentConfigs{configs: make(map[uint32]SegmentConfig)}
+}
+
+// RegisterSegmentConfig sets the decoding configuration of a market segment, replacing the
+// built-in default. A Decoder created without NewDecoder must register its configurations
+// before it decodes concurrently.
+func (d *Decoder) RegisterSegmentConfig(segID uint32, config SegmentConfig) {
+	if d.segments == nil {
+		d.segments = newSegmentConfigs()
+	}
+
+	d.segments.mu.Lock()
+	defer d.segments.mu.Unlock()
+	d.segments.configs[segID] = config
+}
+
+// RegisterSegmentConfig sets the decoding configuration of a market segment for the live
+// feed. It may be called while connected.
+func (tw *ODINMarketFeedClient) RegisterSegmentConfig(segID uint32, config SegmentConfig) {
+	tw.decoder.RegisterSegmentConfig(segID, config)
+}
+
+// segmentConfig returns the registered or built-in configuration of a segment
+func (d *Decoder) segmentConfig(segID uint32) SegmentConfig {
+	if d.segments != nil {
+		d.segments.mu.RLock()
+		config, ok := d.segments.configs[segID]
+		d.segments.mu.RUnlock()
+		if ok {
+			return config
+		}
+	}
+	return defaultSegmentConfigs[segID]
+}
+
+// knownSegment reports whether the segment has a registered or built-in configuration
+func (d *Decoder) knownSegment(segID uint32) bool {
+	if d.segments != nil {
+		d.segments.mu.RLock()
+		_, ok := d.segments.configs[segID]
+		d.segments.mu.RUnlock()
+		if ok {
+			return true
+		}
+	}
+	_, ok := defaultSegmentConfigs[segID]
+	return ok
+}
+
+// segmentTime converts a timestamp count of the segment to a time
+func (d *Decoder) segmentTime(config SegmentConfig, count int32) time.Time {
+	epoch := config.Epoch
+	if epoch.IsZero() {
+		epoch = d.Epoch
+	}
+	unit := config.TimeUnit
+	if unit <= 0 {
+		unit = time.Second
+	}
+	return epoch.Add(time.Duration(count) * unit)
+}
+
+// segmentDecimalLocator returns the decimal locator of the segment's prices
+func segmentDecimalLocator(config SegmentConfig, wire uint32) uint32 {
+	if config.Decimals > 0 {
+		return uint32(math.Pow10(config.Decimals))
+	}
+	return wire
+}
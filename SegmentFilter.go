@@ -0,0 +1,78 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync/atomic"
+)
+
+// segmentTag introduces the market segment of textual responses
+var segmentTag = []byte("|1=")
+
+// SetSegmentFilter delivers market data only for the listed market segments. Messages of
+// other segments are counted in Stats().FilteredMessages and dropped before they are
+// decoded, so they reach no callback, iterator or cache; OnRawBatch still receives them.
+// Only market data is filtered: binary blocks and textual best five and LTP touchline
+// responses. Heartbeats, acks and other replies are always delivered. nil allows every
+// segment. The filter may be changed while connected.
+func (tw *ODINMarketFeedClient) SetSegmentFilter(allow []uint32) {
+	if allow == nil {
+		tw.segmentFilter.Store(nil)
+		return
+	}
+
+	segments := make(map[uint32]struct{}, len(allow))
+	for _, segID := range allow {
+		segments[segID] = struct{}{}
+	}
+	tw.segmentFilter.Store(&segments)
+}
+
+// segmentFiltered reports whether the filter drops the raw inner message, counting it if so
+func (tw *ODINMarketFeedClient) segmentFiltered(raw []byte) bool {
+	filter := tw.segmentFilter.Load()
+	if filter == nil {
+		return false
+	}
+
+	segID, ok := messageSegment(raw)
+	if !ok {
+		return false
+	}
+	if _, allowed := (*filter)[segID]; allowed {
+		return false
+	}
+	atomic.AddUint64(&tw.stats.filteredMessages, 1)
+	return true
+}
+
+// messageSegment reads the market segment of a market data message without decoding it:
+// the first field of a binary block, or the 1= tag of a textual best five or LTP touchline
+// response. ok is false for other messages.
+func messageSegment(raw []byte) (uint32, bool) {
+	if _, block, ok := splitBinaryBlock(raw); ok {
+		if len(block) < 4 {
+			return 0, false
+		}
+		return binary.LittleEndian.Uint32(block[0:4]), true
+	}
+	if code := messageCode(string(raw)); code != msgCodeBestFive && code != msgCodeLTPTouchline {
+		return 0, false
+	}
+
+	index := bytes.Index(raw, segmentTag)
+	if index < 0 {
+		return 0, false
+	}
+
+	var segID uint32
+	digits := 0
+	for _, ch := range raw[index+len(segmentTag):] {
+		if ch < '0' || ch > '9' {
+			break
+		}
+		segID = segID*10 + uint32(ch-'0')
+		digits++
+	}
+	return segID, digits > 0
+}
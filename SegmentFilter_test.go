@@ -0,0 +1,53 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMessageSegment(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want uint32
+		ok   bool
+	}{
+		{"binary block", string(touchlineMessage(3, 22, 24500)), 3, true},
+		{"best five", "63=FT3.0|64=127|1=2|7=22|230=1", 2, true},
+		{"LTP touchline", "63=FT3.0|64=347|1=4|7=22", 4, true},
+		{"subscription ack", "63=FT3.0|64=206|1=1$7=22$230=0", 0, false},
+		{"heartbeat", "63=FT3.0|64=0|1=1", 0, false},
+		{"no segment", "63=FT3.0|64=127|7=22", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := messageSegment([]byte(tt.raw)); got != tt.want || ok != tt.ok {
+			t.Errorf("%s: messageSegment = %d, %v; want %d, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSegmentFilterKeepsSubscriptionAcks(t *testing.T) {
+	ms := newMockServer(t, func(c *mockConn, request string) {
+		if messageCode(request) == msgCodeTouchline {
+			c.send([]byte("63=FT3.0|64=206|1=1$7=22$230=0"))
+		}
+	})
+	tw := newTestClient(WithSubscriptionAcks(230, 0, "0"))
+	tw.SetSegmentFilter([]uint32{2})
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	result, err := tw.SubscribeTouchlineSync(ctx, []string{"1_22"}, TouchlineOptions{})
+	if err != nil {
+		t.Fatalf("SubscribeTouchlineSync: %v", err)
+	}
+	if len(result.Accepted) != 1 || result.Accepted[0].Token != 22 {
+		t.Errorf("accepted %v, want token 22", result.Accepted)
+	}
+	if filtered := tw.Stats().FilteredMessages; filtered != 0 {
+		t.Errorf("FilteredMessages = %d, want 0", filtered)
+	}
+}
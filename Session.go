@@ -0,0 +1,54 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDuplicateSession is reported when the gateway ends the session because the same user
+// logged in elsewhere. Automatic reconnects stop for this condition, since reconnecting
+// would in turn end the other session.
+var ErrDuplicateSession = errors.New("session replaced by another login for the same user")
+
+// DuplicateSession is emitted when the gateway reports a duplicate login
+type DuplicateSession struct {
+	EventSource
+	Reason string
+}
+
+// WithDuplicateSessionCode sets the 64= code of the notification the gateway sends when
+// another login for the same user replaced this session. The code is not in the published
+// message list, so detection is off unless it is set; 0 disables it again.
+func WithDuplicateSessionCode(code int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.decoder.DuplicateSessionCode = code
+	}
+}
+
+// duplicateSessionReceived records the duplicate login so that the connection loss it causes
+// is reported as ErrDuplicateSession
+func (tw *ODINMarketFeedClient) duplicateSessionReceived(text string) {
+	reason := sessionReason(text, tw.decoder.pairDelimiter())
+	err := fmt.Errorf("%w: %s", ErrDuplicateSession, reason)
+
+	tw.mu.Lock()
+	tw.sessionErr = err
+	tw.mu.Unlock()
+
+	tw.emit(DuplicateSession{EventSource: tw.source(), Reason: reason})
+	tw.reportError(err.Error())
+}
+
+// sessionReason returns the non-header tags of a notification
+func sessionReason(text string, pair byte) string {
+	var parts []string
+	for _, field := range parseFields(text, pair) {
+		switch field.Tag {
+		case 63, 64, 65, 66:
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%d=%s", field.Tag, field.Value))
+	}
+	return strings.Join(parts, "|")
+}
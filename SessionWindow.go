@@ -0,0 +1,104 @@
+package ODINMarketFeed
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SessionWindow is a trading session as times of day in ExchangeLocation, expressed as the
+// offset from midnight. A window whose Close is before its Open spans midnight.
+type SessionWindow struct {
+	Open  time.Duration
+	Close time.Duration
+}
+
+// contains reports whether the time of day falls inside the window
+func (sw SessionWindow) contains(timeOfDay time.Duration) bool {
+	if sw.Close < sw.Open {
+		return timeOfDay >= sw.Open || timeOfDay < sw.Close
+	}
+	return timeOfDay >= sw.Open && timeOfDay < sw.Close
+}
+
+// sessionWindows holds the configured windows per market segment
+type sessionWindows struct {
+	mu       sync.RWMutex
+	segments map[uint32][]SessionWindow
+	suppress bool
+	dropped  uint64
+}
+
+// WithOutOfSessionSuppression drops touchlines whose LTT falls outside the session windows
+// of their segment instead of delivering them with OutOfSession set
+func WithOutOfSessionSuppression() Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.sessions.suppress = true
+	}
+}
+
+// SetSessionWindow makes open-close the only session of the segment. Touchlines of the
+// segment with an LTT outside its sessions are tagged OutOfSession, or dropped with
+// WithOutOfSessionSuppression. Segments without sessions are not checked.
+func (tw *ODINMarketFeedClient) SetSessionWindow(segID uint32, open, close time.Duration) {
+	tw.sessions.mu.Lock()
+	defer tw.sessions.mu.Unlock()
+
+	if tw.sessions.segments == nil {
+		tw.sessions.segments = make(map[uint32][]SessionWindow)
+	}
+	tw.sessions.segments[segID] = []SessionWindow{{Open: open, Close: close}}
+}
+
+// AddSessionWindow adds a session to the segment, e.g. the evening session of a commodity
+// segment
+func (tw *ODINMarketFeedClient) AddSessionWindow(segID uint32, open, close time.Duration) {
+	tw.sessions.mu.Lock()
+	defer tw.sessions.mu.Unlock()
+
+	if tw.sessions.segments == nil {
+		tw.sessions.segments = make(map[uint32][]SessionWindow)
+	}
+	tw.sessions.segments[segID] = append(tw.sessions.segments[segID], SessionWindow{Open: open, Close: close})
+}
+
+// ClearSessionWindows removes the sessions of the segment
+func (tw *ODINMarketFeedClient) ClearSessionWindows(segID uint32) {
+	tw.sessions.mu.Lock()
+	defer tw.sessions.mu.Unlock()
+	delete(tw.sessions.segments, segID)
+}
+
+// outOfSession reports whether t falls outside every session of the segment
+func (s *sessionWindows) outOfSession(segID uint32, t time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	windows, ok := s.segments[segID]
+	if !ok || len(windows) == 0 {
+		return false
+	}
+
+	local := t.In(ExchangeLocation)
+	hour, minute, second := local.Clock()
+	timeOfDay := time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second
+	for _, window := range windows {
+		if window.contains(timeOfDay) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkSession tags an out-of-session touchline and reports whether it should be delivered
+func (tw *ODINMarketFeedClient) checkSession(touchline *TouchlineData) bool {
+	if !tw.sessions.outOfSession(touchline.MktSegID, touchline.LTT) {
+		return true
+	}
+	if tw.sessions.suppress {
+		atomic.AddUint64(&tw.sessions.dropped, 1)
+		return false
+	}
+	touchline.OutOfSession = true
+	return true
+}
@@ -0,0 +1,91 @@
+package ODINMarketFeed
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// sessionFixture returns a touchline of the segment whose LTT is the time of day on
+// 2 March 2026 in ExchangeLocation, counted from goldenEpoch
+func sessionFixture(segment, token uint32, hour, minute int) []byte {
+	ltt := time.Date(2026, 3, 2, hour, minute, 0, 0, ExchangeLocation)
+	seconds := int32(ltt.Sub(goldenEpoch) / time.Second)
+	return touchlineFixture(segment, token, seconds, seconds, 24500, 100)
+}
+
+// sessionClient returns a client with the NSE CM day session and the MCX day and evening
+// sessions, and the LTT time of day of the touchlines it delivers with their session state
+func sessionClient(opts ...Option) (*ODINMarketFeedClient, *[]string) {
+	tw := newTestClient(opts...)
+	tw.decoder.Epoch = goldenEpoch
+	tw.SetSessionWindow(SegmentNSECM, 9*time.Hour+15*time.Minute, 15*time.Hour+30*time.Minute)
+	tw.SetSessionWindow(SegmentMCX, 9*time.Hour, 17*time.Hour)
+	tw.AddSessionWindow(SegmentMCX, 17*time.Hour, 23*time.Hour+55*time.Minute)
+
+	delivered := &[]string{}
+	tw.OnTouchline = func(touchline TouchlineData) {
+		state := "in"
+		if touchline.OutOfSession {
+			state = "out"
+		}
+		*delivered = append(*delivered, touchline.LTT.In(ExchangeLocation).Format("15:04")+" "+state)
+	}
+	return tw, delivered
+}
+
+func sessionFixtures() []byte {
+	return frameOf(
+		sessionFixture(SegmentNSECM, 22, 9, 0),
+		sessionFixture(SegmentNSECM, 22, 9, 15),
+		sessionFixture(SegmentNSECM, 22, 15, 30),
+		sessionFixture(SegmentMCX, 234230, 8, 59),
+		sessionFixture(SegmentMCX, 234230, 20, 0),
+		sessionFixture(SegmentMCX, 234230, 23, 55),
+		sessionFixture(SegmentBSECM, 500325, 3, 0),
+	)
+}
+
+func TestSessionWindowTagsOutOfSession(t *testing.T) {
+	tw, delivered := sessionClient()
+	tw.responseReceived(sessionFixtures(), 0)
+
+	want := []string{"09:00 out", "09:15 in", "15:30 out", "08:59 out", "20:00 in", "23:55 out", "03:00 in"}
+	if !reflect.DeepEqual(*delivered, want) {
+		t.Errorf("delivered %q, want %q", *delivered, want)
+	}
+}
+
+func TestSessionWindowSuppression(t *testing.T) {
+	tw, delivered := sessionClient(WithOutOfSessionSuppression())
+	tw.responseReceived(sessionFixtures(), 0)
+
+	if want := []string{"09:15 in", "20:00 in", "03:00 in"}; !reflect.DeepEqual(*delivered, want) {
+		t.Errorf("delivered %q, want %q", *delivered, want)
+	}
+	if drops := tw.Stats().OutOfSessionDrops; drops != 4 {
+		t.Errorf("OutOfSessionDrops = %d, want 4", drops)
+	}
+
+	tw.ClearSessionWindows(SegmentNSECM)
+	*delivered = nil
+	tw.responseReceived(frameOf(sessionFixture(SegmentNSECM, 22, 9, 0)), 0)
+	if want := []string{"09:00 in"}; !reflect.DeepEqual(*delivered, want) {
+		t.Errorf("delivered %q after clearing the sessions, want %q", *delivered, want)
+	}
+}
+
+func TestSessionWindowSpanningMidnight(t *testing.T) {
+	window := SessionWindow{Open: 23 * time.Hour, Close: 2 * time.Hour}
+	for timeOfDay, want := range map[time.Duration]bool{
+		22 * time.Hour:                false,
+		23 * time.Hour:                true,
+		30 * time.Minute:              true,
+		2 * time.Hour:                 false,
+		23*time.Hour + 59*time.Minute: true,
+	} {
+		if got := window.contains(timeOfDay); got != want {
+			t.Errorf("contains(%v) = %v, want %v", timeOfDay, got, want)
+		}
+	}
+}
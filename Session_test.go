@@ -0,0 +1,17 @@
+package ODINMarketFeed
+
+import "testing"
+
+func TestDuplicateSessionDetectionIsOptIn(t *testing.T) {
+	raw := []byte("63=FT3.0|64=111|65=84|66=10:00:00|100=Session replaced")
+
+	msg, _ := NewDecoder().Decode(raw)
+	if msg.Kind == MessageDuplicateSession {
+		t.Errorf("decoded as %v without WithDuplicateSessionCode", msg.Kind)
+	}
+
+	tw := newTestClient(WithDuplicateSessionCode(111))
+	if msg, _ := tw.decoder.Decode(raw); msg.Kind != MessageDuplicateSession {
+		t.Errorf("decoded as %v with WithDuplicateSessionCode(111), want %v", msg.Kind, MessageDuplicateSession)
+	}
+}
@@ -0,0 +1,114 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultIteratorBuffer is the number of values that may wait for an iterator by default
+const defaultIteratorBuffer = 1024
+
+// ErrCallbackMode is returned by the Ticks and Messages iterators when the callback they
+// replace is set, or another iterator of the same kind is already running
+var ErrCallbackMode = errors.New("iterator cannot be used while the callback is set or another iterator is running")
+
+// WithIteratorBuffer sets the number of values that may wait for a Ticks or Messages loop
+// (default 1024). Values arriving while the buffer is full are dropped and counted in
+// Stats.IteratorDrops, so a slow loop body never stalls the receive loop.
+func WithIteratorBuffer(n int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.iteratorBuffer = n
+	}
+}
+
+// deliverySink hands values from the receive loop to an iterator through a buffered
+// channel. deliver never blocks: a value that does not fit is dropped and counted in drops.
+// Buffered values are accounted against WithDeliveryMemoryLimit from deliver until the
+// iterator receives them.
+type deliverySink[T any] struct {
+	tw    *ODINMarketFeedClient
+	ch    chan sinkItem[T]
+	stop  chan struct{}
+	drops *uint64
+
+	mu     sync.RWMutex // held for writing by close
+	closed bool
+}
+
+// sinkItem is a buffered value with its estimated size
+type sinkItem[T any] struct {
+	value T
+	size  int64
+}
+
+func newDeliverySink[T any](tw *ODINMarketFeedClient, size int, drops *uint64) *deliverySink[T] {
+	if size <= 0 {
+		size = defaultIteratorBuffer
+	}
+	return &deliverySink[T]{tw: tw, ch: make(chan sinkItem[T], size), stop: make(chan struct{}), drops: drops}
+}
+
+func (s *deliverySink[T]) deliver(value T) {
+	item := sinkItem[T]{value: value, size: messageSize(value)}
+
+	var pressure *MemoryPressure
+	defer func() { s.tw.memoryPressure(pressure) }()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return
+	}
+	var fits bool
+	if fits, pressure = s.tw.reserve(item.size, s.evictOldest); !fits {
+		return
+	}
+	select {
+	case s.ch <- item:
+	default:
+		s.tw.release(item.size)
+		atomic.AddUint64(s.drops, 1)
+	}
+}
+
+// evictOldest drops the oldest buffered value for reserve
+func (s *deliverySink[T]) evictOldest() (int64, bool) {
+	select {
+	case oldest := <-s.ch:
+		return oldest.size, true
+	default:
+		return 0, false
+	}
+}
+
+// receive returns the value of a buffered item, releasing its memory
+func (s *deliverySink[T]) receive(item sinkItem[T]) T {
+	s.tw.release(item.size)
+	return item.value
+}
+
+// close stops delivery and releases the memory of the values still buffered
+func (s *deliverySink[T]) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for {
+		select {
+		case item := <-s.ch:
+			s.tw.release(item.size)
+		default:
+			return
+		}
+	}
+}
+
+// depth returns the number of values waiting for the iterator; a nil sink has none
+func (s *deliverySink[T]) depth() int {
+	if s == nil {
+		return 0
+	}
+	return len(s.ch)
+}
@@ -0,0 +1,39 @@
+package ODINMarketFeed
+
+import "testing"
+
+func TestSinkDropsWhenBufferIsFull(t *testing.T) {
+	var drops uint64
+	sink := newDeliverySink[int](newTestClient(), 2, &drops)
+	for i := 0; i < 5; i++ {
+		sink.deliver(i)
+	}
+	if drops != 3 || sink.depth() != 2 {
+		t.Fatalf("%d dropped and %d buffered, want 3 and 2", drops, sink.depth())
+	}
+}
+
+func TestSinkAccountsDeliveryMemory(t *testing.T) {
+	tw := newTestClient(WithDeliveryMemoryLimit(1 << 20))
+	var drops uint64
+	sink := newDeliverySink[TouchlineData](tw, 4, &drops)
+	size := messageSize(TouchlineData{})
+
+	sink.deliver(TouchlineData{})
+	sink.deliver(TouchlineData{})
+	if inUse := tw.Stats().DeliveryMemory; inUse != 2*size {
+		t.Fatalf("DeliveryMemory = %d with 2 values buffered, want %d", inUse, 2*size)
+	}
+	sink.receive(<-sink.ch)
+	if inUse := tw.Stats().DeliveryMemory; inUse != size {
+		t.Errorf("DeliveryMemory = %d after a value was received, want %d", inUse, size)
+	}
+	sink.close()
+	if inUse := tw.Stats().DeliveryMemory; inUse != 0 {
+		t.Errorf("DeliveryMemory = %d after close, want 0", inUse)
+	}
+	sink.deliver(TouchlineData{})
+	if inUse := tw.Stats().DeliveryMemory; inUse != 0 || sink.depth() != 0 {
+		t.Errorf("a closed sink buffered a value holding %d bytes", inUse)
+	}
+}
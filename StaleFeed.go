@@ -0,0 +1,82 @@
+package ODINMarketFeed
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// StaleFeed is emitted when no frame has been received for the WithStaleFeedTimeout timeout
+// on a connected client. Since is when the last frame was received, or when the connection
+// was established if none was. It is emitted once per silence.
+type StaleFeed struct {
+	EventSource
+	Since time.Time
+}
+
+func (StaleFeed) isEvent() {}
+
+// staleFeedWatch holds the state of WithStaleFeedTimeout. reported is the UnixNano start of
+// the silence last reported.
+type staleFeedWatch struct {
+	timeout  time.Duration
+	reported int64
+}
+
+// WithStaleFeedTimeout emits StaleFeed when no frame has been received for timeout while
+// connected. The feed is checked on a goroutine of the connection, or by Tick with
+// WithManualReadLoop.
+func WithStaleFeedTimeout(timeout time.Duration) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.staleFeed.timeout = timeout
+	}
+}
+
+// startStaleFeedCheck checks the feed every quarter of the timeout until done is closed
+func (tw *ODINMarketFeedClient) startStaleFeedCheck(done <-chan struct{}) {
+	if tw.staleFeed.timeout <= 0 {
+		return
+	}
+
+	tw.routines.spawn(func() {
+		ticker := time.NewTicker(tw.staleFeed.timeout / 4)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				tw.checkStaleFeed(now)
+			case <-done:
+				return
+			}
+		}
+	})
+}
+
+// checkStaleFeed emits StaleFeed when the feed has been silent for the timeout at now and
+// this silence has not been reported yet
+func (tw *ODINMarketFeedClient) checkStaleFeed(now time.Time) {
+	if tw.staleFeed.timeout <= 0 {
+		return
+	}
+
+	tw.mu.Lock()
+	connected := tw.conn != nil
+	since := tw.connectedAt.UnixNano()
+	tw.mu.Unlock()
+	if !connected {
+		return
+	}
+	if last := atomic.LoadInt64(&tw.stats.lastMessageAt); last > since {
+		since = last
+	}
+
+	if now.Sub(time.Unix(0, since)) < tw.staleFeed.timeout {
+		return
+	}
+	if previous := atomic.LoadInt64(&tw.staleFeed.reported); previous == since ||
+		!atomic.CompareAndSwapInt64(&tw.staleFeed.reported, previous, since) {
+		return
+	}
+	tw.logf("No frame received since %s", time.Unix(0, since).Format(time.RFC3339))
+	tw.emit(StaleFeed{EventSource: tw.source(), Since: time.Unix(0, since)})
+}
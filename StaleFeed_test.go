@@ -0,0 +1,61 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// staleFeeds returns the StaleFeed events received within d
+func staleFeeds(events <-chan Event, d time.Duration) []StaleFeed {
+	var got []StaleFeed
+	timeout := time.After(d)
+	for {
+		select {
+		case event := <-events:
+			if stale, ok := event.(StaleFeed); ok {
+				got = append(got, stale)
+			}
+		case <-timeout:
+			return got
+		}
+	}
+}
+
+func TestStaleFeedOncePerSilence(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithStaleFeedTimeout(40 * time.Millisecond))
+	events := tw.Events()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	if got := staleFeeds(events, 300*time.Millisecond); len(got) != 1 {
+		t.Fatalf("got %d StaleFeed events in a silence, want 1", len(got))
+	}
+
+	ms.latest().send(touchlineMessage(1, 22, 24500))
+	got := staleFeeds(events, 300*time.Millisecond)
+	if len(got) != 1 {
+		t.Fatalf("got %d StaleFeed events after a frame, want 1", len(got))
+	}
+	if last := tw.Stats().LastMessageAt; !got[0].Since.Equal(last) {
+		t.Errorf("Since = %v, want the last frame at %v", got[0].Since, last)
+	}
+}
+
+func TestStaleFeedInTick(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithManualReadLoop(true), WithStaleFeedTimeout(time.Minute))
+	events := tw.Events()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	now := time.Now()
+	tw.Tick(now)
+	tw.Tick(now.Add(2 * time.Minute))
+	tw.Tick(now.Add(3 * time.Minute))
+	if got := staleFeeds(events, 50*time.Millisecond); len(got) != 1 {
+		t.Fatalf("got %d StaleFeed events from Tick, want 1", len(got))
+	}
+}
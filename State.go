@@ -0,0 +1,117 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ConnectionState represents the lifecycle state of the client connection
+type ConnectionState int
+
+const (
+	StateDisconnected ConnectionState = iota
+	StateConnecting
+	StateConnected
+	StateDisposed
+)
+
+// String returns the name of the connection state
+func (cs ConnectionState) String() string {
+	switch cs {
+	case StateDisconnected:
+		return "Disconnected"
+	case StateConnecting:
+		return "Connecting"
+	case StateConnected:
+		return "Connected"
+	case StateDisposed:
+		return "Disposed"
+	default:
+		return fmt.Sprintf("ConnectionState(%d)", int(cs))
+	}
+}
+
+// maxTrackedReconnects bounds the reconnect timestamps kept for health checks
+const maxTrackedReconnects = 100
+
+// State returns the current connection state
+func (tw *ODINMarketFeedClient) State() ConnectionState {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.state
+}
+
+// IsConnected reports whether the client currently has an open connection
+func (tw *ODINMarketFeedClient) IsConnected() bool {
+	return tw.State() == StateConnected
+}
+
+// setState updates the connection state; the caller must hold tw.mu
+func (tw *ODINMarketFeedClient) setState(state ConnectionState) {
+	if tw.state == StateDisposed {
+		return
+	}
+	tw.state = state
+}
+
+// beginConnect moves the client to StateConnecting. An existing connection is closed and its
+// receive loop awaited when WithSwitchOnConnect is set; otherwise ErrAlreadyConnected is
+// returned. replay reports whether a connection was replaced.
+func (tw *ODINMarketFeedClient) beginConnect() (replay bool, err error) {
+	tw.mu.Lock()
+	if tw.state == StateConnecting || (tw.state == StateConnected && !tw.switchOnConnect) {
+		tw.mu.Unlock()
+		return false, ErrAlreadyConnected
+	}
+	if tw.state == StateConnected {
+		done := tw.receiveDone
+		tw.mu.Unlock()
+
+		if err := tw.Disconnect(); err != nil && !errors.Is(err, ErrClientDisposed) {
+			tw.logf("Error closing previous connection: %v", err)
+		}
+		if done != nil {
+			<-done
+		}
+
+		tw.mu.Lock()
+		replay = true
+		if tw.state != StateDisconnected {
+			tw.mu.Unlock()
+			return false, ErrAlreadyConnected
+		}
+	}
+	if tw.isDisposed {
+		tw.mu.Unlock()
+		return false, ErrClientDisposed
+	}
+	tw.setState(StateConnecting)
+	tw.mu.Unlock()
+	return replay, nil
+}
+
+// recordConnected updates the state for a new connection; the caller must hold tw.mu
+func (tw *ODINMarketFeedClient) recordConnected(now time.Time) {
+	tw.setState(StateConnected)
+	if tw.generation > 1 {
+		tw.reconnectTimes = append(tw.reconnectTimes, now)
+		if len(tw.reconnectTimes) > maxTrackedReconnects {
+			tw.reconnectTimes = tw.reconnectTimes[len(tw.reconnectTimes)-maxTrackedReconnects:]
+		}
+	}
+}
+
+// reconnectsSince returns the number of reconnects at or after since
+func (tw *ODINMarketFeedClient) reconnectsSince(since time.Time) int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	count := 0
+	for _, t := range tw.reconnectTimes {
+		if !t.Before(since) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,158 @@
+package ODINMarketFeed
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats is a snapshot of the client counters
+type Stats struct {
+	Client string // the WithName label of the client
+
+	BinaryFrames    uint64 // binary websocket frames received
+	TextFrames      uint64 // text websocket frames received (server notices)
+	OtherFrames     uint64 // websocket frames of any other type
+	KeepAliveFrames uint64 // empty binary frames and outer frames declaring a zero length
+	ResyncBytes     uint64 // bytes skipped while searching for a valid outer frame header
+	PadBytes        uint64 // CR, LF, NUL and space bytes skipped between inner messages
+
+	HeartbeatsAnswered uint64 // server initiated heartbeat requests replied to
+	EventsDropped      uint64 // events discarded because the Events channel was full
+	Discards           uint64 // data discards reported in strict mode (WithStrictErrors)
+	OutOfSessionDrops  uint64 // touchlines dropped by WithOutOfSessionSuppression
+	SkippedMessages    uint64 // market data packets not decoded because nothing consumes them
+	CacheEvictions     uint64 // instruments evicted from the depth cache or token stats by WithCacheCapacity
+	FilteredMessages   uint64 // messages dropped by SetSegmentFilter
+	DuplicateTicks     uint64 // touchlines suppressed by WithTickDedup
+	ThrottleEpisodes   uint64 // gateway throttle notices received, see WithThrottleNotice
+	Published          uint64 // messages passed to the publisher of AttachPublisher
+	PublishDrops       uint64 // messages dropped because the publisher queue was full
+	PublishErrors      uint64 // messages the publisher failed to encode or publish
+	IteratorDrops      uint64 // values dropped because a Ticks or Messages buffer was full
+	MemoryDrops        uint64 // buffered messages dropped by WithDeliveryMemoryLimit
+	DeliveryMemory     int64  // estimated bytes of the messages buffered for delivery
+
+	// UnknownCodes counts the received messages per unrecognised 64= code (-1 for messages
+	// without a code)
+	UnknownCodes map[int]uint64
+
+	// SendFailures counts the failed writes of the requests the client sends on its own per
+	// stage, such as StageHeartbeat; see SendFailed
+	SendFailures map[string]uint64
+
+	ControlQueueDepth int // login, heartbeat and pause/resume requests waiting to be written
+	BulkQueueDepth    int // subscription requests waiting to be written
+
+	// CompressedBytes and DecompressedBytes are the sizes of the outer frame payloads
+	// decompressed successfully before and after decompression; CompressionRatio is
+	// DecompressedBytes / CompressedBytes, 0 before the first frame
+	CompressedBytes   uint64
+	DecompressedBytes uint64
+	CompressionRatio  float64
+
+	// MinFrameSize, AvgFrameSize and MaxFrameSize describe the binary websocket frames
+	// received, in bytes
+	MinFrameSize int
+	AvgFrameSize float64
+	MaxFrameSize int
+
+	// MessagesPerFrame counts the binary frames by the inner messages they completed: 0, 1,
+	// 2, 3-4, 5-8, 9-16, 17-32 and more than 32
+	MessagesPerFrame [len(frameMessageBounds) + 1]uint64
+
+	LastMessageAt time.Time // when the last frame was received
+	Reconnects    uint64    // connections established after the first one
+
+	Goroutines int // running goroutines owned by the client, see Goroutines
+
+	// CallbackQueueDepth is the number of deliveries waiting for the Ticks and Messages
+	// loops. Callbacks are invoked synchronously from the receive loop and add nothing.
+	CallbackQueueDepth int
+
+	// ServerClockOffset is the EWMA of server time minus local time, estimated from
+	// heartbeat responses
+	ServerClockOffset time.Duration
+	ClockSamples      uint64
+}
+
+// clientStats holds the live counters, updated atomically
+type clientStats struct {
+	binaryFrames uint64
+	textFrames   uint64
+	otherFrames  uint64
+
+	heartbeatsAnswered uint64
+	discards           uint64
+	skippedMessages    uint64
+	cacheEvictions     uint64
+	filteredMessages   uint64
+	duplicateTicks     uint64
+	published          uint64
+	publishDrops       uint64
+	publishErrors      uint64
+	iteratorDrops      uint64
+
+	lastMessageAt int64 // UnixNano
+}
+
+// Stats returns a snapshot of the client counters
+func (tw *ODINMarketFeedClient) Stats() Stats {
+	stats := Stats{
+		Client: tw.name,
+
+		BinaryFrames:    atomic.LoadUint64(&tw.stats.binaryFrames),
+		TextFrames:      atomic.LoadUint64(&tw.stats.textFrames),
+		OtherFrames:     atomic.LoadUint64(&tw.stats.otherFrames),
+		KeepAliveFrames: tw.fragHandler.keepAliveCount(),
+		ResyncBytes:     tw.fragHandler.resyncByteCount(),
+		PadBytes:        tw.fragHandler.padByteCount(),
+
+		HeartbeatsAnswered: atomic.LoadUint64(&tw.stats.heartbeatsAnswered),
+		EventsDropped:      atomic.LoadUint64(&tw.events.dropped),
+		Discards:           atomic.LoadUint64(&tw.stats.discards),
+		OutOfSessionDrops:  atomic.LoadUint64(&tw.sessions.dropped),
+		SkippedMessages:    atomic.LoadUint64(&tw.stats.skippedMessages),
+		CacheEvictions:     atomic.LoadUint64(&tw.stats.cacheEvictions),
+		FilteredMessages:   atomic.LoadUint64(&tw.stats.filteredMessages),
+		DuplicateTicks:     atomic.LoadUint64(&tw.stats.duplicateTicks),
+		ThrottleEpisodes:   tw.throttleEpisodes(),
+		Published:          atomic.LoadUint64(&tw.stats.published),
+		PublishDrops:       atomic.LoadUint64(&tw.stats.publishDrops),
+		PublishErrors:      atomic.LoadUint64(&tw.stats.publishErrors),
+		IteratorDrops:      atomic.LoadUint64(&tw.stats.iteratorDrops),
+		MemoryDrops:        atomic.LoadUint64(&tw.memory.dropped),
+		DeliveryMemory:     atomic.LoadInt64(&tw.memory.inUse),
+		UnknownCodes:       tw.unknownCodes.snapshot(),
+		SendFailures:       tw.sendFailures.snapshot(),
+		Goroutines:         tw.Goroutines(),
+		CallbackQueueDepth: tw.tickSink.Load().depth() + tw.messageSink.Load().depth(),
+	}
+
+	stats.CompressedBytes, stats.DecompressedBytes = tw.fragHandler.byteCounts()
+	if stats.CompressedBytes > 0 {
+		stats.CompressionRatio = float64(stats.DecompressedBytes) / float64(stats.CompressedBytes)
+	}
+	tw.frames.fill(&stats)
+
+	if lastMessageAt := atomic.LoadInt64(&tw.stats.lastMessageAt); lastMessageAt != 0 {
+		stats.LastMessageAt = time.Unix(0, lastMessageAt)
+	}
+
+	tw.mu.Lock()
+	if tw.generation > 1 {
+		stats.Reconnects = tw.generation - 1
+	}
+	queue := tw.sendQueue
+	tw.mu.Unlock()
+
+	if queue != nil {
+		stats.ControlQueueDepth, stats.BulkQueueDepth = queue.depths()
+	}
+
+	tw.clock.mu.Lock()
+	stats.ServerClockOffset = tw.clock.offset
+	stats.ClockSamples = tw.clock.samples
+	tw.clock.mu.Unlock()
+
+	return stats
+}
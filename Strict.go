@@ -0,0 +1,72 @@
+package ODINMarketFeed
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+)
+
+// discardExcerptSize is the number of discarded bytes included in a DiscardError
+const discardExcerptSize = 32
+
+// DiscardError describes data dropped by the client, reported in strict mode
+type DiscardError struct {
+	Stage   string // where the data was dropped, e.g. "decompress" or "inner header"
+	Bytes   int    // number of bytes dropped
+	Excerpt []byte // the first bytes of the dropped data
+	Err     error  // the underlying error, if any
+}
+
+func newDiscardError(stage string, data []byte, err error) DiscardError {
+	excerpt := data
+	if len(excerpt) > discardExcerptSize {
+		excerpt = excerpt[:discardExcerptSize]
+	}
+	return DiscardError{
+		Stage:   stage,
+		Bytes:   len(data),
+		Excerpt: append([]byte(nil), excerpt...),
+		Err:     err,
+	}
+}
+
+func (e DiscardError) Error() string {
+	message := fmt.Sprintf("%s: discarded %d bytes [%s]", e.Stage, e.Bytes, hex.EncodeToString(e.Excerpt))
+	if len(e.Excerpt) < e.Bytes {
+		message = message[:len(message)-1] + "...]"
+	}
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e DiscardError) Unwrap() error {
+	return e.Err
+}
+
+// WithStrictErrors reports every condition in which received data is dropped or an error
+// is otherwise swallowed through OnError with the stage, the byte count and a hex excerpt,
+// and counts them in Stats().Discards. Without it, corrupt frames are skipped silently.
+func WithStrictErrors() Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.strictErrors = true
+		tw.fragHandler.reportDiscards = true
+	}
+}
+
+// reportDiscard counts the discard and passes it to OnError
+func (tw *ODINMarketFeedClient) reportDiscard(discard DiscardError) {
+	atomic.AddUint64(&tw.stats.discards, 1)
+	tw.reportError("Data discarded: " + discard.Error())
+}
+
+// reportFrameDiscards reports the discards recorded while defragmenting
+func (tw *ODINMarketFeedClient) reportFrameDiscards() {
+	if !tw.strictErrors {
+		return
+	}
+	for _, discard := range tw.fragHandler.takeDiscards() {
+		tw.reportDiscard(discard)
+	}
+}
@@ -0,0 +1,59 @@
+package ODINMarketFeed
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStrictErrorsReportEachDiscardOnce(t *testing.T) {
+	corruptZlib, _ := EncodeFrame(Frame{Flag: FrameCompressed, Payload: []byte("not a zlib stream")})
+	badInner, _ := (&ZLIBCompressor{}).Compress([]byte("X00010garbage!"))
+	badInnerFrame, _ := EncodeFrame(Frame{Flag: FrameCompressed, Payload: badInner})
+	shortBinary := frameOf(append([]byte("63=FT3.0|64=206|50="), make([]byte, 10)...))
+
+	tests := []struct {
+		name          string
+		frame         []byte
+		stage         string
+		reportLenient bool // decode failures reach OnError without strict mode too
+	}{
+		{"corrupt zlib stream", corruptZlib, "decompress:", false},
+		{"bad inner header", badInnerFrame, "inner header:", false},
+		{"short binary payload", shortBinary, "decode:", true},
+	}
+	for _, tt := range tests {
+		for _, strict := range []bool{true, false} {
+			var opts []Option
+			if strict {
+				opts = append(opts, WithStrictErrors())
+			}
+			tw := newTestClient(opts...)
+			var reported []string
+			tw.OnError = func(message string) { reported = append(reported, message) }
+			var touchlines int
+			tw.OnTouchline = func(TouchlineData) { touchlines++ }
+
+			tw.responseReceived(tt.frame, 0)
+			tw.responseReceived(frameOf(touchlineMessage(1, 22, 24500)), 0)
+
+			if touchlines != 1 {
+				t.Errorf("%s (strict %v): %d touchlines after the discard, want 1", tt.name, strict, touchlines)
+			}
+			discards := tw.Stats().Discards
+			switch {
+			case strict:
+				if len(reported) != 1 || !strings.Contains(reported[0], tt.stage) || discards != 1 {
+					t.Errorf("%s: reported %q with %d discards, want one %s report", tt.name, reported, discards, tt.stage)
+				}
+			case tt.reportLenient:
+				if len(reported) != 1 || discards != 0 {
+					t.Errorf("%s (lenient): reported %q with %d discards, want one uncounted report", tt.name, reported, discards)
+				}
+			default:
+				if len(reported) != 0 || discards != 0 {
+					t.Errorf("%s (lenient): reported %q with %d discards, want none", tt.name, reported, discards)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrSubscriptionAcksDisabled is returned by SubscribeTouchlineSync without WithSubscriptionAcks
+var ErrSubscriptionAcksDisabled = errors.New("subscription acks are not enabled")
+
+// TokenRejection is an instrument refused by the gateway in a subscription ack
+type TokenRejection struct {
+	Instrument Instrument
+	Status     string // value of the status tag
+	Reason     string // value of the reason tag, if the ack carries one
+}
+
+// String formats the rejection for logging
+func (r TokenRejection) String() string {
+	if r.Reason != "" {
+		return fmt.Sprintf("%s: status %s (%s)", r.Instrument, r.Status, r.Reason)
+	}
+	return fmt.Sprintf("%s: status %s", r.Instrument, r.Status)
+}
+
+// SubscriptionAck is emitted and passed to OnSubscriptionAck for each touchline
+// subscription ack decoded with WithSubscriptionAcks. CorrelationID is the echoed
+// correlation tag of the ack, if any.
+type SubscriptionAck struct {
+	EventSource
+	CorrelationID string
+	Accepted      []Instrument
+	Rejected      []TokenRejection
+}
+
+func (SubscriptionAck) isEvent() {}
+
+// subscriptionAcks holds the ack tags, the SubscribeTouchlineSync calls waiting for an ack
+// and the touchline subscriptions the gateway rejected. mu guards waiters and rejected.
+type subscriptionAcks struct {
+	statusTag      int
+	reasonTag      int
+	acceptedStatus string
+
+	mu       sync.Mutex
+	waiters  []*ackWaiter
+	rejected map[subscriptionKey]TokenRejection
+}
+
+// ackWaiter is a SubscribeTouchlineSync call waiting for the verdicts on its instruments
+type ackWaiter struct {
+	correlationID string
+	sent          []Instrument
+	pending       map[uint64]struct{} // depthKey of the instruments without a verdict
+	accepted      []Instrument
+	rejected      []TokenRejection
+	err           error
+	done          chan struct{}
+}
+
+// WithSubscriptionAcks decodes the gateway's replies to touchline subscriptions that list
+// the accepted and rejected tokens as a repeating group of $-separated 1=, 7= and status
+// fields, such as "1=1$7=22$statusTag=0|1=1$7=99$statusTag=3$reasonTag=Invalid token". A
+// token is accepted when its status equals acceptedStatus; a status tag outside the group
+// applies to the entries without one. reasonTag may be 0 when the acks carry no reason.
+// Rejected tokens stay in the registry but are left out of ResubscribeAll, see
+// RejectedSubscriptions and ForceResubscribeAll.
+func WithSubscriptionAcks(statusTag, reasonTag int, acceptedStatus string) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.acks.statusTag = statusTag
+		tw.acks.reasonTag = reasonTag
+		tw.acks.acceptedStatus = acceptedStatus
+	}
+}
+
+// SubscribeTouchlineSync subscribes to touchline like SubscribeTouchlineWithOptions and
+// waits for the gateway's ack of the sent instruments. The result lists the accepted and
+// the rejected instruments with their status and reason. With WithRequestCorrelation and a
+// gateway that echoes the tag, only the ack to this request completes the call. The call
+// fails when ctx is done or the connection drops before every instrument has a verdict; a
+// *BatchError for skipped items is returned together with the result.
+func (tw *ODINMarketFeedClient) SubscribeTouchlineSync(ctx context.Context, tokenList []string, opts TouchlineOptions) (SubscribeResult, error) {
+	if tw.acks.statusTag <= 0 {
+		return SubscribeResult{}, ErrSubscriptionAcksDisabled
+	}
+	if err := tw.checkDisposed(); err != nil {
+		return SubscribeResult{}, err
+	}
+
+	waiter := &ackWaiter{done: make(chan struct{})}
+	defer tw.removeAckWaiter(waiter)
+
+	err := tw.subscribeTouchline(tokenList, opts, waiter, true)
+	result := SubscribeResult{Requested: len(tokenList), Sent: waiter.sent}
+	var batchErr *BatchError
+	if len(waiter.sent) == 0 || !errors.As(err, &batchErr) && err != nil {
+		return result, err
+	}
+	if batchErr != nil {
+		result = batchErr.Result
+	}
+
+	select {
+	case <-waiter.done:
+	case <-ctx.Done():
+		return result, ctx.Err()
+	}
+
+	tw.acks.mu.Lock()
+	result.Accepted = waiter.accepted
+	result.Rejected = waiter.rejected
+	waitErr := waiter.err
+	tw.acks.mu.Unlock()
+
+	if waitErr != nil {
+		return result, waitErr
+	}
+	return result, err
+}
+
+// addAckWaiter registers waiter for the verdicts on instruments before their request is sent
+func (tw *ODINMarketFeedClient) addAckWaiter(waiter *ackWaiter, correlationID string, instruments []Instrument) {
+	tw.acks.mu.Lock()
+	defer tw.acks.mu.Unlock()
+
+	waiter.correlationID = correlationID
+	waiter.sent = instruments
+	waiter.pending = make(map[uint64]struct{}, len(instruments))
+	for _, instrument := range instruments {
+		waiter.pending[instrumentKey(instrument)] = struct{}{}
+	}
+	tw.acks.waiters = append(tw.acks.waiters, waiter)
+}
+
+func (tw *ODINMarketFeedClient) removeAckWaiter(waiter *ackWaiter) {
+	tw.acks.mu.Lock()
+	defer tw.acks.mu.Unlock()
+
+	for i, w := range tw.acks.waiters {
+		if w == waiter {
+			tw.acks.waiters = append(tw.acks.waiters[:i:i], tw.acks.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// subscriptionAckReceived decodes a touchline subscription ack, records the rejected
+// instruments and completes the waiting SubscribeTouchlineSync calls
+func (tw *ODINMarketFeedClient) subscriptionAckReceived(msg ParsedMessage) {
+	if tw.acks.statusTag <= 0 || msg.Kind != MessageUnknown || msg.Code != msgCodeTouchline || bytes.Contains(msg.Raw, binaryTag) {
+		return
+	}
+	ack, ok := tw.parseSubscriptionAck(msg.Text)
+	if !ok {
+		return
+	}
+	ack.EventSource = tw.source()
+	ack.CorrelationID = msg.CorrelationID
+	echoed := tw.correlationEchoed()
+
+	tw.acks.mu.Lock()
+	for _, instrument := range ack.Accepted {
+		delete(tw.acks.rejected, touchlineKey(instrument))
+	}
+	if len(ack.Rejected) > 0 && tw.acks.rejected == nil {
+		tw.acks.rejected = make(map[subscriptionKey]TokenRejection)
+	}
+	for _, rejection := range ack.Rejected {
+		tw.acks.rejected[touchlineKey(rejection.Instrument)] = rejection
+	}
+
+	var completed []*ackWaiter
+	waiting := tw.acks.waiters[:0]
+	for _, waiter := range tw.acks.waiters {
+		if echoed && waiter.correlationID != "" && ack.CorrelationID != waiter.correlationID {
+			waiting = append(waiting, waiter)
+			continue
+		}
+		for _, instrument := range ack.Accepted {
+			if _, ok := waiter.pending[instrumentKey(instrument)]; ok {
+				delete(waiter.pending, instrumentKey(instrument))
+				waiter.accepted = append(waiter.accepted, instrument)
+			}
+		}
+		for _, rejection := range ack.Rejected {
+			if _, ok := waiter.pending[instrumentKey(rejection.Instrument)]; ok {
+				delete(waiter.pending, instrumentKey(rejection.Instrument))
+				waiter.rejected = append(waiter.rejected, rejection)
+			}
+		}
+		if len(waiter.pending) == 0 {
+			completed = append(completed, waiter)
+		} else {
+			waiting = append(waiting, waiter)
+		}
+	}
+	tw.acks.waiters = waiting
+	tw.acks.mu.Unlock()
+
+	for _, waiter := range completed {
+		close(waiter.done)
+	}
+
+	for _, rejection := range ack.Rejected {
+		tw.logf("Touchline subscription rejected: %s", rejection)
+	}
+	tw.emit(ack)
+	if tw.OnSubscriptionAck != nil {
+		tw.invokeCallback("OnSubscriptionAck", func() string {
+			return fmt.Sprintf("%d accepted, %d rejected", len(ack.Accepted), len(ack.Rejected))
+		}, func() { tw.OnSubscriptionAck(ack) })
+	}
+}
+
+// parseSubscriptionAck reads the accepted and rejected instruments of an ack. ok is false
+// when the message lists no instruments with a status.
+func (tw *ODINMarketFeedClient) parseSubscriptionAck(text string) (ack SubscriptionAck, ok bool) {
+	statusTag := strconv.Itoa(tw.acks.statusTag) + string(TagValueSeparator)
+	reasonTag := strconv.Itoa(tw.acks.reasonTag) + string(TagValueSeparator)
+	pair := string(tw.decoder.pairDelimiter())
+
+	type entry struct {
+		segID, token   string
+		status, reason string
+		hasStatus      bool
+	}
+	var entries []entry
+	var status, reason string
+	hasStatus := false
+
+	for _, field := range strings.Split(text, string(FieldDelimiter)) {
+		if !strings.Contains(field, pair) {
+			switch {
+			case strings.HasPrefix(field, statusTag):
+				status, hasStatus = field[len(statusTag):], true
+			case tw.acks.reasonTag > 0 && strings.HasPrefix(field, reasonTag):
+				reason = field[len(reasonTag):]
+			}
+			continue
+		}
+
+		var e entry
+		for _, part := range strings.Split(field, pair) {
+			switch {
+			case strings.HasPrefix(part, "1="):
+				e.segID = part[2:]
+			case strings.HasPrefix(part, "7="):
+				e.token = part[2:]
+			case strings.HasPrefix(part, statusTag):
+				e.status, e.hasStatus = part[len(statusTag):], true
+			case tw.acks.reasonTag > 0 && strings.HasPrefix(part, reasonTag):
+				e.reason = part[len(reasonTag):]
+			}
+		}
+		if e.segID != "" && e.token != "" {
+			entries = append(entries, e)
+		}
+	}
+
+	for _, e := range entries {
+		if !e.hasStatus {
+			if !hasStatus {
+				continue
+			}
+			e.status, e.reason = status, reason
+		}
+		instrument, err := ParseInstrument(e.segID + "_" + e.token)
+		if err != nil {
+			continue
+		}
+		ok = true
+		if e.status == tw.acks.acceptedStatus {
+			ack.Accepted = append(ack.Accepted, instrument)
+		} else {
+			ack.Rejected = append(ack.Rejected, TokenRejection{Instrument: instrument, Status: e.status, Reason: e.reason})
+		}
+	}
+	return ack, ok
+}
+
+// failPendingAcks fails every waiting SubscribeTouchlineSync call
+func (tw *ODINMarketFeedClient) failPendingAcks(err error) {
+	tw.acks.mu.Lock()
+	waiters := tw.acks.waiters
+	tw.acks.waiters = nil
+	for _, waiter := range waiters {
+		waiter.err = errors.Join(errors.New("connection lost while waiting for subscription ack"), err)
+	}
+	tw.acks.mu.Unlock()
+
+	for _, waiter := range waiters {
+		close(waiter.done)
+	}
+}
+
+// RejectedSubscriptions returns the touchline subscriptions the gateway rejected in its
+// latest ack, sorted by instrument. They are not live and ResubscribeAll skips them.
+func (tw *ODINMarketFeedClient) RejectedSubscriptions() []TokenRejection {
+	tw.acks.mu.Lock()
+	rejections := make([]TokenRejection, 0, len(tw.acks.rejected))
+	for _, rejection := range tw.acks.rejected {
+		rejections = append(rejections, rejection)
+	}
+	tw.acks.mu.Unlock()
+
+	instruments := make([]Instrument, len(rejections))
+	byInstrument := make(map[Instrument]TokenRejection, len(rejections))
+	for i, rejection := range rejections {
+		instruments[i] = rejection.Instrument
+		byInstrument[rejection.Instrument] = rejection
+	}
+	sortInstruments(instruments)
+	for i, instrument := range instruments {
+		rejections[i] = byInstrument[instrument]
+	}
+	return rejections
+}
+
+// ForceResubscribeAll is ResubscribeAll including the subscriptions the gateway rejected,
+// which are no longer marked as rejected until a new ack rejects them again
+func (tw *ODINMarketFeedClient) ForceResubscribeAll() error {
+	tw.acks.mu.Lock()
+	tw.acks.rejected = nil
+	tw.acks.mu.Unlock()
+
+	return tw.ResubscribeAll()
+}
+
+// liveSubscriptions drops the subscriptions the gateway rejected
+func (tw *ODINMarketFeedClient) liveSubscriptions(subscriptions []Subscription) []Subscription {
+	tw.acks.mu.Lock()
+	defer tw.acks.mu.Unlock()
+
+	if len(tw.acks.rejected) == 0 {
+		return subscriptions
+	}
+	live := subscriptions[:0:0]
+	for _, sub := range subscriptions {
+		key := subscriptionKey{subType: sub.Type, marketSegmentID: sub.Instrument.MarketSegmentID, token: sub.Instrument.Token}
+		if _, rejected := tw.acks.rejected[key]; !rejected {
+			live = append(live, sub)
+		}
+	}
+	return live
+}
+
+// forgetRejections clears the rejected marks of unsubscribed instruments; nil clears all
+func (tw *ODINMarketFeedClient) forgetRejections(subType SubscriptionType, instruments []Instrument) {
+	if subType != SubscriptionTouchline {
+		return
+	}
+
+	tw.acks.mu.Lock()
+	defer tw.acks.mu.Unlock()
+
+	if instruments == nil {
+		tw.acks.rejected = nil
+		return
+	}
+	for _, instrument := range instruments {
+		delete(tw.acks.rejected, touchlineKey(instrument))
+	}
+}
+
+// instrumentKey identifies an instrument regardless of its symbol
+func instrumentKey(instrument Instrument) uint64 {
+	return depthKey(uint32(instrument.MarketSegmentID), uint32(instrument.Token))
+}
+
+// touchlineKey is the registry key of a touchline subscription
+func touchlineKey(instrument Instrument) subscriptionKey {
+	return subscriptionKey{subType: SubscriptionTouchline, marketSegmentID: instrument.MarketSegmentID, token: instrument.Token}
+}
@@ -0,0 +1,335 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// SubscriptionType identifies the kind of market data subscription
+type SubscriptionType int
+
+const (
+	SubscriptionTouchline SubscriptionType = iota
+	SubscriptionLTPTouchline
+	SubscriptionBestFive
+)
+
+// String returns the name of the subscription type
+func (st SubscriptionType) String() string {
+	switch st {
+	case SubscriptionTouchline:
+		return "Touchline"
+	case SubscriptionLTPTouchline:
+		return "LTPTouchline"
+	case SubscriptionBestFive:
+		return "BestFive"
+	default:
+		return fmt.Sprintf("SubscriptionType(%d)", int(st))
+	}
+}
+
+// Subscription describes a subscription tracked by the client
+type Subscription struct {
+	Type          SubscriptionType
+	Instrument    Instrument
+	ResponseType  string
+	LTPChangeOnly bool
+}
+
+type subscriptionKey struct {
+	subType         SubscriptionType
+	marketSegmentID int
+	token           int
+}
+
+type queuedRequest struct {
+	message     string
+	onSent      func()
+	reservation *quotaReservation
+}
+
+// Subscriptions returns a snapshot of the tracked subscriptions
+func (tw *ODINMarketFeedClient) Subscriptions() []Subscription {
+	tw.subMu.Lock()
+	defer tw.subMu.Unlock()
+
+	result := make([]Subscription, 0, len(tw.subscriptions))
+	for _, sub := range tw.subscriptions {
+		result = append(result, sub)
+	}
+
+	sortSubscriptions(result)
+	return result
+}
+
+// sortSubscriptions orders subscriptions by type, market segment and token
+func sortSubscriptions(subscriptions []Subscription) {
+	sort.Slice(subscriptions, func(i, j int) bool {
+		a, b := subscriptions[i], subscriptions[j]
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		if a.Instrument.MarketSegmentID != b.Instrument.MarketSegmentID {
+			return a.Instrument.MarketSegmentID < b.Instrument.MarketSegmentID
+		}
+		return a.Instrument.Token < b.Instrument.Token
+	})
+}
+
+// trackSubscribe records sent subscriptions. direct is set for the subscribe methods called
+// by the application, whose subscriptions outlive the groups holding them, and unset for
+// groups and resubscribes.
+func (tw *ODINMarketFeedClient) trackSubscribe(subType SubscriptionType, instruments []Instrument, responseType string, ltpChangeOnly, direct bool) {
+	tw.subMu.Lock()
+	for _, instrument := range instruments {
+		key := subscriptionKey{subType: subType, marketSegmentID: instrument.MarketSegmentID, token: instrument.Token}
+		tw.subscriptions[key] = Subscription{
+			Type:          subType,
+			Instrument:    instrument,
+			ResponseType:  responseType,
+			LTPChangeOnly: ltpChangeOnly,
+		}
+		if direct {
+			tw.holdDirectlyLocked(key)
+		}
+		if tw.dedup != nil && subType == SubscriptionTouchline {
+			tw.dedup.forget(depthKey(uint32(instrument.MarketSegmentID), uint32(instrument.Token)))
+		}
+	}
+	tw.subMu.Unlock()
+
+	tw.subscriptionsChanged()
+}
+
+func (tw *ODINMarketFeedClient) trackUnsubscribe(subType SubscriptionType, instruments []Instrument) {
+	tw.subMu.Lock()
+	for _, instrument := range instruments {
+		key := subscriptionKey{subType: subType, marketSegmentID: instrument.MarketSegmentID, token: instrument.Token}
+		delete(tw.subscriptions, key)
+		delete(tw.direct, key)
+		delete(tw.universe, key)
+		tw.leaveGroupsLocked(key)
+	}
+	tw.subMu.Unlock()
+	tw.forgetRejections(subType, instruments)
+
+	tw.subscriptionsChanged()
+
+	for _, instrument := range instruments {
+		tw.releaseToken(instrument.MarketSegmentID, instrument.Token)
+	}
+}
+
+// isSubscribed reports whether the instrument has a tracked subscription of one of the types
+func (tw *ODINMarketFeedClient) isSubscribed(marketSegmentID, token int, subTypes ...SubscriptionType) bool {
+	tw.subMu.Lock()
+	defer tw.subMu.Unlock()
+
+	for _, subType := range subTypes {
+		if _, ok := tw.subscriptions[subscriptionKey{subType: subType, marketSegmentID: marketSegmentID, token: token}]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sendRequest sends a subscription request, or queues it while disconnected when the
+// pre-connect queue is enabled. onSent is invoked once the request has been written. The
+// reservation is released after onSent or when the request fails; a queued request keeps it
+// until it is flushed or dropped.
+func (tw *ODINMarketFeedClient) sendRequest(message string, tokenCount int, onSent func(), reservation *quotaReservation) (queued bool, err error) {
+	span := tw.startSpan(SpanSubscribe, map[string]interface{}{
+		"odin.message_code": messageCode(message),
+		"odin.token_count":  tokenCount,
+	})
+	defer func() {
+		spanEvent(span, "sent", map[string]interface{}{"odin.queued": queued})
+		spanEnd(span, err)
+	}()
+
+	tw.mu.Lock()
+	if tw.queueEnabled && (tw.conn == nil || tw.flushing) {
+		switch {
+		case tw.isDisposed:
+			err = ErrClientDisposed
+		case tw.maxQueued > 0 && len(tw.preConnectQueue) >= tw.maxQueued:
+			err = fmt.Errorf("pre-connect queue is full (max %d requests)", tw.maxQueued)
+		default:
+			tw.preConnectQueue = append(tw.preConnectQueue, queuedRequest{message: message, onSent: onSent, reservation: reservation})
+		}
+		tw.mu.Unlock()
+
+		if err != nil {
+			reservation.release()
+			return false, err
+		}
+		tw.logf("Queued Message: %s", message)
+		return true, nil
+	}
+	tw.mu.Unlock()
+
+	defer reservation.release()
+	if err := tw.SendMessage(message); err != nil {
+		return false, err
+	}
+	if onSent != nil {
+		onSent()
+	}
+	return false, nil
+}
+
+// releaseQueued releases the quota reservations of dropped queued requests
+func releaseQueued(queue []queuedRequest) {
+	for _, request := range queue {
+		request.reservation.release()
+	}
+}
+
+// flushPreConnectQueue sends the queued requests in order after login. Their 66= time is
+// replaced with the time they are sent, as they may have waited long in the queue.
+func (tw *ODINMarketFeedClient) flushPreConnectQueue() {
+	for {
+		tw.mu.Lock()
+		queue := tw.preConnectQueue
+		tw.preConnectQueue = nil
+		if len(queue) == 0 {
+			tw.flushing = false
+			tw.mu.Unlock()
+			return
+		}
+		tw.mu.Unlock()
+
+		for _, request := range queue {
+			if err := tw.SendMessage(tw.restampRequestTime(request.message)); err != nil {
+				request.reservation.release()
+				tw.reportAsyncError(StageQueuedRequest, err)
+				continue
+			}
+			if request.onSent != nil {
+				request.onSent()
+			}
+			request.reservation.release()
+		}
+	}
+}
+
+// ResubscribeAll re-sends subscription requests for every tracked subscription, e.g. after
+// reconnecting. Touchline subscriptions are batched per response type and LTP-change flag.
+// With WithResubscribeVerification the replayed subscriptions are checked for updates.
+// Subscriptions rejected in a WithSubscriptionAcks ack are skipped.
+func (tw *ODINMarketFeedClient) ResubscribeAll() error {
+	subscriptions := tw.liveSubscriptions(tw.Subscriptions())
+	err := tw.resubscribe(subscriptions)
+	tw.emit(Resubscribed{EventSource: tw.source(), Count: len(subscriptions), Err: err})
+	tw.scheduleResubscribeCheck(subscriptions)
+	return err
+}
+
+// resubscribe sends subscription requests for the subscriptions, at most
+// WithMaxTokensPerRequest instruments per touchline and LTP touchline request
+func (tw *ODINMarketFeedClient) resubscribe(subscriptions []Subscription) error {
+	touchline := make(map[TouchlineOptions][]Instrument)
+	var ltpTouchline []Instrument
+	var bestFive []Instrument
+
+	for _, sub := range subscriptions {
+		switch sub.Type {
+		case SubscriptionTouchline:
+			opts := sub.TouchlineOptions()
+			touchline[opts] = append(touchline[opts], sub.Instrument)
+		case SubscriptionLTPTouchline:
+			ltpTouchline = append(ltpTouchline, sub.Instrument)
+		case SubscriptionBestFive:
+			bestFive = append(bestFive, sub.Instrument)
+		}
+	}
+
+	var errs []error
+	for opts, instruments := range touchline {
+		for _, chunk := range chunkInstruments(instruments, tw.maxTokensPerRequest) {
+			if err := tw.subscribeTouchline(instrumentStrings(chunk), opts, nil, false); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for _, chunk := range chunkInstruments(ltpTouchline, tw.maxTokensPerRequest) {
+		if err := tw.subscribeLTPTouchline(instrumentStrings(chunk), false); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(bestFive) > 0 {
+		if err := tw.bestFiveBatch(bestFive, true, false); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// UnsubscribeAll sends batched unsubscribe requests for every tracked subscription, at most
+// WithMaxTokensPerRequest instruments per request. The registry is empty afterwards even
+// when some requests fail; the failures are returned. Subscription groups are kept but left
+// without members.
+func (tw *ODINMarketFeedClient) UnsubscribeAll() error {
+	return tw.unsubscribeAll(context.Background(), &CloseReport{})
+}
+
+// unsubscribeAll empties the registry and unsubscribes its subscriptions, the largest
+// segment and subscription type first, until ctx is done. The instruments sent and skipped
+// are counted in report.
+func (tw *ODINMarketFeedClient) unsubscribeAll(ctx context.Context, report *CloseReport) error {
+	tw.subMu.Lock()
+	subscriptions := tw.subscriptions
+	tw.subscriptions = make(map[subscriptionKey]Subscription)
+	tw.direct = nil
+	tw.universe = nil
+	for _, group := range tw.groups {
+		group.members = make(map[subscriptionKey]struct{})
+	}
+	tw.subMu.Unlock()
+	tw.forgetRejections(SubscriptionTouchline, nil)
+
+	for _, sub := range subscriptions {
+		tw.releaseToken(sub.Instrument.MarketSegmentID, sub.Instrument.Token)
+	}
+	defer tw.subscriptionsChanged()
+
+	chunkSize := tw.maxTokensPerRequest
+	if chunkSize <= 0 {
+		chunkSize = closeUnsubscribeChunk
+	}
+
+	var errs []error
+	for _, batch := range unsubscribeBatches(subscriptions) {
+		for _, chunk := range chunkInstruments(batch.instruments, chunkSize) {
+			sent := 0
+			for _, request := range tw.unsubscribeRequests(batch.subType, chunk) {
+				if ctx.Err() != nil {
+					report.UnsubscribesSkipped += request.count
+					continue
+				}
+				if err := tw.sendMessage(ctx, request.message); err != nil {
+					report.UnsubscribesSkipped += request.count
+					if ctx.Err() == nil {
+						errs = append(errs, fmt.Errorf("%s: %w", batch.subType, err))
+					}
+					continue
+				}
+				sent += request.count
+			}
+			report.UnsubscribesSent += sent
+			if sent > 0 {
+				tw.logf("Unsubscribed from %s tokens: %s", batch.subType, joinInstruments(chunk))
+			}
+		}
+	}
+
+	if err := ctx.Err(); err != nil && report.UnsubscribesSkipped > 0 {
+		report.DeadlineExceeded = true
+		tw.logf("Deadline reached; skipped unsubscribing %d instruments", report.UnsubscribesSkipped)
+		errs = append(errs, fmt.Errorf("%d unsubscribes skipped: %w", report.UnsubscribesSkipped, err))
+	}
+	return errors.Join(errs...)
+}
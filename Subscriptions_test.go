@@ -0,0 +1,166 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestQueuedRequestIsRestamped(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithPreConnectQueue(0))
+	now := time.Date(2026, 10, 16, 9, 15, 0, 0, ExchangeLocation)
+	tw.requestClock.now = func() time.Time { return now }
+
+	if err := tw.SubscribeTouchlineWithOptions([]string{"1_22"}, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(90 * time.Minute)
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	if request := ms.next(t, msgCodeTouchline); !strings.Contains(request, "|66=10:45:00|") {
+		t.Errorf("queued request sent as %q, want the 66= time of the flush", request)
+	}
+}
+
+func TestUnsubscribeAllChunksRequests(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithMaxTokensPerRequest(2))
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	tokens := []string{"1_22", "1_23", "1_24", "1_25", "1_26"}
+	if err := tw.SubscribeTouchlineWithOptions(tokens, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	ms.next(t, msgCodeTouchline)
+
+	if err := tw.UnsubscribeAll(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if request := ms.next(t, msgCodeTouchline); !strings.HasSuffix(request, "230=2") {
+			t.Fatalf("request %d is %q, want an unsubscribe", i, request)
+		}
+	}
+	if n := tw.SubscribedInstruments(); n != 0 {
+		t.Errorf("%d instruments still subscribed after UnsubscribeAll", n)
+	}
+}
+
+func TestResubscribeAllChunksRequests(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithMaxTokensPerRequest(2))
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+
+	if err := tw.SubscribeTouchlineWithOptions([]string{"1_22", "1_23", "1_24", "1_25", "1_26"}, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.SubscribeLTPTouchline([]string{"1_27", "1_28", "1_29"}); err != nil {
+		t.Fatal(err)
+	}
+	ms.next(t, msgCodeTouchline)
+	ms.next(t, msgCodeLTPTouchline)
+
+	if err := tw.ResubscribeAll(); err != nil {
+		t.Fatal(err)
+	}
+	instruments := map[int]int{}
+	requests := map[int]int{}
+	timeout := time.After(5 * time.Second)
+	for requests[msgCodeTouchline] < 3 || requests[msgCodeLTPTouchline] < 2 {
+		select {
+		case request := <-ms.requests:
+			code := messageCode(request)
+			if code != msgCodeTouchline && code != msgCodeLTPTouchline {
+				continue
+			}
+			if n := strings.Count(request, "$7="); n > 2 {
+				t.Errorf("request %q carries %d instruments, want at most 2", request, n)
+			}
+			requests[code]++
+			instruments[code] += strings.Count(request, "$7=")
+		case <-timeout:
+			t.Fatalf("resubscribe sent %v requests, want 3 touchline and 2 LTP", requests)
+		}
+	}
+	if instruments[msgCodeTouchline] != 5 || instruments[msgCodeLTPTouchline] != 3 {
+		t.Errorf("resubscribed %v instruments, want 5 touchline and 3 LTP", instruments)
+	}
+}
+
+func TestParseInstrumentCanonicalises(t *testing.T) {
+	want := Instrument{MarketSegmentID: 1, Token: 2885}
+	for _, spelling := range []string{"1_2885", " 1_02885 ", "01_2885", "1 _ 2885", "\t1_0002885\n"} {
+		if got, err := ParseInstrument(spelling); err != nil || got != want {
+			t.Errorf("ParseInstrument(%q) = %+v, %v, want %+v", spelling, got, err, want)
+		}
+	}
+	for _, spelling := range []string{"1_28 85", "1__2885", "1_2885_", "_2885"} {
+		if got, err := ParseInstrument(spelling); err == nil {
+			t.Errorf("ParseInstrument(%q) = %+v, want an error", spelling, got)
+		}
+	}
+}
+
+func TestUnsubscribeMatchesOtherSpelling(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	instrument := Instrument{MarketSegmentID: 1, Token: 2885}
+	if err := tw.SubscribeTouchlineWithOptions([]string{" 1_02885 "}, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if request := ms.next(t, msgCodeTouchline); !strings.Contains(request, "7=2885|") {
+		t.Errorf("subscribe %q, want the canonical token", request)
+	}
+	if err := tw.SubscribeLTPTouchline([]string{"1_2885"}); err != nil {
+		t.Fatal(err)
+	}
+	subs := tw.Subscriptions()
+	if len(subs) != 2 || subs[0].Instrument != instrument || subs[1].Instrument != instrument {
+		t.Fatalf("Subscriptions() = %+v, want 1_2885 once per type", subs)
+	}
+
+	if err := tw.UnsubscribeTouchline([]string{"1_2885"}); err != nil {
+		t.Fatal(err)
+	}
+	if request := ms.next(t, msgCodeTouchline); !strings.Contains(request, "7=2885|") || !strings.HasSuffix(request, "230=2") {
+		t.Errorf("unsubscribe %q, want the canonical token", request)
+	}
+	if err := tw.UnsubscribeLTPTouchline([]string{"01_ 02885"}); err != nil {
+		t.Fatal(err)
+	}
+	if n := tw.SubscribedInstruments(); n != 0 {
+		t.Errorf("%d instruments still subscribed after unsubscribing other spellings: %+v", n, tw.Subscriptions())
+	}
+}
+
+func TestSubscribeTouchlineOldReturnsMalformedTokens(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	var reported []string
+	tw.OnError = func(message string) { reported = append(reported, message) }
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	err := tw.SubscribeTouchlineOld([]string{"1_22", "bad"})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Result.Skipped) != 1 || batchErr.Result.Skipped[0] != "bad" {
+		t.Fatalf("SubscribeTouchlineOld = %v, want a *BatchError skipping bad", err)
+	}
+	if request := ms.next(t, msgCodeTouchline); !strings.Contains(request, "7=22|230=1") {
+		t.Errorf("request %q, want the subscribe of 22", request)
+	}
+	if len(reported) != 0 {
+		t.Errorf("OnError got %q, want the malformed token only returned", reported)
+	}
+}
@@ -0,0 +1,281 @@
+package ODINMarketFeed
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Instrument identifies a tradable contract by market segment and token
+type Instrument struct {
+	MarketSegmentID int
+	Token           int
+	Symbol          string
+}
+
+// String returns the instrument in 'MarketSegmentID_Token' format
+func (in Instrument) String() string {
+	return fmt.Sprintf("%d_%d", in.MarketSegmentID, in.Token)
+}
+
+// ParseInstrument parses an instrument in 'MarketSegmentID_Token' format. Whitespace around
+// the item and its parts and leading zeros are ignored, so " 1_02885 " and "1_2885" parse
+// to the same instrument.
+func ParseInstrument(item string) (Instrument, error) {
+	parts := strings.Split(strings.TrimSpace(item), "_")
+	if len(parts) != 2 {
+		return Instrument{}, fmt.Errorf("invalid token format: '%s'", item)
+	}
+
+	marketSegmentID, err1 := strconv.Atoi(strings.TrimSpace(parts[0]))
+	token, err2 := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err1 != nil || err2 != nil {
+		return Instrument{}, fmt.Errorf("invalid token format: '%s'", item)
+	}
+
+	return Instrument{MarketSegmentID: marketSegmentID, Token: token}, nil
+}
+
+// validate reports an instrument whose market segment or token is not positive. Every
+// subscribe and unsubscribe path checks instruments with it.
+func (i Instrument) validate() error {
+	if i.MarketSegmentID <= 0 || i.Token <= 0 {
+		return fmt.Errorf("invalid instrument: '%s'", i)
+	}
+	return nil
+}
+
+// SymbolResolver resolves human-readable symbols to instruments
+type SymbolResolver interface {
+	Resolve(symbol string) (Instrument, error)
+}
+
+// ResolverWithReverse is an optional SymbolResolver extension that maps
+// instruments back to symbols, used to enrich typed callbacks
+type ResolverWithReverse interface {
+	SymbolResolver
+	Symbol(marketSegmentID int, token int) (string, bool)
+}
+
+// SetSymbolResolver sets the resolver used by the *Symbols subscription methods
+func (tw *ODINMarketFeedClient) SetSymbolResolver(resolver SymbolResolver) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.resolver = resolver
+}
+
+// SubscribeTouchlineSymbols resolves the symbols and subscribes to touchline for them
+func (tw *ODINMarketFeedClient) SubscribeTouchlineSymbols(symbols []string, responseType string, ltpChangeOnly bool) error {
+	return tw.withResolvedSymbols(symbols, func(tokenList []string) error {
+		return tw.SubscribeTouchline(tokenList, responseType, ltpChangeOnly)
+	})
+}
+
+// UnsubscribeTouchlineSymbols resolves the symbols and unsubscribes from touchline for them
+func (tw *ODINMarketFeedClient) UnsubscribeTouchlineSymbols(symbols []string) error {
+	return tw.withResolvedSymbols(symbols, tw.UnsubscribeTouchline)
+}
+
+// SubscribeLTPTouchlineSymbols resolves the symbols and subscribes to LTP touchline for them
+func (tw *ODINMarketFeedClient) SubscribeLTPTouchlineSymbols(symbols []string) error {
+	return tw.withResolvedSymbols(symbols, tw.SubscribeLTPTouchline)
+}
+
+// UnsubscribeLTPTouchlineSymbols resolves the symbols and unsubscribes from LTP touchline for them
+func (tw *ODINMarketFeedClient) UnsubscribeLTPTouchlineSymbols(symbols []string) error {
+	return tw.withResolvedSymbols(symbols, tw.UnsubscribeLTPTouchline)
+}
+
+// SubscribeBestFiveSymbol resolves the symbol and subscribes to Best Five for it
+func (tw *ODINMarketFeedClient) SubscribeBestFiveSymbol(symbol string) error {
+	instrument, err := tw.resolveSymbol(symbol)
+	if err != nil {
+		tw.returnedError(fmt.Sprintf("Unable to resolve symbol '%s': %v", symbol, err))
+		return err
+	}
+	return tw.SubscribeBestFive(strconv.Itoa(instrument.Token), instrument.MarketSegmentID)
+}
+
+// UnsubscribeBestFiveSymbol resolves the symbol and unsubscribes from Best Five for it
+func (tw *ODINMarketFeedClient) UnsubscribeBestFiveSymbol(symbol string) error {
+	instrument, err := tw.resolveSymbol(symbol)
+	if err != nil {
+		tw.returnedError(fmt.Sprintf("Unable to resolve symbol '%s': %v", symbol, err))
+		return err
+	}
+	return tw.UnsubscribeBestFive(strconv.Itoa(instrument.Token), instrument.MarketSegmentID)
+}
+
+func (tw *ODINMarketFeedClient) resolveSymbol(symbol string) (Instrument, error) {
+	tw.mu.Lock()
+	resolver := tw.resolver
+	tw.mu.Unlock()
+
+	if resolver == nil {
+		return Instrument{}, errors.New("no symbol resolver configured")
+	}
+	return resolver.Resolve(strings.TrimSpace(symbol))
+}
+
+// withResolvedSymbols resolves the symbols into 'MarketSegmentID_Token' tokens and passes
+// the resolved ones to subscribe. Unresolvable symbols are reported in the returned error.
+func (tw *ODINMarketFeedClient) withResolvedSymbols(symbols []string, subscribe func(tokenList []string) error) error {
+	if len(symbols) == 0 {
+		tw.returnedError("Symbol list cannot be null or empty.")
+		return fmt.Errorf("symbol list cannot be empty")
+	}
+
+	tokenList := make([]string, 0, len(symbols))
+	unresolved := make([]string, 0)
+
+	for _, symbol := range symbols {
+		if tw.isNullOrWhiteSpace(symbol) {
+			continue
+		}
+
+		instrument, err := tw.resolveSymbol(symbol)
+		if err != nil {
+			tw.returnedError(fmt.Sprintf("Unable to resolve symbol '%s': %v", symbol, err))
+			unresolved = append(unresolved, symbol)
+			continue
+		}
+		tokenList = append(tokenList, instrument.String())
+	}
+
+	var unresolvedErr error
+	if len(unresolved) > 0 {
+		unresolvedErr = fmt.Errorf("unresolved symbols: %s", strings.Join(unresolved, ", "))
+	}
+
+	if len(tokenList) == 0 {
+		if unresolvedErr != nil {
+			return unresolvedErr
+		}
+		return fmt.Errorf("no valid symbols found")
+	}
+
+	if err := subscribe(tokenList); err != nil {
+		return errors.Join(err, unresolvedErr)
+	}
+	return unresolvedErr
+}
+
+// CSVResolver resolves symbols using an ODIN contract-master CSV file.
+//
+// The first row must be a header. The market segment column is read from one of
+// MarketSegmentID, MktSegID or SegmentID, the token column from Token or TokenNo, and
+// the symbol column from Symbol or TradingSymbol (all matched case-insensitively).
+// Symbol lookups are case-insensitive.
+type CSVResolver struct {
+	bySymbol     map[string]Instrument
+	byInstrument map[[2]int]string
+}
+
+var (
+	csvSegmentColumns = []string{"marketsegmentid", "mktsegid", "segmentid"}
+	csvTokenColumns   = []string{"token", "tokenno"}
+	csvSymbolColumns  = []string{"symbol", "tradingsymbol"}
+)
+
+// NewCSVResolver loads a contract-master CSV from the reader
+func NewCSVResolver(r io.Reader) (*CSVResolver, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read contract master header: %w", err)
+	}
+
+	segmentCol := findCSVColumn(header, csvSegmentColumns)
+	tokenCol := findCSVColumn(header, csvTokenColumns)
+	symbolCol := findCSVColumn(header, csvSymbolColumns)
+	if segmentCol < 0 || tokenCol < 0 || symbolCol < 0 {
+		return nil, errors.New("contract master header must contain market segment, token and symbol columns")
+	}
+
+	resolver := &CSVResolver{
+		bySymbol:     make(map[string]Instrument),
+		byInstrument: make(map[[2]int]string),
+	}
+
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("failed to read contract master line %d: %w", line, err)
+		}
+
+		if segmentCol >= len(record) || tokenCol >= len(record) || symbolCol >= len(record) {
+			return nil, fmt.Errorf("contract master line %d has too few columns", line)
+		}
+
+		marketSegmentID, err1 := strconv.Atoi(strings.TrimSpace(record[segmentCol]))
+		token, err2 := strconv.Atoi(strings.TrimSpace(record[tokenCol]))
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("contract master line %d has an invalid market segment or token", line)
+		}
+
+		symbol := strings.TrimSpace(record[symbolCol])
+		if symbol == "" {
+			continue
+		}
+
+		resolver.bySymbol[strings.ToUpper(symbol)] = Instrument{MarketSegmentID: marketSegmentID, Token: token, Symbol: symbol}
+		resolver.byInstrument[[2]int{marketSegmentID, token}] = symbol
+	}
+
+	return resolver, nil
+}
+
+// NewCSVResolverFromFile loads a contract-master CSV from the given path
+func NewCSVResolverFromFile(path string) (*CSVResolver, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return NewCSVResolver(file)
+}
+
+// Resolve returns the instrument for the symbol
+func (r *CSVResolver) Resolve(symbol string) (Instrument, error) {
+	instrument, ok := r.bySymbol[strings.ToUpper(strings.TrimSpace(symbol))]
+	if !ok {
+		return Instrument{}, fmt.Errorf("symbol not found: %s", symbol)
+	}
+	return instrument, nil
+}
+
+// Symbol returns the symbol for the market segment and token
+func (r *CSVResolver) Symbol(marketSegmentID int, token int) (string, bool) {
+	symbol, ok := r.byInstrument[[2]int{marketSegmentID, token}]
+	return symbol, ok
+}
+
+// Len returns the number of symbols loaded
+func (r *CSVResolver) Len() int {
+	return len(r.bySymbol)
+}
+
+func findCSVColumn(header []string, names []string) int {
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		for _, name := range names {
+			if column == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
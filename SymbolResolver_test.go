@@ -0,0 +1,96 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func loadContractMaster(t *testing.T) *CSVResolver {
+	t.Helper()
+	resolver, err := NewCSVResolverFromFile("testdata/contract_master.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resolver
+}
+
+func TestCSVResolverResolve(t *testing.T) {
+	resolver := loadContractMaster(t)
+	if resolver.Len() != 4 {
+		t.Fatalf("Len = %d, want 4", resolver.Len())
+	}
+
+	tests := []struct {
+		symbol string
+		want   Instrument
+	}{
+		{"RELIANCE", Instrument{MarketSegmentID: 1, Token: 2885, Symbol: "RELIANCE"}},
+		{" reliance ", Instrument{MarketSegmentID: 1, Token: 2885, Symbol: "RELIANCE"}},
+		{"NIFTY26MARFUT", Instrument{MarketSegmentID: 2, Token: 35001, Symbol: "NIFTY26MARFUT"}},
+	}
+	for _, test := range tests {
+		got, err := resolver.Resolve(test.symbol)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", test.symbol, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("Resolve(%q) = %+v, want %+v", test.symbol, got, test.want)
+		}
+	}
+}
+
+func TestCSVResolverSymbol(t *testing.T) {
+	resolver := loadContractMaster(t)
+
+	if symbol, ok := resolver.Symbol(1, 11536); !ok || symbol != "TCS" {
+		t.Errorf("Symbol(1, 11536) = %q, %v, want TCS", symbol, ok)
+	}
+	if symbol, ok := resolver.Symbol(2, 22); ok {
+		t.Errorf("Symbol(2, 22) = %q, want no symbol for token 22 of another segment", symbol)
+	}
+}
+
+func TestCSVResolverUnresolvable(t *testing.T) {
+	resolver := loadContractMaster(t)
+	if _, err := resolver.Resolve("INFY"); err == nil {
+		t.Fatal("Resolve(INFY) succeeded, want an error")
+	}
+
+	ms := newMockServer(t, nil)
+	// Unresolved symbols reach OnError only when legacy error callbacks are on
+	tw := newTestClient(WithLegacyErrorCallbacks(true))
+	tw.SetSymbolResolver(resolver)
+	var reported []string
+	tw.OnError = func(message string) { reported = append(reported, message) }
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	err := tw.SubscribeTouchlineSymbols([]string{"ACC", "INFY"}, "0", false)
+	if err == nil || !strings.Contains(err.Error(), "INFY") {
+		t.Errorf("SubscribeTouchlineSymbols error %v, want INFY reported as unresolved", err)
+	}
+	if len(reported) != 1 || !strings.Contains(reported[0], "INFY") {
+		t.Errorf("OnError got %q, want one report of INFY", reported)
+	}
+	if request := ms.next(t, msgCodeTouchline); !strings.Contains(request, "7=22|") {
+		t.Errorf("request %q, want the subscribe of ACC", request)
+	}
+	if !subscribed(tw, SubscriptionTouchline, Instrument{MarketSegmentID: 1, Token: 22}) {
+		t.Error("ACC is not subscribed")
+	}
+}
+
+func TestCSVResolverEnrichesTouchline(t *testing.T) {
+	tw := newTestClient()
+	tw.SetSymbolResolver(loadContractMaster(t))
+	var symbols []string
+	tw.OnTouchline = func(touchline TouchlineData) { symbols = append(symbols, touchline.Symbol) }
+
+	tw.responseReceived(frameOf(touchlineMessage(1, 2885, 289510), touchlineMessage(1, 4963, 10000)), 0)
+	if len(symbols) != 2 || symbols[0] != "RELIANCE" || symbols[1] != "" {
+		t.Errorf("symbols %q, want RELIANCE and none for an unknown token", symbols)
+	}
+}
@@ -0,0 +1,269 @@
+package ODINMarketFeed
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// tokenThrottle coalesces touchline updates per token. The held updates are delivered by a
+// single flusher goroutine of the connection, or by Tick with WithManualReadLoop.
+type tokenThrottle struct {
+	defaultInterval time.Duration
+	intervals       map[uint64]time.Duration
+	states          map[uint64]*throttleState
+	now             func() time.Time // the clock, time.Now when nil
+
+	flushing bool            // a flusher goroutine is running
+	stopped  bool            // set by Dispose
+	wake     chan struct{}   // wakes the flusher when an update is held
+	done     <-chan struct{} // closed when the connection ends
+	mu       sync.Mutex
+}
+
+type throttleState struct {
+	lastDelivered time.Time
+	pending       *TouchlineData
+	due           time.Time // when pending is delivered
+}
+
+// WithDefaultThrottle delivers at most one touchline update per token every minInterval
+// unless SetTokenThrottle overrides it for the token
+func WithDefaultThrottle(minInterval time.Duration) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.throttle.defaultInterval = minInterval
+	}
+}
+
+// SetTokenThrottle delivers at most one touchline update for the token every minInterval
+// to OnTouchline and OnJSON. Updates arriving within the interval are coalesced and the
+// latest one is delivered when it elapses. A minInterval of 0 removes the setting. Held
+// updates are delivered from one goroutine of the connection, or by Tick with
+// WithManualReadLoop, and dropped when the connection ends; OnMessage is not throttled.
+func (tw *ODINMarketFeedClient) SetTokenThrottle(segID, token uint32, minInterval time.Duration) {
+	tw.throttle.mu.Lock()
+	defer tw.throttle.mu.Unlock()
+
+	key := depthKey(segID, token)
+	if minInterval <= 0 {
+		delete(tw.throttle.intervals, key)
+		return
+	}
+	if tw.throttle.intervals == nil {
+		tw.throttle.intervals = make(map[uint64]time.Duration)
+	}
+	tw.throttle.intervals[key] = minInterval
+}
+
+// submit delivers the update now, or holds it until the token's interval has elapsed. It
+// reports whether a held update needs a flusher goroutine to be started, which is only
+// the case with autoFlush when none is running.
+func (tt *tokenThrottle) submit(data TouchlineData, deliver func(TouchlineData), autoFlush bool) (startFlusher bool) {
+	key := depthKey(data.MktSegID, data.Token)
+	now := tt.clock()
+
+	tt.mu.Lock()
+	interval, ok := tt.intervals[key]
+	if !ok {
+		interval = tt.defaultInterval
+	}
+	if interval <= 0 {
+		tt.mu.Unlock()
+		deliver(data)
+		return false
+	}
+
+	if tt.states == nil {
+		tt.states = make(map[uint64]*throttleState)
+	}
+	state := tt.states[key]
+	if state == nil {
+		state = &throttleState{}
+		tt.states[key] = state
+	}
+
+	if state.pending == nil && now.Sub(state.lastDelivered) >= interval {
+		state.lastDelivered = now
+		tt.mu.Unlock()
+		deliver(data)
+		return false
+	}
+
+	defer tt.mu.Unlock()
+	state.pending = &data
+	state.due = state.lastDelivered.Add(interval)
+	if tt.stopped || !autoFlush {
+		return false
+	}
+	if tt.flushing {
+		tt.signal()
+		return false
+	}
+	tt.flushing = true
+	return true
+}
+
+// signal wakes the flusher to recompute its next due time; the caller must hold tt.mu
+func (tt *tokenThrottle) signal() {
+	select {
+	case tt.wakeChan() <- struct{}{}:
+	default:
+	}
+}
+
+// wakeChan returns the channel that wakes the flusher; the caller must hold tt.mu
+func (tt *tokenThrottle) wakeChan() chan struct{} {
+	if tt.wake == nil {
+		tt.wake = make(chan struct{}, 1)
+	}
+	return tt.wake
+}
+
+// takeDue removes and returns the held updates due at now, oldest first, and the due time
+// of the earliest update still held (zero when none is)
+func (tt *tokenThrottle) takeDue(now time.Time) (due []TouchlineData, next time.Time) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	var states []*throttleState
+	for _, state := range tt.states {
+		if state.pending == nil {
+			continue
+		}
+		if now.Before(state.due) {
+			if next.IsZero() || state.due.Before(next) {
+				next = state.due
+			}
+			continue
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].due.Before(states[j].due) })
+
+	for _, state := range states {
+		due = append(due, *state.pending)
+		state.pending = nil
+		state.lastDelivered = now
+	}
+	return due, next
+}
+
+// connected sets the channel closed when the new connection ends, which stops the flusher
+// and drops the held updates, and drops those held from the previous connection
+func (tt *tokenThrottle) connected(done <-chan struct{}) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	tt.done = done
+	tt.dropPending()
+}
+
+// dropPending drops the held updates; the caller must hold tt.mu
+func (tt *tokenThrottle) dropPending() {
+	for _, state := range tt.states {
+		state.pending = nil
+	}
+}
+
+// stop drops the held updates and ends the flusher for good
+func (tt *tokenThrottle) stop() {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	tt.stopped = true
+	tt.dropPending()
+	tt.signal()
+}
+
+// forget drops the state of a token, including an update held for delivery
+func (tt *tokenThrottle) forget(key uint64) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	delete(tt.states, key)
+}
+
+// clear drops the state of every token, including the updates held for delivery
+func (tt *tokenThrottle) clear() {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	tt.states = nil
+}
+
+// clock returns the current time
+func (tt *tokenThrottle) clock() time.Time {
+	if tt.now != nil {
+		return tt.now()
+	}
+	return time.Now()
+}
+
+// throttleTouchline passes a touchline through the token throttle and starts the flusher
+// when an update is held and none is running
+func (tw *ODINMarketFeedClient) throttleTouchline(touchline TouchlineData) {
+	if tw.throttle.submit(touchline, tw.deliverTouchline, !tw.manual.enabled) {
+		tw.routines.spawn(tw.runThrottleFlush)
+	}
+}
+
+// runThrottleFlush delivers the held updates as they fall due. It returns once none is
+// held, when the connection ends or when the client is disposed; the updates still held
+// then are dropped.
+func (tw *ODINMarketFeedClient) runThrottleFlush() {
+	tt := &tw.throttle
+	for {
+		due, next := tt.takeDue(tt.clock())
+		for _, data := range due {
+			tw.deliverTouchline(data)
+		}
+
+		tt.mu.Lock()
+		if tt.stopped || next.IsZero() && !tt.hasPendingLocked() {
+			tt.flushing = false
+			tt.mu.Unlock()
+			return
+		}
+		done, wake := tt.done, tt.wakeChan()
+		tt.mu.Unlock()
+
+		var timer *time.Timer
+		var fired <-chan time.Time
+		if !next.IsZero() {
+			timer = time.NewTimer(next.Sub(tt.clock()))
+			fired = timer.C
+		}
+		select {
+		case <-fired:
+		case <-wake:
+		case <-done:
+			tt.mu.Lock()
+			if tt.done == done {
+				tt.flushing = false
+				tt.dropPending()
+				tt.mu.Unlock()
+				return
+			}
+			// a new connection has started since the wait began
+			tt.mu.Unlock()
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// hasPendingLocked reports whether an update is held; the caller must hold tt.mu
+func (tt *tokenThrottle) hasPendingLocked() bool {
+	for _, state := range tt.states {
+		if state.pending != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// flushThrottle delivers the held updates due at now, for Tick
+func (tw *ODINMarketFeedClient) flushThrottle(now time.Time) {
+	due, _ := tw.throttle.takeDue(now)
+	for _, data := range due {
+		tw.deliverTouchline(data)
+	}
+}
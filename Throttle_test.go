@@ -0,0 +1,150 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable clock for the token throttle
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) advance(d time.Duration) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	return c.now
+}
+
+// newThrottledClient returns a connected WithManualReadLoop client throttled to one update
+// every interval on a fake clock, a function that has the gateway send it a touchline for
+// token 22 and dispatches it, and the LTPs the client delivers to OnTouchline
+func newThrottledClient(t *testing.T, interval time.Duration) (*ODINMarketFeedClient, *fakeClock, func(ltp uint32), *[]uint32) {
+	t.Helper()
+
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithManualReadLoop(true), WithDefaultThrottle(interval))
+	clock := &fakeClock{now: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)}
+	tw.throttle.now = clock.Now
+	delivered := &[]uint32{}
+	tw.OnTouchline = func(touchline TouchlineData) { *delivered = append(*delivered, touchline.LTP) }
+	ms.connect(t, tw)
+	t.Cleanup(func() { tw.Close(context.Background()) })
+	ms.next(t, msgCodeLogin)
+
+	receive := func(ltp uint32) {
+		t.Helper()
+		ms.latest().send(touchlineMessage(1, 22, ltp))
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tw.ReadAndDispatch(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return tw, clock, receive, delivered
+}
+
+func TestThrottleDeliversTheLastValue(t *testing.T) {
+	tw, clock, receive, delivered := newThrottledClient(t, time.Second)
+
+	for _, ltp := range []uint32{24500, 24510, 24520} {
+		receive(ltp)
+		clock.advance(100 * time.Millisecond)
+	}
+	if want := []uint32{24500}; !reflect.DeepEqual(*delivered, want) {
+		t.Fatalf("delivered %v within the interval, want %v", *delivered, want)
+	}
+
+	tw.Tick(clock.advance(500 * time.Millisecond))
+	if want := []uint32{24500}; !reflect.DeepEqual(*delivered, want) {
+		t.Fatalf("delivered %v before the interval elapsed, want %v", *delivered, want)
+	}
+	tw.Tick(clock.advance(200 * time.Millisecond))
+	if want := []uint32{24500, 24520}; !reflect.DeepEqual(*delivered, want) {
+		t.Fatalf("delivered %v after the interval, want %v", *delivered, want)
+	}
+
+	tw.Tick(clock.advance(5 * time.Second))
+	if len(*delivered) != 2 {
+		t.Errorf("delivered %v, want the coalesced update once", *delivered)
+	}
+}
+
+func TestThrottleDeliversAfterQuietPeriodImmediately(t *testing.T) {
+	_, clock, receive, delivered := newThrottledClient(t, time.Second)
+
+	receive(24500)
+	clock.advance(3 * time.Second)
+	receive(24510)
+	if want := []uint32{24500, 24510}; !reflect.DeepEqual(*delivered, want) {
+		t.Errorf("delivered %v, want the update after the quiet period without a Tick", *delivered)
+	}
+}
+
+func TestThrottleDropsHeldUpdatesOnDispose(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithDefaultThrottle(time.Hour))
+	var mu sync.Mutex
+	var delivered []uint32
+	tw.OnTouchline = func(touchline TouchlineData) {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered = append(delivered, touchline.LTP)
+	}
+	ms.connect(t, tw)
+	ms.next(t, msgCodeLogin)
+
+	ms.latest().send(touchlineMessage(1, 22, 24500), touchlineMessage(1, 22, 24510))
+	eventually(t, 5*time.Second, func() bool { return tw.Stats().BinaryFrames == 1 })
+	tw.Close(context.Background())
+
+	if n := tw.routines.count(); n != 0 {
+		t.Errorf("%d goroutines left after Close, want 0", n)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if want := []uint32{24500}; !reflect.DeepEqual(delivered, want) {
+		t.Errorf("delivered %v, want the held update dropped", delivered)
+	}
+}
+
+func TestThrottleFlusherDeliversHeldUpdate(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithDefaultThrottle(20 * time.Millisecond))
+	ltps := make(chan uint32, 4)
+	tw.OnTouchline = func(touchline TouchlineData) { ltps <- touchline.LTP }
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	ms.latest().send(touchlineMessage(1, 22, 24500), touchlineMessage(1, 22, 24510), touchlineMessage(1, 22, 24520))
+	for _, want := range []uint32{24500, 24520} {
+		select {
+		case got := <-ltps:
+			if got != want {
+				t.Fatalf("delivered %d, want %d", got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%d not delivered", want)
+		}
+	}
+	flushing := func() bool {
+		tw.throttle.mu.Lock()
+		defer tw.throttle.mu.Unlock()
+		return tw.throttle.flushing
+	}
+	if !eventually(t, 5*time.Second, func() bool { return !flushing() }) {
+		t.Error("the flusher still runs with no update held")
+	}
+}
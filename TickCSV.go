@@ -0,0 +1,263 @@
+package ODINMarketFeed
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultCSVFlushInterval is the flush period of a TickCSVWriter by default
+const defaultCSVFlushInterval = time.Second
+
+// tickCSVHeader is the header row written by TickCSVWriter
+var tickCSVHeader = []string{"timestamp", "segID", "token", "ltp", "ltt", "bid", "ask", "bidQty", "askQty", "volume", "oi"}
+
+// TickCSVOptions configures a TickCSVWriter
+type TickCSVOptions struct {
+	// Instruments limits the rows to these instruments; nil writes every instrument
+	Instruments []Instrument
+	// From and To limit the rows to touchlines with a LUT at or after From and before To;
+	// a zero time leaves that end open
+	From, To time.Time
+	// FlushInterval is the period at which the rows are flushed to the writer (default 1s):
+	// a row written once the interval has passed since the previous flush flushes the rows.
+	// A negative interval flushes after every row.
+	FlushInterval time.Duration
+}
+
+// TickCSVWriter writes touchlines as CSV rows, for quick captures of what was received.
+// The header is timestamp, segID, token, ltp, ltt, bid, ask, bidQty, askQty, volume and
+// oi: timestamp is the LUT and ltt the LTT, both RFC3339 in ExchangeLocation or empty when
+// unset, and prices are scaled by the DecimalLocator of the touchline. The touchline
+// carries no volume or open interest, so those columns are left empty.
+//
+// Attach feeds the writer from a client alongside its other consumers; WriteTouchline can
+// instead be called from OnTouchline or a Ticks loop. The writer starts no goroutine: rows
+// are flushed as they are written, per FlushInterval, and by Flush and Close, so rows
+// written just before the feed goes quiet stay buffered until one of those.
+type TickCSVWriter struct {
+	filter   map[uint64]struct{}
+	from, to time.Time
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	csv       *csv.Writer
+	rows      int
+	err       error
+	client    *ODINMarketFeedClient
+	closed    bool
+	lastFlush time.Time
+}
+
+// NewTickCSVWriter creates a TickCSVWriter writing to w, starting with the header row. The
+// writer does not close w.
+func NewTickCSVWriter(w io.Writer, opts TickCSVOptions) *TickCSVWriter {
+	cw := &TickCSVWriter{
+		from:     opts.From,
+		to:       opts.To,
+		interval: opts.FlushInterval,
+		now:      time.Now,
+		csv:      csv.NewWriter(w),
+	}
+	if cw.interval == 0 {
+		cw.interval = defaultCSVFlushInterval
+	}
+	cw.lastFlush = cw.now()
+	if opts.Instruments != nil {
+		cw.filter = make(map[uint64]struct{}, len(opts.Instruments))
+		for _, instrument := range opts.Instruments {
+			cw.filter[instrumentKey(instrument)] = struct{}{}
+		}
+	}
+	cw.err = cw.csv.Write(tickCSVHeader)
+	return cw
+}
+
+// Attach writes the touchlines delivered by client, after throttling and deduplication,
+// until Detach or Close. A writer attached to another client is detached from it first.
+// Other consumers of the client are not affected.
+func (cw *TickCSVWriter) Attach(client *ODINMarketFeedClient) error {
+	cw.mu.Lock()
+	if cw.closed {
+		cw.mu.Unlock()
+		return errors.New("CSV writer is closed")
+	}
+	previous := cw.client
+	cw.client = client
+	cw.mu.Unlock()
+
+	if previous != nil && previous != client {
+		previous.removeCSVWriter(cw)
+	}
+	if err := client.addCSVWriter(cw); err != nil {
+		cw.mu.Lock()
+		cw.client = nil
+		cw.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Detach stops writing the touchlines of the attached client
+func (cw *TickCSVWriter) Detach() {
+	cw.mu.Lock()
+	client := cw.client
+	cw.client = nil
+	cw.mu.Unlock()
+
+	if client != nil {
+		client.removeCSVWriter(cw)
+	}
+}
+
+// WriteTouchline writes a row for the touchline unless the instruments or the time window
+// exclude it. It returns the first write error of the writer.
+func (cw *TickCSVWriter) WriteTouchline(td TouchlineData) error {
+	if cw.filter != nil {
+		if _, ok := cw.filter[depthKey(td.MktSegID, td.Token)]; !ok {
+			return nil
+		}
+	}
+	if (!cw.from.IsZero() && td.LUT.Before(cw.from)) || (!cw.to.IsZero() && !td.LUT.Before(cw.to)) {
+		return nil
+	}
+
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+
+	if cw.closed || cw.err != nil {
+		return cw.err
+	}
+	cw.err = cw.csv.Write(tickCSVRow(td))
+	if cw.err == nil {
+		cw.rows++
+		if cw.interval < 0 || cw.now().Sub(cw.lastFlush) >= cw.interval {
+			cw.flushLocked()
+		}
+	}
+	return cw.err
+}
+
+// Rows returns the number of rows written, not counting the header
+func (cw *TickCSVWriter) Rows() int {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	return cw.rows
+}
+
+// Flush writes the buffered rows and returns the first write error of the writer
+func (cw *TickCSVWriter) Flush() error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	return cw.flushLocked()
+}
+
+// Close detaches the writer and flushes the remaining rows
+func (cw *TickCSVWriter) Close() error {
+	cw.Detach()
+
+	cw.mu.Lock()
+	cw.closed = true
+	cw.mu.Unlock()
+
+	return cw.Flush()
+}
+
+// flushLocked flushes the csv writer and keeps its first error
+func (cw *TickCSVWriter) flushLocked() error {
+	if cw.err != nil {
+		return cw.err
+	}
+	cw.csv.Flush()
+	cw.err = cw.csv.Error()
+	cw.lastFlush = cw.now()
+	return cw.err
+}
+
+// tickCSVRow formats a touchline as a row of tickCSVHeader
+func tickCSVRow(td TouchlineData) []string {
+	price := func(raw uint32) string {
+		value, decimals := (*PriceScaler)(nil).Fixed(td.MktSegID, td.DecimalLocator, raw)
+		return strconv.FormatFloat(float64(value)/math.Pow10(decimals), 'f', decimals, 64)
+	}
+	timestamp := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return formatJSONTime(t)
+	}
+	return []string{
+		timestamp(td.LUT),
+		strconv.FormatUint(uint64(td.MktSegID), 10),
+		strconv.FormatUint(uint64(td.Token), 10),
+		price(td.LTP),
+		timestamp(td.LTT),
+		price(td.BuyPrice),
+		price(td.SellPrice),
+		strconv.FormatUint(uint64(td.BuyQty), 10),
+		strconv.FormatUint(uint64(td.SellQty), 10),
+		"",
+		"",
+	}
+}
+
+// addCSVWriter attaches a TickCSVWriter. The list is replaced rather than modified, so that
+// deliverTouchline reads it without locking.
+func (tw *ODINMarketFeedClient) addCSVWriter(cw *TickCSVWriter) error {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if tw.isDisposed {
+		return ErrClientDisposed
+	}
+	var writers []*TickCSVWriter
+	if current := tw.csvWriters.Load(); current != nil {
+		for _, attached := range *current {
+			if attached == cw {
+				return nil
+			}
+		}
+		writers = append(writers, *current...)
+	}
+	writers = append(writers, cw)
+	tw.csvWriters.Store(&writers)
+	return nil
+}
+
+// removeCSVWriter detaches a TickCSVWriter
+func (tw *ODINMarketFeedClient) removeCSVWriter(cw *TickCSVWriter) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	current := tw.csvWriters.Load()
+	if current == nil {
+		return
+	}
+	var writers []*TickCSVWriter
+	for _, attached := range *current {
+		if attached != cw {
+			writers = append(writers, attached)
+		}
+	}
+	if len(writers) == 0 {
+		tw.csvWriters.Store(nil)
+		return
+	}
+	tw.csvWriters.Store(&writers)
+}
+
+// writeCSV passes a delivered touchline to the attached TickCSVWriters
+func (tw *ODINMarketFeedClient) writeCSV(touchline TouchlineData) {
+	writers := tw.csvWriters.Load()
+	if writers == nil {
+		return
+	}
+	for _, cw := range *writers {
+		cw.WriteTouchline(touchline)
+	}
+}
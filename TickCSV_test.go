@@ -0,0 +1,80 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// csvTouchline returns a touchline response with the fields written by TickCSVWriter
+func csvTouchline(segment, token uint32, lut, ltt int32, ltp, bidQty, bid, askQty, ask uint32) []byte {
+	block := make([]byte, touchlineBlockSize)
+	for offset, value := range map[int]uint32{
+		0: segment, 4: token, 8: uint32(lut), 12: uint32(ltt), 16: ltp,
+		20: bidQty, 24: bid, 28: askQty, 32: ask, 52: 100,
+	} {
+		binary.LittleEndian.PutUint32(block[offset:], value)
+	}
+	return append([]byte("63=FT3.0|64=206|50="), block...)
+}
+
+// csvFixture is a capture of touchlines of two instruments over a few seconds
+func csvFixture() [][]byte {
+	return [][]byte{
+		csvTouchline(1, 22, 1000, 999, 245000, 10, 244950, 25, 245050),
+		csvTouchline(1, 2885, 1001, 1001, 289510, 3, 289500, 7, 289525),
+		csvTouchline(1, 22, 1002, 1002, 245025, 12, 245000, 20, 245050),
+		csvTouchline(1, 2885, 1003, 1003, 289475, 0, 0, 9, 289500),
+		csvTouchline(1, 22, 1004, 1004, 245100, 15, 245075, 5, 245125),
+	}
+}
+
+func TestTickCSVGolden(t *testing.T) {
+	fixture := csvFixture()
+	lut := func(index int) time.Time {
+		return goldenEpoch.Add(time.Duration(1000+index) * time.Second)
+	}
+
+	tests := []struct {
+		golden string
+		opts   TickCSVOptions
+	}{
+		{"ticks.csv", TickCSVOptions{}},
+		{"ticks_token22.csv", TickCSVOptions{Instruments: []Instrument{{MarketSegmentID: 1, Token: 22}}}},
+		{"ticks_window.csv", TickCSVOptions{From: lut(1), To: lut(4)}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		cw := NewTickCSVWriter(&buf, tt.opts)
+		tw := newTestClient()
+		tw.decoder.Epoch = goldenEpoch
+		if err := cw.Attach(tw); err != nil {
+			t.Fatal(err)
+		}
+		for _, msg := range fixture {
+			tw.responseReceived(frameOf(msg), 0)
+		}
+		if err := cw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		checkGolden(t, tt.golden, buf.Bytes())
+	}
+}
+
+func TestTickCSVFlushInterval(t *testing.T) {
+	var buf bytes.Buffer
+	cw := NewTickCSVWriter(&buf, TickCSVOptions{FlushInterval: time.Minute})
+	now := time.Now()
+	cw.now = func() time.Time { return now }
+
+	cw.WriteTouchline(TouchlineData{MktSegID: 1, Token: 22})
+	if buf.Len() != 0 {
+		t.Fatalf("rows flushed before the interval passed: %q", buf.String())
+	}
+	now = now.Add(time.Minute)
+	cw.WriteTouchline(TouchlineData{MktSegID: 1, Token: 23})
+	if cw.Rows() != 2 || bytes.Count(buf.Bytes(), []byte("\n")) != 3 {
+		t.Fatalf("after the interval got %q, want the header and two rows", buf.String())
+	}
+}
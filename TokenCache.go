@@ -0,0 +1,135 @@
+package ODINMarketFeed
+
+import (
+	"container/list"
+	"sync/atomic"
+	"time"
+)
+
+// lruKeys orders token keys by their last update, for WithCacheCapacity
+type lruKeys struct {
+	order *list.List
+	elems map[uint64]*list.Element
+}
+
+func newLRUKeys() *lruKeys {
+	return &lruKeys{order: list.New(), elems: make(map[uint64]*list.Element)}
+}
+
+// touch marks key as the most recently updated. When more than capacity keys are tracked
+// the least recently updated one is removed and returned.
+func (l *lruKeys) touch(key uint64, capacity int) (evicted uint64, ok bool) {
+	if elem, found := l.elems[key]; found {
+		l.order.MoveToFront(elem)
+	} else {
+		l.elems[key] = l.order.PushFront(key)
+	}
+
+	if capacity <= 0 || l.order.Len() <= capacity {
+		return 0, false
+	}
+	oldest := l.order.Back()
+	l.order.Remove(oldest)
+	evicted = oldest.Value.(uint64)
+	delete(l.elems, evicted)
+	return evicted, true
+}
+
+func (l *lruKeys) remove(key uint64) {
+	if elem, ok := l.elems[key]; ok {
+		l.order.Remove(elem)
+		delete(l.elems, key)
+	}
+}
+
+func (l *lruKeys) clear() {
+	l.order.Init()
+	l.elems = make(map[uint64]*list.Element)
+}
+
+// WithCacheCapacity limits the depth cache and the token stats to n instruments each. When
+// an update for a new instrument arrives at the limit, the least recently updated
+// instrument is evicted and counted in Stats().CacheEvictions.
+func WithCacheCapacity(n int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.cacheCapacity = n
+	}
+}
+
+// WithCachePurgeDelay keeps the cached depth, token stats and gap detection state of an
+// unsubscribed instrument for d before removing it, so that a quick resubscribe keeps the
+// data. By default the state is removed as soon as the unsubscribe request has been written.
+func WithCachePurgeDelay(d time.Duration) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.cachePurgeDelay = d
+	}
+}
+
+// PurgeToken removes the cached depth, token stats, gap detection, dedup and throttle state of
+// an instrument regardless of its subscriptions
+func (tw *ODINMarketFeedClient) PurgeToken(segID, token uint32) {
+	key := depthKey(segID, token)
+	if tw.depthCache != nil {
+		tw.depthCache.remove(segID, token)
+	}
+	tw.resetTokenStats(int(segID), int(token))
+	if tw.gapDetector != nil {
+		tw.gapDetector.forget(key)
+	}
+	if tw.dedup != nil {
+		tw.dedup.forget(key)
+	}
+	tw.throttle.forget(key)
+}
+
+// PurgeAll removes the cached state of every instrument
+func (tw *ODINMarketFeedClient) PurgeAll() {
+	if tw.depthCache != nil {
+		tw.depthCache.clear()
+	}
+	if tw.tokenStats != nil {
+		tw.tokenStats.clear()
+	}
+	if tw.gapDetector != nil {
+		tw.gapDetector.clear()
+	}
+	if tw.dedup != nil {
+		tw.dedup.clear()
+	}
+	tw.throttle.clear()
+}
+
+// releaseToken removes the cached state that no remaining subscription of the instrument
+// uses, after the WithCachePurgeDelay grace period
+func (tw *ODINMarketFeedClient) releaseToken(segID, token int) {
+	if tw.cachePurgeDelay <= 0 {
+		tw.purgeUnsubscribed(segID, token)
+		return
+	}
+	time.AfterFunc(tw.cachePurgeDelay, func() {
+		tw.purgeUnsubscribed(segID, token)
+	})
+}
+
+func (tw *ODINMarketFeedClient) purgeUnsubscribed(segID, token int) {
+	key := depthKey(uint32(segID), uint32(token))
+	if tw.depthCache != nil && !tw.isSubscribed(segID, token, SubscriptionBestFive) {
+		tw.depthCache.remove(uint32(segID), uint32(token))
+	}
+	if tw.isSubscribed(segID, token, SubscriptionTouchline, SubscriptionLTPTouchline) {
+		return
+	}
+	tw.resetTokenStats(segID, token)
+	if tw.gapDetector != nil {
+		tw.gapDetector.forget(key)
+	}
+	if tw.dedup != nil {
+		tw.dedup.forget(key)
+	}
+	tw.throttle.forget(key)
+}
+
+// cacheEvicted counts an instrument evicted by WithCacheCapacity
+func (tw *ODINMarketFeedClient) cacheEvicted() {
+	atomic.AddUint64(&tw.stats.cacheEvictions, 1)
+}
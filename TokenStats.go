@@ -0,0 +1,149 @@
+package ODINMarketFeed
+
+import (
+	"sync"
+	"time"
+)
+
+// TokenStat holds the update counters of one instrument
+type TokenStat struct {
+	Count      uint64
+	LastUpdate time.Time
+	LastLTP    uint32
+}
+
+// tokenTracker keeps a TokenStat per subscribed instrument
+type tokenTracker struct {
+	stats    map[uint64]*TokenStat
+	previous map[uint64]TokenStat // the counters at the last WithDayRollover boundary
+	lru      *lruKeys
+	mu       sync.Mutex
+}
+
+// WithTokenStats tracks the number of touchline and LTP updates per subscribed instrument,
+// queryable through TokenStats, SnapshotTokenStats and StaleTokens
+func WithTokenStats() Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.tokenStats = &tokenTracker{stats: make(map[uint64]*TokenStat), lru: newLRUKeys()}
+	}
+}
+
+// TokenStats returns the counters of an instrument
+func (tw *ODINMarketFeedClient) TokenStats(segID, token uint32) (TokenStat, bool) {
+	if tw.tokenStats == nil {
+		return TokenStat{}, false
+	}
+
+	tw.tokenStats.mu.Lock()
+	defer tw.tokenStats.mu.Unlock()
+
+	stat, ok := tw.tokenStats.stats[depthKey(segID, token)]
+	if !ok {
+		return TokenStat{}, false
+	}
+	return *stat, true
+}
+
+// SnapshotTokenStats returns a copy of the counters of every instrument
+func (tw *ODINMarketFeedClient) SnapshotTokenStats() map[Instrument]TokenStat {
+	result := make(map[Instrument]TokenStat)
+	if tw.tokenStats == nil {
+		return result
+	}
+
+	tw.tokenStats.mu.Lock()
+	defer tw.tokenStats.mu.Unlock()
+
+	for key, stat := range tw.tokenStats.stats {
+		result[Instrument{MarketSegmentID: int(key >> 32), Token: int(uint32(key))}] = *stat
+	}
+	return result
+}
+
+// StaleTokens returns the touchline and LTP touchline subscriptions that have not received
+// an update within olderThan, including those that never received one
+func (tw *ODINMarketFeedClient) StaleTokens(olderThan time.Duration) []Instrument {
+	if tw.tokenStats == nil {
+		return nil
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	seen := make(map[uint64]bool)
+	var stale []Instrument
+
+	for _, sub := range tw.Subscriptions() {
+		if sub.Type != SubscriptionTouchline && sub.Type != SubscriptionLTPTouchline {
+			continue
+		}
+		key := depthKey(uint32(sub.Instrument.MarketSegmentID), uint32(sub.Instrument.Token))
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		stat, ok := tw.TokenStats(uint32(sub.Instrument.MarketSegmentID), uint32(sub.Instrument.Token))
+		if !ok || stat.LastUpdate.Before(cutoff) {
+			stale = append(stale, sub.Instrument)
+		}
+	}
+	return stale
+}
+
+// recordTokenUpdate counts an update. Instruments without a tracked subscription are
+// ignored, which bounds the tracker by the subscription count.
+func (tw *ODINMarketFeedClient) recordTokenUpdate(segID, token, ltp uint32) {
+	if tw.tokenStats == nil {
+		return
+	}
+	key := depthKey(segID, token)
+	now := time.Now()
+
+	tw.tokenStats.mu.Lock()
+	_, tracked := tw.tokenStats.stats[key]
+	tw.tokenStats.mu.Unlock()
+
+	if !tracked && !tw.isSubscribed(int(segID), int(token), SubscriptionTouchline, SubscriptionLTPTouchline) {
+		return
+	}
+
+	tw.tokenStats.mu.Lock()
+	stat, ok := tw.tokenStats.stats[key]
+	if !ok {
+		stat = &TokenStat{}
+		tw.tokenStats.stats[key] = stat
+	}
+	stat.Count++
+	stat.LastUpdate = now
+	stat.LastLTP = ltp
+
+	oldest, evicted := tw.tokenStats.lru.touch(key, tw.cacheCapacity)
+	if evicted {
+		delete(tw.tokenStats.stats, oldest)
+	}
+	tw.tokenStats.mu.Unlock()
+
+	if evicted {
+		tw.cacheEvicted()
+	}
+}
+
+// resetTokenStats drops the counters of an instrument
+func (tw *ODINMarketFeedClient) resetTokenStats(segID, token int) {
+	if tw.tokenStats == nil {
+		return
+	}
+
+	tw.tokenStats.mu.Lock()
+	defer tw.tokenStats.mu.Unlock()
+
+	key := depthKey(uint32(segID), uint32(token))
+	delete(tw.tokenStats.stats, key)
+	tw.tokenStats.lru.remove(key)
+}
+
+func (tt *tokenTracker) clear() {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+	tt.stats = make(map[uint64]*TokenStat)
+	tt.lru.clear()
+}
@@ -0,0 +1,47 @@
+package ODINMarketFeed
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+// extendedTouchlineBlockSize is the length of the extended touchline block: the 64 byte block
+// followed by the total traded value (int64, price units) and the average traded price
+// (uint32)
+const extendedTouchlineBlockSize = touchlineBlockSize + 12
+
+// WithExtendedTouchline decodes the 12 bytes that follow the 64 byte touchline block as the
+// total traded value and the average traded price, for gateways known to send that extended
+// form. The published protocol does not describe it, so it is never detected from the
+// payload; blocks shorter than 76 bytes are still decoded in the short form. tradedValueTag
+// and atpTag append the fields to the message text under these tags; 0 leaves them out.
+func WithExtendedTouchline(tradedValueTag, atpTag int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.decoder.ExtendedTouchline = true
+		tw.decoder.TradedValueTag = tradedValueTag
+		tw.decoder.ATPTag = atpTag
+	}
+}
+
+// decodeTouchlineExtension decodes the fields that follow the 64 byte touchline block
+func (d *Decoder) decodeTouchlineExtension(touchline *TouchlineData, data []byte) {
+	touchline.Extended = true
+	touchline.TotalTradedValue = int64(binary.LittleEndian.Uint64(data[0:8]))
+	touchline.ATP = binary.LittleEndian.Uint32(data[8:12])
+}
+
+// extensionTags formats the extended touchline fields under the configured tags
+func (d *Decoder) extensionTags(td TouchlineData) string {
+	if !td.Extended {
+		return ""
+	}
+
+	var tags string
+	if d.TradedValueTag != 0 {
+		tags += strconv.Itoa(d.TradedValueTag) + "=" + strconv.FormatInt(td.TotalTradedValue, 10) + "|"
+	}
+	if d.ATPTag != 0 {
+		tags += strconv.Itoa(d.ATPTag) + "=" + strconv.FormatUint(uint64(td.ATP), 10) + "|"
+	}
+	return tags
+}
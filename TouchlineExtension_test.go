@@ -0,0 +1,52 @@
+package ODINMarketFeed
+
+import (
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+// extendedFixture returns a touchline response with the 12 byte extension after the block
+func extendedFixture(tradedValue int64, atp uint32) []byte {
+	raw := touchlineFixture(SegmentNSECM, 22, 1460000000, 1460000000, 245650, 100)
+	extension := make([]byte, 12)
+	binary.LittleEndian.PutUint64(extension[0:], uint64(tradedValue))
+	binary.LittleEndian.PutUint32(extension[8:], atp)
+	return append(raw, extension...)
+}
+
+func TestExtendedTouchlineIsOptIn(t *testing.T) {
+	tw := newTestClient()
+	msg, err := tw.decoder.Decode(extendedFixture(98765432100, 245120))
+	if err != nil || msg.Touchline == nil {
+		t.Fatalf("Decode = %+v, %v", msg, err)
+	}
+	if msg.Touchline.Extended {
+		t.Errorf("extension decoded without WithExtendedTouchline")
+	}
+}
+
+func TestExtendedTouchline(t *testing.T) {
+	tw := newTestClient(WithExtendedTouchline(9101, 9102))
+
+	msg, err := tw.decoder.Decode(extendedFixture(98765432100, 245120))
+	if err != nil || msg.Touchline == nil {
+		t.Fatalf("Decode = %+v, %v", msg, err)
+	}
+	tl := msg.Touchline
+	if !tl.Extended || tl.TotalTradedValue != 98765432100 || tl.ATP != 245120 {
+		t.Errorf("decoded %+v, want traded value 98765432100 and ATP 245120", tl)
+	}
+	if !strings.HasSuffix(msg.Text, "|9101=98765432100|9102=245120|") {
+		t.Errorf("Text = %q, want the extension under tags 9101 and 9102", msg.Text)
+	}
+
+	// The short form is still decoded when the option is set
+	msg, err = tw.decoder.Decode(touchlineFixture(SegmentNSECM, 22, 1460000000, 1460000000, 245650, 100))
+	if err != nil || msg.Touchline == nil || msg.Touchline.Extended {
+		t.Fatalf("short form decoded as %+v, %v", msg.Touchline, err)
+	}
+	if strings.Contains(msg.Text, "9101=") {
+		t.Errorf("short form Text = %q carries extension tags", msg.Text)
+	}
+}
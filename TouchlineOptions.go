@@ -0,0 +1,79 @@
+package ODINMarketFeed
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// TouchlineFormat selects the encoding of touchline responses
+type TouchlineFormat int
+
+const (
+	// TouchlineNormal requests the normal touchline response
+	TouchlineNormal TouchlineFormat = iota
+	// TouchlineNative requests touchline responses with the fixed length native (binary) block
+	TouchlineNative
+)
+
+// String returns the name of the format
+func (tf TouchlineFormat) String() string {
+	switch tf {
+	case TouchlineNormal:
+		return "Normal"
+	case TouchlineNative:
+		return "Native"
+	default:
+		return fmt.Sprintf("TouchlineFormat(%d)", int(tf))
+	}
+}
+
+// TouchlineOptions holds the parameters of a touchline subscription
+type TouchlineOptions struct {
+	Format TouchlineFormat
+	// LTPChangeOnly sends a response only when the last traded price changes
+	LTPChangeOnly bool
+}
+
+// Validate reports whether the gateway accepts the options
+func (opts TouchlineOptions) Validate() error {
+	if opts.Format != TouchlineNormal && opts.Format != TouchlineNative {
+		return fmt.Errorf("invalid touchline format: %s", opts.Format)
+	}
+	return nil
+}
+
+// responseType returns the legacy "0"/"1" response type of the format
+func (opts TouchlineOptions) responseType() string {
+	return strconv.Itoa(int(opts.Format))
+}
+
+// requestFields returns the option tags of a touchline request, including the trailing
+// delimiter
+func (opts TouchlineOptions) requestFields() string {
+	fields := ""
+	if opts.Format == TouchlineNative {
+		fields = "49=1|"
+	}
+	if opts.LTPChangeOnly {
+		return fields + "200=1|"
+	}
+	return fields + "200=0|"
+}
+
+// touchlineOptions converts the legacy response type and LTP change flag
+func touchlineOptions(responseType string, ltpChangeOnly bool) (TouchlineOptions, bool) {
+	switch responseType {
+	case "0":
+		return TouchlineOptions{Format: TouchlineNormal, LTPChangeOnly: ltpChangeOnly}, true
+	case "1":
+		return TouchlineOptions{Format: TouchlineNative, LTPChangeOnly: ltpChangeOnly}, true
+	default:
+		return TouchlineOptions{}, false
+	}
+}
+
+// TouchlineOptions returns the options of a touchline subscription
+func (sub Subscription) TouchlineOptions() TouchlineOptions {
+	opts, _ := touchlineOptions(sub.ResponseType, sub.LTPChangeOnly)
+	return opts
+}
@@ -0,0 +1,62 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTouchlineOptionsGolden(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	tw.requestClock.now = func() time.Time { return time.Date(2026, 3, 2, 9, 15, 0, 0, ExchangeLocation) }
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	var golden strings.Builder
+	sent := map[string]bool{}
+	token := 22
+	for _, format := range []TouchlineFormat{TouchlineNormal, TouchlineNative} {
+		for _, ltpChangeOnly := range []bool{false, true} {
+			opts := TouchlineOptions{Format: format, LTPChangeOnly: ltpChangeOnly}
+			instrument := fmt.Sprintf("1_%d", token)
+			if err := tw.SubscribeTouchlineWithOptions([]string{instrument}, opts); err != nil {
+				t.Fatal(err)
+			}
+			request := ms.next(t, msgCodeTouchline)
+			fmt.Fprintf(&golden, "%-6s LTPChangeOnly=%-5v %s\n", format, ltpChangeOnly, request)
+
+			// The deprecated signature sends the same request
+			if err := tw.SubscribeTouchline([]string{instrument}, opts.responseType(), ltpChangeOnly); err != nil {
+				t.Fatal(err)
+			}
+			if legacy := ms.next(t, msgCodeTouchline); legacy != request {
+				t.Errorf("SubscribeTouchline sent %q, want %q", legacy, request)
+			}
+
+			sent[request] = true
+			token++
+		}
+	}
+	checkGolden(t, "touchline_options.golden", []byte(golden.String()))
+
+	// Resubscribing replays each subscription with its recorded options
+	if err := tw.ResubscribeAll(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < len(sent); i++ {
+		if replayed := ms.next(t, msgCodeTouchline); !sent[replayed] {
+			t.Errorf("ResubscribeAll sent %q, want one of the original requests", replayed)
+		}
+	}
+
+	if err := tw.SubscribeTouchlineWithOptions([]string{"1_30"}, TouchlineOptions{Format: 2}); err == nil {
+		t.Error("an unknown touchline format was accepted")
+	}
+	if err := tw.SubscribeTouchline([]string{"1_30"}, "2", false); err == nil {
+		t.Error("the unknown response type 2 was accepted")
+	}
+}
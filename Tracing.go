@@ -0,0 +1,61 @@
+package ODINMarketFeed
+
+import (
+	"context"
+)
+
+// Tracer starts spans around client operations. It mirrors the shape of an OpenTelemetry
+// tracer so an adapter can be written without the core package depending on OpenTelemetry.
+type Tracer interface {
+	Start(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, Span)
+}
+
+// Span is a single traced operation started by a Tracer
+type Span interface {
+	AddEvent(name string, attributes map[string]interface{})
+	RecordError(err error)
+	End()
+}
+
+// Span names used by the client
+const (
+	SpanConnect   = "odin.connect"
+	SpanSubscribe = "odin.subscribe"
+)
+
+// WithTracer enables tracing of the connect, login and subscribe flows. A connect span that
+// follows a lost connection carries a reconnect event with the cause of the loss. The otel
+// module adapts an OpenTelemetry TracerProvider.
+func WithTracer(tracer Tracer) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.tracer = tracer
+	}
+}
+
+// startSpan starts a span when a tracer is configured, otherwise returns nil
+func (tw *ODINMarketFeedClient) startSpan(name string, attributes map[string]interface{}) Span {
+	if tw.tracer == nil {
+		return nil
+	}
+	if tw.name != "" {
+		attributes["odin.client"] = tw.name
+	}
+	_, span := tw.tracer.Start(context.Background(), name, attributes)
+	return span
+}
+
+func spanEvent(span Span, name string, attributes map[string]interface{}) {
+	if span != nil {
+		span.AddEvent(name, attributes)
+	}
+}
+
+func spanEnd(span Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
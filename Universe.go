@@ -0,0 +1,149 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// UniverseUpdate summarises the requests sent by SetTouchlineUniverse
+type UniverseUpdate struct {
+	Added   []Instrument // newly subscribed
+	Removed []Instrument // unsubscribed
+	Updated []Instrument // re-subscribed with a different response type or LTP-change flag
+	Failed  []Instrument // instruments whose request could not be sent
+}
+
+// WithMaxTokensPerRequest splits touchline universe updates, watchlists, resubscriptions and
+// UnsubscribeAll into requests of at most n tokens (0 means no limit)
+func WithMaxTokensPerRequest(n int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.maxTokensPerRequest = n
+	}
+}
+
+// SetTouchlineUniverse makes the touchline subscriptions of the universe match tokens. Only
+// instruments that were removed are unsubscribed and only new ones are subscribed, so the
+// stream of unchanged instruments is not interrupted. The universe holds the instruments
+// that SetTouchlineUniverse subscribed: instruments subscribed otherwise are left as they
+// are, and an instrument leaving the universe stays subscribed while a group holds it.
+func (tw *ODINMarketFeedClient) SetTouchlineUniverse(tokens []Instrument, responseType string, ltpChangeOnly bool) (UniverseUpdate, error) {
+	var update UniverseUpdate
+
+	opts, ok := touchlineOptions(responseType, ltpChangeOnly)
+	if !ok {
+		return update, fmt.Errorf("invalid response type %q", responseType)
+	}
+
+	tw.subMu.Lock()
+	wanted := make(map[subscriptionKey]bool, len(tokens))
+	for _, instrument := range tokens {
+		key := subscriptionKey{subType: SubscriptionTouchline, marketSegmentID: instrument.MarketSegmentID, token: instrument.Token}
+		if wanted[key] {
+			continue
+		}
+		wanted[key] = true
+
+		sub, subscribed := tw.subscriptions[key]
+		_, inUniverse := tw.universe[key]
+		switch {
+		case !subscribed:
+			update.Added = append(update.Added, instrument)
+		case inUniverse && (sub.ResponseType != responseType || sub.LTPChangeOnly != ltpChangeOnly):
+			update.Updated = append(update.Updated, instrument)
+		}
+	}
+	for key := range tw.universe {
+		if wanted[key] {
+			continue
+		}
+		if tw.inGroupLocked(key, nil) {
+			delete(tw.universe, key)
+			continue
+		}
+		update.Removed = append(update.Removed, Instrument{MarketSegmentID: key.marketSegmentID, Token: key.token})
+	}
+	tw.subMu.Unlock()
+	sortInstruments(update.Removed)
+
+	var errs []error
+	send := func(instruments []Instrument, subscribe bool) {
+		for _, chunk := range chunkInstruments(instruments, tw.maxTokensPerRequest) {
+			var err error
+			if subscribe {
+				err = tw.subscribeTouchline(instrumentStrings(chunk), opts, nil, true)
+			} else {
+				err = tw.UnsubscribeTouchline(instrumentStrings(chunk))
+			}
+			if err != nil {
+				errs = append(errs, err)
+				update.Failed = append(update.Failed, chunk...)
+				continue
+			}
+			if subscribe {
+				tw.joinUniverse(chunk)
+			}
+		}
+	}
+	send(update.Removed, false)
+	send(update.Added, true)
+	send(update.Updated, true)
+
+	return update, errors.Join(errs...)
+}
+
+// joinUniverse adds the touchline subscriptions of the instruments to the universe
+func (tw *ODINMarketFeedClient) joinUniverse(instruments []Instrument) {
+	tw.subMu.Lock()
+	defer tw.subMu.Unlock()
+
+	if tw.universe == nil {
+		tw.universe = make(map[subscriptionKey]struct{})
+	}
+	for _, instrument := range instruments {
+		tw.universe[subscriptionKey{subType: SubscriptionTouchline, marketSegmentID: instrument.MarketSegmentID, token: instrument.Token}] = struct{}{}
+	}
+}
+
+// key returns the registry key of the subscription
+func (sub Subscription) key() subscriptionKey {
+	return subscriptionKey{subType: sub.Type, marketSegmentID: sub.Instrument.MarketSegmentID, token: sub.Instrument.Token}
+}
+
+// chunkInstruments splits instruments into chunks of at most size (0 means one chunk)
+func chunkInstruments(instruments []Instrument, size int) [][]Instrument {
+	if len(instruments) == 0 {
+		return nil
+	}
+	if size <= 0 || len(instruments) <= size {
+		return [][]Instrument{instruments}
+	}
+
+	chunks := make([][]Instrument, 0, (len(instruments)+size-1)/size)
+	for start := 0; start < len(instruments); start += size {
+		end := start + size
+		if end > len(instruments) {
+			end = len(instruments)
+		}
+		chunks = append(chunks, instruments[start:end])
+	}
+	return chunks
+}
+
+// instrumentStrings returns the 'MarketSegmentID_Token' form of instruments
+func instrumentStrings(instruments []Instrument) []string {
+	tokenList := make([]string, len(instruments))
+	for i, instrument := range instruments {
+		tokenList[i] = instrument.String()
+	}
+	return tokenList
+}
+
+func sortInstruments(instruments []Instrument) {
+	sort.Slice(instruments, func(i, j int) bool {
+		if instruments[i].MarketSegmentID != instruments[j].MarketSegmentID {
+			return instruments[i].MarketSegmentID < instruments[j].MarketSegmentID
+		}
+		return instruments[i].Token < instruments[j].Token
+	})
+}
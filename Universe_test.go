@@ -0,0 +1,150 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func instruments(tokens ...int) []Instrument {
+	result := make([]Instrument, len(tokens))
+	for i, token := range tokens {
+		result[i] = Instrument{MarketSegmentID: 1, Token: token}
+	}
+	return result
+}
+
+// touchlineRequests returns the touchline requests the server receives before an LTP
+// touchline request sent as a marker after them
+func touchlineRequests(t *testing.T, ms *mockServer, tw *ODINMarketFeedClient) []string {
+	t.Helper()
+
+	if err := tw.SubscribeLTPTouchline([]string{"9_9"}); err != nil {
+		t.Fatal(err)
+	}
+	var requests []string
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case request := <-ms.requests:
+			switch messageCode(request) {
+			case msgCodeTouchline:
+				requests = append(requests, request)
+			case msgCodeLTPTouchline:
+				return requests
+			}
+		case <-timeout:
+			t.Fatal("marker request not received")
+			return nil
+		}
+	}
+}
+
+func setUniverse(t *testing.T, tw *ODINMarketFeedClient, tokens ...int) UniverseUpdate {
+	t.Helper()
+	update, err := tw.SetTouchlineUniverse(instruments(tokens...), "0", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return update
+}
+
+func TestTouchlineUniverseOverlappingSets(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	if update := setUniverse(t, tw, 22, 23, 24); !reflect.DeepEqual(update.Added, instruments(22, 23, 24)) {
+		t.Fatalf("Added = %v, want 22, 23 and 24", update.Added)
+	}
+	touchlineRequests(t, ms, tw)
+
+	update := setUniverse(t, tw, 23, 24, 25)
+	if !reflect.DeepEqual(update.Added, instruments(25)) || !reflect.DeepEqual(update.Removed, instruments(22)) {
+		t.Errorf("update = %+v, want 25 added and 22 removed", update)
+	}
+	requests := touchlineRequests(t, ms, tw)
+	if len(requests) != 2 {
+		t.Fatalf("requests %q, want one unsubscribe and one subscribe", requests)
+	}
+	if !strings.Contains(requests[0], "7=22|") || !strings.HasSuffix(requests[0], "230=2") {
+		t.Errorf("first request %q, want the unsubscribe of 22", requests[0])
+	}
+	if !strings.Contains(requests[1], "7=25|") || strings.Contains(requests[1], "7=23|") || !strings.HasSuffix(requests[1], "230=1") {
+		t.Errorf("second request %q, want the subscribe of 25 only", requests[1])
+	}
+}
+
+func TestTouchlineUniverseEmpty(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	setUniverse(t, tw, 22, 23)
+	touchlineRequests(t, ms, tw)
+
+	if update := setUniverse(t, tw); !reflect.DeepEqual(update.Removed, instruments(22, 23)) || len(update.Added) > 0 {
+		t.Errorf("update = %+v, want 22 and 23 removed", update)
+	}
+	if requests := touchlineRequests(t, ms, tw); len(requests) != 1 || !strings.HasSuffix(requests[0], "230=2") {
+		t.Errorf("requests %q, want one unsubscribe", requests)
+	}
+	for _, instrument := range instruments(22, 23) {
+		if subscribed(tw, SubscriptionTouchline, instrument) {
+			t.Errorf("%s still subscribed after an empty universe", instrument)
+		}
+	}
+}
+
+func TestTouchlineUniverseIdenticalSetsSendNothing(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	setUniverse(t, tw, 22, 23)
+	touchlineRequests(t, ms, tw)
+
+	if update := setUniverse(t, tw, 23, 22); !reflect.DeepEqual(update, UniverseUpdate{}) {
+		t.Errorf("update = %+v, want no change", update)
+	}
+	if requests := touchlineRequests(t, ms, tw); len(requests) != 0 {
+		t.Errorf("identical universe sent %q, want no request", requests)
+	}
+}
+
+func TestTouchlineUniverseKeepsOtherSubscriptions(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	if err := tw.SubscribeTouchlineWithOptions([]string{"1_30"}, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	group, _ := tw.CreateGroup("watch")
+	if err := group.Add(instruments(31), SubscriptionTouchline, TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	touchlineRequests(t, ms, tw)
+
+	if update := setUniverse(t, tw, 30, 31, 32); !reflect.DeepEqual(update.Added, instruments(32)) {
+		t.Errorf("Added = %v, want only 32", update.Added)
+	}
+	if update := setUniverse(t, tw); !reflect.DeepEqual(update.Removed, instruments(32)) {
+		t.Errorf("Removed = %v, want only 32", update.Removed)
+	}
+	for _, instrument := range instruments(30, 31) {
+		if !subscribed(tw, SubscriptionTouchline, instrument) {
+			t.Errorf("%s subscribed outside the universe was unsubscribed", instrument)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package ODINMarketFeed
+
+import (
+	"fmt"
+	"sync"
+)
+
+// unknownCodes counts the messages received per unrecognised 64= code
+type unknownCodes struct {
+	counts map[int]uint64
+	mu     sync.Mutex
+}
+
+// WithTouchlineCodes sets the 64= codes of the responses whose 50= block is decoded as a
+// touchline, by default only 206. Messages of other unrecognised codes are passed to
+// OnUnknownMessage without decoding their binary block.
+func WithTouchlineCodes(codes ...int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.decoder.TouchlineCodes = codes
+	}
+}
+
+// isTouchlineCode reports whether the binary block of messages with the code is a touchline
+func (d *Decoder) isTouchlineCode(code int) bool {
+	if d.TouchlineCodes == nil {
+		return code == msgCodeTouchline
+	}
+	for _, touchlineCode := range d.TouchlineCodes {
+		if code == touchlineCode {
+			return true
+		}
+	}
+	return false
+}
+
+// knownCode reports whether the decoder interprets messages with the 64= code
+func (d *Decoder) knownCode(code int) bool {
+	switch code {
+	case msgCodeHeartbeat, msgCodeLogin, msgCodeBestFive, msgCodeLTPTouchline, msgCodeTouchline:
+		return true
+	}
+	if d.DuplicateSessionCode != 0 && code == d.DuplicateSessionCode {
+		return true
+	}
+	for _, touchlineCode := range d.TouchlineCodes {
+		if code == touchlineCode {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownMessageReceived counts a message with an unrecognised code and passes it to
+// OnUnknownMessage. It reports false when OnUnknownMessage is nil, so that the message is
+// delivered to OnMessage instead.
+func (tw *ODINMarketFeedClient) unknownMessageReceived(msg ParsedMessage) bool {
+	tw.unknownCodes.mu.Lock()
+	if tw.unknownCodes.counts == nil {
+		tw.unknownCodes.counts = make(map[int]uint64)
+	}
+	tw.unknownCodes.counts[msg.Code]++
+	tw.unknownCodes.mu.Unlock()
+
+	if tw.OnUnknownMessage == nil {
+		return false
+	}
+	tw.invokeCallback("OnUnknownMessage", func() string { return fmt.Sprintf("64=%d", msg.Code) }, func() {
+		tw.deliverBytes("OnUnknownMessage", msg.Raw, func(raw []byte) { tw.OnUnknownMessage(msg.Code, raw) })
+	})
+	return true
+}
+
+// snapshot returns a copy of the counts, or nil when no unknown code has been seen
+func (uc *unknownCodes) snapshot() map[int]uint64 {
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if len(uc.counts) == 0 {
+		return nil
+	}
+	counts := make(map[int]uint64, len(uc.counts))
+	for code, count := range uc.counts {
+		counts[code] = count
+	}
+	return counts
+}
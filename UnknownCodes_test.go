@@ -0,0 +1,46 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"testing"
+)
+
+// unknownCodeMessage returns touchlineMessage with its 64= code replaced by 999
+func unknownCodeMessage() []byte {
+	return bytes.Replace(touchlineMessage(1, 22, 24500), []byte("64=206"), []byte("64=999"), 1)
+}
+
+func TestUnknownCodeBlockIsNotDecodedByDefault(t *testing.T) {
+	tw := newTestClient()
+	var touchlines []TouchlineData
+	var unknown []byte
+	tw.OnTouchline = func(data TouchlineData) { touchlines = append(touchlines, data) }
+	tw.OnUnknownMessage = func(code int, raw []byte) { unknown = append([]byte(nil), raw...) }
+
+	msg := unknownCodeMessage()
+	tw.responseReceived(frameOf(msg), 0)
+
+	if len(touchlines) != 0 {
+		t.Fatalf("the 50= block of 64=999 was decoded as touchlines %+v", touchlines)
+	}
+	if !bytes.Equal(unknown, msg) {
+		t.Errorf("OnUnknownMessage got %q, want the raw message", unknown)
+	}
+}
+
+func TestTouchlineCodesLeaveUnknownCodesUndecoded(t *testing.T) {
+	tw := newTestClient(WithTouchlineCodes(206))
+	var touchlines int
+	var codes []int
+	tw.OnTouchline = func(TouchlineData) { touchlines++ }
+	tw.OnUnknownMessage = func(code int, _ []byte) { codes = append(codes, code) }
+
+	tw.responseReceived(frameOf(unknownCodeMessage(), touchlineMessage(1, 23, 24600)), 0)
+
+	if touchlines != 1 || len(codes) != 1 || codes[0] != 999 {
+		t.Fatalf("got %d touchlines and unknown codes %v, want 1 touchline and [999]", touchlines, codes)
+	}
+	if count := tw.Stats().UnknownCodes[999]; count != 1 {
+		t.Errorf("UnknownCodes[999] = %d, want 1", count)
+	}
+}
@@ -0,0 +1,210 @@
+package ODINMarketFeed
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ValidationReport describes what a subscribe request would send, as computed by
+// ValidateSubscriptions. It encodes to JSON for keeping as a build artifact.
+type ValidationReport struct {
+	Type      string `json:"type"`      // subscription type, see SubscriptionType.String
+	Requested int    `json:"requested"` // instruments passed to ValidateSubscriptions
+	Valid     int    `json:"valid"`     // instruments that would be sent
+	Messages  int    `json:"messages"`  // requests that would be written
+
+	Chunks   []ValidationChunk `json:"chunks"`
+	Rejected []ValidationEntry `json:"rejected,omitempty"` // instruments that would not be sent
+	Warnings []ValidationEntry `json:"warnings,omitempty"` // instruments sent despite a problem
+
+	Quota *ValidationQuota `json:"quota,omitempty"` // set with WithSubscriptionLimit
+
+	// Error is the error the subscribe request would return, if any
+	Error string `json:"error,omitempty"`
+}
+
+// ValidationChunk is one request of a ValidationReport
+type ValidationChunk struct {
+	Tokens     int    `json:"tokens"`          // instruments in the request
+	Bytes      int    `json:"bytes"`           // length of the request text
+	FrameBytes int    `json:"frame_bytes"`     // length of the compressed frame
+	Error      string `json:"error,omitempty"` // set when the request cannot be framed
+}
+
+// ValidationEntry is an instrument rejected or flagged by ValidateSubscriptions. Index is
+// its position in the list passed in.
+type ValidationEntry struct {
+	Index      int    `json:"index"`
+	Instrument string `json:"instrument"`
+	Reason     string `json:"reason"`
+}
+
+// ValidationQuota is the WithSubscriptionLimit accounting of a ValidationReport
+type ValidationQuota struct {
+	Limit      int  `json:"limit"`
+	Subscribed int  `json:"subscribed"` // distinct instruments subscribed after the request
+	Exceeded   bool `json:"exceeded"`
+	Overflow   bool `json:"overflow"` // WithSubscriptionLimitOverflow would send it anyway
+}
+
+// ValidateSubscriptions checks a subscription without sending anything, for example to
+// validate a watchlist before the market opens; the client need not be connected. The
+// instruments go through the canonicalisation and quota accounting of the subscribe
+// methods and are built into the requests they would send: touchline in requests of
+// WithMaxTokensPerRequest instruments as SubscribeTouchlineFromReader sends them, LTP
+// touchline in one request as SubscribeLTPTouchline sends it and Best Five in one request
+// per instrument as SubscribeBestFiveBatch sends them. No correlation ID is used up; with a
+// custom WithRequestCorrelation generator the IDs are left empty. As with ParseWatchlist, repeated instruments are rejected; instruments of a
+// segment without a built-in or registered SegmentConfig are reported as warnings. opts
+// are the touchline options, the defaults when omitted.
+//
+// The returned error is the one the subscribe request would return: a *BatchError for
+// rejected instruments, ErrSubscriptionLimitExceeded or ErrFrameTooLarge. Neither OnError
+// nor any event is invoked.
+func (tw *ODINMarketFeedClient) ValidateSubscriptions(instruments []Instrument, subType SubscriptionType, opts ...TouchlineOptions) (ValidationReport, error) {
+	report := ValidationReport{Type: subType.String(), Requested: len(instruments), Chunks: []ValidationChunk{}}
+
+	var touchlineOpts TouchlineOptions
+	switch {
+	case len(opts) > 1:
+		return report, errors.New("at most one TouchlineOptions may be passed")
+	case len(opts) == 1:
+		if subType != SubscriptionTouchline {
+			return report, fmt.Errorf("touchline options do not apply to %s subscriptions", subType)
+		}
+		touchlineOpts = opts[0]
+	}
+	if err := touchlineOpts.Validate(); err != nil {
+		return report, err
+	}
+	if subType != SubscriptionTouchline && subType != SubscriptionLTPTouchline && subType != SubscriptionBestFive {
+		return report, fmt.Errorf("unknown subscription type %s", subType)
+	}
+	if len(instruments) == 0 {
+		return report, errors.New("instrument list cannot be empty")
+	}
+
+	valid, skipped, skipErrs := tw.validateInstruments(instruments, &report)
+	report.Valid = len(valid)
+
+	var errs []error
+	if len(skipped) > 0 {
+		errs = append(errs, newBatchError(len(instruments), valid, skipped, skipErrs))
+	}
+	if err := tw.validateQuota(valid, &report); err != nil {
+		errs = append(errs, err)
+	}
+
+	var requests []string
+	var tokens []int
+	switch subType {
+	case SubscriptionTouchline:
+		for _, chunk := range chunkInstruments(valid, tw.maxTokensPerRequest) {
+			requests = append(requests, tw.touchlineRequest(tw.previewRequestHeader(msgCodeTouchline), touchlineOpts, chunk))
+			tokens = append(tokens, len(chunk))
+		}
+	case SubscriptionLTPTouchline:
+		if len(valid) > 0 {
+			requests = append(requests, tw.ltpTouchlineRequest(tw.previewRequestHeader(msgCodeLTPTouchline), valid, 1))
+			tokens = append(tokens, len(valid))
+		}
+	case SubscriptionBestFive:
+		for _, instrument := range valid {
+			requests = append(requests, tw.bestFiveRequest(tw.previewRequestHeader(msgCodeBestFive), instrument, 1))
+			tokens = append(tokens, 1)
+		}
+	}
+
+	for i, request := range requests {
+		chunk := ValidationChunk{Tokens: tokens[i], Bytes: len(request)}
+		packet, err := tw.fragHandler.FragmentData([]byte(request))
+		if err != nil {
+			chunk.Error = err.Error()
+			errs = append(errs, fmt.Errorf("request %d: %w", i+1, err))
+		}
+		chunk.FrameBytes = len(packet)
+		report.Chunks = append(report.Chunks, chunk)
+	}
+	report.Messages = len(report.Chunks)
+
+	if len(valid) == 0 {
+		errs = append(errs, errors.New("no valid instruments found"))
+	}
+	err := errors.Join(errs...)
+	if err != nil {
+		report.Error = err.Error()
+	}
+	return report, err
+}
+
+// validateInstruments canonicalises instruments, resolving symbols of instruments without
+// identifiers, and returns the ones that would be sent
+func (tw *ODINMarketFeedClient) validateInstruments(instruments []Instrument, report *ValidationReport) (valid []Instrument, skipped []string, errs []error) {
+	seen := make(map[uint64]int, len(instruments))
+	reject := func(index int, item string, err error) {
+		report.Rejected = append(report.Rejected, ValidationEntry{Index: index, Instrument: item, Reason: err.Error()})
+		skipped = append(skipped, item)
+		errs = append(errs, err)
+	}
+
+	for index, instrument := range instruments {
+		item := instrument.String()
+		if instrument.MarketSegmentID == 0 && instrument.Token == 0 && instrument.Symbol != "" {
+			item = instrument.Symbol
+			resolved, err := tw.resolveSymbol(instrument.Symbol)
+			if err != nil {
+				reject(index, item, fmt.Errorf("cannot resolve symbol '%s': %w", instrument.Symbol, err))
+				continue
+			}
+			instrument = resolved
+		}
+
+		canonical, err := ParseInstrument(instrument.String())
+		if err == nil && (canonical.MarketSegmentID <= 0 || canonical.Token <= 0) {
+			err = fmt.Errorf("invalid instrument: '%s'", canonical)
+		}
+		if err != nil {
+			reject(index, item, err)
+			continue
+		}
+
+		if first, ok := seen[instrumentKey(canonical)]; ok {
+			reject(index, item, fmt.Errorf("duplicate of entry %d", first))
+			continue
+		}
+		seen[instrumentKey(canonical)] = index
+
+		if !tw.decoder.knownSegment(uint32(canonical.MarketSegmentID)) {
+			report.Warnings = append(report.Warnings, ValidationEntry{
+				Index:      index,
+				Instrument: canonical.String(),
+				Reason:     fmt.Sprintf("segment %d has no SegmentConfig", canonical.MarketSegmentID),
+			})
+		}
+		valid = append(valid, canonical)
+	}
+	return valid, skipped, errs
+}
+
+// validateQuota accounts the instruments against the subscription limit like
+// reserveQuota, without reporting or reserving anything
+func (tw *ODINMarketFeedClient) validateQuota(instruments []Instrument, report *ValidationReport) error {
+	if tw.quota.limit <= 0 {
+		return nil
+	}
+
+	tw.subMu.Lock()
+	subscribed := tw.committedInstrumentsLocked(instruments)
+	tw.subMu.Unlock()
+
+	report.Quota = &ValidationQuota{
+		Limit:      tw.quota.limit,
+		Subscribed: subscribed,
+		Exceeded:   subscribed > tw.quota.limit,
+		Overflow:   subscribed > tw.quota.limit && tw.quota.allowOverflow,
+	}
+	if !report.Quota.Exceeded || report.Quota.Overflow {
+		return nil
+	}
+	return fmt.Errorf("%w: %d instruments requested, limit is %d", ErrSubscriptionLimitExceeded, subscribed, tw.quota.limit)
+}
@@ -0,0 +1,55 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestValidateLTPTouchlineMatchesRequest(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient(WithMaxTokensPerRequest(2), WithRequestCorrelation(9000, nil))
+	instruments := []Instrument{
+		{MarketSegmentID: 1, Token: 22},
+		{MarketSegmentID: 1, Token: 2885},
+		{MarketSegmentID: 3, Token: 500},
+	}
+
+	report, err := tw.ValidateSubscriptions(instruments, SubscriptionLTPTouchline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Messages != 1 || report.Chunks[0].Tokens != 3 {
+		t.Fatalf("report has %d messages %+v, want one of 3 tokens", report.Messages, report.Chunks)
+	}
+
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	if err := tw.SubscribeLTPTouchline([]string{"1_22", "1_2885", "3_500"}); err != nil {
+		t.Fatal(err)
+	}
+	request := ms.next(t, msgCodeLTPTouchline)
+	if !strings.Contains(request, "|9000=2|") {
+		t.Errorf("request %q, want correlation ID 2 after the login; the dry run used one up", request)
+	}
+	if len(request) != report.Chunks[0].Bytes {
+		t.Errorf("request is %d bytes, report says %d", len(request), report.Chunks[0].Bytes)
+	}
+}
+
+func TestValidateChunksTouchline(t *testing.T) {
+	tw := newTestClient(WithMaxTokensPerRequest(2))
+	instruments := []Instrument{
+		{MarketSegmentID: 1, Token: 22},
+		{MarketSegmentID: 1, Token: 2885},
+		{MarketSegmentID: 3, Token: 500},
+	}
+
+	report, err := tw.ValidateSubscriptions(instruments, SubscriptionTouchline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Messages != 2 || report.Chunks[0].Tokens != 2 || report.Chunks[1].Tokens != 1 {
+		t.Errorf("report chunks %+v, want requests of 2 and 1 tokens", report.Chunks)
+	}
+}
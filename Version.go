@@ -0,0 +1,88 @@
+package ODINMarketFeed
+
+// version is the library version. Release builds may override it with
+// -ldflags "-X github.com/SIPL-Dev/go-odinmarketfeedclient.version=..."
+var version = "1.1.0-dev"
+
+// Version returns the library version
+func Version() string {
+	return version
+}
+
+// Capabilities describes the message formats supported by this build
+type Capabilities struct {
+	Version         string
+	ProtocolVersion string // the 63= value sent with requests
+	// Compression lists the outer frame encodings understood
+	Compression []string
+	// MaxTokensPerRequest is the configured universe chunk size; 0 means no limit
+	MaxTokensPerRequest int
+	// RequestCodes lists the 64= codes the client sends
+	RequestCodes []int
+	// ResponseCodes lists the 64= codes decoded into typed messages, including the
+	// touchline codes set with WithTouchlineCodes
+	ResponseCodes []int
+}
+
+// WithClientVersionTag sends Version() in the given login tag. The gateway defines no
+// standard client version field, so the tag must be agreed with the vendor.
+func WithClientVersionTag(tag int) Option {
+	return func(tw *ODINMarketFeedClient) {
+		tw.versionTag = tag
+	}
+}
+
+// Capabilities returns what this build and client configuration support
+func (tw *ODINMarketFeedClient) Capabilities() Capabilities {
+	protocolVersion := tw.header.protocolVersion
+	if protocolVersion == "" {
+		protocolVersion = defaultProtocolVersion
+	}
+
+	return Capabilities{
+		Version:             Version(),
+		ProtocolVersion:     protocolVersion,
+		Compression:         []string{"zlib"},
+		MaxTokensPerRequest: tw.maxTokensPerRequest,
+		RequestCodes: []int{
+			msgCodeHeartbeat,
+			msgCodeLogin,
+			msgCodePauseResume,
+			msgCodeBestFive,
+			msgCodeTouchline,
+			msgCodeLTPTouchline,
+		},
+		ResponseCodes: append([]int{
+			msgCodeHeartbeat,
+			msgCodeLogin,
+			msgCodeBestFive,
+			msgCodeLTPTouchline,
+		}, tw.touchlineCodes()...),
+	}
+}
+
+// touchlineCodes returns the 64= codes decoded as touchlines
+func (tw *ODINMarketFeedClient) touchlineCodes() []int {
+	if tw.decoder.TouchlineCodes == nil {
+		return []int{msgCodeTouchline}
+	}
+	return tw.decoder.TouchlineCodes
+}
+
+// withVersionTag returns login with the client version tag added, unless the caller set it
+func (tw *ODINMarketFeedClient) withVersionTag(login LoginOptions) LoginOptions {
+	if tw.versionTag == 0 {
+		return login
+	}
+	if _, ok := login.Extra[tw.versionTag]; ok {
+		return login
+	}
+
+	extra := make(map[int]string, len(login.Extra)+1)
+	for tag, value := range login.Extra {
+		extra[tag] = value
+	}
+	extra[tw.versionTag] = Version()
+	login.Extra = extra
+	return login
+}
@@ -0,0 +1,140 @@
+package ODINMarketFeed
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WatchlistLineError describes an invalid watchlist line
+type WatchlistLineError struct {
+	Line int
+	Text string
+	Err  error
+}
+
+// Error returns the line number, the line and the cause
+func (e *WatchlistLineError) Error() string {
+	return fmt.Sprintf("line %d: '%s': %v", e.Line, e.Text, e.Err)
+}
+
+// Unwrap returns the cause
+func (e *WatchlistLineError) Unwrap() error {
+	return e.Err
+}
+
+// ParseWatchlist reads a watchlist without subscribing. Each line holds a
+// 'MarketSegmentID_Token' instrument or a symbol resolved by the SymbolResolver; blank
+// lines and text after # are ignored. The instruments are returned in file order without
+// duplicates. Invalid lines are reported in a *BatchError whose skipped items carry their
+// line numbers, with one *WatchlistLineError per line.
+func (tw *ODINMarketFeedClient) ParseWatchlist(r io.Reader) ([]Instrument, error) {
+	instruments, _, err := tw.parseWatchlist(r)
+	return instruments, err
+}
+
+// SubscribeTouchlineFromReader subscribes to touchline for the instruments of a watchlist
+// (see ParseWatchlist), split into requests of WithMaxTokensPerRequest tokens. The valid
+// instruments are subscribed even when some lines are invalid.
+func (tw *ODINMarketFeedClient) SubscribeTouchlineFromReader(r io.Reader, opts TouchlineOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	return tw.watchlistRequest(r, func(tokenList []string) error {
+		return tw.SubscribeTouchlineWithOptions(tokenList, opts)
+	})
+}
+
+// UnsubscribeFromReader unsubscribes from touchline for the instruments of a watchlist
+// (see ParseWatchlist)
+func (tw *ODINMarketFeedClient) UnsubscribeFromReader(r io.Reader) error {
+	return tw.watchlistRequest(r, tw.UnsubscribeTouchline)
+}
+
+// watchlistRequest parses the watchlist and passes its instruments to send in chunks
+func (tw *ODINMarketFeedClient) watchlistRequest(r io.Reader, send func(tokenList []string) error) error {
+	instruments, entries, err := tw.parseWatchlist(r)
+	var batchErr *BatchError
+	if err != nil && !errors.As(err, &batchErr) {
+		return err
+	}
+
+	if len(instruments) == 0 {
+		if batchErr != nil {
+			return fmt.Errorf("no valid instruments in watchlist: %w", batchErr)
+		}
+		return errors.New("watchlist contains no instruments")
+	}
+
+	var errs []error
+	for _, chunk := range chunkInstruments(instruments, tw.maxTokensPerRequest) {
+		if err := send(instrumentStrings(chunk)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if batchErr != nil {
+		errs = append(errs, tw.batchResult(entries, instruments, batchErr.Result.Skipped, batchErr.Result.Errors))
+	}
+	return errors.Join(errs...)
+}
+
+// parseWatchlist returns the instruments of a watchlist, the number of entries read and a
+// *BatchError for the invalid lines. Read errors are returned as they are.
+func (tw *ODINMarketFeedClient) parseWatchlist(r io.Reader) ([]Instrument, int, error) {
+	var instruments []Instrument
+	var skipped []string
+	var errs []error
+	seen := make(map[[2]int]bool)
+	entries := 0
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if index := strings.IndexByte(text, '#'); index >= 0 {
+			text = text[:index]
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		entries++
+
+		instrument, err := tw.watchlistEntry(text)
+		if err != nil {
+			skipped = append(skipped, fmt.Sprintf("line %d: %s", line, text))
+			errs = append(errs, &WatchlistLineError{Line: line, Text: text, Err: err})
+			continue
+		}
+
+		key := [2]int{instrument.MarketSegmentID, instrument.Token}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		instruments = append(instruments, instrument)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, entries, fmt.Errorf("failed to read watchlist: %w", err)
+	}
+
+	if len(skipped) > 0 {
+		return instruments, entries, newBatchError(entries, instruments, skipped, errs)
+	}
+	return instruments, entries, nil
+}
+
+// watchlistEntry parses an instrument, or resolves the entry as a symbol when it is not
+// made of digits and underscores
+func (tw *ODINMarketFeedClient) watchlistEntry(text string) (Instrument, error) {
+	if strings.Trim(text, "0123456789_ ") == "" {
+		instrument, err := ParseInstrument(text)
+		if err == nil && (instrument.MarketSegmentID <= 0 || instrument.Token <= 0) {
+			err = fmt.Errorf("invalid instrument: '%s'", text)
+		}
+		return instrument, err
+	}
+	return tw.resolveSymbol(text)
+}
@@ -0,0 +1,99 @@
+package ODINMarketFeed
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// defaultWireDumpLimit is the number of payload bytes dumped per frame by default
+const defaultWireDumpLimit = 512
+
+// wireDumper writes annotated hex dumps of websocket frames
+type wireDumper struct {
+	w     io.Writer
+	limit int
+	now   func() time.Time
+	mu    sync.Mutex
+}
+
+// SetWireDump writes a hex dump of every outbound and inbound frame to w, with the 6 byte
+// outer header decoded and the decompressed inner messages dumped after the frame. Pass nil
+// to disable. It is safe to call while connected.
+func (tw *ODINMarketFeedClient) SetWireDump(w io.Writer) {
+	tw.SetWireDumpLimit(w, defaultWireDumpLimit)
+}
+
+// SetWireDumpLimit is like SetWireDump but caps each dumped payload at limit bytes
+// (0 means no cap)
+func (tw *ODINMarketFeedClient) SetWireDumpLimit(w io.Writer, limit int) {
+	if w == nil {
+		tw.wireDump.Store(nil)
+		return
+	}
+	tw.wireDump.Store(&wireDumper{w: w, limit: limit, now: time.Now})
+}
+
+// dumpFrame dumps a websocket frame and the inner messages it carries
+func (tw *ODINMarketFeedClient) dumpFrame(direction string, frame []byte, inner [][]byte) {
+	dumper := tw.wireDump.Load()
+	if dumper == nil {
+		return
+	}
+
+	dumper.mu.Lock()
+	defer dumper.mu.Unlock()
+
+	fmt.Fprintf(dumper.w, "%s %s frame len=%d%s\n",
+		dumper.now().Format("2006-01-02T15:04:05.000000Z07:00"), direction, len(frame), describeOuterHeader(frame))
+	dumper.hexDump(frame)
+
+	for i, message := range inner {
+		fmt.Fprintf(dumper.w, "  inner[%d] len=%d\n", i, len(message))
+		dumper.hexDump(message)
+	}
+}
+
+// dumpInner dumps inner messages decoded from earlier inbound frames
+func (tw *ODINMarketFeedClient) dumpInner(inner [][]byte) {
+	dumper := tw.wireDump.Load()
+	if dumper == nil || len(inner) == 0 {
+		return
+	}
+
+	dumper.mu.Lock()
+	defer dumper.mu.Unlock()
+
+	for i, message := range inner {
+		fmt.Fprintf(dumper.w, "  inner[%d] len=%d\n", i, len(message))
+		dumper.hexDump(message)
+	}
+}
+
+func (d *wireDumper) hexDump(data []byte) {
+	truncated := 0
+	if d.limit > 0 && len(data) > d.limit {
+		truncated = len(data) - d.limit
+		data = data[:d.limit]
+	}
+
+	io.WriteString(d.w, hex.Dump(data))
+	if truncated > 0 {
+		fmt.Fprintf(d.w, "  ... %d more bytes\n", truncated)
+	}
+}
+
+// describeOuterHeader decodes the 6 byte outer header (flag byte and 5 digit length)
+func describeOuterHeader(frame []byte) string {
+	flag, declared, err := parseFrameHeader(frame, true)
+	if errors.Is(err, ErrShortFrame) {
+		return " header=short"
+	}
+	if err != nil {
+		return fmt.Sprintf(" flag=%d header=invalid", frame[0])
+	}
+	return fmt.Sprintf(" flag=%d declared=%d", flag, declared)
+}
@@ -0,0 +1,35 @@
+package ODINMarketFeed
+
+import (
+	"bytes"
+	"compress/zlib"
+	"testing"
+	"time"
+)
+
+func TestWireDumpGolden(t *testing.T) {
+	tw := newTestClient()
+	var dump bytes.Buffer
+	tw.SetWireDumpLimit(&dump, 48)
+	tw.wireDump.Load().now = func() time.Time { return time.Date(2026, 3, 2, 9, 15, 0, 123456000, ExchangeLocation) }
+
+	// Stored deflate blocks keep the dumped bytes independent of the compressor version
+	var payload bytes.Buffer
+	w, _ := zlib.NewWriterLevel(&payload, zlib.NoCompression)
+	w.Write(innerFrame(touchlineMessage(1, 22, 24500)))
+	w.Write(innerFrame([]byte("63=FT3.0|64=900|100=Early close")))
+	w.Close()
+	frame, err := EncodeFrame(Frame{Flag: FrameCompressed, Payload: payload.Bytes()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw.responseReceived(frame, 0)
+	checkGolden(t, "wiredump.golden", dump.Bytes())
+
+	tw.SetWireDump(nil)
+	dump.Reset()
+	tw.responseReceived(frame, 0)
+	if dump.Len() != 0 {
+		t.Errorf("dumped %d bytes after SetWireDump(nil)", dump.Len())
+	}
+}
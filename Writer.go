@@ -0,0 +1,295 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// closeWriteTimeout bounds the write of the close frame by Disconnect
+const closeWriteTimeout = 5 * time.Second
+
+// recentBulkRequests is the number of written bulk requests kept to match throttle notices
+const recentBulkRequests = 16
+
+// errWriterStopped is returned for messages still queued when the connection ends
+var errWriterStopped = errors.New("WebSocket is not connected")
+
+// sendPriority is the writer queue a message is placed on
+type sendPriority int
+
+const (
+	// priorityControl carries login, heartbeat, pause/resume and the unsubscribe requests
+	// sent by Close; it is always drained before priorityBulk
+	priorityControl sendPriority = iota
+	// priorityBulk carries subscription traffic
+	priorityBulk
+)
+
+// controlMessageCodes are the request codes sent on the control queue
+var controlMessageCodes = map[int]bool{
+	msgCodeHeartbeat:   true,
+	msgCodeLogin:       true,
+	msgCodePauseResume: true,
+}
+
+// writeRequest is a frame waiting to be written and the channel its result is sent on. A
+// request without a packet is a flush marker, answered once the requests ahead of it have
+// been written.
+type writeRequest struct {
+	packet  []byte
+	display string
+	done    chan error
+	attempt int // times the request was re-sent after a throttle notice
+}
+
+// sendQueue holds the frames waiting for the writer goroutine of one connection
+type sendQueue struct {
+	mu      sync.Mutex
+	control []writeRequest
+	bulk    []writeRequest
+	closed  bool
+	wake    chan struct{}
+
+	// pausedUntil holds back the bulk requests after a throttle notice; resume wakes the
+	// writer when it has passed. recent holds the last bulk requests handed to the writer.
+	pausedUntil time.Time
+	resume      *time.Timer
+	recent      []writeRequest
+
+	// written counts the frames written by the writer
+	written int
+}
+
+func newSendQueue() *sendQueue {
+	return &sendQueue{wake: make(chan struct{}, 1)}
+}
+
+// push queues a request, failing it if the writer has stopped
+func (q *sendQueue) push(priority sendPriority, request writeRequest) error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return errWriterStopped
+	}
+	if priority == priorityControl {
+		q.control = append(q.control, request)
+	} else {
+		q.bulk = append(q.bulk, request)
+	}
+	q.mu.Unlock()
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// pop returns the oldest control request, or the oldest bulk request if there is none and
+// the bulk requests are not paused
+func (q *sendQueue) pop() (writeRequest, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.control) > 0 {
+		request := q.control[0]
+		q.control[0] = writeRequest{}
+		q.control = q.control[1:]
+		return request, true
+	}
+	if len(q.bulk) > 0 && !time.Now().Before(q.pausedUntil) {
+		request := q.bulk[0]
+		q.bulk[0] = writeRequest{}
+		q.bulk = q.bulk[1:]
+		if request.packet == nil {
+			return request, true
+		}
+		if len(q.recent) == recentBulkRequests {
+			q.recent = q.recent[1:]
+		}
+		q.recent = append(q.recent, request)
+		return request, true
+	}
+	return writeRequest{}, false
+}
+
+// close stops the queue and fails the requests still in it
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	pending := append(q.control, q.bulk...)
+	q.control, q.bulk = nil, nil
+	q.closed = true
+	if q.resume != nil {
+		q.resume.Stop()
+	}
+	q.mu.Unlock()
+
+	for _, request := range pending {
+		request.done <- errWriterStopped
+	}
+}
+
+// pauseBulk holds back the bulk requests for d, placing retry ahead of them, and returns
+// the number of bulk requests that were waiting
+func (q *sendQueue) pauseBulk(d time.Duration, retry *writeRequest) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	held := len(q.bulk)
+	if q.closed {
+		return held
+	}
+	if retry != nil {
+		q.bulk = append([]writeRequest{*retry}, q.bulk...)
+	}
+	if until := time.Now().Add(d); until.After(q.pausedUntil) {
+		q.pausedUntil = until
+		if q.resume != nil {
+			q.resume.Stop()
+		}
+		q.resume = time.AfterFunc(d, func() {
+			select {
+			case q.wake <- struct{}{}:
+			default:
+			}
+		})
+	}
+	return held
+}
+
+// takeRecentBulk removes and returns the latest of the recently written bulk requests
+// accepted by match, or the latest one when match is nil
+func (q *sendQueue) takeRecentBulk(match func(writeRequest) bool) (writeRequest, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for i := len(q.recent) - 1; i >= 0; i-- {
+		if match == nil || match(q.recent[i]) {
+			request := q.recent[i]
+			q.recent = append(q.recent[:i], q.recent[i+1:]...)
+			return request, true
+		}
+	}
+	return writeRequest{}, false
+}
+
+// flush waits until the requests queued so far have been written, or ctx is done, and
+// returns the number of frames written meanwhile
+func (q *sendQueue) flush(ctx context.Context) int {
+	q.mu.Lock()
+	start := q.written
+	q.mu.Unlock()
+
+	marker := writeRequest{done: make(chan error, 1)}
+	if err := q.push(priorityBulk, marker); err == nil {
+		select {
+		case <-marker.done:
+		case <-ctx.Done():
+		}
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.written - start
+}
+
+// writtenCount returns the number of frames written by the writer
+func (q *sendQueue) writtenCount() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.written
+}
+
+// pending returns the number of queued frames, flush markers excluded
+func (q *sendQueue) pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	count := 0
+	for _, requests := range [][]writeRequest{q.control, q.bulk} {
+		for _, request := range requests {
+			if request.packet != nil {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// wrote counts a frame written by the writer
+func (q *sendQueue) wrote() {
+	q.mu.Lock()
+	q.written++
+	q.mu.Unlock()
+}
+
+// depths returns the number of queued control and bulk requests
+func (q *sendQueue) depths() (control, bulk int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.control), len(q.bulk)
+}
+
+// writeLoop writes the queued frames of conn, control messages first, until stop is closed
+func (tw *ODINMarketFeedClient) writeLoop(conn *websocket.Conn, queue *sendQueue, stop <-chan struct{}) {
+	defer queue.close()
+
+	for {
+		request, ok := queue.pop()
+		if !ok {
+			select {
+			case <-queue.wake:
+				continue
+			case <-stop:
+				return
+			}
+		}
+
+		tw.writeQueued(conn, queue, request)
+	}
+}
+
+// drainSendQueue writes the requests that can be written now on the goroutine of the
+// caller, for WithManualReadLoop clients, which have no writer goroutine
+func (tw *ODINMarketFeedClient) drainSendQueue(conn *websocket.Conn, queue *sendQueue) {
+	tw.manualWriteMu.Lock()
+	defer tw.manualWriteMu.Unlock()
+
+	for {
+		request, ok := queue.pop()
+		if !ok {
+			return
+		}
+		tw.writeQueued(conn, queue, request)
+	}
+}
+
+// writeQueued writes a request taken from queue and sends the result on its channel
+func (tw *ODINMarketFeedClient) writeQueued(conn *websocket.Conn, queue *sendQueue, request writeRequest) {
+	if request.packet == nil {
+		request.done <- nil
+		return
+	}
+
+	tw.logf("Sending Message: %s", request.display)
+	tw.dumpFrame("OUT", request.packet, [][]byte{[]byte(request.display)})
+	err := conn.WriteMessage(websocket.BinaryMessage, request.packet)
+	if err == nil {
+		queue.wrote()
+		tw.transcribeRequest(request.display, time.Now())
+	}
+	request.done <- err
+}
+
+// sendPriority classifies a request for the writer queues
+func (tw *ODINMarketFeedClient) sendPriority(message string) sendPriority {
+	if controlMessageCodes[messageCode(message)] || atomic.LoadInt32(&tw.closing) != 0 {
+		return priorityControl
+	}
+	return priorityBulk
+}
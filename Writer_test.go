@@ -0,0 +1,63 @@
+package ODINMarketFeed
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestHeartbeatJumpsQueuedSubscriptions(t *testing.T) {
+	ms := newMockServer(t, nil)
+	tw := newTestClient()
+	ms.connect(t, tw)
+	defer tw.Close(context.Background())
+	ms.next(t, msgCodeLogin)
+
+	// Queue 1,000 subscriptions while the bulk queue is held back, as after a throttle notice
+	tw.mu.Lock()
+	queue := tw.sendQueue
+	tw.mu.Unlock()
+	queue.mu.Lock()
+	queue.pausedUntil = time.Now().Add(time.Hour)
+	queue.mu.Unlock()
+	const subscriptions = 1000
+	for i := 0; i < subscriptions; i++ {
+		message := tw.requestHeader(msgCodeTouchline) + fmt.Sprintf("49=1|200=0|1=1$7=%d|230=1", 1000+i)
+		if priority := tw.sendPriority(message); priority != priorityBulk {
+			t.Fatalf("subscription classified as %d, want bulk", priority)
+		}
+		packet, err := tw.fragHandler.FragmentData([]byte(message))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := queue.push(priorityBulk, writeRequest{packet: packet, display: message, done: make(chan error, 1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if stats := tw.Stats(); stats.BulkQueueDepth != subscriptions || stats.ControlQueueDepth != 0 {
+		t.Errorf("queue depths %d control and %d bulk, want %d bulk", stats.ControlQueueDepth, stats.BulkQueueDepth, subscriptions)
+	}
+
+	if err := tw.SendMessage(tw.heartbeatMessage()); err != nil {
+		t.Fatal(err)
+	}
+	queue.mu.Lock()
+	queue.pausedUntil = time.Time{}
+	queue.mu.Unlock()
+	queue.wake <- struct{}{}
+
+	codes := make([]int, 0, subscriptions+1)
+	timeout := time.After(10 * time.Second)
+	for len(codes) < subscriptions+1 {
+		select {
+		case request := <-ms.requests:
+			codes = append(codes, messageCode(request))
+		case <-timeout:
+			t.Fatalf("received %d of %d requests", len(codes), subscriptions+1)
+		}
+	}
+	if codes[0] != msgCodeHeartbeat {
+		t.Errorf("first request written has code %d, want the heartbeat ahead of the subscriptions", codes[0])
+	}
+}
@@ -59,7 +59,8 @@ func main() {
 
 	// Set up graceful shutdown
 	fmt.Println("\n✓ Client is now running. Press Ctrl+C to exit...")
-	fmt.Println("  Listening for market data...\n")
+	fmt.Println("  Listening for market data...")
+	fmt.Println()
 
 	// Subscribe to touchline data for specific tokens
 	// Format: "MarketSegmentID_Token"
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	ODINMarketFeed "github.com/SIPL-Dev/go-odinmarketfeedclient"
+)
+
+// loginMessageCode is the 64= code of the login request
+const loginMessageCode = 101
+
+// encryptCBC encrypts payload with AES-CBC and PKCS#7 padding, prepending the random IV
+func encryptCBC(block cipher.Block, payload []byte) ([]byte, error) {
+	padding := aes.BlockSize - len(payload)%aes.BlockSize
+	plain := append(append([]byte(nil), payload...), bytes.Repeat([]byte{byte(padding)}, padding)...)
+
+	out := make([]byte, aes.BlockSize+len(plain))
+	iv := out[:aes.BlockSize]
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(out[aes.BlockSize:], plain)
+	return out, nil
+}
+
+func main() {
+	// The key is exchanged with the broker out of band, hex encoded (16, 24 or 32 bytes)
+	key, err := hex.DecodeString(os.Getenv("ODIN_LOGIN_KEY"))
+	if err != nil {
+		log.Fatalf("Invalid ODIN_LOGIN_KEY: %v", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		log.Fatalf("Invalid ODIN_LOGIN_KEY: %v", err)
+	}
+
+	client := ODINMarketFeed.NewODINMarketFeedClient()
+
+	// Encrypt the login request only; every other request is sent as built
+	client.SetPayloadTransform(func(msgType int, payload []byte) ([]byte, error) {
+		if msgType != loginMessageCode {
+			return payload, nil
+		}
+		if len(payload) == 0 {
+			return nil, errors.New("empty login payload")
+		}
+		return encryptCBC(block, payload)
+	})
+
+	client.OnError = func(err string) {
+		log.Printf("❌ Error: %s\n", err)
+	}
+
+	// Configuration - Replace with your actual values
+	err = client.Connect("YOUR-SERVER-IP", 4509, false, "DEMO_TEST", "")
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+
+	err = client.SubscribeTouchlineWithOptions([]string{"1_22", "1_2885"}, ODINMarketFeed.TouchlineOptions{})
+	if err != nil {
+		log.Printf("Failed to subscribe to touchline: %v", err)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+
+	client.Disconnect()
+	client.Dispose()
+}
@@ -0,0 +1,44 @@
+//go:build go1.23
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	ODINMarketFeed "github.com/SIPL-Dev/go-odinmarketfeedclient"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	client := ODINMarketFeed.NewODINMarketFeedClient()
+	defer client.Dispose()
+
+	// Configuration - Replace with your actual values
+	if err := client.Connect("YOUR-SERVER-IP", 4509, false, "DEMO_TEST", ""); err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+	if err := client.SubscribeTouchlineWithOptions([]string{"1_22", "1_2885"}, ODINMarketFeed.TouchlineOptions{}); err != nil {
+		log.Printf("Failed to subscribe to touchline: %v", err)
+	}
+
+	// Print ticks until interrupted
+	for tick, err := range client.Ticks(ctx) {
+		if err != nil {
+			if !errors.Is(err, context.Canceled) {
+				log.Printf("Ticks stopped: %v", err)
+			}
+			break
+		}
+		fmt.Printf("%d_%d LTP=%.2f\n", tick.MktSegID, tick.Token, float64(tick.LTP)/float64(tick.DecimalLocator))
+	}
+
+	client.Disconnect()
+}
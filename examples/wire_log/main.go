@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	ODINMarketFeed "github.com/SIPL-Dev/go-odinmarketfeedclient"
+)
+
+func main() {
+	client := ODINMarketFeed.NewODINMarketFeedClient()
+
+	// Write every request and response to a timestamped wire log
+	wireLog, err := os.Create("wire.log")
+	if err != nil {
+		log.Fatalf("Failed to create wire log: %v", err)
+	}
+	defer wireLog.Close()
+
+	var logMu sync.Mutex
+	writeWire := func(direction string, message string) {
+		logMu.Lock()
+		defer logMu.Unlock()
+		fmt.Fprintf(wireLog, "%s %s %s\n", time.Now().Format("15:04:05.000000"), direction, message)
+	}
+
+	client.AddSendInterceptor(func(message string) string {
+		writeWire("OUT", message)
+		return message
+	})
+
+	client.AddReceiveInterceptor(func(raw []byte) {
+		writeWire("IN ", fmt.Sprintf("%q", raw))
+	})
+
+	client.OnError = func(err string) {
+		log.Printf("❌ Error: %s\n", err)
+	}
+
+	// Configuration - Replace with your actual values
+	err = client.Connect("YOUR-SERVER-IP", 4509, false, "DEMO_TEST", "")
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+
+	err = client.SubscribeTouchlineWithOptions([]string{"1_22", "1_2885"}, ODINMarketFeed.TouchlineOptions{})
+	if err != nil {
+		log.Printf("Failed to subscribe to touchline: %v", err)
+	}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	<-sigChan
+
+	client.Disconnect()
+	client.Dispose()
+}
@@ -0,0 +1,51 @@
+// Package health provides an HTTP health check handler for ODIN Market Feed clients.
+//
+//	mux.Handle("/healthz", health.Handler(client, ODINMarketFeed.HealthPolicy{
+//	    MaxSilence:    30 * time.Second,
+//	    RequireState:  true,
+//	    RequiredState: ODINMarketFeed.StateConnected,
+//	}))
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+
+	ODINMarketFeed "github.com/SIPL-Dev/go-odinmarketfeedclient"
+)
+
+// Checker is implemented by clients that can evaluate a health policy
+type Checker interface {
+	Healthy(policy ODINMarketFeed.HealthPolicy) (bool, []string)
+}
+
+// Response is the JSON body served by Handler. Client is the name of clients that have one
+// (see ODINMarketFeed.WithName).
+type Response struct {
+	Client   string   `json:"client,omitempty"`
+	Healthy  bool     `json:"healthy"`
+	Failures []string `json:"failures"`
+}
+
+// Handler serves 200 when the client satisfies the policy and 503 otherwise, with a JSON
+// body describing the failing criteria
+func Handler(client Checker, policy ODINMarketFeed.HealthPolicy) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		healthy, failures := client.Healthy(policy)
+		if failures == nil {
+			failures = []string{}
+		}
+		var name string
+		if named, ok := client.(interface{ Name() string }); ok {
+			name = named.Name()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if healthy {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(Response{Client: name, Healthy: healthy, Failures: failures})
+	})
+}
@@ -0,0 +1,96 @@
+// Package otel traces ODIN Market Feed clients with OpenTelemetry. It is a separate module
+// so that the client itself does not depend on OpenTelemetry.
+//
+//	client := ODINMarketFeed.NewODINMarketFeedClient(otel.WithTracerProvider(provider))
+//
+// The client traces Connect (span odin.connect with the dial, login and reconnect events)
+// and every subscription request (span odin.subscribe with its token count).
+package otel
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	ODINMarketFeed "github.com/SIPL-Dev/go-odinmarketfeedclient"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the spans
+const ScopeName = "github.com/SIPL-Dev/go-odinmarketfeedclient/otel"
+
+// WithTracerProvider traces the client with a tracer of provider
+func WithTracerProvider(provider trace.TracerProvider) ODINMarketFeed.Option {
+	return ODINMarketFeed.WithTracer(NewTracer(provider))
+}
+
+// NewTracer returns an ODINMarketFeed.Tracer that starts its spans with a tracer of provider
+func NewTracer(provider trace.TracerProvider) ODINMarketFeed.Tracer {
+	return tracer{provider.Tracer(ScopeName, trace.WithInstrumentationVersion(ODINMarketFeed.Version()))}
+}
+
+// tracer adapts an OpenTelemetry tracer
+type tracer struct {
+	tracer trace.Tracer
+}
+
+func (t tracer) Start(ctx context.Context, name string, attributes map[string]interface{}) (context.Context, ODINMarketFeed.Span) {
+	ctx, s := t.tracer.Start(ctx, name, trace.WithAttributes(keyValues(attributes)...))
+	return ctx, span{s}
+}
+
+// span adapts an OpenTelemetry span
+type span struct {
+	span trace.Span
+}
+
+func (s span) AddEvent(name string, attributes map[string]interface{}) {
+	s.span.AddEvent(name, trace.WithAttributes(keyValues(attributes)...))
+}
+
+// RecordError records err and marks the span as failed
+func (s span) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s span) End() {
+	s.span.End()
+}
+
+// keyValues converts the attributes of the client to OpenTelemetry attributes in key order.
+// Values of other types than string, bool and the numbers are formatted with fmt.Sprint.
+func keyValues(attributes map[string]interface{}) []attribute.KeyValue {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]attribute.KeyValue, len(keys))
+	for i, key := range keys {
+		switch value := attributes[key].(type) {
+		case string:
+			kvs[i] = attribute.String(key, value)
+		case bool:
+			kvs[i] = attribute.Bool(key, value)
+		case int:
+			kvs[i] = attribute.Int(key, value)
+		case int64:
+			kvs[i] = attribute.Int64(key, value)
+		case uint32:
+			kvs[i] = attribute.Int64(key, int64(value))
+		case float64:
+			kvs[i] = attribute.Float64(key, value)
+		default:
+			kvs[i] = attribute.String(key, fmt.Sprint(value))
+		}
+	}
+	return kvs
+}
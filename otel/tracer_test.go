@@ -0,0 +1,211 @@
+package otel
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ODINMarketFeed "github.com/SIPL-Dev/go-odinmarketfeedclient"
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// gateway accepts websocket connections and reads until they are dropped
+type gateway struct {
+	host  string
+	port  int
+	mu    sync.Mutex
+	conns []*websocket.Conn
+}
+
+func newGateway(t *testing.T) *gateway {
+	g := &gateway{}
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		g.mu.Lock()
+		g.conns = append(g.conns, ws)
+		g.mu.Unlock()
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				ws.Close()
+				return
+			}
+		}
+	}))
+	t.Cleanup(func() {
+		g.drop()
+		srv.Close()
+	})
+
+	host, port, _ := net.SplitHostPort(strings.TrimPrefix(srv.URL, "http://"))
+	g.host = host
+	g.port, _ = strconv.Atoi(port)
+	return g
+}
+
+// drop closes every connection without a close handshake
+func (g *gateway) drop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, ws := range g.conns {
+		ws.NetConn().Close()
+	}
+}
+
+func newTracedClient() (*ODINMarketFeed.ODINMarketFeedClient, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	client := ODINMarketFeed.NewODINMarketFeedClient(WithTracerProvider(provider), ODINMarketFeed.WithLogger(func(string) {}))
+	return client, exporter
+}
+
+// spansNamed returns the ended spans with the given name in the order they ended
+func spansNamed(exporter *tracetest.InMemoryExporter, name string) []tracetest.SpanStub {
+	var spans []tracetest.SpanStub
+	for _, s := range exporter.GetSpans() {
+		if s.Name == name {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+func attributeMap(kvs []attribute.KeyValue) map[string]string {
+	m := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		m[string(kv.Key)] = kv.Value.Emit()
+	}
+	return m
+}
+
+func eventNames(s tracetest.SpanStub) []string {
+	names := make([]string, len(s.Events))
+	for i, event := range s.Events {
+		names[i] = event.Name
+	}
+	return names
+}
+
+func TestConnectAndSubscribeSpans(t *testing.T) {
+	g := newGateway(t)
+	client, exporter := newTracedClient()
+	if err := client.Connect(g.host, g.port, false, "U", "k"); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close(context.Background())
+	if err := client.SubscribeTouchlineWithOptions([]string{"1_22", "1_23"}, ODINMarketFeed.TouchlineOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	connects := spansNamed(exporter, ODINMarketFeed.SpanConnect)
+	if len(connects) != 1 {
+		t.Fatalf("%d connect spans, want 1", len(connects))
+	}
+	connect := connects[0]
+	attributes := attributeMap(connect.Attributes)
+	want := map[string]string{"odin.host": g.host, "odin.port": strconv.Itoa(g.port), "odin.ssl": "false", "odin.user": "U"}
+	for key, value := range want {
+		if attributes[key] != value {
+			t.Errorf("connect attribute %s = %q, want %q", key, attributes[key], value)
+		}
+	}
+	if got := strings.Join(eventNames(connect), ","); got != "dial,login" {
+		t.Errorf("connect events %s, want dial,login", got)
+	}
+	if connect.InstrumentationScope.Name != ScopeName {
+		t.Errorf("instrumentation scope %q, want %q", connect.InstrumentationScope.Name, ScopeName)
+	}
+
+	subscribes := spansNamed(exporter, ODINMarketFeed.SpanSubscribe)
+	if len(subscribes) != 1 {
+		t.Fatalf("%d subscribe spans, want 1", len(subscribes))
+	}
+	if count := attributeMap(subscribes[0].Attributes)["odin.token_count"]; count != "2" {
+		t.Errorf("odin.token_count = %q, want 2", count)
+	}
+	if subscribes[0].Status.Code == codes.Error {
+		t.Errorf("subscribe span failed: %s", subscribes[0].Status.Description)
+	}
+}
+
+func TestReconnectEvent(t *testing.T) {
+	g := newGateway(t)
+	client, exporter := newTracedClient()
+	if err := client.Connect(g.host, g.port, false, "U", "k"); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close(context.Background())
+
+	g.drop()
+	deadline := time.Now().Add(5 * time.Second)
+	for client.State() != ODINMarketFeed.StateDisconnected {
+		if time.Now().After(deadline) {
+			t.Fatal("the dropped connection was not noticed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := client.Connect(g.host, g.port, false, "U", "k"); err != nil {
+		t.Fatal(err)
+	}
+
+	connects := spansNamed(exporter, ODINMarketFeed.SpanConnect)
+	if len(connects) != 2 {
+		t.Fatalf("%d connect spans, want 2", len(connects))
+	}
+	if names := eventNames(connects[0]); len(names) > 0 && names[0] == "reconnect" {
+		t.Error("the first connect span has a reconnect event")
+	}
+	reconnect := connects[1].Events[0]
+	if reconnect.Name != "reconnect" {
+		t.Fatalf("first event of the second connect span is %q, want reconnect", reconnect.Name)
+	}
+	if cause := attributeMap(reconnect.Attributes)["odin.cause"]; cause != "network error" {
+		t.Errorf("odin.cause = %q, want network error", cause)
+	}
+}
+
+func TestFailedConnectSpan(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	client, exporter := newTracedClient()
+	if err := client.Connect("127.0.0.1", port, false, "U", "k"); err == nil {
+		t.Fatal("Connect to a closed port succeeded")
+	}
+
+	connects := spansNamed(exporter, ODINMarketFeed.SpanConnect)
+	if len(connects) != 1 || connects[0].Status.Code != codes.Error {
+		t.Fatalf("connect spans %+v, want one failed span", connects)
+	}
+	if len(connects[0].Events) == 0 || connects[0].Events[len(connects[0].Events)-1].Name != "exception" {
+		t.Error("the connect error was not recorded")
+	}
+}
+
+func TestKeyValues(t *testing.T) {
+	kvs := keyValues(map[string]interface{}{"b": 2, "a": "x", "c": true, "d": []int{1}})
+	var got []string
+	for _, kv := range kvs {
+		got = append(got, string(kv.Key)+"="+kv.Value.Emit())
+	}
+	if strings.Join(got, " ") != "a=x b=2 c=true d=[1]" {
+		t.Errorf("keyValues = %v", got)
+	}
+}